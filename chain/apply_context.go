@@ -40,7 +40,7 @@ type ApplyContext struct {
 	InlineActions        []types.Action
 	CfaInlineActions     []types.Action
 	PendingConsoleOutput string
-	AccountRamDeltas     types.FlatSet
+	AccountRamDeltas     []types.AccountDelta
 }
 
 func NewApplyContext(control *Controller, trxContext *TransactionContext, act *types.Action, recurseDepth uint32) *ApplyContext {
@@ -205,7 +205,7 @@ func (a *ApplyContext) execOne() (trace types.ActionTrace) {
 			a.Control.CheckActionList(a.Act.Account, a.Act.Name)
 		}
 		//try
-		a.Control.GetWasmInterface().Apply(&action.CodeVersion, action.Code, a)
+		a.Control.GetWasmInterface().Apply(&action.CodeVersion, action.VmType, action.VmVersion, action.Code, a)
 		//}catch(const wasm_exit&){}
 	}
 
@@ -887,6 +887,9 @@ func (a *ApplyContext) TaposBlockPrefix() int { return int(a.TrxContext.Trx.RefB
 
 //context system api
 func (a *ApplyContext) CheckTime() {
+	if a.TrxContext == nil { // not applied in a transaction, there is no deadline
+		return
+	}
 	a.TrxContext.CheckTime()
 }
 func (a *ApplyContext) CurrentTime() int64 {
@@ -184,7 +184,7 @@ func (a *AuthorizationManager) LookupMinimumPermission(authorizerAccount common.
 	return
 }
 
-func (a *AuthorizationManager) CheckUpdateauthAuthorization(update updateAuth, auths []types.PermissionLevel) {
+func (a *AuthorizationManager) CheckUpdateauthAuthorization(update UpdateAuth, auths []types.PermissionLevel) {
 	EosAssert(len(auths) == 1, &IrrelevantAuthException{}, "updateauth action should only have one declared authorization")
 	auth := auths[0]
 	EosAssert(auth.Actor == update.Account, &IrrelevantAuthException{}, "the owner of the affected permission needs to be the actor of the declared authorization")
@@ -196,7 +196,7 @@ func (a *AuthorizationManager) CheckUpdateauthAuthorization(update updateAuth, a
 	EosAssert(a.GetPermission(&auth).Satisfies(*minPermission), &IrrelevantAuthException{}, "") //TODO
 }
 
-func (a *AuthorizationManager) CheckDeleteauthAuthorization(del deleteAuth, auths []types.PermissionLevel) {
+func (a *AuthorizationManager) CheckDeleteauthAuthorization(del DeleteAuth, auths []types.PermissionLevel) {
 	EosAssert(len(auths) == 1, &IrrelevantAuthException{}, "deleteauth action should only have one declared authorization")
 	auth := auths[0]
 	EosAssert(auth.Actor == del.Account, &IrrelevantAuthException{}, "the owner of the affected permission needs to be the actor of the declared authorization")
@@ -209,8 +209,8 @@ func (a *AuthorizationManager) CheckLinkauthAuthorization(link linkAuth, auths [
 	auth := auths[0]
 	EosAssert(auth.Actor == link.Account, &IrrelevantAuthException{}, "the owner of the affected permission needs to be the actor of the declared authorization")
 
-	EosAssert(link.Type != updateAuth{}.getName(), &ActionValidateException{}, "Cannot link eosio::updateauth to a minimum permission")
-	EosAssert(link.Type != deleteAuth{}.getName(), &ActionValidateException{}, "Cannot link eosio::deleteauth to a minimum permission")
+	EosAssert(link.Type != UpdateAuth{}.getName(), &ActionValidateException{}, "Cannot link eosio::updateauth to a minimum permission")
+	EosAssert(link.Type != DeleteAuth{}.getName(), &ActionValidateException{}, "Cannot link eosio::deleteauth to a minimum permission")
 	EosAssert(link.Type != linkAuth{}.getName(), &ActionValidateException{}, "Cannot link eosio::linkauth to a minimum permission")
 	EosAssert(link.Type != unlinkAuth{}.getName(), &ActionValidateException{}, "Cannot link eosio::unlinkauth to a minimum permission")
 	EosAssert(link.Type != cancelDelay{}.getName(), &ActionValidateException{}, "Cannot link eosio::canceldelay to a minimum permission")
@@ -306,13 +306,13 @@ func (a *AuthorizationManager) CheckAuthorization(actions []*types.Action,
 		if act.Account == common.DefaultConfig.SystemAccountName {
 			specialCase = true
 			switch act.Name{
-			case updateAuth{}.getName():
-				updateAuth := updateAuth{}
+			case UpdateAuth{}.getName():
+				updateAuth := UpdateAuth{}
 				rlp.DecodeBytes(act.Data, &updateAuth)
 				a.CheckUpdateauthAuthorization(updateAuth, act.Authorization)
 
-			case deleteAuth{}.getName():
-				deleteAuth := deleteAuth{}
+			case DeleteAuth{}.getName():
+				deleteAuth := DeleteAuth{}
 				rlp.DecodeBytes(act.Data, &deleteAuth)
 				a.CheckDeleteauthAuthorization(deleteAuth, act.Authorization)
 
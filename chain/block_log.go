@@ -1,12 +1,20 @@
 package chain
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
 	"github.com/eosspark/eos-go/crypto/rlp"
 	"github.com/eosspark/eos-go/exception"
-	"math"
-	"os"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/eosspark/eos-go/log"
 )
 
 type BlockLog struct {
@@ -111,7 +119,9 @@ func NewBlockLog(dataDir string) *BlockLog {
 
 		blockLog.genesisWriteToBlockLog = true
 		blockLog.head = blockLog.ReadHead()
-		blockLog.headId = blockLog.head.BlockID()
+		if blockLog.head != nil {
+			blockLog.headId = blockLog.head.BlockID()
+		}
 
 		if indexSize > 0 {
 			var blockPos int64 = 0
@@ -174,6 +184,11 @@ func (b *BlockLog) Append(block *types.SignedBlock) uint64 {
 	return uint64(pos)
 }
 func (b *BlockLog) flush() {
+	b.blockStream.Sync()
+	b.indexStream.Sync()
+}
+
+func (b *BlockLog) Close() {
 	b.blockStream.Close()
 	b.indexStream.Close()
 }
@@ -267,11 +282,13 @@ func (b *BlockLog) GetBlockPos(blockNum uint32) uint64 {
 
 	return pos //, nextPos - pos
 }
-func (b *BlockLog) ReadHead() *types.SignedBlock {
 
+// ReadHead returns the last block of the log, nil when it holds no blocks
+func (b *BlockLog) ReadHead() *types.SignedBlock {
+	first, err := blockLogFirstEntry(b.blockStream)
 	s, _ := b.blockStream.Seek(0, 2)
-	if s == 0 {
-		return &types.SignedBlock{}
+	if err != nil || s <= first {
+		return nil
 	}
 
 	var pos uint64
@@ -295,42 +312,195 @@ func (b *BlockLog) ConstructIndex() {
 	b.indexStream.Close()
 	b.indexStream, _ = os.OpenFile(b.indexFile, os.O_RDWR, os.ModePerm)
 
-	var gsSize uint32
-	b.blockStream.Seek(4, 0)
-	gsSizeBytes := make([]byte, 4)
-	b.blockStream.Read(gsSizeBytes)
-	rlp.DecodeBytes(gsSizeBytes, &gsSize)
-	pos, _ := b.blockStream.Seek(int64(gsSize), 1)
-
-	bytes, _ := rlp.EncodeToBytes(pos)
-
-	if pos == 0 {
+	pos, err := blockLogFirstEntry(b.blockStream)
+	if err != nil {
 		return
 	}
+	end, _ := b.blockStream.Seek(0, 2)
 
-	for pos > 0 {
+	for pos < end {
+		size, err := readBlockLogSize(b.blockStream, pos)
+		if err != nil {
+			break
+		}
+		bytes, _ := rlp.EncodeToBytes(uint64(pos))
 		b.indexStream.Write(bytes)
+		pos += 4 + int64(size) + 8
+	}
+}
+
+// block log layout: the version, the size of the genesis state and the genesis state, followed by
+// an entry for every block holding the size of the packed block, the packed block and a trailer
+// with the position the entry starts at
+
+func blockLogFirstEntry(f *os.File) (int64, error) {
+	bytes := make([]byte, 8)
+	if _, err := f.ReadAt(bytes, 0); err != nil {
+		return 0, err
+	}
+	var version, gsSize uint32
+	rlp.DecodeBytes(bytes[:4], &version)
+	rlp.DecodeBytes(bytes[4:], &gsSize)
+	if version == 0 {
+		return 0, errors.New("block log was not setup properly with genesis information")
+	}
+	return 8 + int64(gsSize), nil
+}
+
+func readBlockLogSize(f *os.File, pos int64) (uint32, error) {
+	bytes := make([]byte, 4)
+	if _, err := f.ReadAt(bytes, pos); err != nil {
+		return 0, err
+	}
+	var size uint32
+	err := rlp.DecodeBytes(bytes, &size)
+	return size, err
+}
+
+// readBlockLogEntry reads the entry at pos and checks that its trailer points back at it, the
+// whole entry is returned so that it can be copied as it is
+func readBlockLogEntry(f *os.File, pos int64) (block *types.SignedBlock, entry []byte, err error) {
+	size, err := readBlockLogSize(f, pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if pos+4+int64(size)+8 > info.Size() {
+		return nil, nil, fmt.Errorf("entry of %d bytes at %d runs past the end of the block log", size, pos)
+	}
 
-		var size uint32
-		sizeBytes := make([]byte, 4)
-		b.blockStream.Read(sizeBytes)
-		rlp.DecodeBytes(sizeBytes, &size)
-		if size == 0 {
+	entry = make([]byte, 4+int(size)+8)
+	if _, err = f.ReadAt(entry, pos); err != nil {
+		return nil, nil, err
+	}
+	var trailer uint64
+	rlp.DecodeBytes(entry[4+size:], &trailer)
+	if int64(trailer) != pos {
+		return nil, nil, fmt.Errorf("trailer of entry at %d points at %d", pos, trailer)
+	}
+
+	block = &types.SignedBlock{}
+	try.Try(func() {
+		err = rlp.DecodeBytes(entry[4:4+size], block)
+	}).Catch(func(e interface{}) {
+		err = fmt.Errorf("%v", e)
+	}).End()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to unpack block at %d: %s", pos, err)
+	}
+	return block, entry, nil
+}
+
+// copyBlockLog writes the genesis state and the blocks of the block log file src, up to
+// truncateAtBlock when it is not 0, into a new block log in dstDir. Copying stops at the first
+// block that can not be read back or does not link to the block before it, the position of that
+// entry is returned as badPos, -1 when every block was good
+func copyBlockLog(src string, dstDir string, truncateAtBlock uint32) (lastBlock uint32, badPos int64) {
+	in, err := os.Open(src)
+	exception.EosAssert(err == nil, &exception.BlockLogNotFound{}, "unable to open block log %s: %s", src, err)
+	defer in.Close()
+
+	first, err := blockLogFirstEntry(in)
+	exception.EosAssert(err == nil, &exception.BlockLogException{}, "block log %s has no genesis state: %s", src, err)
+	header := make([]byte, first)
+	_, err = in.ReadAt(header, 0)
+	exception.EosAssert(err == nil, &exception.BlockLogException{}, "block log %s has no genesis state: %s", src, err)
+	var version uint32
+	rlp.DecodeBytes(header[:4], &version)
+	exception.EosAssert(version == 1, &exception.BlockLogUnsupportedVersion{},
+		"Unsupported version of block log. Block log version is %d while code supports version %d", version, 1)
+
+	err = os.MkdirAll(dstDir, os.ModePerm)
+	exception.EosAssert(err == nil, &exception.BlockLogException{}, "unable to create blocks directory %s: %s", dstDir, err)
+	out, err := os.Create(filepath.Join(dstDir, "blocks.log"))
+	exception.EosAssert(err == nil, &exception.BlockLogException{}, "unable to create block log in %s: %s", dstDir, err)
+	out.Write(header)
+
+	info, _ := in.Stat()
+	var previous common.BlockIdType
+	badPos = -1
+	for pos := first; pos < info.Size(); {
+		if truncateAtBlock > 0 && lastBlock >= truncateAtBlock {
 			break
 		}
-
-		pos, _ = b.blockStream.Seek(int64(size), 1)
-		if pos == 0 {
+		block, entry, err := readBlockLogEntry(in, pos)
+		if err == nil && block.BlockNumber() != lastBlock+1 {
+			err = fmt.Errorf("block %d at %d follows block %d", block.BlockNumber(), pos, lastBlock)
+		}
+		if err == nil && lastBlock > 0 && block.Previous != previous {
+			err = fmt.Errorf("block %d at %d does not link to block %d", block.BlockNumber(), pos, lastBlock)
+		}
+		if err != nil {
+			log.Warn(fmt.Sprintf("Recovery of block log stopped: %s", err))
+			badPos = pos
 			break
 		}
+		// entries keep their positions because the header is copied as it is
+		out.Write(entry)
+		lastBlock = block.BlockNumber()
+		previous = block.BlockID()
+		pos += int64(len(entry))
+	}
+	out.Close()
 
-		pos += 8 //8 bytes pos
-		bytes, _ = rlp.EncodeToBytes(pos)
+	// opening the new log builds its index
+	NewBlockLog(dstDir).Close()
+	return lastBlock, badPos
+}
+
+// RepairLog moves the blocks directory dataDir to a backup directory and writes a new block log
+// holding every block of the old one up to the first corrupt block, or up to truncateAtBlock
+// when it is not 0. An unreadable tail of the old log is saved as blocks-bad-tail.log in the
+// backup directory, which is returned
+func RepairLog(dataDir string, truncateAtBlock uint32) string {
+	dataDir = filepath.Clean(dataDir)
+	_, err := os.Stat(filepath.Join(dataDir, "blocks.log"))
+	exception.EosAssert(err == nil, &exception.BlockLogNotFound{}, "Block log not found in '%s'", dataDir)
+
+	backupDir := dataDir + "-" + time.Now().Format("20060102T150405.000")
+	_, err = os.Stat(backupDir)
+	exception.EosAssert(os.IsNotExist(err), &exception.BlockLogBackupDirExist{},
+		"Cannot move existing blocks directory to already existing directory '%s'", backupDir)
+	err = os.Rename(dataDir, backupDir)
+	exception.EosAssert(err == nil, &exception.BlockLogException{}, "unable to move blocks directory to '%s': %s", backupDir, err)
+	log.Info(fmt.Sprintf("Moved existing blocks directory to backup location: '%s'", backupDir))
+
+	oldLog := filepath.Join(backupDir, "blocks.log")
+	lastBlock, badPos := copyBlockLog(oldLog, dataDir, truncateAtBlock)
+
+	if badPos >= 0 {
+		in, err := os.Open(oldLog)
+		if err == nil {
+			tail, _ := os.Create(filepath.Join(backupDir, "blocks-bad-tail.log"))
+			in.Seek(badPos, 0)
+			io.Copy(tail, in)
+			tail.Close()
+			in.Close()
+		}
+		log.Info(fmt.Sprintf("Data at tail end of block log which should contain the (incomplete) serialization of block %d "+
+			"has been written out to '%s'", lastBlock+1, filepath.Join(backupDir, "blocks-bad-tail.log")))
 	}
+	if truncateAtBlock > 0 && lastBlock == truncateAtBlock {
+		log.Info(fmt.Sprintf("Stopped recovery of block log early at specified block number: %d", truncateAtBlock))
+	}
+	log.Info(fmt.Sprintf("Existing block log was undamaged up to block %d. Recovered all irreversible blocks up to block %d.", lastBlock, lastBlock))
+	return backupDir
+}
 
-	return
+// TrimLog writes the genesis state and the blocks 1 to lastBlock of the block log in dataDir to a
+// new block log in outDir and returns the number of the last block written
+func TrimLog(dataDir string, outDir string, lastBlock uint32) uint32 {
+	exception.EosAssert(filepath.Clean(dataDir) != filepath.Clean(outDir), &exception.BlockLogException{},
+		"trimmed block log can not be written over the block log it is read from")
+	last, badPos := copyBlockLog(filepath.Join(dataDir, "blocks.log"), outDir, lastBlock)
+	exception.EosAssert(badPos < 0, &exception.BlockLogException{},
+		"block log in %s is corrupted after block %d, repair it first", dataDir, last)
+	return last
 }
-func (b *BlockLog) repairLog(dataDir string, truncateAtBlock uint32) string { return "" }
+
 func (b *BlockLog) ExtractGenesisState(dataDir string) types.GenesisState {
 
 	blockStream, _ := os.OpenFile(dataDir+"/blocks.log", os.O_RDWR, os.ModePerm)
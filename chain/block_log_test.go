@@ -5,8 +5,12 @@ import (
 	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
 	"github.com/eosspark/eos-go/crypto/rlp"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -49,3 +53,107 @@ func TestBlockTest(t *testing.T) {
 	})
 
 }
+
+func newTestBlockLog(t *testing.T, blocks int) (string, []*types.SignedBlock) {
+	root, err := ioutil.TempDir("", "block_log")
+	assert.NoError(t, err)
+	dataDir := filepath.Join(root, "blocks")
+
+	signedBlocks := make([]*types.SignedBlock, 0, blocks)
+	block := &types.SignedBlock{}
+	block.Timestamp = common.BlockTimeStamp(1)
+	for i := 0; i < blocks; i++ {
+		signedBlocks = append(signedBlocks, block)
+		next := &types.SignedBlock{}
+		next.Previous = block.BlockID()
+		next.Timestamp = block.Timestamp + 1
+		block = next
+	}
+
+	blockLog := NewBlockLog(dataDir)
+	blockLog.ResetToGenesis(&types.GenesisState{EosioRootKey: "i'm root key"}, signedBlocks[0])
+	for _, b := range signedBlocks[1:] {
+		blockLog.Append(b)
+	}
+	blockLog.Close()
+	return dataDir, signedBlocks
+}
+
+func blockLogHead(dataDir string) uint32 {
+	blockLog := NewBlockLog(dataDir)
+	defer blockLog.Close()
+	if blockLog.Head() == nil {
+		return 0
+	}
+	return blockLog.Head().BlockNumber()
+}
+
+func TestBlockLog_RepairLog(t *testing.T) {
+	dataDir, blocks := newTestBlockLog(t, 10)
+	defer os.RemoveAll(filepath.Dir(dataDir))
+	assert.Equal(t, uint32(10), blockLogHead(dataDir))
+
+	// a crash while appending leaves half an entry behind
+	logFile, err := os.OpenFile(filepath.Join(dataDir, "blocks.log"), os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	logFile.Write([]byte{0xff, 0x01, 0x00, 0x00, 0x01, 0x02})
+	logFile.Close()
+
+	backupDir := RepairLog(dataDir, 0)
+	tail, err := ioutil.ReadFile(filepath.Join(backupDir, "blocks-bad-tail.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(tail))
+
+	blockLog := NewBlockLog(dataDir)
+	assert.Equal(t, blocks[9].BlockID(), blockLog.Head().BlockID())
+	assert.Equal(t, blocks[4].BlockID(), blockLog.ReadBlockByNum(5).BlockID())
+	blockLog.Close()
+
+	// a bad trailer in the middle of the log drops that block and everything after it
+	blockLog = NewBlockLog(dataDir)
+	pos := blockLog.GetBlockPos(8)
+	blockLog.Close()
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "blocks.log"))
+	assert.NoError(t, err)
+	logFile, err = os.Open(filepath.Join(dataDir, "blocks.log"))
+	assert.NoError(t, err)
+	size, err := readBlockLogSize(logFile, int64(pos))
+	assert.NoError(t, err)
+	logFile.Close()
+	data[int(pos)+4+int(size)] ^= 0xff
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dataDir, "blocks.log"), data, 0644))
+
+	RepairLog(dataDir, 0)
+	assert.Equal(t, uint32(7), blockLogHead(dataDir))
+
+	RepairLog(dataDir, 4)
+	assert.Equal(t, uint32(4), blockLogHead(dataDir))
+
+	gs := NewBlockLog(dataDir).ExtractGenesisState(dataDir)
+	assert.Equal(t, "i'm root key", gs.EosioRootKey)
+}
+
+func TestBlockLog_TrimLogAndIndex(t *testing.T) {
+	dataDir, blocks := newTestBlockLog(t, 10)
+	defer os.RemoveAll(filepath.Dir(dataDir))
+
+	outDir := filepath.Join(filepath.Dir(dataDir), "trimmed")
+	assert.Equal(t, uint32(6), TrimLog(dataDir, outDir, 6))
+	assert.Equal(t, uint32(6), blockLogHead(outDir))
+	assert.Equal(t, uint32(10), blockLogHead(dataDir))
+
+	returned := false
+	try.Try(func() {
+		TrimLog(dataDir, dataDir, 6)
+	}).Catch(func(e *BlockLogException) {
+		returned = true
+	}).End()
+	assert.True(t, returned)
+
+	assert.NoError(t, os.Remove(filepath.Join(dataDir, "blocks.index")))
+	blockLog := NewBlockLog(dataDir)
+	defer blockLog.Close()
+	for _, b := range blocks {
+		assert.Equal(t, b.BlockID(), blockLog.ReadBlockByNum(b.BlockNumber()).BlockID())
+	}
+}
@@ -3,6 +3,8 @@ package chain
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/eosspark/eos-go/chain/types"
@@ -13,6 +15,7 @@ import (
 	"github.com/eosspark/eos-go/database"
 	"github.com/eosspark/eos-go/entity"
 	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
 	"github.com/eosspark/eos-go/log"
 	"github.com/eosspark/eos-go/wasmgo"
 )
@@ -37,6 +40,9 @@ const (
 	IRREVERSIBLE
 )
 
+// ReplayProgressInterval is the number of blocks between two progress reports while the block log is replayed
+var ReplayProgressInterval uint32 = 100
+
 type ValidationMode int8
 
 const (
@@ -80,7 +86,6 @@ type v func(ctx *ApplyContext)
 //type HandlerKey common.Tuple
 type Controller struct {
 	DB                             database.DataBase
-	DbSession                      database.Session
 	ReversibleBlocks               database.DataBase
 	Blog                           *BlockLog
 	Pending                        *types.PendingState
@@ -99,6 +104,15 @@ type Controller struct {
 	TrustedProducerLightValidation bool                //default value false
 	ApplyHandlers                  map[common.AccountName]map[HandlerKey]v
 	UnAppliedTransactions          map[crypto.Sha256]types.TransactionMetadata
+	skipBlockLog                   bool //the node started from a snapshot without the blocks before it
+
+	PreAcceptedBlock     SignedBlockSignal
+	AcceptedBlockHeader  BlockStateSignal
+	AcceptedBlock        BlockStateSignal
+	IrreversibleBlock    BlockStateSignal
+	AcceptedTransaction  TransactionMetadataSignal
+	AppliedTransaction   TransactionTraceSignal
+	AcceptedConfirmation HeaderConfirmationSignal
 }
 
 func GetControllerInstance() *Controller {
@@ -127,6 +141,18 @@ func validPath() {
 		}
 	}
 }
+// StateHistoryBlocks is the number of irreversible blocks the state database keeps the changes of, so that
+// StateViewAt reaches back to them. It is set by the --state-history-blocks option before the controller is created
+var StateHistoryBlocks uint32
+
+// WasmCodeCacheSize is the number of compiled contracts the wasm interface keeps, it is set by the
+// --wasm-code-cache-size option before the controller is created
+var WasmCodeCacheSize uint32 = wasmgo.DefaultCodeCacheSize
+
+// WasmInstructionLimit is the number of wasm instructions an action may execute, 0 leaves them unmetered and only
+// the deadline stops the action. It is set by the --wasm-instruction-limit option before the controller is created
+var WasmInstructionLimit uint64
+
 func newController() *Controller {
 	isActiveController = true //controller is active
 	//init db
@@ -144,18 +170,23 @@ func newController() *Controller {
 		fmt.Println("newController init reversibleDB is error", err)
 	}
 	con := &Controller{InTrxRequiringChecks: false, RePlaying: false, TrustedProducerLightValidation: false}
+	registerDatabaseTypes()
+	db.SetHistory(int64(StateHistoryBlocks))
 	con.DB = db
 	con.ReversibleBlocks = reversibleDB
 
 	con.Blog = NewBlockLog(common.DefaultConfig.DefaultBlocksDirName)
 
-	con.ForkDB = types.GetForkDbInstance(common.DefaultConfig.DefaultBlocksDirName)
+	con.ForkDB = types.NewForkDatabase(common.DefaultConfig.DefaultBlocksDirName)
+	con.ForkDB.Irreversible = con.OnIrreversible
 	con.ChainID = types.GetGenesisStateInstance().ComputeChainID()
 
 	con.initConfig()
 	con.ReadMode = con.Config.readMode
 	con.ApplyHandlers = make(map[common.AccountName]map[HandlerKey]v)
 	con.WasmIf = wasmgo.NewWasmGo()
+	con.WasmIf.SetCodeCacheSize(int(WasmCodeCacheSize))
+	con.WasmIf.SetInstructionLimit(WasmInstructionLimit)
 
 	con.SetApplayHandler(common.AccountName(common.N("eosio")), common.AccountName(common.N("eosio")),
 		common.ActionName(common.N("eosio")), applyEosioNewaccount)
@@ -178,6 +209,7 @@ func newController() *Controller {
 	//readycontroller = make(chan bool)
 	//go initResource(con, readycontroller)
 	con.Pending = &types.PendingState{}
+	con.UnAppliedTransactions = make(map[crypto.Sha256]types.TransactionMetadata)
 	con.ResourceLimists = newResourceLimitsManager(con)
 	con.Authorization = newAuthorizationManager(con)
 	con.initialize()
@@ -218,9 +250,67 @@ func capitalize(str string) string {
 	return upperStr
 }
 
-//TODO wait append block_log
-func (c *Controller) OnIrreversible(b *types.BlockState) {
+// OnIrreversible is called by the fork database for every block that becomes irreversible, the
+// block is moved from the reversible blocks into the block log and its undo state is committed
+func (c *Controller) OnIrreversible(s *types.BlockState) {
+	logHead := c.Blog.Head()
+	appendToBlog := false
+	if logHead == nil {
+		// a node started from a snapshot has none of the blocks before it in the block log
+		EosAssert(s.BlockNum == 1 || c.skipBlockLog, &BlockLogException{},
+			"block log has no blocks and is appending the wrong first block, expected 1, but received: %d", s.BlockNum)
+		appendToBlog = !c.skipBlockLog
+	} else if lhBlockNum := logHead.BlockNumber(); s.BlockNum > lhBlockNum {
+		EosAssert(s.BlockNum-1 == lhBlockNum, &UnlinkableBlockException{},
+			"unlinkable block, block num: %d, log head block num: %d", s.BlockNum, lhBlockNum)
+		EosAssert(s.Header.Previous == logHead.BlockID(), &UnlinkableBlockException{},
+			"irreversible doesn't link to block log head")
+		appendToBlog = true
+	}
+
+	err := c.DB.Commit(int64(s.BlockNum))
+	EosAssert(err == nil, &DatabaseException{}, "commit of the state database at block %d failed: %s", s.BlockNum, err)
+	if appendToBlog {
+		c.Blog.Append(s.SignedBlock)
+	}
+
+	c.removeReversibleBlocks(s.BlockNum)
+
+	if c.ReadMode == IRREVERSIBLE {
+		c.applyBlock(s.SignedBlock, types.Complete)
+		c.ForkDB.MarkInCurrentChain(s, true)
+		c.ForkDB.SetValidity(s, true)
+		c.Head = s
+	}
+	c.IrreversibleBlock.Emit(s)
+}
+
+// removeReversibleBlocks drops every reversible block up to and including blockNum
+func (c *Controller) removeReversibleBlocks(blockNum uint32) {
+	idx, err := c.ReversibleBlocks.GetIndex("byNum", entity.ReversibleBlockObject{})
+	if err != nil {
+		log.Warn("get reversible block index is error,detail:", err)
+		return
+	}
+	it, err := idx.LowerBound(entity.ReversibleBlockObject{BlockNum: 1})
+	if err != nil {
+		return
+	}
+	objs := make([]entity.ReversibleBlockObject, 0)
+	for it.Next() {
+		obj := entity.ReversibleBlockObject{}
+		if it.Data(&obj) != nil || obj.BlockNum > blockNum {
+			break
+		}
+		objs = append(objs, obj)
+	}
+	it.Release()
 
+	for i := range objs {
+		if err := c.ReversibleBlocks.Remove(&objs[i]); err != nil {
+			log.Warn("remove reversible block is error,detail:", err)
+		}
+	}
 }
 
 func (c *Controller) PopBlock() {
@@ -242,7 +332,8 @@ func (c *Controller) PopBlock() {
 		}
 	}
 	c.Head = prev
-	c.DbSession.Undo() //TODO
+	err := c.DB.Undo() // the session of the popped block is the last one on the undo stack
+	EosAssert(err == nil, &DatabaseException{}, "undo of the state database at block %d failed: %s", prev.BlockNum+1, err)
 }
 
 func (c *Controller) SetApplayHandler(receiver common.AccountName, contract common.AccountName, action common.ActionName, handler func(a *ApplyContext)) {
@@ -254,8 +345,9 @@ func (c *Controller) SetApplayHandler(receiver common.AccountName, contract comm
 	c.ApplyHandlers[receiver] = second
 }
 
+// AbortBlock drops the pending block and undoes its changes, its transactions are applied again to the next block
 func (c *Controller) AbortBlock() {
-	if common.Empty(c.Pending) {
+	if c.Pending != nil && c.Pending.Valid {
 		if c.ReadMode == SPECULATIVE {
 			trx := append(c.Pending.PendingBlockState.Trxs)
 			step := 0
@@ -263,27 +355,36 @@ func (c *Controller) AbortBlock() {
 				c.UnAppliedTransactions[crypto.Sha256(trx[step].SignedID)] = *trx[step]
 			}
 		}
+		err := c.Pending.Reset()
+		EosAssert(err == nil, &DatabaseException{}, "undo of the pending block failed: %s", err)
+	}
+	if c.Pending != nil {
+		c.Pending.PendingBlockState = nil
 	}
 }
 func (c *Controller) StartBlock(when common.BlockTimeStamp, confirmBlockCount uint16) {
+	c.ValidateDbAvailableSize()
 	pbi := common.BlockIdType(*crypto.NewSha256Nil())
 	c.startBlock1(when, confirmBlockCount, types.Incomplete, &pbi)
-	//c.VValidateDbAvailableSize()
 }
 func (c *Controller) startBlock1(when common.BlockTimeStamp, confirmBlockCount uint16, s types.BlockStatus, producerBlockId *common.BlockIdType) {
 	//fmt.Println(c.Config)
-	EosAssert(nil != c.Pending, &BlockValidateException{}, "pending block already exists")
+	EosAssert(c.Pending == nil || !c.Pending.Valid, &BlockValidateException{}, "pending block already exists")
 	defer func() {
-		if c.Pending.Valid {
-			c.Pending.Reset()
+		if !c.Pending.Valid { // the block failed to start, its changes are undone
+			if err := c.Pending.Reset(); err != nil {
+				log.Error("startBlock reset pending is error,detail:", err)
+			}
 		}
 	}()
-	if c.SkipDbSession(s) {
-		/*EosAssert( c.DB.revision() == head->block_num, database_exception, "db revision is not on par with head block",
-		("db.revision()", db.revision())("controller_head_block", head->block_num)("fork_db_head_block", fork_db.head()->block_num) )*/
-		c.Pending = types.NewPendingState(c.DB)
+	if !c.SkipDbSession(s) {
+		EosAssert(c.DB.Revision() == int64(c.Head.BlockNum), &DatabaseException{},
+			"db revision is not on par with head block, db revision: %d, controller head block: %d", c.DB.Revision(), c.Head.BlockNum)
+		session, err := c.DB.StartSession()
+		EosAssert(err == nil, &DatabaseException{}, "start of the state database session failed: %s", err)
+		c.Pending = types.NewPendingState(session)
 	} else {
-		c.Pending = types.GetInstance()
+		c.Pending = types.NewPendingState(nil)
 	}
 
 	c.Pending.BlockStatus = s
@@ -359,6 +460,7 @@ func (c *Controller) PushReceipt(trx interface{}, status types.BlockStatus, cpuU
 	return &trxReceipt
 }
 func (c *Controller) PushTransaction(trx types.TransactionMetadata, deadLine common.TimePoint, billedCpuTimeUs uint32, explicitBilledCpuTime bool) (trxTrace *types.TransactionTrace) {
+	c.ValidateDbAvailableSize()
 	EosAssert(deadLine != common.TimePoint(0), &TransactionException{}, "deadline cannot be uninitialized")
 
 	trxContext := *NewTransactionContext(c, trx.Trx, trx.ID, common.Now())
@@ -388,7 +490,7 @@ func (c *Controller) PushTransaction(trx types.TransactionMetadata, deadLine com
 	trxContext.Delay = common.Microseconds(trx.Trx.DelaySec)
 	if !c.SkipAuthCheck() && !trx.Implicit {
 		c.Authorization.CheckAuthorization(trx.Trx.Actions,
-			trx.RecoverKeys(c.ChainID),
+			trx.RecoverKeys(&c.ChainID),
 			nil,
 			trxContext.Delay,
 			nil,
@@ -418,10 +520,10 @@ func (c *Controller) PushTransaction(trx types.TransactionMetadata, deadLine com
 	//fc::move_append(pending->_actions, move(trx_context.executed))
 	if !trx.Accepted {
 		trx.Accepted = true
-		//emit( c.accepted_transaction, trx)
+		c.AcceptedTransaction.Emit(&trx)
 	}
 
-	//emit(c.applied_transaction, trace)
+	c.AppliedTransaction.Emit(trace)
 	if c.ReadMode != SPECULATIVE && c.Pending.BlockStatus == types.Incomplete {
 		trxContext.Undo()
 	} else {
@@ -484,16 +586,15 @@ func (c *Controller) GetOnBlockTransaction() types.SignedTransaction {
 	}
 	trx := types.SignedTransaction{}
 	trx.Actions = append(trx.Actions, &onBlockAction)
-	trx.SetReferenceBlock(c.Head.BlockId)
+	trx.SetReferenceBlock(&c.Head.BlockId)
 	in := c.Pending.PendingBlockState.Header.Timestamp + 999999
 	trx.Expiration = common.TimePointSec(in)
 	log.Error("getOnBlockTransaction trx.Expiration:", trx)
 	return trx
 }
 func (c *Controller) SkipDbSession(bs types.BlockStatus) bool {
-	considerSkipping := (bs == types.BlockStatus(IRREVERSIBLE))
-	//log.Info("considerSkipping:", considerSkipping)
-	return considerSkipping
+	considerSkipping := bs == types.Irreversible
+	return considerSkipping && !c.Config.disableReplayOpts && !c.InTrxRequiringChecks
 }
 
 func (c *Controller) SkipDbSessions() bool {
@@ -526,9 +627,10 @@ func (c *Controller) IsProducingBlock() bool {
 
 func (c *Controller) Close() {
 	//session.close()
-	c.ForkDB.DB.Close()
+	c.ForkDB.Close()
 	c.DB.Close()
 	c.ReversibleBlocks.Close()
+	c.Blog.Close()
 	fmt.Println("Controller destory!")
 }
 
@@ -555,22 +657,28 @@ func (c *Controller) DropUnAppliedTransaction(metadata *types.TransactionMetadat
 }
 
 func (c *Controller) DropAllUnAppliedTransactions() {
-	c.UnAppliedTransactions = nil
+	c.UnAppliedTransactions = make(map[crypto.Sha256]types.TransactionMetadata)
 }
 func (c *Controller) GetScheduledTransactions() []common.TransactionIdType {
 
 	result := []common.TransactionIdType{}
-	gto := entity.GeneratedTransactionObject{}
-	idx, err := c.DB.GetIndex("byDelay", &gto)
+	idx, err := c.DB.GetIndex("byDelay", entity.GeneratedTransactionObject{})
+	if err != nil {
+		log.Warn("get generated transaction index is error,detail:", err)
+		return result
+	}
 	itr := idx.BeginIterator()
-	err = itr.Data(&gto)
-	for itr != idx.End() && gto.DelayUntil <= c.PendingBlockTime() {
+	defer itr.Release()
+	for itr.Next() {
+		gto := entity.GeneratedTransactionObject{}
+		if err = itr.Data(&gto); err != nil {
+			log.Warn("read generated transaction is error,detail:", err)
+			break
+		}
+		if gto.DelayUntil > c.PendingBlockTime() {
+			break
+		}
 		result = append(result, gto.TrxId)
-		itr.Next()
-		err = itr.Data(&gto)
-	}
-	if err != nil {
-		fmt.Println(err)
 	}
 	return result
 }
@@ -578,6 +686,7 @@ func (c *Controller) GetScheduledTransactions() []common.TransactionIdType {
 func (c *Controller) PushScheduledTransactionById(sheduled common.TransactionIdType,
 	deadLine common.TimePoint,
 	billedCpuTimeUs uint32, explicitBilledCpuTime bool) *types.TransactionTrace {
+	c.ValidateDbAvailableSize()
 
 	in := entity.GeneratedTransactionObject{}
 	in.TrxId = sheduled
@@ -711,8 +820,8 @@ func (c *Controller) FinalizeBlock() {
 	cpu.Periods = common.DefaultConfig.BlockCpuUsageAverageWindowMs / uint32(common.DefaultConfig.BlockIntervalMs)
 	cpu.MaxMultiplier = m
 
-	cpu.ContractRate.Numerator = 99
-	cpu.ExpandRate.Denominator = 100
+	cpu.ContractRate = types.Ratio{99, 100}
+	cpu.ExpandRate = types.Ratio{1000, 999}
 
 	net := types.ElasticLimitParameters{}
 	netTarget := common.EosPercent(uint64(chainConfig.MaxBlockNetUsage), chainConfig.TargetBlockNetUsagePct)
@@ -721,8 +830,8 @@ func (c *Controller) FinalizeBlock() {
 	net.Periods = common.DefaultConfig.BlockSizeAverageWindowMs / uint32(common.DefaultConfig.BlockIntervalMs)
 	net.MaxMultiplier = m
 
-	net.ContractRate.Numerator = 99
-	net.ExpandRate.Denominator = 100
+	net.ContractRate = types.Ratio{99, 100}
+	net.ExpandRate = types.Ratio{1000, 999}
 	c.ResourceLimists.SetBlockParameters(cpu, net)
 
 	c.setActionMaerkle()
@@ -750,21 +859,20 @@ func (c *Controller) applyBlock(b *types.SignedBlock, s types.BlockStatus) {
 	trace := &types.TransactionTrace{}
 	for _, receipt := range b.Transactions {
 		numPendingReceipts := len(c.Pending.PendingBlockState.SignedBlock.Transactions)
-		if common.Empty(receipt.Trx.PackedTransaction) {
+		if receipt.Trx.PackedTransaction != nil {
 			pt := receipt.Trx.PackedTransaction
 			mtrx := types.TransactionMetadata{}
 			mtrx.PackedTrx = pt
 			trace = c.PushTransaction(mtrx, common.TimePoint(common.MaxMicroseconds()), receipt.CpuUsageUs, true)
-		} else if common.Empty(receipt.Trx.TransactionID) {
+		} else if !common.Empty(receipt.Trx.TransactionID) {
 			trace = c.PushScheduledTransactionById(receipt.Trx.TransactionID, common.TimePoint(common.MaxMicroseconds()), receipt.CpuUsageUs, true)
 		} else {
 			EosAssert(false, &BlockValidateException{}, "encountered unexpected receipt type")
 		}
-		transactionFailed := common.Empty(trace) && common.Empty(trace.Except)
-		transactionCanFail := receipt.Status == types.TransactionStatusHardFail && common.Empty(receipt.Trx.TransactionID)
+		transactionFailed := trace != nil && trace.Except != nil
+		transactionCanFail := receipt.Status == types.TransactionStatusHardFail && receipt.Trx.PackedTransaction == nil
 		if transactionFailed && !transactionCanFail {
-			/*edump((*trace));
-			throw *trace->except;*/
+			try.Throw(trace.Except)
 		}
 		EosAssert(len(c.Pending.PendingBlockState.SignedBlock.Transactions) > 0,
 			&BlockValidateException{}, "expected a receipt:", *b, "expected_receipt:", receipt)
@@ -779,19 +887,29 @@ func (c *Controller) applyBlock(b *types.SignedBlock, s types.BlockStatus) {
 		("producer_receipt", receipt)("validator_receipt", pending->_pending_block_state->block->transactions.back()) );*/
 	}
 
+	c.FinalizeBlock()
+	// this implicitly asserts that all header fields (less the signature) are identical
+	EosAssert(producerBlockId == c.Pending.PendingBlockState.Header.BlockID(), &BlockValidateException{},
+		"Block ID does not match, producer block id: %s, validator block id: %s", producerBlockId, c.Pending.PendingBlockState.Header.BlockID())
+
+	// the signature can be trusted, the block was added to the fork database before it was applied
+	c.Pending.PendingBlockState.Header.ProducerSignature = b.ProducerSignature
+	c.Pending.PendingBlockState.SignedBlock.SignedBlockHeader = c.Pending.PendingBlockState.Header
+	c.CommitBlock(false)
 }
 
 func (c *Controller) CommitBlock(addToForkDb bool) {
 	defer func() {
 		if c.Pending.Valid {
-			c.Pending.Reset()
+			err := c.Pending.Reset()
+			EosAssert(err == nil, &DatabaseException{}, "undo of the pending block failed: %s", err)
 		}
 	}()
 	//try{
 	if addToForkDb {
 		c.Pending.PendingBlockState.Validated = true
 		newBsp := c.ForkDB.AddBlockState(c.Pending.PendingBlockState)
-		//emit(self.accepted_block_header, pending->_pending_block_state)
+		c.AcceptedBlockHeader.Emit(c.Pending.PendingBlockState)
 		c.Head = c.ForkDB.Header()
 		EosAssert(newBsp == c.Head, &ForkDatabaseException{}, "committed block did not become the new head in fork database")
 	}
@@ -800,9 +918,9 @@ func (c *Controller) CommitBlock(addToForkDb bool) {
 		ubo := entity.ReversibleBlockObject{}
 		ubo.BlockNum = c.Pending.PendingBlockState.BlockNum
 		ubo.SetBlock(c.Pending.PendingBlockState.SignedBlock)
-		c.DB.Insert(ubo)
+		c.ReversibleBlocks.Insert(&ubo)
 	}
-	//emit( self.accepted_block, pending->_pending_block_state )
+	c.AcceptedBlock.Emit(c.Pending.PendingBlockState)
 	//catch(){
 	// reset_pending_on_exit.cancel();
 	//         abort_block();
@@ -812,33 +930,33 @@ func (c *Controller) CommitBlock(addToForkDb bool) {
 }
 
 func (c *Controller) PushBlock(b *types.SignedBlock, s types.BlockStatus) {
+	c.ValidateDbAvailableSize()
+	c.ValidateReversibleAvailableSize()
 	EosAssert(c.Pending != nil, &BlockValidateException{}, "it is not valid to push a block when there is a pending block")
-	//resetProdLightValidation := c.makeBlockRestorePoint()
-
-	EosAssert(b == nil, &BlockValidateException{}, "trying to push empty block")
+	EosAssert(b != nil, &BlockValidateException{}, "trying to push empty block")
 	EosAssert(s != types.Incomplete, &BlockLogException{}, "invalid block status for a completed block")
-	//emit(self.pre_accepted_block, b )
-	//trust := !c.Config.forceAllChecks && (s== types.Irreversible || s== types.Validated)
-	//newHeader := c.ForkDB.AddSignedBlockState(b,trust)
+	c.PreAcceptedBlock.Emit(b)
 
+	trust := !c.Config.forceAllChecks && (s == types.Irreversible || s == types.Validated)
+	newHeaderState := c.ForkDB.AddSignedBlockState(b, trust)
 	if _, ok := c.Config.trustedProducers[b.Producer]; ok {
-		//	resetProdLightValidation = true
+		c.TrustedProducerLightValidation = true
+		defer func() { c.TrustedProducerLightValidation = false }()
 	}
-	//emit( self.accepted_block_header, new_header_state )
+	c.AcceptedBlockHeader.Emit(newHeaderState)
 	if c.ReadMode != IRREVERSIBLE {
-		//maybe_switch_forks( s )
+		c.maybeSwitchForks(s)
 	}
 
 	if s == types.Irreversible {
-		//emit( self.irreversible_block, new_header_state )
+		c.IrreversibleBlock.Emit(newHeaderState)
 	}
-
 } //status default value block_status s = block_status::complete
 
 func (c *Controller) PushConfirmation(hc types.HeaderConfirmation) {
 	EosAssert(c.Pending != nil, &BlockValidateException{}, "it is not valid to push a confirmation when there is a pending block")
-	//c.ForkDB.Add(hc)
-	//emit( c.accepted_confirmation, hc )
+	c.ForkDB.Add(&hc)
+	c.AcceptedConfirmation.Emit(&hc)
 	if c.ReadMode != IRREVERSIBLE {
 		c.maybeSwitchForks(types.Complete)
 	}
@@ -848,17 +966,15 @@ func (c *Controller) maybeSwitchForks(s types.BlockStatus) {
 	//TODO
 	newHead := c.ForkDB.Head
 	if newHead.Header.Previous == c.Head.BlockId {
-		//try{
-
-		c.applyBlock(newHead.SignedBlock, s)
-		c.ForkDB.MarkInCurrentChain(newHead, true)
-		c.ForkDB.SetValidity(newHead, true)
-		c.Head = newHead
-
-		//}catch(){
-		c.ForkDB.SetValidity(newHead, false)
-		//try.Throw()
-		//}
+		try.Try(func() {
+			c.applyBlock(newHead.SignedBlock, s)
+			c.ForkDB.MarkInCurrentChain(newHead, true)
+			c.ForkDB.SetValidity(newHead, true)
+			c.Head = newHead
+		}).Catch(func(e Exception) {
+			c.ForkDB.SetValidity(newHead, false)
+			try.Throw(e)
+		}).End()
 	} else if newHead.ID != c.Head.ID {
 		//branches := c.ForkDB.FetchBranchFrom( newHead.ID, c.Head.ID )
 		/*for( auto itr = branches.second.begin(); itr != branches.second.end(); ++itr ) {
@@ -909,6 +1025,24 @@ func (c *Controller) DataBase() database.DataBase {
 	return c.DB
 }
 
+// StateViewAt is a read only view of the state database as it was once block blockNum was applied, the
+// reversible blocks and the last StateHistoryBlocks irreversible ones can be viewed. The caller has to release it
+func (c *Controller) StateViewAt(blockNum uint32) database.DataBaseView {
+	view, err := c.DB.ViewAt(int64(blockNum))
+	EosAssert(err != database.ErrRevisionUnavailable, &DatabaseException{},
+		"state of block %d is not kept, the node keeps the state of its last %d irreversible blocks", blockNum, StateHistoryBlocks)
+	EosAssert(err == nil, &DatabaseException{}, "unable to take a view of the state at block %d: %s", blockNum, err)
+	return view
+}
+
+// StateView is a read only view of the state database as of the last change applied to it, the queries
+// read from it while blocks keep being applied. The caller has to release it
+func (c *Controller) StateView() database.DataBaseView {
+	view, err := c.DB.View()
+	EosAssert(err == nil, &DatabaseException{}, "unable to take a view of the state database: %s", err)
+	return view
+}
+
 func (c *Controller) ForkDataBase() *types.ForkDatabase {
 	return c.ForkDB
 }
@@ -976,17 +1110,17 @@ func (c *Controller) HeadBlockNum() uint32 { return c.Head.BlockNum }
 
 func (c *Controller) HeadBlockTime() common.TimePoint { return c.Head.Header.Timestamp.ToTimePoint() }
 
-func (c *Controller) HeadBlockId() common.BlockIdType { return common.BlockIdType{} }
+func (c *Controller) HeadBlockId() common.BlockIdType { return c.Head.BlockId }
 
 func (c *Controller) HeadBlockProducer() common.AccountName { return c.Head.Header.Producer }
 
 func (c *Controller) HeadBlockHeader() *types.BlockHeader { return &c.Head.Header.BlockHeader }
 
-func (c *Controller) HeadBlockState() types.BlockState { return types.BlockState{} }
+func (c *Controller) HeadBlockState() *types.BlockState { return c.Head }
 
 func (c *Controller) ForkDbHeadBlockNum() uint32 { return c.ForkDB.Header().BlockNum }
 
-func (c *Controller) ForkDbHeadBlockId() common.BlockIdType { return common.BlockIdType{} }
+func (c *Controller) ForkDbHeadBlockId() common.BlockIdType { return c.ForkDB.Header().BlockId }
 
 func (c *Controller) ForkDbHeadBlockTime() common.TimePoint {
 	return c.ForkDB.Header().Header.Timestamp.ToTimePoint()
@@ -1048,9 +1182,18 @@ func (c *Controller) LightValidationAllowed(dro bool) (b bool) {
 	return considerSkippingOnReplay || considerSkippingOnvalidate
 }
 
-func (c *Controller) LastIrreversibleBlockNum() uint32 { return 0 }
+func (c *Controller) LastIrreversibleBlockNum() uint32 { return types.LastIrreversibleBlockNum(c.Head) }
 
-func (c *Controller) LastIrreversibleBlockId() common.BlockIdType { return common.BlockIdType{} }
+func (c *Controller) LastIrreversibleBlockId() common.BlockIdType {
+	libNum := c.LastIrreversibleBlockNum()
+	if libNum == c.Head.BlockNum {
+		return c.Head.BlockId
+	}
+	if b := c.FetchBlockByNumber(libNum); b != nil {
+		return b.BlockID()
+	}
+	return common.BlockIdType{}
+}
 
 func (c *Controller) FetchBlockByNumber(blockNum uint32) *types.SignedBlock {
 	blkState := c.ForkDB.GetBlockInCurrentChainByNum(blockNum)
@@ -1087,7 +1230,7 @@ func (c *Controller) GetBlockIdForNum(blockNum uint32) common.BlockIdType {
 	}
 
 	signedBlk := c.Blog.ReadBlockByNum(blockNum)
-	EosAssert(common.Empty(signedBlk), &UnknownBlockException{}, "Could not find block: d%", blockNum)
+	EosAssert(signedBlk != nil, &UnknownBlockException{}, "Could not find block: %d", blockNum)
 	return signedBlk.BlockID()
 }
 
@@ -1193,20 +1336,66 @@ func (c *Controller) ValidateTapos(t *types.Transaction) {
 	if err != nil {
 		fmt.Println("ValidateTapos Is Error:", err)
 	}
-	EosAssert(t.VerifyReferenceBlock(taposBlockSummary.BlockId), &InvalidRefBlockException{},
+	EosAssert(t.VerifyReferenceBlock(&taposBlockSummary.BlockId), &InvalidRefBlockException{},
 		"Transaction's reference block did not match. Is this transaction from a different fork?", taposBlockSummary)
 }
 
+// ValidateDbAvailableSize stops the node from applying more state once the state database gets within
+// the guard size of its configured size
 func (c *Controller) ValidateDbAvailableSize() {
-	/*const auto free = db().get_segment_manager()->get_free_memory();
-	const auto guard = my->conf.state_guard_size;
-	EOS_ASSERT(free >= guard, database_guard_exception, "database free: ${f}, guard size: ${g}", ("f", free)("g",guard));*/
+	used := c.DB.Size()
+	free := uint64(0)
+	if used < c.Config.stateSize {
+		free = c.Config.stateSize - used
+	}
+	guard := c.Config.stateGuardSize
+	EosAssert(free >= guard, &DatabaseGuardException{}, "database free: %d, guard size: %d, largest tables: %s",
+		free, guard, largestTables(c.DB.TableSizes(), 5))
 }
 
 func (c *Controller) ValidateReversibleAvailableSize() {
-	/*const auto free = my->reversible_blocks.get_segment_manager()->get_free_memory();
-	const auto guard = my->conf.reversible_guard_size;
-	EOS_ASSERT(free >= guard, reversible_guard_exception, "reversible free: ${f}, guard size: ${g}", ("f", free)("g",guard));*/
+	used := c.ReversibleBlocks.Size()
+	free := uint64(0)
+	if used < c.Config.reversibleCacheSize {
+		free = c.Config.reversibleCacheSize - used
+	}
+	guard := c.Config.reversibleGuardSize
+	EosAssert(free >= guard, &ReversibleGuardException{}, "reversible free: %d, guard size: %d", free, guard)
+}
+
+// DbSizeBreakdown logs the size of every table of the state database, largest first
+func (c *Controller) DbSizeBreakdown() {
+	tables := c.DB.TableSizes()
+	log.Info(fmt.Sprintf("state database size: %d bytes of %d, guard size: %d", c.DB.Size(), c.Config.stateSize, c.Config.stateGuardSize))
+	for _, table := range sortedTables(tables) {
+		log.Info(fmt.Sprintf("  %-32s %d", table, tables[table]))
+	}
+}
+
+func sortedTables(tables map[string]uint64) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if tables[names[i]] != tables[names[j]] {
+			return tables[names[i]] > tables[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func largestTables(tables map[string]uint64, count int) string {
+	names := sortedTables(tables)
+	if len(names) > count {
+		names = names[:count]
+	}
+	largest := make([]string, 0, len(names))
+	for _, name := range names {
+		largest = append(largest, fmt.Sprintf("%s=%d", name, tables[name]))
+	}
+	return strings.Join(largest, ", ")
 }
 
 func (c *Controller) IsKnownUnexpiredTransaction(id *common.TransactionIdType) bool {
@@ -1308,10 +1497,23 @@ func (c *Controller) GetWasmInterface() *wasmgo.WasmGo {
 	return c.WasmIf
 }
 
-/*func (c *Controller) GetAbiSerializer(name common.AccountName,
-	maxSerializationTime common.Microseconds) types.AbiSerializer {
-	return types.AbiSerializer{}
-}*/
+func (c *Controller) GetAbiSerializer(name common.AccountName,
+	maxSerializationTime common.Microseconds) *types.AbiSerializer {
+	if name.Empty() {
+		return nil
+	}
+	var abis *types.AbiSerializer
+	try.Try(func() {
+		account := c.GetAccount(name)
+		if len(account.Abi) > 0 {
+			abi := account.GetAbi()
+			abis = types.NewAbiSerializer(&abi, maxSerializationTime)
+		}
+	}).Catch(func(e Exception) {
+		log.Error("GetAbiSerializer is error,detail:", e.Message())
+	}).End()
+	return abis
+}
 
 /*func (c *Controller) ToVariantWithAbi(obj interface{}, maxSerializationTime common.Microseconds) {}*/
 
@@ -1363,9 +1565,9 @@ func (c *Controller) initializeForkDB() {
 	signedBlock := types.SignedBlock{}
 	signedBlock.SignedBlockHeader = genHeader.Header
 	c.Head.SignedBlock = &signedBlock
-	fmt.Println("initializeForkDB:", c.ForkDB.DB)
 	c.ForkDB.SetHead(c.Head)
-	c.DB.SetRevision(int64(c.Head.BlockNum))
+	err := c.DB.SetRevision(int64(c.Head.BlockNum))
+	EosAssert(err == nil, &DatabaseException{}, "set revision of the state database failed: %s", err)
 	c.initializeDatabase()
 }
 
@@ -1429,56 +1631,147 @@ func (c *Controller) initializeDatabase() {
 }
 
 func (c *Controller) initialize() {
-	if common.Empty(c.Head) {
+	c.Head = c.ForkDB.Header()
+	if c.Head == nil && SnapshotFile != "" {
+		c.initializeFromSnapshot(SnapshotFile)
+	} else if c.Head == nil {
 		c.initializeForkDB()
 		end := c.Blog.ReadHead()
-		if common.Empty(end) && end.BlockNumber() > 1 {
-			endTime := end.Timestamp.ToTimePoint()
-			replaying := true
-			replayHeadTime := endTime
-			//ilog( "existing block log, attempting to replay ${n} blocks", ("n",end->block_num()) )
-			start := common.Now()
-			next := c.Blog.ReadBlockByNum(c.Head.BlockNum + 1)
-			/*while( auto next = blog.read_block_by_num( head->block_num + 1 ) ) {
-				self.push_block( next, controller::block_status::irreversible );
-				if( next->block_num() % 100 == 0 ) {
-					std::cerr << std::setw(10) << next->block_num() << " of " << end->block_num() <<"\r";
-				}
-			}*/
-			//ilog( "${n} blocks replayed", ("n", head->block_num) )
-			//c.DB.set_revision(head->block_num)
-			rev := 0
-			//c.ReversibleBlocks.Find("",)
-			r := entity.ReversibleBlockObject{}
-			for {
-				r.BlockNum = c.HeadBlockNum() + 1
-				err := c.ReversibleBlocks.Find("blockNum", r, r)
-				if err != nil {
-					break
-				}
-				c.PushBlock(r.GetBlock(), types.Validated)
-			}
-			//ilog( "${n} reversible blocks replayed", ("n",rev) )
-			end := time.Now()
-			/*ilog( "replayed ${n} blocks in ${duration} seconds, ${mspb} ms/block",
-				("n", head->block_num)("duration", (end-start).count()/1000000)
-			("mspb", ((end-start).count()/1000.0)/head->block_num)        )*/
-			c.RePlaying = false
-			//c.ReplayHeadTime = nil
-
-			fmt.Println("test print:", replaying, replayHeadTime, start, next, rev, end)
-		} else if !common.Empty(end) {
+		if end != nil && end.BlockNumber() > 1 {
+			c.replayBlockLog(end)
+		} else if end == nil {
 			c.Blog.ResetToGenesis(&c.Config.genesis, c.Head.SignedBlock)
 		}
-		//TODO	wait append
-		/*rbi := entity.ReversibleBlockObject{}
-		ubi,err := c.ReversibleBlocks.GetIndex("byNum",&rbi)
-		if err!= nil{
-			fmt.Errorf("initialize database is error :",err)
+	} else {
+		// the fork database was restored, the node resumes at the head it had when it was closed
+		c.undoToHead()
+		c.skipBlockLog = c.Blog.Head() == nil && c.Head.BlockNum > 1
+	}
+
+}
+
+// StateObjects are the types of the chain objects kept in the state database, in the order snapshots list them
+var StateObjects = []interface{}{
+	&entity.AccountObject{}, &entity.AccountSequenceObject{}, &entity.BlockSummaryObject{},
+	&entity.TableIdObject{}, &entity.KeyValueObject{}, &entity.SecondaryObjectI64{}, &entity.SecondaryObjectDouble{},
+	&entity.GeneratedTransactionObject{}, &entity.GlobalPropertyObject{}, &entity.DynamicGlobalPropertyObject{},
+	&entity.PermissionLinkObject{}, &entity.PermissionObject{}, &entity.PermissionUsageObject{},
+	&entity.ProducerObject{}, &entity.ResourceLimitsConfigObject{}, &entity.ResourceLimitsObject{},
+	&entity.ResourceLimitsStateObject{}, &entity.ResourceUsageObject{}, &entity.TransactionObject{},
+}
+
+// registerDatabaseTypes makes the chain objects known to the undo states the state database reads back from disk
+func registerDatabaseTypes() {
+	database.RegisterType(StateObjects...)
+}
+
+// initializeFromSnapshot rebuilds the state database and the head block from a snapshot. A block log
+// that goes past the snapshot head is replayed from there, an empty one stays empty because it can
+// only hold the blocks that follow genesis
+func (c *Controller) initializeFromSnapshot(path string) {
+	log.Info(fmt.Sprintf("starting from snapshot %s", path))
+	chainId, head := readSnapshot(c.DB, path)
+	c.ChainID = chainId
+	c.Head = types.NewBlockState(head)
+	c.Head.SignedBlock = &types.SignedBlock{SignedBlockHeader: head.Header}
+	c.ForkDB.SetHead(c.Head)
+	err := c.DB.SetRevision(int64(c.Head.BlockNum))
+	EosAssert(err == nil, &DatabaseException{}, "set revision of the state database failed: %s", err)
+
+	end := c.Blog.ReadHead()
+	if end == nil {
+		log.Warn("block log is empty, blocks that follow the snapshot are not written to the block log")
+		c.skipBlockLog = true
+		return
+	}
+	EosAssert(end.BlockNumber() >= head.BlockNum, &SnapshotException{},
+		"block log ends at block %d before the snapshot head block %d", end.BlockNumber(), head.BlockNum)
+	logged := c.Blog.ReadBlockByNum(head.BlockNum)
+	EosAssert(logged != nil && logged.BlockID() == head.BlockId, &SnapshotException{},
+		"block log does not contain the snapshot head block %s", head.BlockId)
+	if end.BlockNumber() > head.BlockNum {
+		c.replayBlockLog(end)
+	}
+}
+
+// undoToHead rolls the state database back to the head block, the undo states of the reversible blocks
+// applied before an unclean shutdown are kept by the database across the restart
+func (c *Controller) undoToHead() {
+	head := int64(c.Head.BlockNum)
+	for revision := c.DB.Revision(); revision > head; revision = c.DB.Revision() {
+		err := c.DB.Undo()
+		EosAssert(err == nil, &DatabaseException{}, "undo of the state database revision %d failed: %s", revision, err)
+		if c.DB.Revision() == revision {
+			break
+		}
+	}
+	EosAssert(c.DB.Revision() == head, &ForkDatabaseException{},
+		"fork database is inconsistent with the state database, state database revision: %d, head block: %d", c.DB.Revision(), head)
+}
+
+// replayBlockLog rebuilds the state database by pushing every block of the block log as irreversible,
+// followed by the reversible blocks that were not yet written to the log
+func (c *Controller) replayBlockLog(end *types.SignedBlock) {
+	log.Info(fmt.Sprintf("existing block log, attempting to replay %d blocks", end.BlockNumber()))
+	c.RePlaying = true
+	c.ReplayHeadTime = end.Timestamp.ToTimePoint()
+	defer func() {
+		c.RePlaying = false
+		c.ReplayHeadTime = 0
+	}()
+
+	start := time.Now()
+	for next := c.Blog.ReadBlockByNum(c.Head.BlockNum + 1); next != nil; next = c.Blog.ReadBlockByNum(c.Head.BlockNum + 1) {
+		c.PushBlock(next, types.Irreversible)
+		if next.BlockNumber()%ReplayProgressInterval == 0 {
+			log.Info(fmt.Sprintf("%d of %d blocks replayed", next.BlockNumber(), end.BlockNumber()))
+		}
+	}
+	log.Info(fmt.Sprintf("%d blocks replayed", c.Head.BlockNum))
+
+	// if the irreversible log is played without undo sessions enabled, we need to sync the
+	// revision ordinal to the appropriate expected value here.
+	if c.SkipDbSession(types.Irreversible) {
+		err := c.DB.SetRevision(int64(c.Head.BlockNum))
+		EosAssert(err == nil, &DatabaseException{}, "set revision of the state database failed: %s", err)
+	}
+
+	rev := 0
+	for _, r := range c.reversibleBlocksFrom(c.Head.BlockNum + 1) {
+		if r.BlockNum != c.Head.BlockNum+1 {
+			break
 		}
-		objitr := ubi.Begin()*/
+		c.PushBlock(r.GetBlock(), types.Validated)
+		rev++
 	}
+	log.Info(fmt.Sprintf("%d reversible blocks replayed", rev))
+
+	duration := time.Since(start)
+	log.Info(fmt.Sprintf("replayed %d blocks in %d seconds, %.3f ms/block", c.Head.BlockNum,
+		int64(duration/time.Second), float64(duration/time.Microsecond)/1000.0/float64(c.Head.BlockNum)))
+}
 
+// reversibleBlocksFrom returns the reversible blocks from blockNum on, ordered by block number
+func (c *Controller) reversibleBlocksFrom(blockNum uint32) []entity.ReversibleBlockObject {
+	objs := make([]entity.ReversibleBlockObject, 0)
+	idx, err := c.ReversibleBlocks.GetIndex("byNum", entity.ReversibleBlockObject{})
+	if err != nil {
+		log.Warn("get reversible block index is error,detail:", err)
+		return objs
+	}
+	it, err := idx.LowerBound(entity.ReversibleBlockObject{BlockNum: blockNum})
+	if err != nil {
+		return objs
+	}
+	defer it.Release()
+	for it.Next() {
+		obj := entity.ReversibleBlockObject{}
+		if it.Data(&obj) != nil {
+			break
+		}
+		objs = append(objs, obj)
+	}
+	return objs
 }
 
 //c++ pair<scope_name,action_name>
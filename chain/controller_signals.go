@@ -0,0 +1,127 @@
+package chain
+
+import (
+	"sync"
+
+	"github.com/eosspark/eos-go/chain/types"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/eosspark/eos-go/log"
+)
+
+// see: libraries/chain/include/eosio/chain/controller.hpp signals
+
+// Connection is returned by Connect, it removes the handler from its signal
+type Connection struct {
+	signal *signal
+	id     uint64
+}
+
+func (c Connection) Disconnect() {
+	if c.signal != nil {
+		c.signal.disconnect(c.id)
+	}
+}
+
+type slot struct {
+	id      uint64
+	handler func(arg interface{})
+}
+
+// signal calls its handlers synchronously in the order they were connected
+type signal struct {
+	lock   sync.Mutex
+	slots  []slot
+	nextId uint64
+}
+
+func (s *signal) connect(handler func(arg interface{})) Connection {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.nextId++
+	s.slots = append(s.slots, slot{id: s.nextId, handler: handler})
+	return Connection{signal: s, id: s.nextId}
+}
+
+func (s *signal) disconnect(id uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, sl := range s.slots {
+		if sl.id == id {
+			s.slots = append(s.slots[:i:i], s.slots[i+1:]...)
+			return
+		}
+	}
+}
+
+// emit hands arg to every handler, a handler may connect or disconnect while it runs. Only
+// ControllerEmitSignalExceptions get out of emit, anything else a handler throws is logged and
+// the next handler still runs
+func (s *signal) emit(arg interface{}) {
+	s.lock.Lock()
+	slots := s.slots
+	s.lock.Unlock()
+
+	for _, sl := range slots {
+		try.Try(func() {
+			sl.handler(arg)
+		}).Catch(func(e ControllerEmitSignalExceptions) {
+			log.Warn("signal handler threw exception,detail:", e.(Exception).Message())
+			try.Throw(e)
+		}).Catch(func(e Exception) {
+			log.Warn("signal handler threw exception,detail:", e.Message())
+		}).Catch(func(e interface{}) {
+			log.Warn("signal handler threw exception,detail:", e)
+		}).End()
+	}
+}
+
+type SignedBlockSignal struct{ signal }
+
+func (s *SignedBlockSignal) Connect(handler func(block *types.SignedBlock)) Connection {
+	return s.connect(func(arg interface{}) { handler(arg.(*types.SignedBlock)) })
+}
+
+func (s *SignedBlockSignal) Emit(block *types.SignedBlock) {
+	s.emit(block)
+}
+
+type BlockStateSignal struct{ signal }
+
+func (s *BlockStateSignal) Connect(handler func(bsp *types.BlockState)) Connection {
+	return s.connect(func(arg interface{}) { handler(arg.(*types.BlockState)) })
+}
+
+func (s *BlockStateSignal) Emit(bsp *types.BlockState) {
+	s.emit(bsp)
+}
+
+type TransactionMetadataSignal struct{ signal }
+
+func (s *TransactionMetadataSignal) Connect(handler func(trx *types.TransactionMetadata)) Connection {
+	return s.connect(func(arg interface{}) { handler(arg.(*types.TransactionMetadata)) })
+}
+
+func (s *TransactionMetadataSignal) Emit(trx *types.TransactionMetadata) {
+	s.emit(trx)
+}
+
+type TransactionTraceSignal struct{ signal }
+
+func (s *TransactionTraceSignal) Connect(handler func(trace *types.TransactionTrace)) Connection {
+	return s.connect(func(arg interface{}) { handler(arg.(*types.TransactionTrace)) })
+}
+
+func (s *TransactionTraceSignal) Emit(trace *types.TransactionTrace) {
+	s.emit(trace)
+}
+
+type HeaderConfirmationSignal struct{ signal }
+
+func (s *HeaderConfirmationSignal) Connect(handler func(hc *types.HeaderConfirmation)) Connection {
+	return s.connect(func(arg interface{}) { handler(arg.(*types.HeaderConfirmation)) })
+}
+
+func (s *HeaderConfirmationSignal) Emit(hc *types.HeaderConfirmation) {
+	s.emit(hc)
+}
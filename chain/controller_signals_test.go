@@ -0,0 +1,53 @@
+package chain
+
+import (
+	"testing"
+
+	"github.com/eosspark/eos-go/chain/types"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignalOrder(t *testing.T) {
+	s := BlockStateSignal{}
+	calls := make([]int, 0)
+	s.Connect(func(bsp *types.BlockState) { calls = append(calls, 1) })
+	second := s.Connect(func(bsp *types.BlockState) { calls = append(calls, 2) })
+	s.Connect(func(bsp *types.BlockState) { calls = append(calls, 3) })
+
+	s.Emit(&types.BlockState{})
+	assert.Equal(t, []int{1, 2, 3}, calls)
+
+	second.Disconnect()
+	calls = calls[:0]
+	s.Emit(&types.BlockState{})
+	assert.Equal(t, []int{1, 3}, calls)
+}
+
+func TestSignalHandlerPanics(t *testing.T) {
+	s := TransactionTraceSignal{}
+	calls := 0
+	s.Connect(func(trace *types.TransactionTrace) {
+		EosThrow(&DatabaseException{}, "handler failed")
+	})
+	s.Connect(func(trace *types.TransactionTrace) { panic("handler failed") })
+	s.Connect(func(trace *types.TransactionTrace) { calls++ })
+
+	s.Emit(&types.TransactionTrace{})
+	assert.Equal(t, 1, calls)
+
+	s.Connect(func(trace *types.TransactionTrace) {
+		EosThrow(&CheckpointException{}, "block does not match checkpoint")
+	})
+	s.Connect(func(trace *types.TransactionTrace) { calls++ })
+
+	returned := false
+	try.Try(func() {
+		s.Emit(&types.TransactionTrace{})
+	}).Catch(func(e ControllerEmitSignalExceptions) {
+		returned = true
+	}).End()
+	assert.True(t, returned)
+	assert.Equal(t, 2, calls)
+}
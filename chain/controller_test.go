@@ -11,8 +11,15 @@ import (
 	"fmt"
 	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto"
+	"github.com/eosspark/eos-go/crypto/ecc"
+	"github.com/eosspark/eos-go/database"
 	"github.com/eosspark/eos-go/entity"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -119,6 +126,193 @@ func TestController_StartBlock(t *testing.T) {
 	c.Close()
 }
 
+func TestController_ValidateDbAvailableSize(t *testing.T) {
+	db, err := database.NewDataBase(database.InMemory)
+	assert.NoError(t, err)
+	defer db.Close()
+	con := &Controller{DB: db, Config: Config{stateSize: 4096, stateGuardSize: 1024}}
+	con.ValidateDbAvailableSize()
+
+	for i := 0; i < 16 && db.Size() <= 4096-1024; i++ {
+		account := entity.AccountObject{Name: common.AccountName(uint64(i + 1)), Code: make([]byte, 256)}
+		assert.NoError(t, db.Insert(&account))
+	}
+	guarded := false
+	try.Try(func() {
+		con.ValidateDbAvailableSize()
+	}).Catch(func(e *DatabaseGuardException) {
+		guarded = true
+	}).End()
+	assert.True(t, guarded)
+	assert.True(t, db.TableSizes()["AccountObject"] > 0)
+}
+
+func TestController_GetScheduledTransactions(t *testing.T) {
+	db, err := database.NewDataBase(database.InMemory)
+	assert.NoError(t, err)
+	defer db.Close()
+	pending := types.NewBlockState(types.BlockHeaderState{})
+	pending.Header.Timestamp = common.NewBlockTimeStamp(common.Now())
+	now := pending.Header.Timestamp.ToTimePoint()
+	con := &Controller{DB: db, Pending: &types.PendingState{PendingBlockState: pending}}
+
+	// the later ids are due first, the first one is past the pending block time
+	for i, delay := range []int64{300, -10, 0, -90, -10} {
+		gto := entity.GeneratedTransactionObject{DelayUntil: now.AddUs(common.Seconds(delay))}
+		gto.TrxId.Hash[0] = uint64(i)
+		gto.SenderId.Low = uint64(i)
+		assert.NoError(t, db.Insert(&gto))
+	}
+
+	scheduled := con.GetScheduledTransactions()
+	order := make([]uint64, 0, len(scheduled))
+	for _, id := range scheduled {
+		order = append(order, id.Hash[0])
+	}
+	assert.Equal(t, []uint64{3, 1, 4, 2}, order)
+}
+
+// newTestController opens a new controller on the data directories, the managers are singletons as well
+func newTestController() *Controller {
+	isActiveController, IsActiveRc, IsActiveAz = false, false, false
+	return GetControllerInstance()
+}
+
+func TestController_StateViewAt(t *testing.T) {
+	os.RemoveAll("/tmp/data")
+	defer os.RemoveAll("/tmp/data")
+	history := StateHistoryBlocks
+	StateHistoryBlocks = 10
+	defer func() { StateHistoryBlocks = history }()
+
+	c := newTestController()
+	defer func() {
+		c.Close()
+		isActiveController, IsActiveRc, IsActiveAz = false, false, false
+	}()
+	key, err := ecc.NewPrivateKey("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3")
+	assert.NoError(t, err)
+	names := []string{"alice", "bob", "carol"}
+	for _, name := range names {
+		c.StartBlock(c.Head.Header.Timestamp+1, 0)
+		c.CreateNativeAccount(common.AccountName(common.N(name)), types.Authority{Threshold: 1}, types.Authority{Threshold: 1}, false)
+		c.FinalizeBlock()
+		c.SignBlock(func(digest crypto.Sha256) ecc.Signature {
+			sig, _ := key.Sign(digest.Bytes())
+			return sig
+		})
+		c.CommitBlock(true)
+	}
+	assert.Equal(t, uint32(4), c.HeadBlockNum())
+	assert.Equal(t, int64(c.HeadBlockNum()), c.DB.Revision())
+	assert.True(t, c.LastIrreversibleBlockNum() > 1)
+
+	// block 2 created alice and block 3 bob, the irreversible genesis block is rebuilt from the history
+	for blockNum, created := range map[uint32][]string{1: {}, 3: {"alice", "bob"}, 4: names} {
+		view := c.StateViewAt(blockNum)
+		found := make([]string, 0)
+		for _, name := range names {
+			account := entity.AccountObject{Name: common.AccountName(common.N(name))}
+			if view.Find("byName", account, &account) == nil {
+				found = append(found, name)
+			}
+		}
+		view.Release()
+		assert.Equal(t, created, found, "block %d", blockNum)
+	}
+
+	unavailable := false
+	try.Try(func() {
+		c.StateViewAt(c.HeadBlockNum() + 1)
+	}).Catch(func(e *DatabaseException) {
+		unavailable = true
+	}).End()
+	assert.True(t, unavailable)
+}
+
+func TestController_undoToHead(t *testing.T) {
+	os.RemoveAll("/tmp/data")
+	defer os.RemoveAll("/tmp/data")
+	defer func() { isActiveController, IsActiveRc, IsActiveAz = false, false, false }()
+
+	c := newTestController()
+	key, err := ecc.NewPrivateKey("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3")
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		c.StartBlock(c.Head.Header.Timestamp+1, 0)
+		c.FinalizeBlock()
+		c.SignBlock(func(digest crypto.Sha256) ecc.Signature {
+			sig, _ := key.Sign(digest.Bytes())
+			return sig
+		})
+		c.CommitBlock(true)
+	}
+	head := c.HeadBlockId()
+
+	// the node stops with a pending block, its changes are undone on restart
+	c.StartBlock(c.Head.Header.Timestamp+1, 0)
+	alice := entity.AccountObject{Name: common.AccountName(common.N("alice"))}
+	c.CreateNativeAccount(alice.Name, types.Authority{Threshold: 1}, types.Authority{Threshold: 1}, false)
+	c.Close()
+	c = newTestController()
+	assert.Equal(t, head, c.HeadBlockId())
+	assert.Equal(t, int64(c.HeadBlockNum()), c.DB.Revision())
+	assert.Error(t, c.DB.Find("byName", alice, &alice))
+	c.Close()
+
+	// a state database behind the fork database can not be rolled forward
+	db, err := database.NewDataBase(common.DefaultConfig.DefaultStateDirName)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Commit(db.Revision()))
+	assert.NoError(t, db.SetRevision(db.Revision()-1))
+	db.Close()
+	inconsistent := false
+	try.Try(func() {
+		newTestController()
+	}).Catch(func(e *ForkDatabaseException) {
+		inconsistent = true
+	}).End()
+	assert.True(t, inconsistent)
+}
+
+func TestController_replayBlockLog(t *testing.T) {
+	os.RemoveAll("/tmp/data")
+	defer os.RemoveAll("/tmp/data")
+	interval := ReplayProgressInterval
+	ReplayProgressInterval = 2
+	defer func() { ReplayProgressInterval = interval }()
+
+	c := newTestController()
+	key, err := ecc.NewPrivateKey("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3")
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		c.StartBlock(c.Head.Header.Timestamp+1, 0)
+		c.FinalizeBlock()
+		c.SignBlock(func(digest crypto.Sha256) ecc.Signature {
+			sig, _ := key.Sign(digest.Bytes())
+			return sig
+		})
+		c.CommitBlock(true)
+	}
+	head, lib := c.Head.BlockId, c.LastIrreversibleBlockNum()
+	assert.Equal(t, uint32(6), c.HeadBlockNum())
+	assert.True(t, c.Blog.Head().BlockNumber() < c.HeadBlockNum())
+	c.Close()
+
+	// without the state database and the fork database the block log and the reversible blocks are replayed
+	os.RemoveAll(common.DefaultConfig.DefaultStateDirName)
+	os.Remove(filepath.Join(common.DefaultConfig.DefaultBlocksDirName, common.DefaultConfig.ForkDBName))
+	c = newTestController()
+	defer func() {
+		c.Close()
+		isActiveController, IsActiveRc, IsActiveAz = false, false, false
+	}()
+	assert.Equal(t, head, c.Head.BlockId)
+	assert.Equal(t, lib, c.LastIrreversibleBlockNum())
+	assert.Equal(t, lib, c.Blog.Head().BlockNumber())
+	assert.False(t, c.RePlaying)
+}
+
 func TestController_Clean(t *testing.T) {
 	c := GetControllerInstance()
 	c.Clean()
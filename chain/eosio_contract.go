@@ -1,6 +1,7 @@
 package chain
 
 import (
+	"crypto/sha256"
 	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
 	arithmetic "github.com/eosspark/eos-go/common/arithmetic_types"
@@ -67,7 +68,7 @@ func empty(a uint64) bool {
 
 func applyEosioNewaccount(context *ApplyContext) {
 
-	create := &newAccount{}
+	create := &NewAccount{}
 	rlp.DecodeBytes(context.Act.Data, create)
 
 	//try.Try()
@@ -135,8 +136,9 @@ func applyEosioSetcode(context *ApplyContext) {
 
 	var codeId *crypto.Sha256
 	if len(act.Code) > 0 {
-		codeId = crypto.NewSha256Byte(act.Code)
-		//exec.validate(context.Control, act.Code)
+		digest := sha256.Sum256(act.Code)
+		codeId = crypto.NewSha256Byte(digest[:])
+		context.Control.GetWasmInterface().Validate(act.Code)
 	}
 
 	accountObject := entity.AccountObject{Name: act.Account}
@@ -147,6 +149,7 @@ func applyEosioSetcode(context *ApplyContext) {
 	newSize := codeSize * int(common.DefaultConfig.SetcodeRamBytesMultiplier)
 
 	EosAssert(accountObject.CodeVersion != *codeId, &SetExactCode{}, "contract is already running this version of code")
+	oldCodeVersion := accountObject.CodeVersion
 
 	db.Modify(&accountObject, func(a *entity.AccountObject) {
 		a.LastCodeUpdate = context.Control.PendingBlockTime()
@@ -156,6 +159,10 @@ func applyEosioSetcode(context *ApplyContext) {
 		}
 	})
 
+	if oldCodeVersion != (crypto.Sha256{}) {
+		context.Control.GetWasmInterface().InvalidateCode(oldCodeVersion)
+	}
+
 	accountSequenceObj := entity.AccountSequenceObject{Name: act.Account}
 	db.Modify(&accountSequenceObj, func(aso *entity.AccountSequenceObject) {
 		aso.CodeSequence += 1
@@ -175,6 +182,14 @@ func applyEosioSetabi(context *ApplyContext) {
 
 	context.RequireAuthorization(int64(act.Account))
 
+	/// if an ABI is specified make sure it is well formed and doesn't
+	/// reference any undefined types
+	if len(act.Abi) > 0 {
+		abi, err := types.DecodeAbiDef(act.Abi)
+		EosAssert(err == nil, &AbiException{}, "setabi of %s: unable to unpack abi: %s", act.Account, err)
+		types.NewAbiSerializer(abi, common.MaxMicroseconds())
+	}
+
 	accountObject := entity.AccountObject{Name: act.Account}
 	db.Find("byName", accountObject, &accountObject)
 
@@ -201,7 +216,7 @@ func applyEosioSetabi(context *ApplyContext) {
 
 func applyEosioUpdateauth(context *ApplyContext) {
 
-	update := updateAuth{}
+	update := UpdateAuth{}
 	rlp.DecodeBytes(context.Act.Data, &update)
 	context.RequireAuthorization(int64(update.Account))
 
@@ -268,7 +283,7 @@ func applyEosioUpdateauth(context *ApplyContext) {
 
 func applyEosioDeleteauth(context *ApplyContext) {
 
-	remove := deleteAuth{}
+	remove := DeleteAuth{}
 	rlp.DecodeBytes(context.Act.Data, &remove)
 	context.RequireAuthorization(int64(remove.Account))
 
@@ -7,13 +7,13 @@ import (
 
 func CommonTypeDefs() []types.TypeDef {
 	ts := []types.TypeDef{}
-	ts = append(ts, types.TypeDef{"AccountName", "name"})
-	ts = append(ts, types.TypeDef{"PermissionName", "name"})
-	ts = append(ts, types.TypeDef{"ActionName", "name"})
-	ts = append(ts, types.TypeDef{"TableName", "name"})
-	ts = append(ts, types.TypeDef{"TransactionIdType", "checksum256"})
-	ts = append(ts, types.TypeDef{"BlockIdType", "checksum256"})
-	ts = append(ts, types.TypeDef{"WeightType", "uint16"})
+	ts = append(ts, types.TypeDef{"account_name", "name"})
+	ts = append(ts, types.TypeDef{"permission_name", "name"})
+	ts = append(ts, types.TypeDef{"action_name", "name"})
+	ts = append(ts, types.TypeDef{"table_name", "name"})
+	ts = append(ts, types.TypeDef{"transaction_id_type", "checksum256"})
+	ts = append(ts, types.TypeDef{"block_id_type", "checksum256"})
+	ts = append(ts, types.TypeDef{"weight_type", "uint16"})
 	return ts
 }
 
@@ -114,9 +114,9 @@ func EosioContractAbi(eosioSystemAbi types.AbiDef) types.AbiDef {
 	eosAbi.Structs = append(eosAbi.Structs, types.StructDef{"authority", "",
 		[]types.FieldDef{
 			types.FieldDef{"threshold", "uint32"},
-			types.FieldDef{"keys", "weight_type[]"},
+			types.FieldDef{"keys", "key_weight[]"},
 			types.FieldDef{"accounts", "permission_level_weight[]"},
-			types.FieldDef{"waits", "weight_type[]"},
+			types.FieldDef{"waits", "wait_weight[]"},
 		},
 	})
 
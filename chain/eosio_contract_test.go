@@ -26,7 +26,7 @@ func TestApplyEosioNewAccount(t *testing.T) {
 		privKey, _ := ecc.NewPrivateKey(wif)
 		pubKey := privKey.PublicKey()
 
-		creator := newAccount{
+		creator := NewAccount{
 			Creator: common.AccountName(common.N("eosio")),
 			Name:    common.AccountName(common.N("xiaoyu")),
 			Owner: types.Authority{
@@ -149,7 +149,7 @@ func TestApplyEosioUpdateauth(t *testing.T) {
 		account2 := "michael"
 		createNewAccount(control, account2)
 
-		updateAuth := updateAuth{
+		updateAuth := UpdateAuth{
 			Account:    common.AccountName(common.N(account1)),
 			Permission: common.PermissionName(common.N("active")),
 			Parent:     common.PermissionName(common.N("owner")),
@@ -325,7 +325,7 @@ func createNewAccount(control *Controller, name string) {
 	privKey, _ := ecc.NewPrivateKey(wif)
 	pubKey := privKey.PublicKey()
 
-	creator := newAccount{
+	creator := NewAccount{
 		Creator: common.AccountName(common.N("eosio")),
 		Name:    common.AccountName(common.N(name)),
 		Owner: types.Authority{
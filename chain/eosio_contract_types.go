@@ -11,18 +11,19 @@ type contractTypesInterface interface {
 	getName() common.AccountName
 }
 
-type newAccount struct {
+// NewAccount is the data of eosio::newaccount, the history plugin reads it as well
+type NewAccount struct {
 	Creator common.AccountName
 	Name    common.AccountName
 	Owner   types.Authority
 	Active  types.Authority
 }
 
-func (n newAccount) getAccount() common.AccountName {
+func (n NewAccount) getAccount() common.AccountName {
 	return common.DefaultConfig.SystemAccountName
 }
 
-func (n newAccount) getName() common.AccountName {
+func (n NewAccount) getName() common.AccountName {
 	return common.AccountName(common.N("newaccount"))
 }
 
@@ -54,31 +55,33 @@ func (s setAbi) getName() common.ActionName {
 	return common.ActionName(common.N("setabi"))
 }
 
-type updateAuth struct {
+// UpdateAuth is the data of eosio::updateauth
+type UpdateAuth struct {
 	Account    common.AccountName
 	Permission common.PermissionName
 	Parent     common.PermissionName
 	Auth       types.Authority
 }
 
-func (u updateAuth) getAccount() common.AccountName {
+func (u UpdateAuth) getAccount() common.AccountName {
 	return common.DefaultConfig.SystemAccountName
 }
 
-func (u updateAuth) getName() common.ActionName {
+func (u UpdateAuth) getName() common.ActionName {
 	return common.ActionName(common.N("updateauth"))
 }
 
-type deleteAuth struct {
+// DeleteAuth is the data of eosio::deleteauth
+type DeleteAuth struct {
 	Account    common.AccountName
 	Permission common.PermissionName
 }
 
-func (d deleteAuth) getAccount() common.AccountName {
+func (d DeleteAuth) getAccount() common.AccountName {
 	return common.DefaultConfig.SystemAccountName
 }
 
-func (d deleteAuth) getName() common.ActionName {
+func (d DeleteAuth) getName() common.ActionName {
 	return common.ActionName(common.N("deleteauth"))
 }
 
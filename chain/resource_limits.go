@@ -26,6 +26,65 @@ func newResourceLimitsManager(control *Controller) *ResourceLimitsManager {
 	return &rcInstance
 }
 
+// ResourceLimitsView reads the resource limits and usage of the accounts the way ResourceLimitsManager does,
+// from a view of the state, the api plugins answer from the same view as the rest of a query
+type ResourceLimitsView struct {
+	db stateReader
+}
+
+// stateReader is what the resource limits are read from, the state database or a view of it
+type stateReader interface {
+	Find(tagName string, in interface{}, out interface{}) error
+}
+
+func NewResourceLimitsView(db database.DataBaseView) *ResourceLimitsView {
+	return &ResourceLimitsView{db: db}
+}
+
+func (r *ResourceLimitsManager) view() *ResourceLimitsView {
+	return &ResourceLimitsView{db: r.db}
+}
+
+func (r *ResourceLimitsManager) GetAccountRamUsage(account common.AccountName) int64 {
+	return r.view().GetAccountRamUsage(account)
+}
+
+func (r *ResourceLimitsManager) GetAccountLimits(account common.AccountName, ramBytes *int64, netWeight *int64, cpuWeight *int64) {
+	r.view().GetAccountLimits(account, ramBytes, netWeight, cpuWeight)
+}
+
+func (r *ResourceLimitsManager) GetVirtualBlockCpuLimit() uint64 {
+	return r.view().GetVirtualBlockCpuLimit()
+}
+
+func (r *ResourceLimitsManager) GetVirtualBlockNetLimit() uint64 {
+	return r.view().GetVirtualBlockNetLimit()
+}
+
+func (r *ResourceLimitsManager) GetBlockCpuLimit() uint64 {
+	return r.view().GetBlockCpuLimit()
+}
+
+func (r *ResourceLimitsManager) GetBlockNetLimit() uint64 {
+	return r.view().GetBlockNetLimit()
+}
+
+func (r *ResourceLimitsManager) GetAccountCpuLimit(name common.AccountName, elastic bool) int64 {
+	return r.view().GetAccountCpuLimit(name, elastic)
+}
+
+func (r *ResourceLimitsManager) GetAccountCpuLimitEx(name common.AccountName, elastic bool) AccountResourceLimit {
+	return r.view().GetAccountCpuLimitEx(name, elastic)
+}
+
+func (r *ResourceLimitsManager) GetAccountNetLimit(name common.AccountName, elastic bool) int64 {
+	return r.view().GetAccountNetLimit(name, elastic)
+}
+
+func (r *ResourceLimitsManager) GetAccountNetLimitEx(name common.AccountName, elastic bool) AccountResourceLimit {
+	return r.view().GetAccountNetLimitEx(name, elastic)
+}
+
 func (r *ResourceLimitsManager) InitializeDatabase() {
 	config := entity.NewResourceLimitsConfigObject()
 	r.db.Insert(&config)
@@ -156,7 +215,7 @@ func (r *ResourceLimitsManager) VerifyAccountRamUsage(account common.AccountName
 	}
 }
 
-func (r *ResourceLimitsManager) GetAccountRamUsage(account common.AccountName) int64 {
+func (r *ResourceLimitsView) GetAccountRamUsage(account common.AccountName) int64 {
 	usage := entity.ResourceUsageObject{}
 	usage.Owner = account
 	r.db.Find("byOwner", usage, &usage)
@@ -201,7 +260,7 @@ func (r *ResourceLimitsManager) SetAccountLimits(account common.AccountName, ram
 	return decreasedLimit
 }
 
-func (r *ResourceLimitsManager) GetAccountLimits(account common.AccountName, ramBytes *int64, netWeight *int64, cpuWeight *int64) {
+func (r *ResourceLimitsView) GetAccountLimits(account common.AccountName, ramBytes *int64, netWeight *int64, cpuWeight *int64) {
 	pendingBuo := entity.ResourceLimitsObject{}
 	pendingBuo.Owner = account
 	pendingBuo.Pending = true
@@ -294,19 +353,19 @@ func (r *ResourceLimitsManager) ProcessBlockUsage(blockNum uint32) {
 	})
 }
 
-func (r *ResourceLimitsManager) GetVirtualBlockCpuLimit() uint64 {
+func (r *ResourceLimitsView) GetVirtualBlockCpuLimit() uint64 {
 	state := entity.DefaultResourceLimitsStateObject
 	r.db.Find("id", state, &state)
 	return state.VirtualCpuLimit
 }
 
-func (r *ResourceLimitsManager) GetVirtualBlockNetLimit() uint64 {
+func (r *ResourceLimitsView) GetVirtualBlockNetLimit() uint64 {
 	state := entity.DefaultResourceLimitsStateObject
 	r.db.Find("id", state, &state)
 	return state.VirtualNetLimit
 }
 
-func (r *ResourceLimitsManager) GetBlockCpuLimit() uint64 {
+func (r *ResourceLimitsView) GetBlockCpuLimit() uint64 {
 	state := entity.DefaultResourceLimitsStateObject
 	r.db.Find("id", state, &state)
 	config := entity.DefaultResourceLimitsConfigObject
@@ -314,7 +373,7 @@ func (r *ResourceLimitsManager) GetBlockCpuLimit() uint64 {
 	return config.CpuLimitParameters.Max - state.PendingCpuUsage
 }
 
-func (r *ResourceLimitsManager) GetBlockNetLimit() uint64 {
+func (r *ResourceLimitsView) GetBlockNetLimit() uint64 {
 	state := entity.DefaultResourceLimitsStateObject
 	r.db.Find("id", state, &state)
 	config := entity.DefaultResourceLimitsConfigObject
@@ -322,12 +381,12 @@ func (r *ResourceLimitsManager) GetBlockNetLimit() uint64 {
 	return config.NetLimitParameters.Max - state.PendingNetUsage
 }
 
-func (r *ResourceLimitsManager) GetAccountCpuLimit(name common.AccountName, elastic bool) int64 {
+func (r *ResourceLimitsView) GetAccountCpuLimit(name common.AccountName, elastic bool) int64 {
 	arl := r.GetAccountCpuLimitEx(name, elastic)
 	return arl.Available
 }
 
-func (r *ResourceLimitsManager) GetAccountCpuLimitEx(name common.AccountName, elastic bool) AccountResourceLimit {
+func (r *ResourceLimitsView) GetAccountCpuLimitEx(name common.AccountName, elastic bool) AccountResourceLimit {
 	state := entity.DefaultResourceLimitsStateObject
 	r.db.Find("id", state, &state)
 	config := entity.DefaultResourceLimitsConfigObject
@@ -374,12 +433,12 @@ func (r *ResourceLimitsManager) GetAccountCpuLimitEx(name common.AccountName, el
 	return arl
 }
 
-func (r *ResourceLimitsManager) GetAccountNetLimit(name common.AccountName, elastic bool) int64 {
+func (r *ResourceLimitsView) GetAccountNetLimit(name common.AccountName, elastic bool) int64 {
 	arl := r.GetAccountNetLimitEx(name, elastic)
 	return arl.Available
 }
 
-func (r *ResourceLimitsManager) GetAccountNetLimitEx(name common.AccountName, elastic bool) AccountResourceLimit {
+func (r *ResourceLimitsView) GetAccountNetLimitEx(name common.AccountName, elastic bool) AccountResourceLimit {
 	state := entity.DefaultResourceLimitsStateObject
 	r.db.Find("id", state, &state)
 	config := entity.DefaultResourceLimitsConfigObject
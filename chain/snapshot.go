@@ -0,0 +1,146 @@
+package chain
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto/rlp"
+	"github.com/eosspark/eos-go/database"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+)
+
+// see: libraries/chain/snapshot.cpp
+
+// SnapshotFile is the snapshot the controller starts from when there is no fork database to resume,
+// it is set by the --snapshot option before the controller is created
+var SnapshotFile string
+
+const (
+	snapshotMagic   uint32 = 0x30510550
+	snapshotVersion uint32 = 1
+)
+
+// snapshotHead is the first record of a snapshot, a versioned file. A section of each type of
+// state objects follows it: the snapshotSection record, one record per encoded object in id
+// order and an empty record. Nothing is held in memory but the current object
+type snapshotHead struct {
+	ChainId common.ChainIdType
+	Head    types.BlockHeaderState
+}
+
+type snapshotSection struct {
+	Name string
+}
+
+// CreateSnapshot aborts the pending block, the snapshot is only consistent between blocks, and
+// writes a snapshot of the head block to dir. It returns the path of the snapshot
+func (c *Controller) CreateSnapshot(dir string) string {
+	c.AbortBlock()
+	path := filepath.Join(dir, fmt.Sprintf("snapshot-%s.bin", c.HeadBlockId()))
+	_, err := os.Stat(path)
+	EosAssert(os.IsNotExist(err), &SnapshotException{}, "snapshot named %s already exists", path)
+	c.WriteSnapshot(path)
+	return path
+}
+
+// WriteSnapshot saves the state database and the head block state to path, the snapshot is only
+// consistent between blocks so there must be no pending block
+func (c *Controller) WriteSnapshot(path string) {
+	EosAssert(c.Pending == nil || !c.Pending.Valid, &BlockValidateException{},
+		"cannot take a consistent snapshot with a pending block")
+	writeSnapshot(c.DB, c.ChainID, &c.Head.BlockHeaderState, path)
+}
+
+func writeSnapshot(db database.DataBase, chainId common.ChainIdType, head *types.BlockHeaderState, path string) {
+	file, err := types.CreateVersionedFile(path, snapshotMagic, snapshotVersion)
+	EosAssert(err == nil, &SnapshotException{}, "unable to write snapshot %s: %s", path, err)
+	defer file.Abort()
+
+	err = file.Encode(&snapshotHead{ChainId: chainId, Head: *head})
+	for _, object := range StateObjects {
+		if err == nil {
+			err = writeSnapshotSection(file, db, reflect.TypeOf(object).Elem())
+		}
+	}
+	if err == nil {
+		err = file.Close()
+	}
+	EosAssert(err == nil, &SnapshotException{}, "unable to write snapshot %s: %s", path, err)
+}
+
+func writeSnapshotSection(file *types.VersionedFileWriter, db database.DataBase, objectType reflect.Type) error {
+	idx, err := db.GetIndex("id", reflect.New(objectType).Elem().Interface())
+	if err != nil {
+		return fmt.Errorf("unable to read %s objects: %s", objectType.Name(), err)
+	}
+	if err = file.Encode(&snapshotSection{Name: objectType.Name()}); err != nil {
+		return err
+	}
+	it := idx.BeginIterator()
+	defer it.Release()
+	for it.Next() {
+		if err = file.Write(it.Value()); err != nil {
+			return err
+		}
+	}
+	return file.Write(nil)
+}
+
+// readSnapshot restores the objects of the snapshot at path into db, which has to be empty, and
+// returns the chain id and the head block state the snapshot was taken at
+func readSnapshot(db database.DataBase, path string) (common.ChainIdType, types.BlockHeaderState) {
+	file, err := types.OpenVersionedFile(path, snapshotMagic, snapshotVersion)
+	_, unreadable := err.(*os.PathError)
+	EosAssert(!unreadable, &SnapshotException{}, "unable to read snapshot %s: %s", path, err)
+	EosAssert(err == nil, &SnapshotValidationException{}, "snapshot %s is not valid: %s", path, err)
+	defer file.Close()
+
+	content := snapshotHead{}
+	err = file.Decode(&content)
+	EosAssert(err == nil, &SnapshotValidationException{}, "snapshot %s is corrupted: %s", path, err)
+	head := &content.Head
+	EosAssert(head.BlockId == head.Header.BlockID() && head.BlockNum == head.Header.BlockNumber(),
+		&SnapshotValidationException{}, "head block state %s of snapshot %s does not match its header", head.BlockId, path)
+
+	objectTypes := make(map[string]reflect.Type, len(StateObjects))
+	for _, object := range StateObjects {
+		objectType := reflect.TypeOf(object).Elem()
+		objectTypes[objectType.Name()] = objectType
+	}
+	for {
+		section := snapshotSection{}
+		if err = file.Decode(&section); err == io.EOF {
+			break
+		}
+		EosAssert(err == nil, &SnapshotValidationException{}, "snapshot %s is corrupted: %s", path, err)
+		objectType, ok := objectTypes[section.Name]
+		EosAssert(ok, &SnapshotValidationException{}, "snapshot %s has a section of unknown objects %s", path, section.Name)
+		readSnapshotSection(file, db, objectType, path)
+	}
+	return content.ChainId, content.Head
+}
+
+func readSnapshotSection(file *types.VersionedFileReader, db database.DataBase, objectType reflect.Type, path string) {
+	for {
+		row, err := file.Read()
+		EosAssert(err == nil, &SnapshotValidationException{}, "snapshot %s is corrupted: %s", path, err)
+		if len(row) == 0 {
+			return
+		}
+		object := reflect.New(objectType).Interface()
+		try.Try(func() {
+			err = rlp.DecodeBytes(row, object)
+		}).Catch(func(e interface{}) {
+			err = fmt.Errorf("%v", e)
+		}).End()
+		EosAssert(err == nil, &SnapshotValidationException{}, "snapshot %s holds a bad %s: %s", path, objectType.Name(), err)
+		err = db.Restore(object)
+		EosAssert(err == nil, &SnapshotException{}, "unable to restore %s from snapshot %s: %s", objectType.Name(), path, err)
+	}
+}
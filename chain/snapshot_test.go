@@ -0,0 +1,173 @@
+package chain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto"
+	"github.com/eosspark/eos-go/crypto/ecc"
+	"github.com/eosspark/eos-go/crypto/rlp"
+	"github.com/eosspark/eos-go/database"
+	"github.com/eosspark/eos-go/entity"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSnapshot(t *testing.T) (string, database.DataBase, types.BlockHeaderState) {
+	root, err := ioutil.TempDir("", "snapshot")
+	assert.NoError(t, err)
+
+	db, err := database.NewDataBase(database.InMemory)
+	assert.NoError(t, err)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		assert.NoError(t, db.Insert(&entity.AccountObject{Name: common.AccountName(common.N(name))}))
+	}
+	bob := entity.AccountObject{}
+	assert.NoError(t, db.Find("byName", entity.AccountObject{Name: common.AccountName(common.N("bob"))}, &bob))
+	assert.NoError(t, db.Remove(&bob))
+	assert.NoError(t, db.Insert(&entity.GlobalPropertyObject{}))
+
+	head := types.BlockHeaderState{}
+	head.Header.Timestamp = common.NewBlockTimeStamp(common.Now())
+	head.Header.Producer = common.AccountName(common.N("eosio"))
+	head.BlockId = head.Header.BlockID()
+	head.BlockNum = head.Header.BlockNumber()
+	return filepath.Join(root, "snapshot.bin"), db, head
+}
+
+func TestSnapshot_roundTrip(t *testing.T) {
+	path, db, head := newTestSnapshot(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	chainId := common.ChainIdType(crypto.Hash256("snapshot"))
+	writeSnapshot(db, chainId, &head, path)
+
+	restored, err := database.NewDataBase(database.InMemory)
+	assert.NoError(t, err)
+	restoredId, restoredHead := readSnapshot(restored, path)
+	assert.Equal(t, chainId, restoredId)
+	assert.Equal(t, head.BlockId, restoredHead.BlockId)
+
+	// the removed account leaves a gap, the ids of the other accounts are kept
+	for _, name := range []string{"alice", "carol"} {
+		expected, out := entity.AccountObject{}, entity.AccountObject{}
+		key := entity.AccountObject{Name: common.AccountName(common.N(name))}
+		assert.NoError(t, db.Find("byName", key, &expected))
+		assert.NoError(t, restored.Find("byName", key, &out))
+		assert.Equal(t, expected, out)
+	}
+	gpo := entity.GlobalPropertyObject{}
+	assert.NoError(t, restored.Find("id", entity.GlobalPropertyObject{ID: 1}, &gpo))
+
+	next := entity.AccountObject{Name: common.AccountName(common.N("dave"))}
+	assert.NoError(t, restored.Insert(&next))
+	assert.Equal(t, common.IdType(4), next.ID)
+}
+
+func TestSnapshot_corrupted(t *testing.T) {
+	path, db, head := newTestSnapshot(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	writeSnapshot(db, common.ChainIdType{}, &head, path)
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	assert.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	restored, err := database.NewDataBase(database.InMemory)
+	assert.NoError(t, err)
+	corrupted := false
+	try.Try(func() {
+		readSnapshot(restored, path)
+	}).Catch(func(e *SnapshotValidationException) {
+		corrupted = true
+	}).End()
+	assert.True(t, corrupted)
+}
+
+func TestSnapshot_createSnapshot(t *testing.T) {
+	os.RemoveAll("/tmp/data")
+	defer os.RemoveAll("/tmp/data")
+	c := newTestController()
+	defer func() {
+		c.Close()
+		isActiveController, IsActiveRc, IsActiveAz = false, false, false
+	}()
+	key, err := ecc.NewPrivateKey("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3")
+	assert.NoError(t, err)
+	c.StartBlock(c.Head.Header.Timestamp+1, 0)
+	c.FinalizeBlock()
+	c.SignBlock(func(digest crypto.Sha256) ecc.Signature {
+		sig, _ := key.Sign(digest.Bytes())
+		return sig
+	})
+	c.CommitBlock(true)
+
+	// the pending block is aborted before the snapshot is written, the changes of the transaction
+	// it pushed are undone and the snapshot holds the state of the head block
+	before := filepath.Join("/tmp/data", "before.bin")
+	c.WriteSnapshot(before)
+	c.StartBlock(c.Head.Header.Timestamp+1, 0)
+	trx := newAccountTransaction(c, key, "alice")
+	trx.Implicit = true
+	c.PushTransaction(*trx, common.Now().AddUs(common.Seconds(30)), 100, true)
+	pending := filepath.Join("/tmp/data", "pending.bin")
+	writeSnapshot(c.DB, c.ChainID, &c.Head.BlockHeaderState, pending)
+	assert.NotEqual(t, readTestFile(t, before), readTestFile(t, pending))
+
+	dir := filepath.Join("/tmp/data", "snapshots")
+	path := c.CreateSnapshot(dir)
+	assert.Equal(t, filepath.Join(dir, "snapshot-"+c.HeadBlockId().String()+".bin"), path)
+	assert.Nil(t, c.Pending.PendingBlockState)
+	assert.Equal(t, int64(c.HeadBlockNum()), c.DB.Revision())
+	assert.Equal(t, readTestFile(t, before), readTestFile(t, path))
+
+	restored, err := database.NewDataBase(database.InMemory)
+	assert.NoError(t, err)
+	chainId, head := readSnapshot(restored, path)
+	assert.Equal(t, c.ChainID, chainId)
+	assert.Equal(t, c.HeadBlockId(), head.BlockId)
+
+	exists := false
+	try.Try(func() {
+		c.CreateSnapshot(dir)
+	}).Catch(func(e *SnapshotException) {
+		exists = true
+	}).End()
+	assert.True(t, exists)
+}
+
+// newAccountTransaction is a transaction of eosio creating the account name, signed with key
+func newAccountTransaction(c *Controller, key *ecc.PrivateKey, name string) *types.TransactionMetadata {
+	authority := types.Authority{Threshold: 1, Keys: []types.KeyWeight{{Key: key.PublicKey(), Weight: 1}}}
+	data, err := rlp.EncodeToBytes(&NewAccount{
+		Creator: common.AccountName(common.DefaultConfig.SystemAccountName),
+		Name:    common.AccountName(common.N(name)),
+		Owner:   authority,
+		Active:  authority,
+	})
+	if err != nil {
+		panic(err)
+	}
+	trx := types.Transaction{Actions: []*types.Action{{
+		Account:       common.AccountName(common.DefaultConfig.SystemAccountName),
+		Name:          common.ActionName(common.N("newaccount")),
+		Authorization: []types.PermissionLevel{{Actor: common.AccountName(common.DefaultConfig.SystemAccountName), Permission: common.PermissionName(common.DefaultConfig.ActiveName)}},
+		Data:          data,
+	}}}
+	trx.Expiration = common.NewTimePointSecTp(c.PendingBlockTime().AddUs(common.Seconds(60)))
+	trx.SetReferenceBlock(&c.Head.BlockId)
+	signed := types.NewSignedTransaction(&trx, []ecc.Signature{}, []common.HexBytes{})
+	signed.Sign(key, &c.ChainID)
+	return types.NewTransactionMetadataBySignedTrx(signed, common.CompressionNone)
+}
+
+func readTestFile(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	return data
+}
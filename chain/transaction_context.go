@@ -313,13 +313,15 @@ func (t *TransactionContext) Finalize() {
 
 func (t *TransactionContext) Squash() {
 	if t.UndoSession != nil {
-		t.UndoSession.Squash()
+		err := t.UndoSession.Squash()
+		EosAssert(err == nil, &DatabaseException{}, "squash of the transaction session failed: %s", err)
 	}
 }
 
 func (t *TransactionContext) Undo() {
 	if t.UndoSession != nil {
-		t.UndoSession.Undo()
+		err := t.UndoSession.Undo()
+		EosAssert(err == nil, &DatabaseException{}, "undo of the transaction session failed: %s", err)
 	}
 }
 
@@ -2,6 +2,7 @@ package types
 
 import (
 	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto/rlp"
 )
 
 // see: libraries/chain/contracts/abi_serializer.cpp:53...
@@ -19,6 +20,7 @@ type AbiDef struct {
 	RicardianClauses []ClausePair      `json:"ricardian_clauses,omitempty"`
 	ErrorMessages    []AbiErrorMessage `json:"error_messages,omitempty"`
 	Extensions       []*Extension      `json:"abi_extensions,omitempty"`
+	Variants         []VariantDef      `json:"variants,omitempty" eos:"-"` //binary extension, see PackAbiDef
 }
 
 type StructDef struct {
@@ -59,8 +61,13 @@ type AbiErrorMessage struct {
 }
 
 type TypeDef struct {
-	NewTypeName TypeName
-	Type        TypeName
+	NewTypeName TypeName `json:"new_type_name"`
+	Type        TypeName `json:"type"`
+}
+
+type VariantDef struct {
+	Name  string   `json:"name"`
+	Types []string `json:"types"`
 }
 
 func AbiDefs(types []TypeDef, structs []StructDef, actions []ActionDef, tables []TableDef, clauses []ClausePair, errorMsgs []AbiErrorMessage) {
@@ -72,3 +79,35 @@ func AbiDefs(types []TypeDef, structs []StructDef, actions []ActionDef, tables [
 	abi.RicardianClauses = clauses
 	abi.ErrorMessages = errorMsgs
 }
+
+// EncodeAbiDef packs an abi the way nodeos does, variants are appended only when present
+// so that abis without variants keep the eosio::abi/1.0 layout.
+func EncodeAbiDef(abi *AbiDef) ([]byte, error) {
+	data, err := rlp.EncodeToBytes(abi)
+	if err != nil || len(abi.Variants) == 0 {
+		return data, err
+	}
+	variants, err := rlp.EncodeToBytes(abi.Variants)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, variants...), nil
+}
+
+// DecodeAbiDef unpacks an abi produced by EncodeAbiDef or by eosiocpp/eosio-cpp.
+func DecodeAbiDef(data []byte) (*AbiDef, error) {
+	abi := &AbiDef{}
+	if err := rlp.DecodeBytes(data, abi); err != nil {
+		return nil, err
+	}
+	base, err := rlp.EncodeToBytes(abi)
+	if err != nil {
+		return nil, err
+	}
+	if len(base) < len(data) {
+		if err := rlp.DecodeBytes(data[len(base):], &abi.Variants); err != nil {
+			return nil, err
+		}
+	}
+	return abi, nil
+}
@@ -1,4 +1,1015 @@
 package types
 
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto/ecc"
+	. "github.com/eosspark/eos-go/exception"
+)
+
+// see: libraries/chain/abi_serializer.cpp
+
+const maxRecursionDepth = 32
+
+const timePointFormat = "2006-01-02T15:04:05.000"
+
+type unpackFunc func(r *abiReader) interface{}
+type packFunc func(w *bytes.Buffer, v interface{})
+
+type builtInType struct {
+	unpack unpackFunc
+	pack   packFunc
+}
+
+/**
+ *  Describes the binary representation message and table contents so that it can
+ *  be converted to and from JSON.
+ */
 type AbiSerializer struct {
+	typeDefs      map[TypeName]TypeName
+	structs       map[TypeName]StructDef
+	actions       map[common.ActionName]TypeName
+	tables        map[common.TableName]TypeName
+	errorMessages map[uint64]string
+	variants      map[TypeName]VariantDef
+	builtInTypes  map[TypeName]builtInType
+}
+
+func NewAbiSerializer(abi *AbiDef, maxSerializationTime common.Microseconds) *AbiSerializer {
+	a := &AbiSerializer{}
+	a.configureBuiltInTypes()
+	a.SetAbi(abi, maxSerializationTime)
+	return a
+}
+
+func (a *AbiSerializer) SetAbi(abi *AbiDef, maxSerializationTime common.Microseconds) {
+	ctx := newAbiTraverseContext(maxSerializationTime)
+
+	EosAssert(strings.HasPrefix(abi.Version, "eosio::abi/1."), &UnsupportedAbiVersionException{},
+		"ABI has an unsupported version %s", abi.Version)
+
+	a.typeDefs = make(map[TypeName]TypeName, len(abi.Types))
+	a.structs = make(map[TypeName]StructDef, len(abi.Structs))
+	a.actions = make(map[common.ActionName]TypeName, len(abi.Actions))
+	a.tables = make(map[common.TableName]TypeName, len(abi.Tables))
+	a.errorMessages = make(map[uint64]string, len(abi.ErrorMessages))
+	a.variants = make(map[TypeName]VariantDef, len(abi.Variants))
+
+	for _, st := range abi.Structs {
+		a.structs[TypeName(st.Name)] = st
+	}
+	// typedefs are registered before they are validated, a typedef may name one defined after it and
+	// cycles among them are only reported once they are all known, see: validate
+	for i, td := range abi.Types {
+		_, isTypeDef := a.typeDefs[td.NewTypeName]
+		_, isStruct := a.structs[td.NewTypeName]
+		EosAssert(!isTypeDef && !isStruct && !a.IsBuiltinType(td.NewTypeName), &DuplicateAbiTypeDefException{},
+			"types[%d]: type already exists: %s", i, td.NewTypeName)
+		a.typeDefs[td.NewTypeName] = td.Type
+	}
+	for _, ac := range abi.Actions {
+		a.actions[ac.Name] = TypeName(ac.Type)
+	}
+	for _, t := range abi.Tables {
+		a.tables[t.Name] = TypeName(t.Type)
+	}
+	for _, e := range abi.ErrorMessages {
+		a.errorMessages[e.Code] = e.Message
+	}
+	for _, v := range abi.Variants {
+		a.variants[TypeName(v.Name)] = v
+	}
+
+	/** The ABI vector may contain duplicates which would make it an invalid ABI */
+	EosAssert(len(a.typeDefs) == len(abi.Types), &DuplicateAbiTypeDefException{}, "duplicate type definition detected")
+	EosAssert(len(a.structs) == len(abi.Structs), &DuplicateAbiStructDefException{}, "duplicate struct definition detected: %s",
+		duplicateName(len(abi.Structs), func(i int) string { return abi.Structs[i].Name }))
+	EosAssert(len(a.actions) == len(abi.Actions), &DuplicateAbiActionDefException{}, "duplicate action definition detected: %s",
+		duplicateName(len(abi.Actions), func(i int) string { return abi.Actions[i].Name.String() }))
+	EosAssert(len(a.tables) == len(abi.Tables), &DuplicateAbiTableDefException{}, "duplicate table definition detected: %s",
+		duplicateName(len(abi.Tables), func(i int) string { return abi.Tables[i].Name.String() }))
+	EosAssert(len(a.errorMessages) == len(abi.ErrorMessages), &DuplicateAbiErrMsgDefException{}, "duplicate error message definition detected: %s",
+		duplicateName(len(abi.ErrorMessages), func(i int) string { return strconv.FormatUint(abi.ErrorMessages[i].Code, 10) }))
+	EosAssert(len(a.variants) == len(abi.Variants), &DuplicateAbiVariantDefException{}, "duplicate variant definition detected: %s",
+		duplicateName(len(abi.Variants), func(i int) string { return abi.Variants[i].Name }))
+
+	a.validate(abi, ctx)
+}
+
+// validate makes sure every type referenced by the abi is defined and that typedefs and struct
+// bases have no cycles, errors name the offending path such as "structs[1].fields[2]"
+func (a *AbiSerializer) validate(abi *AbiDef, ctx *abiTraverseContext) {
+	for i, td := range abi.Types {
+		seen := []TypeName{td.NewTypeName, td.Type}
+		next, ok := a.typeDefs[td.Type]
+		for ok {
+			ctx.checkDeadline()
+			EosAssert(!containsType(seen, next), &AbiCircularDefException{},
+				"types[%d]: Circular reference in type %s", i, td.NewTypeName)
+			seen = append(seen, next)
+			next, ok = a.typeDefs[next]
+		}
+	}
+	for i, td := range abi.Types {
+		EosAssert(a.isType(td.Type, ctx), &InvalidTypeInsideAbi{}, "types[%d]: %s", i, td.Type)
+	}
+	for i, st := range abi.Structs {
+		if len(st.Base) > 0 {
+			current := st
+			seen := []TypeName{TypeName(current.Name)}
+			for len(current.Base) > 0 {
+				ctx.checkDeadline()
+				base, ok := a.structs[a.ResolveType(TypeName(current.Base))]
+				EosAssert(ok, &InvalidTypeInsideAbi{}, "structs[%d].base: %s of struct %s is not a struct", i, current.Base, current.Name)
+				EosAssert(!containsType(seen, TypeName(base.Name)), &AbiCircularDefException{},
+					"structs[%d].base: Circular reference in struct %s", i, st.Name)
+				seen = append(seen, TypeName(base.Name))
+				current = base
+			}
+		}
+		for j, field := range st.Fields {
+			ctx.checkDeadline()
+			EosAssert(a.isType(removeBinExtension(TypeName(field.Type)), ctx), &InvalidTypeInsideAbi{},
+				"structs[%d].fields[%d]: %s.%s has unknown type %s", i, j, st.Name, field.Name, field.Type)
+		}
+	}
+	for i, v := range abi.Variants {
+		for j, t := range v.Types {
+			ctx.checkDeadline()
+			EosAssert(a.isType(TypeName(t), ctx), &InvalidTypeInsideAbi{},
+				"variants[%d].types[%d]: %s has unknown type %s", i, j, v.Name, t)
+		}
+	}
+	for i, ac := range abi.Actions {
+		ctx.checkDeadline()
+		EosAssert(a.isType(TypeName(ac.Type), ctx), &InvalidTypeInsideAbi{},
+			"actions[%d]: %s has unknown type %s", i, ac.Name, ac.Type)
+	}
+	for i, t := range abi.Tables {
+		ctx.checkDeadline()
+		EosAssert(a.IsStruct(TypeName(t.Type)), &InvalidTypeInsideAbi{},
+			"tables[%d]: %s references missing struct %s", i, t.Name, t.Type)
+	}
+}
+
+func containsType(types []TypeName, t TypeName) bool {
+	for _, typ := range types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicateName returns the first name which occurs twice among the n names
+func duplicateName(n int, name func(i int) string) string {
+	seen := make(map[string]struct{}, n)
+	for i := 0; i < n; i++ {
+		if _, ok := seen[name(i)]; ok {
+			return name(i)
+		}
+		seen[name(i)] = struct{}{}
+	}
+	return ""
+}
+
+func (a *AbiSerializer) IsBuiltinType(t TypeName) bool {
+	_, ok := a.builtInTypes[t]
+	return ok
+}
+
+func (a *AbiSerializer) IsInteger(t TypeName) bool {
+	s := string(t)
+	return strings.HasPrefix(s, "uint") || strings.HasPrefix(s, "int")
+}
+
+func (a *AbiSerializer) GetIntegerSize(t TypeName) int {
+	s := string(t)
+	EosAssert(a.IsInteger(t), &InvalidTypeInsideAbi{}, "%s is not an integer type", s)
+	if strings.HasPrefix(s, "uint") {
+		size, _ := strconv.Atoi(s[4:])
+		return size
+	}
+	size, _ := strconv.Atoi(s[3:])
+	return size
+}
+
+func (a *AbiSerializer) IsStruct(t TypeName) bool {
+	_, ok := a.structs[a.ResolveType(t)]
+	return ok
+}
+
+func (a *AbiSerializer) IsArray(t TypeName) bool {
+	return strings.HasSuffix(string(t), "[]")
+}
+
+func (a *AbiSerializer) IsOptional(t TypeName) bool {
+	return strings.HasSuffix(string(t), "?")
+}
+
+func (a *AbiSerializer) IsType(t TypeName, maxSerializationTime common.Microseconds) bool {
+	return a.isType(t, newAbiTraverseContext(maxSerializationTime))
+}
+
+// FundamentalType strips the array or optional suffix, "uint8[]" becomes "uint8"
+func (a *AbiSerializer) FundamentalType(t TypeName) TypeName {
+	if a.IsArray(t) {
+		return t[:len(t)-2]
+	} else if a.IsOptional(t) {
+		return t[:len(t)-1]
+	}
+	return t
+}
+
+func (a *AbiSerializer) ResolveType(t TypeName) TypeName {
+	if next, ok := a.typeDefs[t]; ok {
+		for i := len(a.typeDefs); i > 0; i-- { // avoid infinite recursion
+			t = next
+			if next, ok = a.typeDefs[t]; !ok {
+				return t
+			}
+		}
+	}
+	return t
+}
+
+func (a *AbiSerializer) GetStruct(t TypeName) *StructDef {
+	st, ok := a.structs[a.ResolveType(t)]
+	EosAssert(ok, &InvalidTypeInsideAbi{}, "Unknown struct %s", t)
+	return &st
+}
+
+func (a *AbiSerializer) GetActionType(action common.ActionName) TypeName {
+	if t, ok := a.actions[action]; ok {
+		return t
+	}
+	return ""
+}
+
+func (a *AbiSerializer) GetTableType(table common.TableName) TypeName {
+	if t, ok := a.tables[table]; ok {
+		return t
+	}
+	return ""
+}
+
+func (a *AbiSerializer) GetErrorMessage(errorCode uint64) (string, bool) {
+	msg, ok := a.errorMessages[errorCode]
+	return msg, ok
+}
+
+// BinaryToVariant unpacks binary as type t, structs become common.Variants, arrays []interface{}
+// and variants a two items array of the selected type name and value.
+func (a *AbiSerializer) BinaryToVariant(t TypeName, binary []byte, maxSerializationTime common.Microseconds) interface{} {
+	ctx := newAbiTraverseContext(maxSerializationTime)
+	ctx.root = t
+	r := &abiReader{data: binary}
+	return a.binaryToVariant(t, r, ctx)
+}
+
+// VariantToBinary packs v as type t, v usually comes from json.Unmarshal into an interface{}
+func (a *AbiSerializer) VariantToBinary(t TypeName, v interface{}, maxSerializationTime common.Microseconds) []byte {
+	ctx := newAbiTraverseContext(maxSerializationTime)
+	ctx.root = t
+	w := bytes.NewBuffer(nil)
+	a.variantToBinary(t, v, w, ctx)
+	return w.Bytes()
+}
+
+func (a *AbiSerializer) BinaryToJson(t TypeName, binary []byte, maxSerializationTime common.Microseconds) ([]byte, error) {
+	return json.Marshal(a.BinaryToVariant(t, binary, maxSerializationTime))
+}
+
+func (a *AbiSerializer) JsonToBinary(t TypeName, data []byte, maxSerializationTime common.Microseconds) []byte {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&v)
+	EosAssert(err == nil, &PackException{}, "Invalid JSON for type %s: %s", t, err)
+	return a.VariantToBinary(t, v, maxSerializationTime)
+}
+
+func (a *AbiSerializer) isType(rtype TypeName, ctx *abiTraverseContext) bool {
+	defer ctx.enterScope()()
+	t := a.FundamentalType(rtype)
+	if a.IsBuiltinType(t) {
+		return true
+	}
+	if td, ok := a.typeDefs[t]; ok {
+		return a.isType(td, ctx)
+	}
+	if _, ok := a.structs[t]; ok {
+		return true
+	}
+	if _, ok := a.variants[t]; ok {
+		return true
+	}
+	return false
+}
+
+func (a *AbiSerializer) binaryToVariant(t TypeName, r *abiReader, ctx *abiTraverseContext) interface{} {
+	defer ctx.enterScope()()
+	rtype := a.ResolveType(t)
+	ftype := a.FundamentalType(rtype)
+
+	if a.IsArray(rtype) {
+		size := r.readUvarint()
+		vars := make([]interface{}, 0, common.Min(size, uint64(r.remaining())))
+		for i := uint64(0); i < size; i++ {
+			ctx.pushIndex(i)
+			v := a.binaryToVariant(ftype, r, ctx)
+			ctx.pop()
+			vars = append(vars, v)
+		}
+		return vars
+	}
+
+	if a.IsOptional(rtype) {
+		if r.readByte() == 0 {
+			return nil
+		}
+		return a.binaryToVariant(ftype, r, ctx)
+	}
+
+	if bt, ok := a.builtInTypes[rtype]; ok {
+		return bt.unpack(r)
+	}
+
+	if vd, ok := a.variants[rtype]; ok {
+		selected := r.readUvarint()
+		EosAssert(selected < uint64(len(vd.Types)), &UnpackException{},
+			"Unpacked invalid tag (%d) for variant '%s'", selected, ctx.path(rtype))
+		vt := TypeName(vd.Types[selected])
+		return []interface{}{vt, a.binaryToVariant(vt, r, ctx)}
+	}
+
+	obj := common.Variants{}
+	a.binaryToStruct(rtype, r, obj, ctx)
+	return obj
+}
+
+func (a *AbiSerializer) binaryToStruct(t TypeName, r *abiReader, obj common.Variants, ctx *abiTraverseContext) {
+	defer ctx.enterScope()()
+	st, ok := a.structs[t]
+	EosAssert(ok, &InvalidTypeInsideAbi{}, "Unknown type %s", t)
+
+	if len(st.Base) > 0 {
+		a.binaryToStruct(a.ResolveType(TypeName(st.Base)), r, obj, ctx)
+	}
+	for _, field := range st.Fields {
+		if r.remaining() == 0 && strings.HasSuffix(field.Type, "$") {
+			continue // binary extension
+		}
+		ctx.push(field.Name)
+		obj[field.Name] = a.binaryToVariant(removeBinExtension(TypeName(field.Type)), r, ctx)
+		ctx.pop()
+	}
+}
+
+func (a *AbiSerializer) variantToBinary(t TypeName, v interface{}, w *bytes.Buffer, ctx *abiTraverseContext) {
+	defer ctx.enterScope()()
+	rtype := a.ResolveType(t)
+
+	if a.IsArray(rtype) {
+		vars, ok := v.([]interface{})
+		if !ok && v != nil {
+			rv := reflect.ValueOf(v)
+			EosAssert(rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array, &PackException{},
+				"Expected input to be an array while processing '%s'", ctx.path(rtype))
+			vars = make([]interface{}, rv.Len())
+			for i := range vars {
+				vars[i] = rv.Index(i).Interface()
+			}
+		}
+		writeUvarint(w, uint64(len(vars)))
+		for i, item := range vars {
+			ctx.pushIndex(uint64(i))
+			a.variantToBinary(a.FundamentalType(rtype), item, w, ctx)
+			ctx.pop()
+		}
+		return
+	}
+
+	if a.IsOptional(rtype) {
+		if v == nil {
+			w.WriteByte(0)
+			return
+		}
+		w.WriteByte(1)
+		a.variantToBinary(a.FundamentalType(rtype), v, w, ctx)
+		return
+	}
+
+	if bt, ok := a.builtInTypes[rtype]; ok {
+		bt.pack(w, v)
+		return
+	}
+
+	if vd, ok := a.variants[rtype]; ok {
+		pair, ok := v.([]interface{})
+		EosAssert(ok && len(pair) == 2, &PackException{},
+			"Expected input to be an array of two items while processing variant '%s'", ctx.path(rtype))
+		vt, ok := pair[0].(string)
+		EosAssert(ok, &PackException{},
+			"Encountered non-string as first item of input array while processing variant '%s'", ctx.path(rtype))
+		for i, typ := range vd.Types {
+			if typ == vt {
+				writeUvarint(w, uint64(i))
+				a.variantToBinary(TypeName(typ), pair[1], w, ctx)
+				return
+			}
+		}
+		EosThrow(&PackException{}, "Specified type '%s' in input array is not valid within the variant '%s'", vt, ctx.path(rtype))
+	}
+
+	st, ok := a.structs[rtype]
+	EosAssert(ok, &InvalidTypeInsideAbi{}, "Unknown type %s", t)
+
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		a.structToBinary(&st, obj, w, ctx)
+	case common.Variants:
+		a.structToBinary(&st, obj, w, ctx)
+	case []interface{}:
+		EosAssert(len(st.Base) == 0, &InvalidTypeInsideAbi{},
+			"Using input array to specify the fields of the derived struct '%s'; input arrays are currently only allowed for structs without a base", ctx.path(rtype))
+		for i, field := range st.Fields {
+			if i < len(obj) {
+				ctx.push(field.Name)
+				a.variantToBinary(removeBinExtension(TypeName(field.Type)), obj[i], w, ctx)
+				ctx.pop()
+			} else if strings.HasSuffix(field.Type, "$") {
+				break
+			} else {
+				EosThrow(&PackException{}, "Early end to input array specifying the fields of struct '%s'; require input for field '%s'",
+					ctx.path(rtype), field.Name)
+			}
+		}
+	default:
+		EosThrow(&PackException{}, "Unexpected input encountered while processing struct '%s'", ctx.path(rtype))
+	}
+}
+
+func (a *AbiSerializer) structToBinary(st *StructDef, obj map[string]interface{}, w *bytes.Buffer, ctx *abiTraverseContext) {
+	defer ctx.enterScope()()
+	if len(st.Base) > 0 {
+		base := a.GetStruct(TypeName(st.Base))
+		a.structToBinary(base, obj, w, ctx)
+	}
+	disallowAdditionalFields := false
+	for _, field := range st.Fields {
+		if value, ok := obj[field.Name]; ok {
+			EosAssert(!disallowAdditionalFields, &PackException{},
+				"Unexpected field '%s' found in input object while processing struct '%s'", field.Name, ctx.path(TypeName(st.Name)))
+			ctx.push(field.Name)
+			a.variantToBinary(removeBinExtension(TypeName(field.Type)), value, w, ctx)
+			ctx.pop()
+		} else if strings.HasSuffix(field.Type, "$") {
+			disallowAdditionalFields = true
+		} else {
+			EosThrow(&PackException{}, "Missing field '%s' in input object while processing struct '%s'", field.Name, ctx.path(TypeName(st.Name)))
+		}
+	}
+}
+
+func removeBinExtension(t TypeName) TypeName {
+	if strings.HasSuffix(string(t), "$") {
+		return t[:len(t)-1]
+	}
+	return t
+}
+
+type abiTraverseContext struct {
+	maxSerializationTime common.Microseconds
+	deadline             common.TimePoint
+	recursionDepth       int
+	root                 TypeName
+	fields               []string
+}
+
+func newAbiTraverseContext(maxSerializationTime common.Microseconds) *abiTraverseContext {
+	ctx := &abiTraverseContext{maxSerializationTime: maxSerializationTime, deadline: common.MaxTimePoint()}
+	now := common.Now()
+	if maxSerializationTime < common.MaxTimePoint().Sub(now) {
+		ctx.deadline = now.AddUs(maxSerializationTime)
+	}
+	return ctx
+}
+
+func (ctx *abiTraverseContext) checkDeadline() {
+	EosAssert(common.Now() < ctx.deadline, &AbiSerializationDeadlineException{},
+		"serialization time limit %dus exceeded", ctx.maxSerializationTime)
+}
+
+// enterScope checks recursion depth and deadline, the returned func leaves the scope:
+//
+//	defer ctx.enterScope()()
+func (ctx *abiTraverseContext) enterScope() func() {
+	ctx.recursionDepth++
+	EosAssert(ctx.recursionDepth < maxRecursionDepth, &AbiRecursionDepthException{},
+		"recursive definition, max_recursion_depth %d", maxRecursionDepth)
+	ctx.checkDeadline()
+	return func() { ctx.recursionDepth-- }
+}
+
+func (ctx *abiTraverseContext) push(field string) {
+	ctx.fields = append(ctx.fields, "."+field)
+}
+
+func (ctx *abiTraverseContext) pushIndex(i uint64) {
+	ctx.fields = append(ctx.fields, fmt.Sprintf("[%d]", i))
+}
+
+func (ctx *abiTraverseContext) pop() {
+	ctx.fields = ctx.fields[:len(ctx.fields)-1]
+}
+
+// path names the value being processed, e.g. "transfer.quantity" or "action.authorization[1]"
+func (ctx *abiTraverseContext) path(t TypeName) string {
+	if len(ctx.fields) == 0 {
+		return string(t)
+	}
+	return string(ctx.root) + strings.Join(ctx.fields, "")
+}
+
+type abiReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *abiReader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *abiReader) read(n int) []byte {
+	EosAssert(n >= 0 && r.remaining() >= n, &UnpackException{},
+		"stream unexpectedly ended; unable to unpack %d bytes at position %d", n, r.pos)
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *abiReader) readByte() byte {
+	return r.read(1)[0]
+}
+
+func (r *abiReader) readUvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	EosAssert(n > 0, &UnpackException{}, "invalid varint at position %d", r.pos)
+	r.pos += n
+	return v
+}
+
+func writeUvarint(w *bytes.Buffer, v uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	w.Write(buf[:binary.PutUvarint(buf, v)])
+}
+
+func writeUint64(w *bytes.Buffer, v uint64) {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, v)
+	w.Write(buf)
+}
+
+func (a *AbiSerializer) configureBuiltInTypes() {
+	a.builtInTypes = map[TypeName]builtInType{
+		"bool": {
+			func(r *abiReader) interface{} { return r.readByte() != 0 },
+			func(w *bytes.Buffer, v interface{}) {
+				if variantToBool(v) {
+					w.WriteByte(1)
+				} else {
+					w.WriteByte(0)
+				}
+			},
+		},
+		"int8":    intType(1),
+		"uint8":   uintType(1),
+		"int16":   intType(2),
+		"uint16":  uintType(2),
+		"int32":   intType(4),
+		"uint32":  uintType(4),
+		"int64":   intType(8),
+		"uint64":  uintType(8),
+		"int128":  int128Type(true),
+		"uint128": int128Type(false),
+		"varint32": {
+			func(r *abiReader) interface{} {
+				v := uint32(r.readUvarint())
+				return int32(v>>1) ^ -int32(v&1)
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				i := int32(variantToInt64(v, 32))
+				writeUvarint(w, uint64(uint32((i<<1)^(i>>31))))
+			},
+		},
+		"varuint32": {
+			func(r *abiReader) interface{} { return uint32(r.readUvarint()) },
+			func(w *bytes.Buffer, v interface{}) { writeUvarint(w, variantToUint64(v, 32)) },
+		},
+		"float32": {
+			func(r *abiReader) interface{} {
+				return math.Float32frombits(binary.LittleEndian.Uint32(r.read(4)))
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				buf := make([]byte, 4)
+				binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(variantToFloat64(v))))
+				w.Write(buf)
+			},
+		},
+		"float64": {
+			func(r *abiReader) interface{} {
+				return math.Float64frombits(binary.LittleEndian.Uint64(r.read(8)))
+			},
+			func(w *bytes.Buffer, v interface{}) { writeUint64(w, math.Float64bits(variantToFloat64(v))) },
+		},
+		"float128":    fixedBytesType(16, "0x"),
+		"checksum160": fixedBytesType(20, ""),
+		"checksum256": fixedBytesType(32, ""),
+		"checksum512": fixedBytesType(64, ""),
+		"time_point": {
+			func(r *abiReader) interface{} {
+				us := int64(binary.LittleEndian.Uint64(r.read(8)))
+				return time.Unix(0, us*1000).UTC().Format(timePointFormat)
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				tp, err := common.FromIsoString(variantToString(v))
+				EosAssert(err == nil, &PackException{}, "invalid time_point %v: %s", v, err)
+				writeUint64(w, uint64(tp))
+			},
+		},
+		"time_point_sec": {
+			func(r *abiReader) interface{} {
+				sec := binary.LittleEndian.Uint32(r.read(4))
+				return time.Unix(int64(sec), 0).UTC().Format(common.JSONTimeFormat)
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				tps, err := common.FromIsoStringSec(variantToString(v))
+				EosAssert(err == nil, &PackException{}, "invalid time_point_sec %v: %s", v, err)
+				buf := make([]byte, 4)
+				binary.LittleEndian.PutUint32(buf, uint32(tps))
+				w.Write(buf)
+			},
+		},
+		"block_timestamp_type": {
+			func(r *abiReader) interface{} {
+				bt := common.BlockTimeStamp(binary.LittleEndian.Uint32(r.read(4)))
+				return bt.Totime().Format(timePointFormat)
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				tp, err := common.FromIsoString(variantToString(v))
+				EosAssert(err == nil, &PackException{}, "invalid block_timestamp_type %v: %s", v, err)
+				buf := make([]byte, 4)
+				binary.LittleEndian.PutUint32(buf, uint32(common.NewBlockTimeStamp(tp)))
+				w.Write(buf)
+			},
+		},
+		"name": {
+			func(r *abiReader) interface{} { return common.S(binary.LittleEndian.Uint64(r.read(8))) },
+			func(w *bytes.Buffer, v interface{}) { writeUint64(w, common.N(variantToString(v))) },
+		},
+		"bytes": {
+			func(r *abiReader) interface{} { return hex.EncodeToString(r.read(int(r.readUvarint()))) },
+			func(w *bytes.Buffer, v interface{}) {
+				b, err := hex.DecodeString(variantToString(v))
+				EosAssert(err == nil, &PackException{}, "invalid hex bytes %v: %s", v, err)
+				writeUvarint(w, uint64(len(b)))
+				w.Write(b)
+			},
+		},
+		"string": {
+			func(r *abiReader) interface{} { return string(r.read(int(r.readUvarint()))) },
+			func(w *bytes.Buffer, v interface{}) {
+				s := variantToString(v)
+				writeUvarint(w, uint64(len(s)))
+				w.WriteString(s)
+			},
+		},
+		"public_key": {
+			func(r *abiReader) interface{} {
+				key := ecc.PublicKey{Curve: ecc.CurveID(r.readByte())}
+				copy(key.Content[:], r.read(len(key.Content)))
+				return key.String()
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				key, err := ecc.NewPublicKey(variantToString(v))
+				EosAssert(err == nil, &PackException{}, "invalid public_key %v: %s", v, err)
+				w.WriteByte(byte(key.Curve))
+				w.Write(key.Content[:])
+			},
+		},
+		"signature": {
+			func(r *abiReader) interface{} {
+				sig := ecc.Signature{Curve: ecc.CurveID(r.readByte())}
+				copy(sig.Content[:], r.read(len(sig.Content)))
+				return sig.String()
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				sig, err := ecc.NewSignature(variantToString(v))
+				EosAssert(err == nil, &PackException{}, "invalid signature %v: %s", v, err)
+				w.WriteByte(byte(sig.Curve))
+				w.Write(sig.Content[:])
+			},
+		},
+		"symbol": {
+			func(r *abiReader) interface{} {
+				sym := unpackSymbol(binary.LittleEndian.Uint64(r.read(8)))
+				return fmt.Sprintf("%d,%s", sym.Precision, sym.Symbol)
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				s := variantToString(v)
+				parts := strings.SplitN(s, ",", 2)
+				EosAssert(len(parts) == 2, &PackException{}, "invalid symbol %s, expected precision,CODE", s)
+				precision, err := strconv.ParseUint(parts[0], 10, 8)
+				EosAssert(err == nil && precision <= 18, &PackException{}, "invalid symbol precision %s", s)
+				writeUint64(w, packSymbol(common.Symbol{Precision: uint8(precision), Symbol: parts[1]}))
+			},
+		},
+		"symbol_code": {
+			func(r *abiReader) interface{} {
+				return unpackSymbolCode(binary.LittleEndian.Uint64(r.read(8)))
+			},
+			func(w *bytes.Buffer, v interface{}) { writeUint64(w, packSymbolCode(variantToString(v))) },
+		},
+		"asset": {
+			func(r *abiReader) interface{} { return unpackAsset(r).String() },
+			func(w *bytes.Buffer, v interface{}) { packAsset(w, variantToString(v)) },
+		},
+		"extended_asset": {
+			func(r *abiReader) interface{} {
+				quantity := unpackAsset(r).String()
+				contract := common.S(binary.LittleEndian.Uint64(r.read(8)))
+				return common.Variants{"quantity": quantity, "contract": contract}
+			},
+			func(w *bytes.Buffer, v interface{}) {
+				var obj map[string]interface{}
+				switch o := v.(type) {
+				case map[string]interface{}:
+					obj = o
+				case common.Variants:
+					obj = o
+				default:
+					EosThrow(&PackException{}, "invalid extended_asset %v", v)
+				}
+				packAsset(w, variantToString(obj["quantity"]))
+				writeUint64(w, common.N(variantToString(obj["contract"])))
+			},
+		},
+	}
+}
+
+func intType(size int) builtInType {
+	return builtInType{
+		func(r *abiReader) interface{} {
+			b := r.read(size)
+			switch size {
+			case 1:
+				return int8(b[0])
+			case 2:
+				return int16(binary.LittleEndian.Uint16(b))
+			case 4:
+				return int32(binary.LittleEndian.Uint32(b))
+			}
+			// fc prints 64 bits values which not fit in 32 bits as strings
+			i := int64(binary.LittleEndian.Uint64(b))
+			if i > 0xffffffff || i < -0xffffffff {
+				return strconv.FormatInt(i, 10)
+			}
+			return i
+		},
+		func(w *bytes.Buffer, v interface{}) {
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, uint64(variantToInt64(v, size*8)))
+			w.Write(buf[:size])
+		},
+	}
+}
+
+func uintType(size int) builtInType {
+	return builtInType{
+		func(r *abiReader) interface{} {
+			b := r.read(size)
+			switch size {
+			case 1:
+				return b[0]
+			case 2:
+				return binary.LittleEndian.Uint16(b)
+			case 4:
+				return binary.LittleEndian.Uint32(b)
+			}
+			u := binary.LittleEndian.Uint64(b)
+			if u > 0xffffffff {
+				return strconv.FormatUint(u, 10)
+			}
+			return u
+		},
+		func(w *bytes.Buffer, v interface{}) {
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, variantToUint64(v, size*8))
+			w.Write(buf[:size])
+		},
+	}
+}
+
+// int128Type packs 128 bits integers little endian and represents them as decimal strings
+func int128Type(signed bool) builtInType {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 128)
+	return builtInType{
+		func(r *abiReader) interface{} {
+			b := r.read(16)
+			be := make([]byte, 16)
+			for i := range b {
+				be[15-i] = b[i]
+			}
+			n := new(big.Int).SetBytes(be)
+			if signed && b[15]&0x80 != 0 {
+				n.Sub(n, modulus)
+			}
+			return n.String()
+		},
+		func(w *bytes.Buffer, v interface{}) {
+			s := variantToString(v)
+			n, ok := new(big.Int).SetString(s, 0)
+			EosAssert(ok, &PackException{}, "invalid 128 bits integer %s", s)
+			if signed {
+				limit := new(big.Int).Lsh(big.NewInt(1), 127)
+				EosAssert(n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0, &PackException{}, "int128 out of range %s", s)
+				if n.Sign() < 0 {
+					n.Add(n, modulus)
+				}
+			} else {
+				EosAssert(n.Sign() >= 0 && n.Cmp(modulus) < 0, &PackException{}, "uint128 out of range %s", s)
+			}
+			be := n.FillBytes(make([]byte, 16))
+			for i := 15; i >= 0; i-- {
+				w.WriteByte(be[i])
+			}
+		},
+	}
+}
+
+func fixedBytesType(size int, prefix string) builtInType {
+	return builtInType{
+		func(r *abiReader) interface{} { return prefix + hex.EncodeToString(r.read(size)) },
+		func(w *bytes.Buffer, v interface{}) {
+			s := strings.TrimPrefix(variantToString(v), prefix)
+			b, err := hex.DecodeString(s)
+			EosAssert(err == nil && len(b) == size, &PackException{}, "expected %d bytes hex string, got %v", size, v)
+			w.Write(b)
+		},
+	}
+}
+
+func unpackSymbolCode(code uint64) string {
+	var s []byte
+	for ; code > 0; code >>= 8 {
+		s = append(s, byte(code&0xff))
+	}
+	return string(s)
+}
+
+func packSymbolCode(code string) uint64 {
+	EosAssert(len(code) > 0 && len(code) <= 7, &PackException{}, "invalid symbol code %s", code)
+	var result uint64
+	for i := len(code) - 1; i >= 0; i-- {
+		EosAssert(code[i] >= 'A' && code[i] <= 'Z', &PackException{}, "invalid character in symbol code %s", code)
+		result = result<<8 | uint64(code[i])
+	}
+	return result
+}
+
+func unpackSymbol(v uint64) common.Symbol {
+	return common.Symbol{Precision: uint8(v & 0xff), Symbol: unpackSymbolCode(v >> 8)}
+}
+
+func packSymbol(sym common.Symbol) uint64 {
+	return packSymbolCode(sym.Symbol)<<8 | uint64(sym.Precision)
+}
+
+func unpackAsset(r *abiReader) common.Asset {
+	amount := int64(binary.LittleEndian.Uint64(r.read(8)))
+	sym := unpackSymbol(binary.LittleEndian.Uint64(r.read(8)))
+	return common.Asset{Amount: amount, Symbol: sym}
+}
+
+func packAsset(w *bytes.Buffer, s string) {
+	asset, err := common.NewAsset(strings.TrimSpace(s))
+	EosAssert(err == nil, &PackException{}, "invalid asset %s: %s", s, err)
+	writeUint64(w, uint64(asset.Amount))
+	writeUint64(w, packSymbol(asset.Symbol))
+}
+
+func variantToString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case json.Number:
+		return s.String()
+	case fmt.Stringer:
+		return s.String()
+	}
+	EosThrow(&PackException{}, "expected string, got %v", v)
+	return ""
+}
+
+func variantToBool(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true" || b == "1"
+	}
+	return variantToUint64(v, 8) != 0
+}
+
+func variantToInt64(v interface{}, bits int) int64 {
+	var i int64
+	var err error
+	switch n := v.(type) {
+	case json.Number:
+		i, err = strconv.ParseInt(string(n), 10, bits)
+	case string:
+		i, err = strconv.ParseInt(n, 10, bits)
+	case float64:
+		i = int64(n)
+		EosAssert(float64(i) == n, &PackException{}, "expected integer, got %v", v)
+	case bool:
+		if n {
+			i = 1
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			i = int64(rv.Uint())
+		default:
+			EosThrow(&PackException{}, "expected integer, got %v", v)
+		}
+	}
+	EosAssert(err == nil, &PackException{}, "invalid int%d %v: %s", bits, v, err)
+	EosAssert(bits == 64 || (i >= -1<<uint(bits-1) && i < 1<<uint(bits-1)), &PackException{}, "int%d out of range %v", bits, v)
+	return i
+}
+
+func variantToUint64(v interface{}, bits int) uint64 {
+	var u uint64
+	var err error
+	switch n := v.(type) {
+	case json.Number:
+		u, err = strconv.ParseUint(string(n), 10, bits)
+	case string:
+		u, err = strconv.ParseUint(n, 10, bits)
+	case float64:
+		u = uint64(n)
+		EosAssert(n >= 0 && float64(u) == n, &PackException{}, "expected unsigned integer, got %v", v)
+	case bool:
+		if n {
+			u = 1
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			EosAssert(rv.Int() >= 0, &PackException{}, "expected unsigned integer, got %v", v)
+			u = uint64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u = rv.Uint()
+		default:
+			EosThrow(&PackException{}, "expected unsigned integer, got %v", v)
+		}
+	}
+	EosAssert(err == nil, &PackException{}, "invalid uint%d %v: %s", bits, v, err)
+	EosAssert(bits == 64 || u < 1<<uint(bits), &PackException{}, "uint%d out of range %v", bits, v)
+	return u
+}
+
+func variantToFloat64(v interface{}) float64 {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		EosAssert(err == nil, &PackException{}, "invalid float %v: %s", v, err)
+		return f
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		EosAssert(err == nil, &PackException{}, "invalid float %v: %s", v, err)
+		return f
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	}
+	return float64(variantToInt64(v, 64))
 }
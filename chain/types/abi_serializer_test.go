@@ -0,0 +1,277 @@
+package types
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/stretchr/testify/assert"
+)
+
+var maxSerializationTime = common.Seconds(1)
+
+func testAbi() *AbiDef {
+	return &AbiDef{
+		Version: "eosio::abi/1.0",
+		Types: []TypeDef{
+			{NewTypeName: "account_name", Type: "name"},
+			{NewTypeName: "token_amount", Type: "asset"},
+		},
+		Structs: []StructDef{
+			{Name: "permission_level", Fields: []FieldDef{
+				{Name: "actor", Type: "account_name"},
+				{Name: "permission", Type: "name"},
+			}},
+			{Name: "transfer_base", Fields: []FieldDef{
+				{Name: "from", Type: "account_name"},
+				{Name: "to", Type: "account_name"},
+			}},
+			{Name: "transfer", Base: "transfer_base", Fields: []FieldDef{
+				{Name: "quantity", Type: "token_amount"},
+				{Name: "memo", Type: "string"},
+				{Name: "auths", Type: "permission_level[]"},
+				{Name: "note", Type: "string?"},
+				{Name: "payload", Type: "payload_variant"},
+			}},
+			{Name: "account", Fields: []FieldDef{
+				{Name: "balance", Type: "asset"},
+			}},
+			{Name: "node", Fields: []FieldDef{
+				{Name: "next", Type: "node"},
+			}},
+		},
+		Actions: []ActionDef{
+			{Name: common.ActionName(common.N("transfer")), Type: "transfer"},
+		},
+		Tables: []TableDef{
+			{Name: common.TableName(common.N("accounts")), IndexType: "i64", Type: "account"},
+		},
+		ErrorMessages: []AbiErrorMessage{{Code: 1, Message: "overdrawn balance"}},
+		Variants: []VariantDef{
+			{Name: "payload_variant", Types: []string{"uint64", "string"}},
+		},
+	}
+}
+
+func catchCode(f func()) (code exception.ExcTypes) {
+	try.Try(f).Catch(func(e exception.Exception) {
+		code = e.Code()
+	}).End()
+	return
+}
+
+func TestAbiSerializer_Resolve(t *testing.T) {
+	abis := NewAbiSerializer(testAbi(), maxSerializationTime)
+
+	assert.Equal(t, TypeName("name"), abis.ResolveType("account_name"))
+	assert.Equal(t, TypeName("uint8"), abis.FundamentalType("uint8[]"))
+	assert.Equal(t, TypeName("uint8"), abis.FundamentalType("uint8?"))
+	assert.True(t, abis.IsType("account_name[]", maxSerializationTime))
+	assert.True(t, abis.IsType("payload_variant", maxSerializationTime))
+	assert.False(t, abis.IsType("unknown", maxSerializationTime))
+	assert.True(t, abis.IsStruct("transfer"))
+	assert.Equal(t, 64, abis.GetIntegerSize("uint64"))
+	assert.Equal(t, TypeName("transfer"), abis.GetActionType(common.ActionName(common.N("transfer"))))
+	assert.Equal(t, TypeName("account"), abis.GetTableType(common.TableName(common.N("accounts"))))
+
+	msg, ok := abis.GetErrorMessage(1)
+	assert.True(t, ok)
+	assert.Equal(t, "overdrawn balance", msg)
+}
+
+func TestAbiSerializer_Transfer(t *testing.T) {
+	abis := NewAbiSerializer(testAbi(), maxSerializationTime)
+
+	data := []byte(`{"from":"alice","to":"bob","quantity":"1.0000 EOS","memo":"hi",
+		"auths":[{"actor":"alice","permission":"active"}],"note":null,"payload":["string","abc"]}`)
+	bin := abis.JsonToBinary("transfer", data, maxSerializationTime)
+
+	v := abis.BinaryToVariant("transfer", bin, maxSerializationTime).(common.Variants)
+	assert.Equal(t, "alice", v["from"])
+	assert.Equal(t, "bob", v["to"])
+	assert.Equal(t, "1.0000 EOS", v["quantity"])
+	assert.Nil(t, v["note"])
+	assert.Equal(t, []interface{}{TypeName("string"), "abc"}, v["payload"])
+
+	// the json form packs back to the same binary
+	js, err := abis.BinaryToJson("transfer", bin, maxSerializationTime)
+	assert.NoError(t, err)
+	assert.Equal(t, bin, abis.JsonToBinary("transfer", js, maxSerializationTime))
+
+	// structs without base accept an array of field values
+	assert.Equal(t,
+		abis.JsonToBinary("permission_level", []byte(`{"actor":"alice","permission":"active"}`), maxSerializationTime),
+		abis.JsonToBinary("permission_level", []byte(`["alice","active"]`), maxSerializationTime))
+}
+
+func TestAbiSerializer_BuiltInTypes(t *testing.T) {
+	abis := NewAbiSerializer(testAbi(), maxSerializationTime)
+
+	cases := []struct {
+		typ TypeName
+		js  string
+		hex string
+	}{
+		{"bool", `true`, "01"},
+		{"int8", `-1`, "ff"},
+		{"uint16", `258`, "0201"},
+		{"int32", `-2`, "feffffff"},
+		{"uint64", `"18446744073709551615"`, "ffffffffffffffff"},
+		{"int64", `-1`, "ffffffffffffffff"},
+		{"varuint32", `300`, "ac02"},
+		{"varint32", `-1`, "01"},
+		{"int128", `"-1"`, "ffffffffffffffffffffffffffffffff"},
+		{"uint128", `"1"`, "01000000000000000000000000000000"},
+		{"float64", `1.5`, "000000000000f83f"},
+		{"float128", `"0x0000000000000000000000000000ff3f"`, "0000000000000000000000000000ff3f"},
+		{"name", `"eosio"`, "0000000000ea3055"},
+		{"bytes", `"0a0b"`, "020a0b"},
+		{"string", `"abc"`, "03616263"},
+		{"checksum160", `"0102030405060708090a0b0c0d0e0f1011121314"`, "0102030405060708090a0b0c0d0e0f1011121314"},
+		{"symbol", `"4,EOS"`, "04454f5300000000"},
+		{"symbol_code", `"EOS"`, "454f530000000000"},
+		{"asset", `"1.0000 EOS"`, "102700000000000004454f5300000000"},
+		{"time_point_sec", `"2018-06-01T12:00:00"`, "4035115b"},
+		{"time_point", `"2018-06-01T12:00:00.500"`, "2071cf52936d0500"},
+		{"block_timestamp_type", `"2018-06-01T12:00:00.500"`, "81e34745"},
+		{"public_key", `"EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV"`,
+			"0002c0ded2bc1f1305fb0faac5e6c03ee3a1924234985427b6167ca569d13df435cf"},
+		{"uint8[]", `[1,2]`, "020102"},
+		{"account_name?", `null`, "00"},
+		{"payload_variant", `["uint64",7]`, "000700000000000000"},
+	}
+
+	for _, c := range cases {
+		bin := abis.JsonToBinary(c.typ, []byte(c.js), maxSerializationTime)
+		assert.Equal(t, c.hex, hex.EncodeToString(bin), string(c.typ))
+
+		js, err := abis.BinaryToJson(c.typ, bin, maxSerializationTime)
+		assert.NoError(t, err)
+		var expected, actual interface{}
+		json.Unmarshal([]byte(c.js), &expected)
+		json.Unmarshal(js, &actual)
+		assert.Equal(t, expected, actual, string(c.typ))
+	}
+
+	extended := abis.BinaryToVariant("extended_asset",
+		abis.JsonToBinary("extended_asset", []byte(`{"quantity":"1.0000 EOS","contract":"eosio.token"}`), maxSerializationTime),
+		maxSerializationTime)
+	assert.Equal(t, common.Variants{"quantity": "1.0000 EOS", "contract": "eosio.token"}, extended)
+}
+
+func TestAbiSerializer_Errors(t *testing.T) {
+	abis := NewAbiSerializer(testAbi(), maxSerializationTime)
+
+	assert.Equal(t, exception.UnpackException{}.Code(), catchCode(func() {
+		abis.BinaryToVariant("uint64", []byte{1, 2}, maxSerializationTime)
+	}))
+	assert.Equal(t, exception.PackException{}.Code(), catchCode(func() {
+		abis.JsonToBinary("permission_level", []byte(`{"actor":"alice"}`), maxSerializationTime)
+	}))
+	assert.Equal(t, exception.PackException{}.Code(), catchCode(func() {
+		abis.JsonToBinary("uint8", []byte(`256`), maxSerializationTime)
+	}))
+	assert.Equal(t, exception.PackException{}.Code(), catchCode(func() {
+		abis.JsonToBinary("payload_variant", []byte(`["bool",true]`), maxSerializationTime)
+	}))
+	assert.Equal(t, exception.AbiRecursionDepthException{}.Code(), catchCode(func() {
+		abis.BinaryToVariant("node", make([]byte, 64), maxSerializationTime)
+	}))
+	assert.Equal(t, exception.AbiSerializationDeadlineException{}.Code(), catchCode(func() {
+		abis.BinaryToVariant("uint8", []byte{1}, 0)
+	}))
+	assert.Equal(t, exception.UnsupportedAbiVersionException{}.Code(), catchCode(func() {
+		NewAbiSerializer(&AbiDef{Version: "eosio::abi/2.0"}, maxSerializationTime)
+	}))
+}
+
+func TestAbiDef_Encode(t *testing.T) {
+	abi := testAbi()
+	data, err := EncodeAbiDef(abi)
+	assert.NoError(t, err)
+
+	decoded, err := DecodeAbiDef(data)
+	assert.NoError(t, err)
+	assert.Equal(t, abi.Variants, decoded.Variants)
+	assert.Equal(t, abi.Structs, decoded.Structs)
+
+	abi.Variants = nil
+	data, err = EncodeAbiDef(abi)
+	assert.NoError(t, err)
+	decoded, err = DecodeAbiDef(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(decoded.Variants))
+}
+
+func TestAbiSerializer_Validate(t *testing.T) {
+	duplicateStruct := testAbi()
+	duplicateStruct.Structs = append(duplicateStruct.Structs, StructDef{Name: "account"})
+
+	duplicateAction := testAbi()
+	duplicateAction.Actions = append(duplicateAction.Actions, duplicateAction.Actions[0])
+
+	duplicateTable := testAbi()
+	duplicateTable.Tables = append(duplicateTable.Tables, duplicateTable.Tables[0])
+
+	duplicateType := testAbi()
+	duplicateType.Types = append(duplicateType.Types, TypeDef{NewTypeName: "account_name", Type: "uint64"})
+
+	duplicateVariant := testAbi()
+	duplicateVariant.Variants = append(duplicateVariant.Variants, duplicateVariant.Variants[0])
+
+	circularStruct := testAbi()
+	circularStruct.Structs = append(circularStruct.Structs,
+		StructDef{Name: "a", Base: "b"}, StructDef{Name: "b", Base: "a"})
+
+	circularType := testAbi()
+	circularType.Types = append(circularType.Types,
+		TypeDef{NewTypeName: "a", Type: "b"}, TypeDef{NewTypeName: "b", Type: "a"})
+
+	selfType := testAbi()
+	selfType.Types = append(selfType.Types, TypeDef{NewTypeName: "self", Type: "self"})
+
+	forwardType := testAbi()
+	forwardType.Types = append([]TypeDef{{NewTypeName: "owner", Type: "account_name"}}, forwardType.Types...)
+
+	unknownType := testAbi()
+	unknownType.Types = append(unknownType.Types, TypeDef{NewTypeName: "amount", Type: "money"})
+
+	unknownField := testAbi()
+	unknownField.Structs[0].Fields[1].Type = "permission"
+
+	missingTable := testAbi()
+	missingTable.Tables[0].Type = "uint64"
+
+	cases := []struct {
+		abi  *AbiDef
+		code exception.ExcTypes
+	}{
+		{testAbi(), 0},
+		{duplicateStruct, exception.DuplicateAbiStructDefException{}.Code()},
+		{duplicateAction, exception.DuplicateAbiActionDefException{}.Code()},
+		{duplicateTable, exception.DuplicateAbiTableDefException{}.Code()},
+		{duplicateType, exception.DuplicateAbiTypeDefException{}.Code()},
+		{duplicateVariant, exception.DuplicateAbiVariantDefException{}.Code()},
+		{circularStruct, exception.AbiCircularDefException{}.Code()},
+		{circularType, exception.AbiCircularDefException{}.Code()},
+		{selfType, exception.AbiCircularDefException{}.Code()},
+		{forwardType, 0},
+		{unknownType, exception.InvalidTypeInsideAbi{}.Code()},
+		{unknownField, exception.InvalidTypeInsideAbi{}.Code()},
+		{missingTable, exception.InvalidTypeInsideAbi{}.Code()},
+	}
+	for i, c := range cases {
+		assert.Equal(t, c.code, catchCode(func() { NewAbiSerializer(c.abi, maxSerializationTime) }), i)
+	}
+
+	var message string
+	try.Try(func() {
+		NewAbiSerializer(unknownField, maxSerializationTime)
+	}).Catch(func(e exception.Exception) {
+		message = e.Message()
+	}).End()
+	assert.Equal(t, "structs[0].fields[1]: permission_level.permission has unknown type permission", message)
+}
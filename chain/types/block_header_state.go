@@ -9,23 +9,23 @@ import (
 )
 
 type BlockHeaderState struct {
-	ID                               common.IdType      `multiIndex:"id,increment"`
-	BlockId                          common.BlockIdType `multiIndex:"byId,orderedUnique"`
-	BlockNum                         uint32             `multiIndex:"block_num,orderedUnique:byLibBlockNum,orderedNonUnique"`
-	Header                           SignedBlockHeader  `multiIndex:"inline"`
-	DposProposedIrreversibleBlocknum uint32             `json:"dpos_proposed_irreversible_blocknum"`
-	DposIrreversibleBlocknum         uint32             `multiIndex:"byLibBlockNum,orderedNonUnique" json:"dpos_irreversible_blocknum"`
-	BftIrreversibleBlocknum          uint32             `multiIndex:"byLibBlockNum,orderedNonUnique" json:"bft_irreversible_blocknum"`
-	PendingScheduleLibNum            uint32             `json:"pending_schedule_lib_num"`
-	PendingScheduleHash              crypto.Sha256      `json:"pending_schedule_hash"`
-	PendingSchedule                  ProducerScheduleType
-	ActiveSchedule                   ProducerScheduleType
-	BlockrootMerkle                  IncrementalMerkle
-	ProducerToLastProduced           map[common.AccountName]uint32
-	ProducerToLastImpliedIrb         map[common.AccountName]uint32
-	BlockSigningKey                  ecc.PublicKey
-	ConfirmCount                     []uint8              `json:"confirm_count"`
-	Confirmations                    []HeaderConfirmation `json:"confirmations"`
+	ID                               common.IdType                 `multiIndex:"id,increment" json:"-"`
+	BlockId                          common.BlockIdType            `multiIndex:"byId,orderedUnique" json:"id"`
+	BlockNum                         uint32                        `multiIndex:"block_num,orderedUnique:byLibBlockNum,orderedNonUnique" json:"block_num"`
+	Header                           SignedBlockHeader             `multiIndex:"inline" json:"header"`
+	DposProposedIrreversibleBlocknum uint32                        `json:"dpos_proposed_irreversible_blocknum"`
+	DposIrreversibleBlocknum         uint32                        `multiIndex:"byLibBlockNum,orderedNonUnique" json:"dpos_irreversible_blocknum"`
+	BftIrreversibleBlocknum          uint32                        `multiIndex:"byLibBlockNum,orderedNonUnique" json:"bft_irreversible_blocknum"`
+	PendingScheduleLibNum            uint32                        `json:"pending_schedule_lib_num"`
+	PendingScheduleHash              crypto.Sha256                 `json:"pending_schedule_hash"`
+	PendingSchedule                  ProducerScheduleType          `json:"pending_schedule"`
+	ActiveSchedule                   ProducerScheduleType          `json:"active_schedule"`
+	BlockrootMerkle                  IncrementalMerkle             `json:"blockroot_merkle"`
+	ProducerToLastProduced           map[common.AccountName]uint32 `json:"producer_to_last_produced"`
+	ProducerToLastImpliedIrb         map[common.AccountName]uint32 `json:"producer_to_last_implied_irb"`
+	BlockSigningKey                  ecc.PublicKey                 `json:"block_signing_key"`
+	ConfirmCount                     []uint8                       `json:"confirm_count"`
+	Confirmations                    []HeaderConfirmation          `json:"confirmations"`
 }
 
 func (bs *BlockHeaderState) GetScheduledProducer(t common.BlockTimeStamp) ProducerKey {
@@ -1,223 +1,330 @@
 package types
 
 import (
-	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
 	"github.com/eosspark/eos-go/common"
-	"github.com/eosspark/eos-go/database"
 	"github.com/eosspark/eos-go/exception"
 	"github.com/eosspark/eos-go/log"
 )
 
-var isFdActive bool = false
+// see: libraries/chain/fork_database.cpp
 
+// ForkDatabase keeps every reversible block state the node knows about. Blocks are linked by
+// their previous id, the head is the block with the highest irreversible block numbers and once
+// a block falls behind the last irreversible block it is handed to Irreversible and dropped
 type ForkDatabase struct {
-	DB database.DataBase
-	//Index   *ForkMultiIndexType `json:"index"`
-	Head *BlockState `json:"head"`
-	//DataDir string
+	DataDir string      `json:"-"`
+	Head    *BlockState `json:"head"`
+
+	// Irreversible is called with every block that is pruned because it became irreversible,
+	// oldest first
+	Irreversible func(s *BlockState) `json:"-"`
+
+	index map[common.BlockIdType]*BlockState
+	// states keeps insertion order, states with the same sort key are ordered by it
+	states []*BlockState
 }
 
-//var IrreversibleBlock chan BlockState = make(chan BlockState)
-
-/*type ForkMultiIndexType struct {
-	ByBlockID  common.BlockIdType `storm:"unique" json:"id"`
-	ByPrev     common.BlockIdType `storm:"index"  json:"prev"`
-	ByBlockNum common.Tuple       `storm:"index"  json:"block_num"`
-	BlockState *BlockState         `storm:"inline"`
-}*/
-
-func GetForkDbInstance(stateDir string) *ForkDatabase {
-	forkDB := &ForkDatabase{}
-	if !isFdActive {
-		forkd, err := newForkDatabase(stateDir, common.DefaultConfig.ForkDBName, true)
-		if err != nil {
-			log.Error("GetForkDbInstance is error ,detail:", err)
-		}
-		//forkd.DB.Insert("test")
-		forkDB = forkd
-		//
-		isFdActive = true
+const (
+	forkDatabaseMagic   uint32 = 0x30510FDB
+	forkDatabaseVersion uint32 = 1
+)
+
+// forkDatabaseContent is the single record of forkdb.dat, a versioned file
+type forkDatabaseContent struct {
+	States []BlockState
+	HeadId common.BlockIdType
+}
+
+// NewForkDatabase restores the block states saved in dataDir by Close, the file is removed once
+// it is loaded. A file that can not be read back raises a ForkDatabaseException
+func NewForkDatabase(dataDir string) *ForkDatabase {
+	f := &ForkDatabase{
+		DataDir: dataDir,
+		index:   make(map[common.BlockIdType]*BlockState),
+		states:  make([]*BlockState, 0),
 	}
-	return forkDB
+
+	forkDbDat := filepath.Join(dataDir, common.DefaultConfig.ForkDBName)
+	file, err := OpenVersionedFile(forkDbDat, forkDatabaseMagic, forkDatabaseVersion)
+	if os.IsNotExist(err) {
+		return f
+	}
+	exception.EosAssert(err == nil, &exception.ForkDatabaseException{}, "unable to read fork database file %s: %s", forkDbDat, err)
+
+	content := forkDatabaseContent{}
+	err = file.Decode(&content)
+	file.Close()
+	exception.EosAssert(err == nil, &exception.ForkDatabaseException{}, "fork database file %s is corrupted: %s", forkDbDat, err)
+
+	f.load(forkDbDat, &content)
+	os.Remove(forkDbDat)
+	return f
 }
 
-func newForkDatabase(path string, fileName string, rw bool) (*ForkDatabase, error) {
+func (f *ForkDatabase) load(forkDbDat string, content *forkDatabaseContent) {
+	for i := range content.States {
+		s := &content.States[i]
+		exception.EosAssert(s.SignedBlock != nil && s.BlockId == s.Header.BlockID() && s.BlockNum == s.Header.BlockNumber(),
+			&exception.ForkDatabaseException{}, "block state %s in fork database file %s does not match its header", s.BlockId, forkDbDat)
+		exception.EosAssert(f.insert(s), &exception.ForkDatabaseException{},
+			"block state %s is duplicated in fork database file %s", s.BlockId, forkDbDat)
+	}
+	if len(f.states) == 0 {
+		return
+	}
 
-	db, err := database.NewDataBase(path + "/" + fileName)
-	if err != nil {
-		log.Error("newForkDatabase is error:", err)
-		return nil, err
+	// every block but the oldest ones has to link to a block that was saved with it
+	root := f.first(numOrder).BlockNum
+	for _, s := range f.states {
+		_, linked := f.index[s.Header.Previous]
+		exception.EosAssert(linked || s.BlockNum == root, &exception.ForkDatabaseException{},
+			"block state %s in fork database file %s does not link to previous block %s", s.BlockId, forkDbDat, s.Header.Previous)
 	}
 
-	return &ForkDatabase{DB: db}, err
+	f.Head = f.GetBlock(&content.HeadId)
+	exception.EosAssert(f.Head != nil, &exception.ForkDbBlockNotFound{},
+		"head block %s of fork database file %s is missing", content.HeadId, forkDbDat)
 }
 
-func (f *ForkDatabase) SetHead(s *BlockState) {
+// Close saves every reversible block state to forkdb.dat so that they can be restored on the
+// next start, the last irreversible block is pruned before the fork database is emptied
+func (f *ForkDatabase) Close() {
+	if len(f.states) == 0 {
+		return
+	}
 
-	exception.EosAssert(s.BlockId == s.Header.BlockID(), &exception.ForkDatabaseException{},
-		"block state id:d%, is different from block state header id:d%", s.ID, s.Header.BlockID())
+	content := forkDatabaseContent{States: make([]BlockState, 0, len(f.states))}
+	for _, s := range f.states {
+		content.States = append(content.States, *s)
+	}
+	if f.Head != nil {
+		content.HeadId = f.Head.BlockId
+	}
+	f.store(&content)
 
-	exception.EosAssert(s.BlockNum == s.Header.BlockNumber(), &exception.ForkDatabaseException{}, "unable to insert block state, duplicate state detected")
-	if f.Head == nil {
-		f.Head = s
-	} else if f.Head.BlockNum < s.BlockNum {
-		f.Head = s
+	// the head block is not normally pruned because the next block builds on it, we are exiting
+	// so it can be marked irreversible now
+	lib := LastIrreversibleBlockNum(f.Head)
+	if oldest := f.first(numOrder); oldest.BlockNum <= lib {
+		f.Prune(oldest)
 	}
 
-	err := f.DB.Insert(s)
+	f.index = make(map[common.BlockIdType]*BlockState)
+	f.states = make([]*BlockState, 0)
+	f.Head = nil
+}
+
+func (f *ForkDatabase) store(content *forkDatabaseContent) {
+	forkDbDat := filepath.Join(f.DataDir, common.DefaultConfig.ForkDBName)
+	file, err := CreateVersionedFile(forkDbDat, forkDatabaseMagic, forkDatabaseVersion)
+	if err == nil {
+		if err = file.Encode(content); err == nil {
+			err = file.Close()
+		} else {
+			file.Abort()
+		}
+	}
 	if err != nil {
-		fmt.Println("ForkDB SetHead is error:", err)
+		log.Error("write fork database file is error,detail:", err)
 	}
+}
 
+// libOrder is the by_lib_block_num order, the first state is the head
+func libOrder(a, b *BlockState) bool {
+	if a.DposIrreversibleBlocknum != b.DposIrreversibleBlocknum {
+		return a.DposIrreversibleBlocknum > b.DposIrreversibleBlocknum
+	}
+	if a.BftIrreversibleBlocknum != b.BftIrreversibleBlocknum {
+		return a.BftIrreversibleBlocknum > b.BftIrreversibleBlocknum
+	}
+	return a.BlockNum > b.BlockNum
 }
 
-func (f *ForkDatabase) AddBlockState(blockState *BlockState) *BlockState {
+// numOrder is the by_block_num order, blocks in the current chain come first
+func numOrder(a, b *BlockState) bool {
+	if a.BlockNum != b.BlockNum {
+		return a.BlockNum < b.BlockNum
+	}
+	return a.InCurrentChain && !b.InCurrentChain
+}
 
-	result := BlockState{}
-	err := f.DB.Insert(blockState)
-	//TODO try catch
-	//exception.EosAssert(err == nil, &exception.ForkDatabaseException{}, "ForkDB AddBlockState Is Error:", err)
+func (f *ForkDatabase) first(less func(a, b *BlockState) bool) *BlockState {
+	var result *BlockState
+	for _, s := range f.states {
+		if result == nil || less(s, result) {
+			result = s
+		}
+	}
+	return result
+}
 
-	multiIndex, err := f.DB.GetIndex("byLibBlockNum", &result)
-	//exception.EosAssert(err == nil, &exception.ForkDatabaseException{}, "ForkDB AddBlockState Is Error:", err)
+func (f *ForkDatabase) byNum() []*BlockState {
+	sorted := make([]*BlockState, len(f.states))
+	copy(sorted, f.states)
+	sort.SliceStable(sorted, func(i, j int) bool { return numOrder(sorted[i], sorted[j]) })
+	return sorted
+}
 
-	err = multiIndex.Begin(&result)
-	//exception.EosAssert(err == nil, &exception.ForkDatabaseException{}, "ForkDB AddBlockState Is Error:", err)
+func (f *ForkDatabase) insert(s *BlockState) bool {
+	if _, ok := f.index[s.BlockId]; ok {
+		return false
+	}
+	f.index[s.BlockId] = s
+	f.states = append(f.states, s)
+	return true
+}
 
-	lib := f.Head.DposIrreversibleBlocknum
-	oldest, err := f.DB.GetIndex("byBlockNum", &result)
-	//exception.EosAssert(err == nil, &exception.ForkDatabaseException{}, "ForkDB AddBlockState Is Error:", err)
-	err = oldest.Begin(&result)
-	exception.EosAssert(err == nil, &exception.ForkDatabaseException{}, "ForkDB AddBlockState Is Error:", err)
+func (f *ForkDatabase) erase(id common.BlockIdType) {
+	if _, ok := f.index[id]; !ok {
+		return
+	}
+	delete(f.index, id)
+	for i, s := range f.states {
+		if s.BlockId == id {
+			f.states = append(f.states[:i:i], f.states[i+1:]...)
+			break
+		}
+	}
+}
 
-	if result.BlockNum < lib {
-		f.Prune(&result)
+// LastIrreversibleBlockNum is the highest of the dpos and bft irreversible block numbers of s
+func LastIrreversibleBlockNum(s *BlockState) uint32 {
+	if s.BftIrreversibleBlocknum > s.DposIrreversibleBlocknum {
+		return s.BftIrreversibleBlocknum
 	}
-	return blockState
+	return s.DposIrreversibleBlocknum
 }
-func (f *ForkDatabase) AddSignedBlockState(signedBlcok *SignedBlock, trust bool) *BlockState {
-	exception.EosAssert(signedBlcok != nil, &exception.ForkDatabaseException{}, "attempt to add null block")
-	exception.EosAssert(f.Head != nil, &exception.ForkDbBlockNotFound{}, "no head block set")
 
-	blockId := signedBlcok.BlockID()
-	blockState := BlockState{}
+func (f *ForkDatabase) SetHead(s *BlockState) {
+	exception.EosAssert(s.BlockId == s.Header.BlockID(), &exception.ForkDatabaseException{},
+		"block state id: %s, is different from block state header id: %s", s.BlockId, s.Header.BlockID())
 
-	index, err := f.DB.GetIndex("byBlockId", &blockState) //find all data multiIndex
-	if err != nil {
-		log.Error("AddSignedBlockState is error,detail:", err)
+	exception.EosAssert(s.BlockNum == s.Header.BlockNumber(), &exception.ForkDatabaseException{},
+		"unable to insert block state, duplicate state detected")
+
+	if !f.insert(s) {
+		f.erase(s.BlockId)
+		f.insert(s)
 	}
-	blockState.BlockId = blockId
-	bs := BlockState{}
-	index.Find(blockState, &bs)
-	exception.EosAssert(&bs == nil, &exception.ForkDatabaseException{}, "we already know about this block")
+	if f.Head == nil || f.Head.BlockNum < s.BlockNum {
+		f.Head = s
+	}
+}
 
-	previous := BlockState{}
-	b := BlockState{}
-	b.BlockId = signedBlcok.Previous
-	erro := index.Find(b, &previous)
-	if erro != nil {
-		log.Error("AddSignedBlockState is error,detail:", err)
+// AddBlockState inserts a block state whose previous block is known, the head moves to the best
+// block and the oldest block is pruned once it is behind the last irreversible block
+func (f *ForkDatabase) AddBlockState(n *BlockState) *BlockState {
+	exception.EosAssert(n != nil, &exception.ForkDatabaseException{}, "attempt to add null block state")
+	exception.EosAssert(f.insert(n), &exception.ForkDatabaseException{}, "duplicate block added?")
+
+	f.Head = f.first(libOrder)
+	lib := LastIrreversibleBlockNum(f.Head)
+	oldest := f.first(numOrder)
+	if oldest.BlockNum < lib {
+		f.Prune(oldest)
 	}
-	exception.EosAssert(&previous != nil, &exception.UnlinkableBlockException{}, "unlinkable block", signedBlcok.BlockID(), signedBlcok.Previous)
+	return n
+}
+
+func (f *ForkDatabase) AddSignedBlockState(b *SignedBlock, trust bool) *BlockState {
+	exception.EosAssert(b != nil, &exception.ForkDatabaseException{}, "attempt to add null block")
+	exception.EosAssert(f.Head != nil, &exception.ForkDbBlockNotFound{}, "no head block set")
 
-	result := NewBlockState3(&previous.BlockHeaderState, signedBlcok, trust)
+	id := b.BlockID()
+	_, existing := f.index[id]
+	exception.EosAssert(!existing, &exception.ForkDatabaseException{}, "we already know about this block")
 
+	prior, ok := f.index[b.Previous]
+	exception.EosAssert(ok, &exception.UnlinkableBlockException{}, "unlinkable block, id: %s, previous: %s", id, b.Previous)
+
+	result := NewBlockState3(&prior.BlockHeaderState, b, trust)
 	return f.AddBlockState(result)
 }
 
+// Add records a producer confirmation, the block becomes bft irreversible once 2/3+1 of the
+// active producers confirmed it
 func (f *ForkDatabase) Add(c *HeaderConfirmation) {
-	header := f.GetBlock(&c.BlockId)
-
-	exception.EosAssert(header != nil, &exception.ForkDbBlockNotFound{}, "unable to find block id ", c.BlockId)
-	header.AddConfirmation(c)
+	b := f.GetBlock(&c.BlockId)
+	exception.EosAssert(b != nil, &exception.ForkDbBlockNotFound{}, "unable to find block id %s", c.BlockId)
+	b.AddConfirmation(c)
 
-	if header.BftIrreversibleBlocknum < header.BlockNum && len(header.Confirmations) >= ((len(header.ActiveSchedule.Producers)*2)/3+1) {
+	if b.BftIrreversibleBlocknum < b.BlockNum &&
+		len(b.Confirmations) >= (len(b.ActiveSchedule.Producers)*2)/3+1 {
 		f.SetBftIrreversible(c.BlockId)
 	}
 }
-func (f *ForkDatabase) Header() *BlockState { return f.Head }
 
-/*type BranchType struct {
-	branch []BlockState
-}*/
+func (f *ForkDatabase) Header() *BlockState { return f.Head }
 
+// FetchBranch holds two branches ordered from the newest block down to the block right after
+// their common ancestor
 type FetchBranch struct {
-	first  []BlockState
-	second []BlockState
+	First  []*BlockState
+	Second []*BlockState
 }
 
 func (f *ForkDatabase) FetchBranchFrom(first *common.BlockIdType, second *common.BlockIdType) FetchBranch {
 	result := FetchBranch{}
 	firstBranch := f.GetBlock(first)
-
 	secondBranch := f.GetBlock(second)
+	exception.EosAssert(firstBranch != nil, &exception.ForkDbBlockNotFound{}, "block %s does not exist", first)
+	exception.EosAssert(secondBranch != nil, &exception.ForkDbBlockNotFound{}, "block %s does not exist", second)
 
 	for firstBranch.BlockNum > secondBranch.BlockNum {
-		result.first = append(result.first, *firstBranch)
-		firstBranch = f.GetBlock(&firstBranch.Header.Previous)
-		exception.EosAssert(firstBranch != nil, &exception.ForkDbBlockNotFound{}, "block d% does not exist", firstBranch.Header.Previous)
+		result.First = append(result.First, firstBranch)
+		previous := firstBranch.Header.Previous
+		firstBranch = f.GetBlock(&previous)
+		exception.EosAssert(firstBranch != nil, &exception.ForkDbBlockNotFound{}, "block %s does not exist", previous)
 	}
 
-	for firstBranch.BlockNum < secondBranch.BlockNum {
-		result.second = append(result.second, *secondBranch)
-		secondBranch = f.GetBlock(&firstBranch.Header.Previous)
-		exception.EosAssert(secondBranch != nil, &exception.ForkDbBlockNotFound{}, "block d% does not exist", secondBranch.Header.Previous)
+	for secondBranch.BlockNum > firstBranch.BlockNum {
+		result.Second = append(result.Second, secondBranch)
+		previous := secondBranch.Header.Previous
+		secondBranch = f.GetBlock(&previous)
+		exception.EosAssert(secondBranch != nil, &exception.ForkDbBlockNotFound{}, "block %s does not exist", previous)
 	}
 
 	for firstBranch.Header.Previous != secondBranch.Header.Previous {
-		result.first = append(result.first, *firstBranch)
-		result.second = append(result.second, *secondBranch)
-		firstBranch = f.GetBlock(&firstBranch.Header.Previous)
-		secondBranch = f.GetBlock(&secondBranch.Header.Previous)
+		result.First = append(result.First, firstBranch)
+		result.Second = append(result.Second, secondBranch)
+		firstPrevious, secondPrevious := firstBranch.Header.Previous, secondBranch.Header.Previous
+		firstBranch = f.GetBlock(&firstPrevious)
+		secondBranch = f.GetBlock(&secondPrevious)
 		exception.EosAssert(firstBranch != nil && secondBranch != nil, &exception.ForkDbBlockNotFound{},
-			"either block d% or d% does not exist",
-			firstBranch.Header.Previous, secondBranch.Header.Previous)
-	}
-
-	if firstBranch != nil && secondBranch != nil {
-		result.first = append(result.first, *firstBranch)
-		result.second = append(result.second, *secondBranch)
+			"either block %s or %s does not exist", firstPrevious, secondPrevious)
 	}
 
+	result.First = append(result.First, firstBranch)
+	result.Second = append(result.Second, secondBranch)
 	return result
 }
 
+// Remove drops the block and every block built on top of it
 func (f *ForkDatabase) Remove(id *common.BlockIdType) {
 	removeQueue := []common.BlockIdType{*id}
 
 	for i := 0; i < len(removeQueue); i++ {
-		p := BlockState{}
-		err := f.DB.Find("ID", p, &p)
-		exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "ForkDB Remove Find Block Is Error:", err)
-		f.DB.Remove(&p)
-		previdx, err := f.DB.GetIndex("byPrev", &BlockState{})
-		param := &BlockState{}
-		param.BlockId = removeQueue[i]
-		previtr, err := previdx.LowerBound(param)
-		exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "ForkDB Remove LowerBound Is Error:", err)
-		pre := BlockState{}
-		err = previtr.Data(pre)
-		exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "ForkDB Remove previtr.Data Is Error:", err)
-		for previtr != previdx.End() && pre.Header.Previous == removeQueue[i] {
-			removeQueue = append(removeQueue, pre.BlockId)
+		f.erase(removeQueue[i])
+		for _, s := range f.states {
+			if s.Header.Previous == removeQueue[i] {
+				removeQueue = append(removeQueue, s.BlockId)
+			}
 		}
 	}
-	mi, err := f.DB.GetIndex("byLibBlockNum", &BlockState{})
-	exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "ForkDB Remove f.DB.GetIndex Is Error:", err)
-	err = mi.Begin(f.Head)
-	exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "ForkDB Remove mi.Begin(f.Head) Is Error:", err)
+	f.Head = f.first(libOrder)
 }
 
 func (f *ForkDatabase) GetBlockInCurrentChainByNum(n uint32) *BlockState {
-	numidx, err := f.DB.GetIndex("byBlockNum", &BlockState{})
-	exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "ForkDB Remove mi.Begin(f.Head) Is Error:", err)
-	param := BlockState{}
-	param.BlockNum = n
-	nitr, err := numidx.LowerBound(param)
-	exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "ForkDB Remove LowerBound Is Error:", err)
-	result := BlockState{}
-	nitr.Data(result)
-	return &result
+	for _, s := range f.states {
+		if s.BlockNum == n && s.InCurrentChain {
+			return s
+		}
+	}
+	return nil
 }
 
 func (f *ForkDatabase) SetValidity(h *BlockState, valid bool) {
@@ -227,118 +334,70 @@ func (f *ForkDatabase) SetValidity(h *BlockState, valid bool) {
 		h.Validated = true
 	}
 }
+
 func (f *ForkDatabase) MarkInCurrentChain(h *BlockState, inCurrentChain bool) {
 	if h.InCurrentChain == inCurrentChain {
 		return
 	}
-	byIdIdx, err := f.DB.GetIndex("byBlockId", &BlockState{})
-	exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "could not find block in fork database")
-	param := BlockState{}
-	param.ID = h.ID
-	result := BlockState{}
-	err = byIdIdx.Find(param, &result)
-	exception.EosAssert(&result == nil, &exception.ForkDbBlockNotFound{}, "could not find block in fork database")
-	f.DB.Modify(result, func(b *BlockState) {
-		b.InCurrentChain = inCurrentChain
-	})
+	s, ok := f.index[h.BlockId]
+	exception.EosAssert(ok, &exception.ForkDbBlockNotFound{}, "could not find block in fork database")
+	s.InCurrentChain = inCurrentChain
 }
 
+// Prune hands h and every older block to Irreversible and removes them, blocks with the same
+// number as h are on dead forks and are removed as well
 func (f *ForkDatabase) Prune(h *BlockState) {
-
 	num := h.BlockNum
-	param := BlockState{}
-	mIndex, err := f.DB.GetIndex("byBlockNum", &param)
-	err = mIndex.Begin(&param)
-	bItr := mIndex.IteratorTo(&param)
-	for !mIndex.CompareEnd(bItr) && param.BlockNum < num {
-		f.Prune(&param)
-		err = mIndex.Begin(&param)
-		bItr = mIndex.IteratorTo(&param)
-		f.DB.Remove(bItr)
-	}
-	p := BlockState{}
-	p.ID = h.ID
-	result := BlockState{}
-	err = f.DB.Find("ID", p, &result)
-	exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "Prune could not find block in fork database")
-	if !common.Empty(result) {
-		//irreversible(*itr) TODO channel
-		//my->index.erase(itr)
-		//f.DB.Remove(result)
-	}
-
-	in := BlockState{}
-	in.BlockNum = num
-	numIdx, err := f.DB.GetIndex("byBlockNun", &in)
-	nitr, err := numIdx.LowerBound(&in)
-
-	err = nitr.Data(&in)
-	for !numIdx.CompareEnd(nitr) && in.BlockNum == num {
-
-		itrToRemove := nitr
-		nitr.Next()
-		err = itrToRemove.Data(&in)
-		id := in.BlockId
-		f.Remove(&id)
+
+	for oldest := f.first(numOrder); oldest != nil && oldest.BlockNum < num; oldest = f.first(numOrder) {
+		f.Prune(oldest)
 	}
-}
 
-func (f *ForkDatabase) GetBlock(id *common.BlockIdType) *BlockState {
+	if s, ok := f.index[h.BlockId]; ok {
+		if f.Irreversible != nil {
+			f.Irreversible(s)
+		}
+		f.erase(s.BlockId)
+	}
 
-	blockState := BlockState{}
-	blockState.BlockId = *id
-	multiIndex, err := f.DB.GetIndex("ID", &blockState)
-	if err != nil {
-		fmt.Println("ForkDb GetBlock Is Error:", err)
-		return &BlockState{}
+	for _, s := range f.byNum() {
+		if s.BlockNum == num {
+			id := s.BlockId
+			f.Remove(&id)
+		}
 	}
-	err = multiIndex.Begin(&blockState)
-	if err != nil {
-		fmt.Println("ForkDB GetBlock MultiIndex.Begin Is Error :", err)
+}
+
+func (f *ForkDatabase) GetBlock(id *common.BlockIdType) *BlockState {
+	if s, ok := f.index[*id]; ok {
+		return s
 	}
-	return &blockState
+	return nil
 }
 
+// SetBftIrreversible marks the block bft irreversible and raises the bft irreversible block
+// number of every block built on top of it
 func (f *ForkDatabase) SetBftIrreversible(id common.BlockIdType) {
-	param := BlockState{}
-	param.BlockId = id
-	result := BlockState{}
-	idx, err := f.DB.GetIndex("byID", param)
-	exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "could not find block in fork database")
-	err = idx.Find(param, &result)
-	exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "could not find idx in fork database")
-	blockNum := result.BlockNum
-	f.DB.Modify(result, func(b *BlockState) {
-		b.BftIrreversibleBlocknum = b.BlockNum
-	})
+	s, ok := f.index[id]
+	exception.EosAssert(ok, &exception.ForkDbBlockNotFound{}, "could not find block in fork database")
+	blockNum := s.BlockNum
+	s.BftIrreversibleBlocknum = blockNum
+
 	update := func(in []common.BlockIdType) []common.BlockIdType {
-		updated := []common.BlockIdType{}
+		updated := make([]common.BlockIdType, 0)
 		for _, i := range in {
-			pidx, err := f.DB.GetIndex("byPrev", BlockState{})
-			//exception.EosAssert(err==nil,&exception.ForkDbBlockNotFound{},"SetBftIrreversible could not find idx in fork database")
-			in := BlockState{}
-			in.BlockId = i
-			pitr, err := pidx.LowerBound(in)
-			epitr, err := pidx.UpperBound(in)
-			exception.EosAssert(err == nil, &exception.ForkDbBlockNotFound{}, "SetBftIrreversible could not find idx in fork database")
-			for pitr != epitr {
-				f.DB.Modify(pitr, func(bsp *BlockState) {
-					if bsp.BftIrreversibleBlocknum < blockNum {
-						bsp.BftIrreversibleBlocknum = blockNum
-						updated = append(updated, bsp.BlockId)
-					}
-
-				})
-				pitr.Next()
+			for _, bsp := range f.states {
+				if bsp.Header.Previous == i && bsp.BftIrreversibleBlocknum < blockNum {
+					bsp.BftIrreversibleBlocknum = blockNum
+					updated = append(updated, bsp.BlockId)
+				}
 			}
 		}
-
-		fmt.Println(updated)
 		return updated
 	}
 
-	fmt.Println(blockNum)
-
-	queue := []common.BlockIdType{id}
-	update(queue)
+	for queue := []common.BlockIdType{id}; len(queue) > 0; {
+		queue = update(queue)
+	}
+	f.Head = f.first(libOrder)
 }
@@ -0,0 +1,199 @@
+package types
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto/ecc"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/stretchr/testify/assert"
+)
+
+func newForkDbGenesis(t *testing.T) (*ForkDatabase, *BlockState, func()) {
+	dir, err := ioutil.TempDir("", "forkdb")
+	assert.NoError(t, err)
+	gen := NewBlockState(*NewBlockHeaderState(t))
+	gen.InCurrentChain = true
+	forkDB := NewForkDatabase(dir)
+	forkDB.SetHead(gen)
+	return forkDB, gen, func() { os.RemoveAll(dir) }
+}
+
+// nextForkDbBlock builds a block on top of prev, skip changes the timestamp so that siblings get
+// different ids
+func nextForkDbBlock(prev *BlockState, skip uint32, dposLib uint32) *BlockState {
+	s := &BlockState{SignedBlock: &SignedBlock{}}
+	s.ActiveSchedule = prev.ActiveSchedule
+	s.Header.Previous = prev.BlockId
+	s.Header.Timestamp = prev.Header.Timestamp + common.BlockTimeStamp(1+skip)
+	s.BlockId = s.Header.BlockID()
+	s.BlockNum = s.Header.BlockNumber()
+	s.DposIrreversibleBlocknum = dposLib
+	s.BftIrreversibleBlocknum = prev.BftIrreversibleBlocknum
+	s.SignedBlock.SignedBlockHeader = s.Header
+	return s
+}
+
+func TestForkDatabase_PruneIrreversible(t *testing.T) {
+	forkDB, gen, closer := newForkDbGenesis(t)
+	defer closer()
+	irreversible := make([]uint32, 0)
+	forkDB.Irreversible = func(s *BlockState) { irreversible = append(irreversible, s.BlockNum) }
+
+	b2 := forkDB.AddBlockState(nextForkDbBlock(gen, 0, 0))
+	b3 := forkDB.AddBlockState(nextForkDbBlock(b2, 0, 1))
+	assert.Equal(t, b3, forkDB.Header())
+	assert.Equal(t, 0, len(irreversible))
+
+	b4 := forkDB.AddBlockState(nextForkDbBlock(b3, 0, 3))
+	assert.Equal(t, b4, forkDB.Header())
+	assert.Equal(t, []uint32{1}, irreversible)
+	assert.Nil(t, forkDB.GetBlock(&gen.BlockId))
+
+	forkDB.AddBlockState(nextForkDbBlock(b4, 0, 3))
+	assert.Equal(t, []uint32{1, 2}, irreversible)
+	assert.Nil(t, forkDB.GetBlock(&b2.BlockId))
+	assert.Equal(t, b3, forkDB.GetBlock(&b3.BlockId))
+
+	returned := false
+	try.Try(func() {
+		forkDB.AddBlockState(b4)
+	}).Catch(func(e *ForkDatabaseException) {
+		returned = true
+	}).End()
+	assert.True(t, returned)
+}
+
+func TestForkDatabase_ForkSwitch(t *testing.T) {
+	forkDB, gen, closer := newForkDbGenesis(t)
+	defer closer()
+
+	a2 := forkDB.AddBlockState(nextForkDbBlock(gen, 0, 0))
+	a3 := forkDB.AddBlockState(nextForkDbBlock(a2, 0, 0))
+	b2 := forkDB.AddBlockState(nextForkDbBlock(gen, 1, 0))
+	assert.Equal(t, a3, forkDB.Header())
+
+	b3 := forkDB.AddBlockState(nextForkDbBlock(b2, 0, 0))
+	assert.Equal(t, a3, forkDB.Header(), "the first block seen at a height stays head")
+
+	b4 := forkDB.AddBlockState(nextForkDbBlock(b3, 0, 0))
+	assert.Equal(t, b4, forkDB.Header())
+
+	branches := forkDB.FetchBranchFrom(&b4.BlockId, &a3.BlockId)
+	assert.Equal(t, []*BlockState{b4, b3, b2}, branches.First)
+	assert.Equal(t, []*BlockState{a3, a2}, branches.Second)
+
+	forkDB.MarkInCurrentChain(a2, true)
+	assert.Equal(t, a2, forkDB.GetBlockInCurrentChainByNum(2))
+	assert.Nil(t, forkDB.GetBlockInCurrentChainByNum(3))
+
+	forkDB.SetValidity(b2, false)
+	assert.Nil(t, forkDB.GetBlock(&b2.BlockId))
+	assert.Nil(t, forkDB.GetBlock(&b3.BlockId))
+	assert.Nil(t, forkDB.GetBlock(&b4.BlockId))
+	assert.Equal(t, a3, forkDB.Header())
+
+	unlinkable := &SignedBlock{}
+	unlinkable.Previous = b4.BlockId
+	returned := false
+	try.Try(func() {
+		forkDB.AddSignedBlockState(unlinkable, true)
+	}).Catch(func(e *UnlinkableBlockException) {
+		returned = true
+	}).End()
+	assert.True(t, returned)
+}
+
+func TestForkDatabase_BftIrreversible(t *testing.T) {
+	forkDB, gen, closer := newForkDbGenesis(t)
+	defer closer()
+	b2 := forkDB.AddBlockState(nextForkDbBlock(gen, 0, 0))
+	b3 := forkDB.AddBlockState(nextForkDbBlock(b2, 0, 0))
+	c3 := forkDB.AddBlockState(nextForkDbBlock(b2, 1, 0))
+
+	initPriKey, _ := ecc.NewPrivateKey("5KYZdUEo39z3FPrtuX2QbbwGnNP5zTd7yyr2SC1j299sBCnWjss")
+	for _, producer := range []string{"eosio", "yuanc"} {
+		sig, err := initPriKey.Sign(b2.SigDigest().Bytes())
+		assert.NoError(t, err)
+		forkDB.Add(&HeaderConfirmation{BlockId: b2.BlockId, Producer: common.AccountName(common.N(producer)), ProducerSignature: sig})
+		assert.Equal(t, uint32(0), b2.BftIrreversibleBlocknum)
+	}
+
+	sig, err := initPriKey.Sign(b2.SigDigest().Bytes())
+	assert.NoError(t, err)
+	forkDB.Add(&HeaderConfirmation{BlockId: b2.BlockId, Producer: common.AccountName(common.N("tester")), ProducerSignature: sig})
+	assert.Equal(t, uint32(2), b2.BftIrreversibleBlocknum)
+	assert.Equal(t, uint32(2), b3.BftIrreversibleBlocknum)
+	assert.Equal(t, uint32(2), c3.BftIrreversibleBlocknum)
+	assert.Equal(t, uint32(2), LastIrreversibleBlockNum(forkDB.Header()))
+
+	irreversible := make([]uint32, 0)
+	forkDB.Irreversible = func(s *BlockState) { irreversible = append(irreversible, s.BlockNum) }
+	b4 := forkDB.AddBlockState(nextForkDbBlock(b3, 0, 0))
+	assert.Equal(t, b4, forkDB.Header())
+	assert.Equal(t, []uint32{1}, irreversible)
+}
+
+func TestForkDatabase_CloseAndRestore(t *testing.T) {
+	forkDB, gen, closer := newForkDbGenesis(t)
+	defer closer()
+
+	b2 := forkDB.AddBlockState(nextForkDbBlock(gen, 0, 0))
+	b3 := forkDB.AddBlockState(nextForkDbBlock(b2, 0, 1))
+	c3 := forkDB.AddBlockState(nextForkDbBlock(b2, 1, 0))
+	forkDB.MarkInCurrentChain(b3, true)
+
+	irreversible := make([]uint32, 0)
+	forkDB.Irreversible = func(s *BlockState) { irreversible = append(irreversible, s.BlockNum) }
+	forkDB.Close()
+	assert.Equal(t, []uint32{1}, irreversible)
+	assert.Nil(t, forkDB.Header())
+
+	restored := NewForkDatabase(forkDB.DataDir)
+	assert.Equal(t, b3.BlockId, restored.Header().BlockId)
+	assert.Equal(t, 4, len(restored.states))
+	assert.Equal(t, c3.BlockId, restored.GetBlock(&c3.BlockId).Header.BlockID())
+	assert.Equal(t, b3.BlockId, restored.GetBlockInCurrentChainByNum(3).BlockId)
+
+	_, err := os.Stat(filepath.Join(forkDB.DataDir, common.DefaultConfig.ForkDBName))
+	assert.True(t, os.IsNotExist(err), "fork database file is removed once it is loaded")
+
+	b4 := restored.AddBlockState(nextForkDbBlock(restored.GetBlock(&b3.BlockId), 0, 1))
+	assert.Equal(t, b4, restored.Header())
+}
+
+func TestForkDatabase_CorruptedFile(t *testing.T) {
+	forkDB, gen, closer := newForkDbGenesis(t)
+	defer closer()
+	forkDbDat := filepath.Join(forkDB.DataDir, common.DefaultConfig.ForkDBName)
+
+	forkDB.AddBlockState(nextForkDbBlock(gen, 0, 0))
+	forkDB.Close()
+	data, err := ioutil.ReadFile(forkDbDat)
+	assert.NoError(t, err)
+
+	wrongVersion := append([]byte{}, data...)
+	wrongVersion[4] = 9
+	flipped := append([]byte{}, data...)
+	flipped[len(flipped)-40] ^= 0xff
+
+	for name, content := range map[string][]byte{
+		"garbage":   []byte("not a fork database"),
+		"version":   wrongVersion,
+		"truncated": data[:len(data)/2],
+		"flipped":   flipped,
+	} {
+		assert.NoError(t, ioutil.WriteFile(forkDbDat, content, 0644))
+		returned := false
+		try.Try(func() {
+			NewForkDatabase(forkDB.DataDir)
+		}).Catch(func(e ForkDatabaseExceptions) {
+			returned = true
+		}).End()
+		assert.True(t, returned, name)
+	}
+}
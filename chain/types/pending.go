@@ -3,7 +3,6 @@ package types
 import (
 	"github.com/eosspark/eos-go/common"
 	"github.com/eosspark/eos-go/database"
-	"github.com/eosspark/eos-go/log"
 )
 
 type PendingState struct {
@@ -15,47 +14,26 @@ type PendingState struct {
 	Valid             bool
 }
 
-//TODO wait modify Singleton
-func NewPendingState(db database.DataBase) *PendingState {
-	pending := PendingState{}
-	/*db, err := eosiodb.NewDatabase(config.DefaultConfig.BlockDir, "eos.db", true)
-	if err != nil {
-		log.Error("pending NewPendingState is error detail:",err)
-	}
-	defer db.Close()*/
-	/*session := db.StartSession()
-
-	pending.DBSession = session
-	//pending.Valid = true
-	pending.DBSession = session*/
-	return &pending
-}
-
-//TODO wait modify Singleton
-func GetInstance() *PendingState {
-	pending := PendingState{}
-	db, err := database.NewDataBase(common.DefaultConfig.DefaultBlocksDirName + "/" + common.DefaultConfig.DBFileName)
-	if err != nil {
-		log.Error("pending NewPendingState is error detail:", err)
-	}
-	defer db.Close()
-	/*session := db.StartSession()
-	if err != nil {
-		fmt.Println(err.Error())
-	}
-	pending.DBSession = session
-	//pending.Valid = false
-	pending.DBSession = session*/
-	return &pending
+// NewPendingState starts a pending block whose changes are recorded by session, a block applied
+// without undo session has none
+func NewPendingState(session *database.Session) *PendingState {
+	return &PendingState{MaybeSession: session}
 }
 
-func (p *PendingState) Reset() {
-	if p.Valid {
-		p = nil
+// Reset drops the pending block, the changes of its session are undone unless the session was pushed
+func (p *PendingState) Reset() error {
+	p.Valid = false
+	session := p.MaybeSession
+	p.MaybeSession = nil
+	if session == nil {
+		return nil
 	}
-
+	return session.Undo()
 }
 
+// Push keeps the changes of the block on the undo stack of the database once the block is committed
 func (p *PendingState) Push() {
-	//p.DBSession.Push()
+	if p.MaybeSession != nil {
+		p.MaybeSession.Push()
+	}
 }
@@ -6,45 +6,45 @@ import (
 )
 
 type BaseActionTrace struct {
-	Receipt          ActionReceipt
-	Act              Action
-	ContextFree      bool //default false
-	Elapsed          common.Microseconds
-	CpuUsage         uint64
-	Console          string
-	TotalCpuUsage    uint64                   /// total of inline_traces[x].cpu_usage + cpu_usage
-	TrxId            common.TransactionIdType ///< the transaction that generated this action
-	BlockNum         uint32
-	BlockTime        common.BlockTimeStamp
-	ProducerBlockId  common.BlockIdType
-	AccountRamDeltas common.FlatSet
+	Receipt          ActionReceipt            `json:"receipt"`
+	Act              Action                   `json:"act"`
+	ContextFree      bool                     `json:"context_free"` //default false
+	Elapsed          common.Microseconds      `json:"elapsed"`
+	CpuUsage         uint64                   `json:"cpu_usage"`
+	Console          string                   `json:"console"`
+	TotalCpuUsage    uint64                   `json:"total_cpu_usage"` /// total of inline_traces[x].cpu_usage + cpu_usage
+	TrxId            common.TransactionIdType `json:"trx_id"`          ///< the transaction that generated this action
+	BlockNum         uint32                   `json:"block_num"`
+	BlockTime        common.BlockTimeStamp    `json:"block_time"`
+	ProducerBlockId  common.BlockIdType       `json:"producer_block_id"`
+	AccountRamDeltas []AccountDelta           `json:"account_ram_deltas"`
 }
 
 type ActionTrace struct {
 	BaseActionTrace
-	InlineTraces []ActionTrace
+	InlineTraces []ActionTrace `json:"inline_traces"`
 }
 
 type TransactionTrace struct {
-	ID              common.TransactionIdType
-	BlockNum        uint32
-	BlockTime       common.BlockTimeStamp
-	ProducerBlockId common.BlockIdType
-	Receipt         TransactionReceiptHeader
-	Elapsed         common.Microseconds
-	NetUsage        uint64
-	Scheduled       bool //false
-	ActionTraces    []ActionTrace
-	FailedDtrxTrace *TransactionTrace
+	ID              common.TransactionIdType `json:"id"`
+	BlockNum        uint32                   `json:"block_num"`
+	BlockTime       common.BlockTimeStamp    `json:"block_time"`
+	ProducerBlockId common.BlockIdType       `json:"producer_block_id"`
+	Receipt         TransactionReceiptHeader `json:"receipt"`
+	Elapsed         common.Microseconds      `json:"elapsed"`
+	NetUsage        uint64                   `json:"net_usage"`
+	Scheduled       bool                     `json:"scheduled"` //false
+	ActionTraces    []ActionTrace            `json:"action_traces"`
+	FailedDtrxTrace *TransactionTrace        `json:"failed_dtrx_trace"`
 	//TODO exception
-	Except Exception
+	Except Exception `json:"except"`
 	/*fc::optional<fc::exception>                except;
 	std::exception_ptr                         except_ptr;*/
 }
 
 type AccountDelta struct {
-	Account common.AccountName
-	Delta   int64
+	Account common.AccountName `json:"account"`
+	Delta   int64              `json:"delta"`
 }
 
 func (a AccountDelta) Compare(first common.FlatSet, second common.FlatSet) bool {
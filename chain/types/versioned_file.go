@@ -0,0 +1,211 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/eosspark/eos-go/crypto/rlp"
+	"github.com/eosspark/eos-go/exception/try"
+)
+
+// A versioned file holds a magic and version header followed by records and the checksum of the
+// records. The header is read on its own so that an unsupported version is reported before the
+// rest of the file is decoded, the checksum is verified before the first record is handed out.
+// forkdb.dat and the snapshots are versioned files
+
+const (
+	versionedFileRecord byte = 1
+	versionedFileEnd    byte = 0
+)
+
+type versionedFileHeader struct {
+	Magic   uint32
+	Version uint32
+}
+
+// VersionedFileWriter writes the records to a temporary file which replaces the file on Close, a
+// crash while writing must not leave half a file behind
+type VersionedFileWriter struct {
+	path string
+	file *os.File
+	out  *bufio.Writer
+	hash hash.Hash
+}
+
+// CreateVersionedFile starts a versioned file at path, the directory is created if needed
+func CreateVersionedFile(path string, magic uint32, version uint32) (*VersionedFileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	w := &VersionedFileWriter{path: path, file: file, out: bufio.NewWriter(file), hash: sha256.New()}
+	if err = rlp.Encode(w.out, &versionedFileHeader{Magic: magic, Version: version}); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends data as one record
+func (w *VersionedFileWriter) Write(data []byte) error {
+	frame := make([]byte, 5, 5+len(data))
+	frame[0] = versionedFileRecord
+	binary.LittleEndian.PutUint32(frame[1:], uint32(len(data)))
+	frame = append(frame, data...)
+	w.hash.Write(frame)
+	_, err := w.out.Write(frame)
+	return err
+}
+
+// Encode appends the encoded v as one record
+func (w *VersionedFileWriter) Encode(v interface{}) error {
+	data, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		return err
+	}
+	return w.Write(data)
+}
+
+// Close writes the checksum and moves the file in place, the writer is aborted if that fails
+func (w *VersionedFileWriter) Close() error {
+	err := w.out.WriteByte(versionedFileEnd)
+	if err == nil {
+		_, err = w.out.Write(w.hash.Sum(nil))
+	}
+	if err == nil {
+		err = w.out.Flush()
+	}
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if err != nil {
+		w.Abort()
+		return err
+	}
+	if err = w.file.Close(); err == nil {
+		err = os.Rename(w.file.Name(), w.path)
+	}
+	w.file = nil
+	return err
+}
+
+// Abort drops the temporary file, the file at path is left untouched. It does nothing once the
+// writer is closed
+func (w *VersionedFileWriter) Abort() {
+	if w.file != nil {
+		w.file.Close()
+		os.Remove(w.file.Name())
+		w.file = nil
+	}
+}
+
+// VersionedFileReader hands out the records of a versioned file in the order they were written
+type VersionedFileReader struct {
+	file *os.File
+	in   *bufio.Reader
+}
+
+// OpenVersionedFile checks the header and the checksum of the file at path. The error of a file
+// that can not be opened is the one of os.Open
+func OpenVersionedFile(path string, magic uint32, version uint32) (*VersionedFileReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &VersionedFileReader{file: file, in: bufio.NewReader(file)}
+	if err = r.verify(magic, version); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *VersionedFileReader) verify(magic uint32, version uint32) error {
+	raw := make([]byte, 8)
+	header := versionedFileHeader{}
+	if _, err := io.ReadFull(r.in, raw); err != nil || rlp.DecodeBytes(raw, &header) != nil || header.Magic != magic {
+		return fmt.Errorf("bad magic number, expected %#x", magic)
+	}
+	if header.Version != version {
+		return fmt.Errorf("unsupported version, file version is %d while code supports version %d", header.Version, version)
+	}
+
+	// the records are hashed in a first pass, nothing is kept in memory
+	h := sha256.New()
+	for {
+		flag, err := r.in.ReadByte()
+		if err != nil {
+			return errors.New("unexpected end of file")
+		}
+		if flag == versionedFileEnd {
+			break
+		}
+		size := make([]byte, 4)
+		if flag != versionedFileRecord {
+			return fmt.Errorf("bad record flag %d", flag)
+		} else if _, err = io.ReadFull(r.in, size); err != nil {
+			return errors.New("unexpected end of file")
+		}
+		h.Write([]byte{flag})
+		h.Write(size)
+		if _, err = io.CopyN(h, r.in, int64(binary.LittleEndian.Uint32(size))); err != nil {
+			return errors.New("unexpected end of file")
+		}
+	}
+	checksum := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(r.in, checksum); err != nil || !bytes.Equal(checksum, h.Sum(nil)) {
+		return errors.New("checksum mismatch")
+	}
+
+	if _, err := r.file.Seek(int64(len(raw)), io.SeekStart); err != nil {
+		return err
+	}
+	r.in.Reset(r.file)
+	return nil
+}
+
+// Read returns the next record, io.EOF follows the last one
+func (r *VersionedFileReader) Read() ([]byte, error) {
+	flag, err := r.in.ReadByte()
+	if err != nil {
+		return nil, err
+	} else if flag == versionedFileEnd {
+		return nil, io.EOF
+	}
+	size := make([]byte, 4)
+	if _, err = io.ReadFull(r.in, size); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.LittleEndian.Uint32(size))
+	_, err = io.ReadFull(r.in, data)
+	return data, err
+}
+
+// Decode reads the next record into v
+func (r *VersionedFileReader) Decode(v interface{}) error {
+	data, err := r.Read()
+	if err != nil {
+		return err
+	}
+	try.Try(func() {
+		err = rlp.DecodeBytes(data, v)
+	}).Catch(func(e interface{}) {
+		err = fmt.Errorf("%v", e)
+	}).End()
+	return err
+}
+
+func (r *VersionedFileReader) Close() error {
+	return r.file.Close()
+}
@@ -97,6 +97,7 @@ func init() {
 	DefaultConfig.DefaultBlocksDirName = "/tmp/data/blocks"
 	DefaultConfig.DefaultReversibleBlocksDirName = "/tmp/data/reversible"
 	DefaultConfig.DefaultStateDirName = "/tmp/data/state"
+	DefaultConfig.DefaultSnapshotsDirName = "/tmp/data/snapshots"
 	DefaultConfig.DefaultStateSize = 1*1024*1024*1024
 	DefaultConfig.DefaultStateGuardSize = 128*1024*1024
 	DefaultConfig.DefaultReversibleCacheSize = 340*1024*1024
@@ -186,6 +187,7 @@ type Config struct {
 	DefaultBlocksDirName           string
 	DefaultReversibleBlocksDirName string
 	DefaultStateDirName            string
+	DefaultSnapshotsDirName        string
 	DefaultStateSize               uint64
 	DefaultStateGuardSize          uint64
 	DefaultReversibleCacheSize     uint64
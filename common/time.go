@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/json"
 	"strconv"
 	"strings"
 	"time"
@@ -64,6 +65,33 @@ func (tp TimePoint) SubUs(m Microseconds) TimePoint     { return TimePoint(Micro
 func (tp TimePoint) Sub(t TimePoint) Microseconds       { return Microseconds(tp - t) }
 func (tp TimePoint) SubTps(t TimePointSec) Microseconds { return tp.Sub(t.ToTimePoint()) }
 
+func (tp TimePoint) MarshalJSON() ([]byte, error) {
+	t := time.Unix(int64(tp)/1e6, int64(tp)%1e6*1000).UTC()
+	return json.Marshal(t.Format(format + ".000"))
+}
+
+// UnmarshalJSON accepts the iso string written by MarshalJSON as well as the raw microseconds
+func (tp *TimePoint) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var us int64
+		if err := json.Unmarshal(data, &us); err != nil {
+			return err
+		}
+		*tp = TimePoint(us)
+		return nil
+	}
+	t, err := FromIsoString(s)
+	if err != nil {
+		return err
+	}
+	*tp = t
+	return nil
+}
+
 /**
  *  A lower resolution time_point accurate only to seconds from 1970
  */
@@ -89,6 +117,32 @@ func (tp TimePointSec) AddUs(m Microseconds) TimePoint    { return tp.ToTimePoin
 func (tp TimePointSec) SubUs(m Microseconds) TimePoint    { return tp.ToTimePoint().SubUs(m) }
 func (tp TimePointSec) Sub(t TimePointSec) Microseconds   { return tp.ToTimePoint().Sub(t.ToTimePoint()) }
 
+func (tp TimePointSec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Unix(int64(tp), 0).UTC().Format(format))
+}
+
+// UnmarshalJSON accepts the iso string written by MarshalJSON as well as the raw seconds
+func (tp *TimePointSec) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var sec uint32
+		if err := json.Unmarshal(data, &sec); err != nil {
+			return err
+		}
+		*tp = TimePointSec(sec)
+		return nil
+	}
+	t, err := FromIsoStringSec(s)
+	if err != nil {
+		return err
+	}
+	*tp = t
+	return nil
+}
+
 /**
  * inherit from asio.DeadlineTimer
  */
@@ -1,7 +1,9 @@
 package common
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/eosspark/eos-go/plugins/appbase/asio"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	_ "net/http/pprof"
@@ -37,7 +39,7 @@ func Test_Timer(t *testing.T) {
 
 		timerCorelationId++
 		cid := timerCorelationId
-		timer.AsyncWait(func() {
+		timer.AsyncWait(func(ec asio.ErrorCode) {
 			if cid == timerCorelationId {
 				fmt.Println("exec async1...", time.Now())
 				blockNum++
@@ -94,7 +96,7 @@ func Test_Timer_Memory(t *testing.T) {
 	loop = func() {
 		timer.Cancel()
 		timer.ExpiresFromNow(1)
-		timer.AsyncWait(func() {
+		timer.AsyncWait(func(ec asio.ErrorCode) {
 			after := memConsumed()
 			fmt.Printf("%.3f KB\n", float64(after-before)/1e3)
 			loop()
@@ -128,4 +130,26 @@ func Test_FromIsoString(t *testing.T) {
 	fmt.Println(tps)
 }
 
+func Test_TimePointJSON(t *testing.T) {
+	tp, _ := FromIsoString("2018-06-01T12:00:00.500")
+	data, err := json.Marshal(tp)
+	assert.NoError(t, err)
+	assert.Equal(t, `"2018-06-01T12:00:00.500"`, string(data))
+
+	var out TimePoint
+	assert.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, tp, out)
+
+	tps := TimePointSec(1527854400)
+	data, err = json.Marshal(tps)
+	assert.NoError(t, err)
+	assert.Equal(t, `"2018-06-01T12:00:00"`, string(data))
+
+	var outSec TimePointSec
+	assert.NoError(t, json.Unmarshal(data, &outSec))
+	assert.Equal(t, tps, outSec)
+	assert.NoError(t, json.Unmarshal([]byte(`1527854400`), &outSec))
+	assert.Equal(t, tps, outSec)
+}
+
 func Test_BlockTimestamp(t *testing.T) {}
@@ -524,6 +524,9 @@ func (t *JSONTime) UnmarshalJSON(data []byte) (err error) {
 	return err
 }
 
+// Variants is the dynamic form of an object, the same role as fc::mutable_variant_object.
+type Variants map[string]interface{}
+
 // HexBytes
 
 type HexBytes []byte
@@ -194,7 +194,6 @@ func (e *encoder) encode(v interface{}) (err error) {
 		if err = e.writeUVarInt(l); err != nil {
 			return
 		}
-		println(fmt.Sprintf("Map [%T] of length: %d", v, l))
 		for _, key := range rv.MapKeys() {
 			value := rv.MapIndex(key)
 			if err = e.encode(key.Interface()); err != nil {
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+/*
+dbBatch collects the key writes of one object mutation, or of a whole session undo,
+and hands them to the store as one leveldb.Batch. LevelDB replays a batch from its journal
+completely or not at all, so after a crash the indexes of an object are never found without
+the object itself. Reads through the batch already see the writes that are not applied yet
+*/
+type dbBatch struct {
+	store   kvStore
+	batch   *leveldb.Batch
+	pending map[string]*batchEntry
+	// stored is the size the keys take in the store before the batch, as far as the batch read them,
+	// so that the size counters of a sizedStore need no other lookup
+	stored map[string]int64
+}
+
+type batchEntry struct {
+	value   []byte
+	deleted bool
+}
+
+func newDbBatch(store kvStore) *dbBatch {
+	return &dbBatch{store: store, batch: new(leveldb.Batch), pending: make(map[string]*batchEntry), stored: make(map[string]int64)}
+}
+
+func (b *dbBatch) Has(key []byte, ro *opt.ReadOptions) (bool, error) {
+	if entry, ok := b.pending[string(key)]; ok {
+		return !entry.deleted, nil
+	}
+	ok, err := b.store.Has(key, ro)
+	if err == nil && !ok {
+		b.seen(key, nil, false)
+	}
+	return ok, err
+}
+
+func (b *dbBatch) Get(key []byte, ro *opt.ReadOptions) ([]byte, error) {
+	if entry, ok := b.pending[string(key)]; ok {
+		if entry.deleted {
+			return nil, leveldb.ErrNotFound
+		}
+		return entry.value, nil
+	}
+	value, err := b.store.Get(key, ro)
+	if err == nil || err == leveldb.ErrNotFound {
+		b.seen(key, value, err == nil)
+	}
+	return value, err
+}
+
+func (b *dbBatch) Put(key, value []byte, wo *opt.WriteOptions) error {
+	b.batch.Put(key, value)
+	b.pending[string(key)] = &batchEntry{value: value}
+	return nil
+}
+
+func (b *dbBatch) Delete(key []byte, wo *opt.WriteOptions) error {
+	b.batch.Delete(key)
+	b.pending[string(key)] = &batchEntry{deleted: true}
+	return nil
+}
+
+// seen records what the store holds for key before the batch
+func (b *dbBatch) seen(key, value []byte, found bool) {
+	if _, ok := b.stored[string(key)]; ok {
+		return
+	}
+	b.stored[string(key)] = 0
+	if found {
+		b.stored[string(key)] = int64(len(key) + len(value))
+	}
+}
+
+// putNew writes a key the store does not hold
+func (b *dbBatch) putNew(key, value []byte) {
+	b.seen(key, nil, false)
+	b.Put(key, value, nil)
+}
+
+// deleteStored drops a key holding value in the store, as read by an iterator
+func (b *dbBatch) deleteStored(key, value []byte) {
+	b.seen(key, value, true)
+	b.Delete(key, nil)
+}
+
+// commit applies every collected write atomically
+func (b *dbBatch) commit() error {
+	if b.batch.Len() == 0 {
+		return nil
+	}
+	if s, ok := b.store.(*sizedStore); ok {
+		sizes := make(batchSizes, len(b.pending))
+		for key, entry := range b.pending {
+			sizes[key] = 0
+			if !entry.deleted {
+				sizes[key] = int64(len(key) + len(entry.value))
+			}
+		}
+		return s.write(b.batch, sizes, b.stored)
+	}
+	return b.store.Write(b.batch, nil)
+}
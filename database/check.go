@@ -0,0 +1,239 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/eosspark/eos-go/crypto/rlp"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// IndexProblem is the way an index key of an object type is out of sync with the objects
+type IndexProblem int
+
+const (
+	// MissingIndexKey is a key an object has to have in one of its indexes and does not
+	MissingIndexKey IndexProblem = iota
+	// OrphanedIndexKey is a key no object of the type has, the object is gone or its fields changed
+	OrphanedIndexKey
+	// WrongIndexValue is a key of an object that holds the id of another object
+	WrongIndexValue
+	// DuplicateIndexKey is a key of a unique index more objects have
+	DuplicateIndexKey
+	// BadObject is an object that can not be decoded or is stored under another id than its own
+	BadObject
+)
+
+func (p IndexProblem) String() string {
+	switch p {
+	case MissingIndexKey:
+		return "missing"
+	case OrphanedIndexKey:
+		return "orphaned"
+	case WrongIndexValue:
+		return "wrong value"
+	case DuplicateIndexKey:
+		return "duplicate"
+	case BadObject:
+		return "bad object"
+	}
+	return "unknown"
+}
+
+// IndexIssue is a key of the type found out of sync, Index is empty for the keys of the objects
+type IndexIssue struct {
+	Problem  IndexProblem
+	Index    string
+	Key      []byte
+	Repaired bool
+}
+
+func (i IndexIssue) String() string {
+	repaired := ""
+	if i.Repaired {
+		repaired = ", repaired"
+	}
+	return fmt.Sprintf("%s key %x of index %q%s", i.Problem, i.Key, i.Index, repaired)
+}
+
+// CheckReport is what Check found for one object type
+type CheckReport struct {
+	Type    string
+	Objects int
+	Keys    int
+	Issues  []IndexIssue
+}
+
+// Ok is true when every index key of the type points to the object it is built from
+func (r *CheckReport) Ok() bool {
+	return len(r.Issues) == 0
+}
+
+/*
+check that the index keys of a type match its objects: every object has the keys its fields give
+in each of its indexes, holding its id, and there is no other index key of the type.
+repair puts the missing keys and the keys holding a wrong id and deletes the orphaned ones in one batch,
+duplicates and bad objects are only reported. The repair is not recorded in the undo states
+@param object 		--> 	object of the type (struct or pointer)
+@param repair 		--> 	fix the keys that are out of sync
+
+@return
+success 			-->		report, error is nil
+error 				-->		error
+
+*/
+
+func (ldb *LDataBase) Check(object interface{}, repair bool) (*CheckReport, error) {
+	typ := reflect.TypeOf(object)
+	if typ == nil {
+		return nil, ErrBadType
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, ErrBadType
+	}
+	layout, err := layoutOf(typ)
+	if err != nil {
+		return nil, err
+	}
+	if layout.id == nil {
+		return nil, ErrNoID
+	}
+
+	report := &CheckReport{Type: layout.name}
+	objects, indexes := ldb.scanType(layout, report)
+
+	// the keys every object has to have, owners keeps the id each key is expected to hold
+	owners := make(map[string][]byte)
+	duplicates := make(map[string]bool)
+	bad := make(map[string]bool)
+	ids := make([]string, 0, len(objects))
+	for id := range objects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		cfg, ok := decodeObject(objects[id], reflect.New(typ), []byte(id))
+		if !ok {
+			bad[id] = true
+			report.Issues = append(report.Issues, IndexIssue{Problem: BadObject, Key: idKey([]byte(id), []byte(layout.name))})
+			continue
+		}
+		report.Objects++
+
+		dbKV := &dbKeyValue{}
+		cfgToKV([]byte(id), cfg, dbKV)
+		for _, index := range dbKV.index {
+			key := string(index.key)
+			if _, ok := owners[key]; ok {
+				duplicates[key] = true
+				continue
+			}
+			owners[key] = index.value
+		}
+	}
+
+	batch := newDbBatch(ldb.db)
+	keys := make([]string, 0, len(owners)+len(indexes))
+	for key := range owners {
+		keys = append(keys, key)
+	}
+	for key := range indexes {
+		if _, ok := owners[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		expected, owned := owners[key]
+		value, found := indexes[key]
+		issue := IndexIssue{Key: []byte(key), Index: indexOfKey(layout, []byte(key))}
+		switch {
+		case duplicates[key]:
+			issue.Problem = DuplicateIndexKey
+		case !owned:
+			if bad[string(value)] {
+				// the key may belong to an object that could not be decoded, it is left to be looked at
+				issue.Problem = OrphanedIndexKey
+				break
+			}
+			issue.Problem, issue.Repaired = OrphanedIndexKey, repair
+			if repair {
+				batch.deleteStored(issue.Key, value)
+			}
+		case !found:
+			issue.Problem, issue.Repaired = MissingIndexKey, repair
+			if repair {
+				batch.putNew(issue.Key, expected)
+			}
+		case !bytes.Equal(value, expected):
+			issue.Problem, issue.Repaired = WrongIndexValue, repair
+			if repair {
+				batch.seen(issue.Key, value, true)
+				batch.Put(issue.Key, expected, nil)
+			}
+		default:
+			continue
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+	if repair {
+		if err = batch.commit(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// scanType splits the keys of the type into its objects by id and its index keys
+func (ldb *LDataBase) scanType(layout *typeLayout, report *CheckReport) (objects, indexes map[string][]byte) {
+	objects, indexes = make(map[string][]byte), make(map[string][]byte)
+	prefix := typeNameFieldName([]byte(layout.name), nil)
+
+	it := ldb.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+	for it.Next() {
+		report.Keys++
+		key, value := cloneByte(it.Key()), cloneByte(it.Value())
+		if indexOfKey(layout, key) != "" {
+			indexes[string(key)] = value
+		} else {
+			objects[string(key[len(prefix):])] = value
+		}
+	}
+	return
+}
+
+// indexOfKey is the tag of the index key belongs to, empty for the keys of the objects
+func indexOfKey(layout *typeLayout, key []byte) string {
+	tag := ""
+	for name := range layout.indexes {
+		prefix := append(typeNameFieldName([]byte(layout.name), []byte(name)), '_', '_')
+		if bytes.HasPrefix(key, prefix) && len(name) > len(tag) {
+			tag = name
+		}
+	}
+	return tag
+}
+
+// decodeObject decodes the object stored under id into in, false when it can not or its id is another one
+func decodeObject(data []byte, in reflect.Value, id []byte) (cfg *structInfo, ok bool) {
+	defer func() {
+		if recover() != nil { // rlp panics on some corrupted data
+			cfg, ok = nil, false
+		}
+	}()
+	if err := rlp.DecodeBytes(data, in.Interface()); err != nil {
+		return nil, false
+	}
+	cfg, err := parseObjectToCfg(in.Interface())
+	if err != nil {
+		return nil, false
+	}
+	objId, err := cfg.encodeId()
+	return cfg, err == nil && bytes.Equal(objId, id)
+}
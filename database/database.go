@@ -15,17 +15,19 @@ import (
 )
 
 type LDataBase struct {
-	db       *leveldb.DB
+	db       kvStore
 	stack    *deque
 	path     string
 	revision int64
 	nextId   map[string]int64
 	logFlag  bool
+	usage    *sizedStore
+	history  int64
 }
 
 /*
 
-@param path 		--> 	database file (note:type-->d)
+@param path 		--> 	database file (note:type-->d), InMemory keeps the database in memory
 
 @return
 
@@ -35,15 +37,7 @@ error 				-->		database is nil ,error
 */
 func NewDataBase(path string, flag ...bool) (DataBase, error) {
 
-	db, err := leveldb.OpenFile(path, &opt.Options{
-		OpenFilesCacheCapacity: 16,
-		BlockCacheCapacity:     16 / 2 * opt.MiB,
-		WriteBuffer:            16 / 4 * opt.MiB, // Two of these are used internally
-		Filter:                 filter.NewBloomFilter(10),
-	})
-	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
-		db, err = leveldb.RecoverFile(path, nil)
-	}
+	db, err := openStore(path)
 	if err != nil {
 		return nil, err
 	}
@@ -57,10 +51,52 @@ func NewDataBase(path string, flag ...bool) (DataBase, error) {
 	if len(flag) > 0 {
 		logFlag = flag[0]
 	}
-	return &LDataBase{db: db, stack: newDeque(), path: path, nextId: nextId, logFlag: logFlag}, nil
+	usage, err := newSizedStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	ldb := &LDataBase{db: usage, stack: newDeque(), path: path, nextId: nextId, logFlag: logFlag, usage: usage}
+	/*	read the revision and the undo stack	*/
+	err = ldb.loadUndo()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return ldb, nil
 }
 
-func typeIncrement(db *leveldb.DB) (map[string]int64, error) {
+func openStore(path string) (kvStore, error) {
+	if path == InMemory {
+		return newMemStore(), nil
+	}
+
+	db, err := leveldb.OpenFile(path, &opt.Options{
+		OpenFilesCacheCapacity: 16,
+		BlockCacheCapacity:     16 / 2 * opt.MiB,
+		WriteBuffer:            16 / 4 * opt.MiB, // Two of these are used internally
+		Filter:                 filter.NewBloomFilter(10),
+	})
+	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+		db, err = leveldb.RecoverFile(path, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Size is the approximate number of bytes taken by the objects, the indexes and the undo states
+func (ldb *LDataBase) Size() uint64 {
+	return ldb.usage.size()
+}
+
+// TableSizes breaks Size down by the type name of the objects, the other keys count under their own name
+func (ldb *LDataBase) TableSizes() map[string]uint64 {
+	return ldb.usage.tableSizes()
+}
+
+func typeIncrement(db kvStore) (map[string]int64, error) {
 	nextId := make(map[string]int64)
 	dbIncrement := dbIncrement
 	key := []byte(dbIncrement)
@@ -92,75 +128,115 @@ func (ldb *LDataBase) Close() {
 }
 
 func (ldb *LDataBase) WriteIncrement() error {
-	val, err := rlp.EncodeToBytes(ldb.nextId)
+	return putIncrement(ldb.db, ldb.nextId)
+}
+
+func putIncrement(batch kvReadWriter, nextId map[string]int64) error {
+	val, err := rlp.EncodeToBytes(nextId)
 	if err != nil {
 		return err
 	}
 	dbIncrement := dbIncrement
 	key := []byte(dbIncrement)
-	err = saveKey(key, val, ldb.db)
-	if err != nil {
-		return err
-	}
-	return nil
+	return batch.Put(key, val, nil)
 }
 
 func (ldb *LDataBase) Revision() int64 {
 	return ldb.revision
 }
 
-func (ldb *LDataBase) Undo() {
+func (ldb *LDataBase) Undo() error {
 	//
 	stack := ldb.getStack()
 	if stack == nil {
-		return
+		return nil
 	}
 
+	batch := newDbBatch(ldb.db) /* the whole session is reverted at once */
+	if err := ldb.undoObjects(batch, stack); err != nil {
+		return err
+	}
+	ldb.deleteUndoState(batch, stack.reversion)
+	if err := putRevision(batch, ldb.revision-1); err != nil {
+		return err
+	}
+	if err := putIncrement(batch, stack.oldIds); err != nil {
+		return err
+	}
+	if err := batch.commit(); err != nil {
+		return err
+	}
 	ldb.nextId = stack.oldIds
+	ldb.stack.Pop()
+	ldb.revision--
+	return nil
+}
 
+// undoObjects writes the objects of the state back the way they were before its session
+func (ldb *LDataBase) undoObjects(batch kvReadWriter, stack *undoState) error {
+	var result error
+	keep := func(err error) {
+		if result == nil {
+			result = err
+		}
+	}
 	for key, _ := range stack.OldValue {
 
-		ldb.undoModifyKv(key)
+		keep(ldb.undoModifyKv(batch, resolveObject(key)))
 	}
 	for key, _ := range stack.NewValue {
 		// db.remove
-		ldb.remove(key)
+		keep(ldb.remove(batch, resolveObject(key)))
 
 	}
 	for key, _ := range stack.RemoveValue {
 		// db.insert
-		ldb.insert(key, true)
+		keep(ldb.insert(batch, resolveObject(key), true))
 		//save(key,ldb.db,true)
 	}
-	ldb.stack.Pop()
-	ldb.revision--
+	return result
 }
 
-func (ldb *LDataBase) UndoAll() {
-	// TODO wait Undo
-	log.Fatalln("UndoAll do not work,Please wait")
+func (ldb *LDataBase) UndoAll() error {
+	for ldb.stack.Size() != 0 {
+		if err := ldb.Undo(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (ldb *LDataBase) squash() {
+func (ldb *LDataBase) squash() error {
+	stack := ldb.getStack()
+	if stack == nil {
+		return nil
+	}
 
-	if ldb.stack.Size() == 1 {
+	batch := newDbBatch(ldb.db)
+	if err := putRevision(batch, ldb.revision-1); err != nil {
+		return err
+	}
+	preStack := ldb.getSecond()
+	if preStack == nil {
+		ldb.deleteUndoState(batch, stack.reversion)
+		if err := batch.commit(); err != nil {
+			return err
+		}
 		ldb.stack.Pop()
 		ldb.revision--
-		return
+		return nil
 	}
-	stack := ldb.getStack()
-	preStack := ldb.getSecond()
+	if err := ldb.moveUndoEntries(batch, stack.reversion, preStack); err != nil {
+		return err
+	}
+	if err := batch.commit(); err != nil {
+		return err
+	}
+
 	for key, value := range stack.OldValue {
-		if _, ok := preStack.NewValue[key]; ok {
+		if preStack.find(preStack.NewValue, key) != nil || preStack.find(preStack.OldValue, key) != nil {
 			continue
 		}
-		if _, ok := preStack.OldValue[key]; ok {
-			continue
-		}
-		if _, ok := preStack.RemoveValue[key]; ok {
-			//fmt.Println("squash failed")
-			// panic ?
-		}
 		preStack.OldValue[key] = value
 	}
 
@@ -169,49 +245,74 @@ func (ldb *LDataBase) squash() {
 	}
 
 	for key, value := range stack.RemoveValue {
-		//fmt.Println(key, " --> ", value)
-		if _, ok := preStack.NewValue[key]; ok {
-			k := undoEqual(preStack.NewValue, key)
+		if k := preStack.find(preStack.NewValue, key); k != nil {
 			delete(preStack.NewValue, k)
+			continue
 		}
-		if _, ok := preStack.OldValue[key]; ok {
-			preStack.RemoveValue[key] = value
-			k := undoEqual(preStack.OldValue, key)
+		if k := preStack.find(preStack.OldValue, key); k != nil {
+			preStack.RemoveValue[k] = preStack.OldValue[k]
 			delete(preStack.OldValue, k)
+			continue
 		}
 		preStack.RemoveValue[key] = value
 	}
+	preStack.entries += stack.entries
 	ldb.stack.Pop()
 	ldb.revision--
+	return nil
 }
 
-func (ldb *LDataBase) StartSession() *Session {
-	ldb.revision++
-	state := newUndoState(ldb.revision, ldb.nextId)
+func (ldb *LDataBase) StartSession() (*Session, error) {
+	state := newUndoState(ldb.revision+1, ldb.nextId)
+
+	batch := newDbBatch(ldb.db)
+	if err := putRevision(batch, state.reversion); err != nil {
+		return nil, err
+	}
+	if err := putUndoHeader(batch, state); err != nil {
+		return nil, err
+	}
+	if err := batch.commit(); err != nil {
+		return nil, err
+	}
+	ldb.revision = state.reversion
 	ldb.stack.Append(state)
-	return &Session{db: ldb, apply: true, revision: ldb.revision}
+	return &Session{db: ldb, apply: true, revision: ldb.revision}, nil
 }
 
-func (ldb *LDataBase) Commit(revision int64) {
+func (ldb *LDataBase) Commit(revision int64) error {
 
-	for {
-		if ldb.stack.Size() == 0 {
-			return
-		}
-
-		stack := ldb.getFirstStack()
-		if stack == nil {
-			break
-		}
-		if stack.reversion > revision {
+	lib := revision /* the history is counted back from the last committed revision, not from the head */
+	if lib > ldb.revision {
+		lib = ldb.revision
+	}
+	batch := newDbBatch(ldb.db)
+	committed := 0
+	for i := 0; i < ldb.stack.Size(); i++ {
+		stack, ok := ldb.stack.At(i).(*undoState)
+		if !ok || stack.reversion > revision {
 			break
 		}
 
+		ldb.deleteUndoState(batch, stack.reversion)
+		if stack.reversion > lib-ldb.history { /* the committed state is kept to rebuild the revisions before it */
+			if err := ldb.putHistoryState(batch, stack); err != nil {
+				return err
+			}
+		}
+		committed++
+	}
+	ldb.pruneHistory(batch, lib-ldb.history)
+	if err := batch.commit(); err != nil {
+		return err
+	}
+	for ; committed > 0; committed-- {
 		ldb.stack.PopFront()
 	}
+	return nil
 }
 
-func (ldb *LDataBase) SetRevision(revision int64) {
+func (ldb *LDataBase) SetRevision(revision int64) error {
 	if ldb.stack.Size() != 0 {
 		panic("cannot set revision while there is an existing undo stack")
 		// throw
@@ -220,7 +321,17 @@ func (ldb *LDataBase) SetRevision(revision int64) {
 		//throw
 		panic("revision to set is too high")
 	}
+
+	batch := newDbBatch(ldb.db)
+	if err := putRevision(batch, revision); err != nil {
+		return err
+	}
+	ldb.pruneHistory(batch, math.MaxInt64) /* the history does not lead to the new revision */
+	if err := batch.commit(); err != nil {
+		return err
+	}
 	ldb.revision = revision
+	return nil
 }
 
 /*
@@ -234,16 +345,44 @@ error 				-->		error
 */
 
 func (ldb *LDataBase) Insert(in interface{}) error {
-	err := ldb.insert(in)
+	batch := newDbBatch(ldb.db)
+	err := ldb.insert(batch, in)
 	if err != nil {
 		return err
 	}
+	return ldb.commitMutation(batch, undoOpInsert, in)
+}
 
-	ldb.undoInsert(in) // undo
-	return nil
+/*
+insert object to database keeping its id, the increment id of its type moves past it
+used to rebuild a database from a snapshot
+@param in 			--> 	object(pointer)
+
+@return
+success 			-->		nil
+error 				-->		error
+
+*/
+
+func (ldb *LDataBase) Restore(in interface{}) error {
+	cfg, err := parseObjectToCfg(in)
+	if err != nil {
+		return err
+	}
+	id := cfg.Id.Convert(reflect.TypeOf(int64(0))).Int()
+
+	batch := newDbBatch(ldb.db)
+	err = ldb.insert(batch, in, true)
+	if err != nil {
+		return err
+	}
+	if next, ok := ldb.nextId[cfg.Name]; !ok || next <= id {
+		ldb.nextId[cfg.Name] = id + 1
+	}
+	return ldb.commitMutation(batch, undoOpInsert, in)
 }
 
-func (ldb *LDataBase) insert(in interface{}, flag ...bool) error { /* struct cfg --> KV struct --> kv to db --> undo db */
+func (ldb *LDataBase) insert(batch kvReadWriter, in interface{}, flag ...bool) error { /* struct cfg --> KV struct --> kv to db --> undo db */
 
 	cfg, err := parseObjectToCfg(in) /* (struct cfg) parse object tag */
 	if err != nil {
@@ -266,36 +405,23 @@ func (ldb *LDataBase) insert(in interface{}, flag ...bool) error { /* struct cfg
 	structKV(in, dbKV, cfg) /* (kv.index) all key and value*/
 
 	// dbKV.showDbKV()
-	err = ldb.insertKvToDb(dbKV) /* (kv to db) kv insert database (atomic) */
+	err = insertKvToDb(dbKV, batch) /* (kv to batch) kv insert database (atomic) */
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (ldb *LDataBase) insertKvToDb(dbKV *dbKeyValue) error {
+func insertKvToDb(dbKV *dbKeyValue, batch kvReadWriter) error {
 
-	undoKeyValue := dbKeyValue{} /* 	Record all operations before a db operation is completed		*/
-	/* 	If you need to roll back before the operation is complete 		*/
-	/* 	execute the undoKV function										*/
-	undoKeyValue.typeName = dbKV.typeName
-	undo := false
-	defer func() { /*	undo  */
-		if !undo {
-			return
-		}
-		ldb.undoInsertKV(&undoKeyValue)
-	}()
 	for _, v := range dbKV.index {
-		err := saveKey(v.key, v.value, ldb.db)
+		err := saveKey(v.key, v.value, batch)
 		if err != nil {
-			undo = true
 			return err
 		}
-		undoKeyValue.index = append(undoKeyValue.index, v)
 	}
 
-	err := saveKey(dbKV.id.key, dbKV.id.value, ldb.db)
+	err := saveKey(dbKV.id.key, dbKV.id.value, batch)
 	if err != nil {
 		return err
 	}
@@ -330,15 +456,15 @@ error 				-->		error
 */
 
 func (ldb *LDataBase) Remove(in interface{}) error {
-	err := ldb.remove(in)
+	batch := newDbBatch(ldb.db)
+	err := ldb.remove(batch, in)
 	if err != nil {
 		return err
 	}
-	ldb.undoRemove(in)
-	return nil
+	return ldb.commitMutation(batch, undoOpRemove, in)
 }
 
-func (ldb *LDataBase) remove(in interface{}) error {
+func (ldb *LDataBase) remove(batch kvReadWriter, in interface{}) error {
 
 	cfg, err := parseObjectToCfg(in)
 	if err != nil {
@@ -353,7 +479,7 @@ func (ldb *LDataBase) remove(in interface{}) error {
 
 	//dbKV.showDbKV()
 
-	err = ldb.removeKvToDb(dbKV)
+	err = removeKvToDb(dbKV, batch)
 	if err != nil {
 		return err
 	}
@@ -361,35 +487,20 @@ func (ldb *LDataBase) remove(in interface{}) error {
 	return nil
 }
 
-func (ldb *LDataBase) removeKvToDb(dbKV *dbKeyValue) error {
-	undo := false
-	undoKeyValue := &dbKeyValue{}
-
-	defer func() {
-		if !undo {
-			return
-		}
-		/* 		assert(undo == true) */
-		/* 		remove undo 			*/
-		ldb.undoRemoveKV(undoKeyValue)
-	}()
+func removeKvToDb(dbKV *dbKeyValue, batch kvReadWriter) error {
 	for _, v := range dbKV.index {
-		err := removeKey(v.key, ldb.db)
+		err := removeKey(v.key, batch)
 		if err != nil {
 			fmt.Println("delete key error ", v.key)
-			undo = true
 			return err
 		}
-		undoKeyValue.index = append(undoKeyValue.index, v)
 	}
 
-	err := removeKey(dbKV.id.key, ldb.db)
+	err := removeKey(dbKV.id.key, batch)
 	if err != nil {
 		fmt.Println("delete key error ", dbKV.id.key)
-		undo = true
 		return err
 	}
-	// assert(undo == false)
 	return nil
 }
 
@@ -407,15 +518,15 @@ error 				-->		error
 
 func (ldb *LDataBase) Modify(old interface{}, fn interface{}) error {
 	copy_ := cloneInterface(old)
-	err := ldb.modify(old, fn)
+	batch := newDbBatch(ldb.db)
+	err := ldb.modify(batch, old, fn)
 	if err != nil {
 		return err
 	}
-	ldb.undoModify(copy_)
-	return nil
+	return ldb.commitMutation(batch, undoOpModify, copy_)
 }
 
-func (ldb *LDataBase) modify(data interface{}, fn interface{}) error {
+func (ldb *LDataBase) modify(batch kvReadWriter, data interface{}, fn interface{}) error {
 
 	dataRef := reflect.ValueOf(data)
 	if dataRef.Kind() != reflect.Ptr {
@@ -439,10 +550,10 @@ func (ldb *LDataBase) modify(data interface{}, fn interface{}) error {
 	fnRef.Call([]reflect.Value{dataRef}) /*	call fn */
 	// modify
 	oldRef := reflect.ValueOf(oldInter)
-	return ldb.modifyKvToDb(&oldRef, &dataRef)
+	return modifyKvToDb(&oldRef, &dataRef, batch)
 }
 
-func (ldb *LDataBase) modifyKvToDb(oldRef, newRef *reflect.Value) error {
+func modifyKvToDb(oldRef, newRef *reflect.Value, batch kvReadWriter) error {
 
 	oldCfg, err := extractObjectTagInfo(oldRef)
 	if err != nil {
@@ -461,12 +572,12 @@ func (ldb *LDataBase) modifyKvToDb(oldRef, newRef *reflect.Value) error {
 	structKV(newRef.Interface(), newKV, newCfg)
 	//newKV.showDbKV()
 	//oldKV.showDbKV()
-	err = ldb.removeKvToDb(oldKV)
+	err = removeKvToDb(oldKV, batch)
 	if err != nil {
 		return err
 	}
 
-	err = ldb.insertKvToDb(newKV)
+	err = insertKvToDb(newKV, batch)
 	if err != nil {
 		return err
 	}
@@ -480,7 +591,7 @@ so special processing is required when undo
 
 */
 
-func (ldb *LDataBase) undoModifyKv(old interface{}) error {
+func (ldb *LDataBase) undoModifyKv(batch kvReadWriter, old interface{}) error {
 
 	oldRef := reflect.ValueOf(old)
 	if oldRef.Kind() != reflect.Ptr {
@@ -491,13 +602,13 @@ func (ldb *LDataBase) undoModifyKv(old interface{}) error {
 	if err != nil {
 		return err
 	}
-	id, err := rlp.EncodeToBytes(oldCfg.Id.Interface())
+	id, err := oldCfg.encodeId()
 	if err != nil {
 		return err
 	}
 	typeName := []byte(oldCfg.Name)
 	key := idKey(id, typeName)
-	val, err := getDbKey(key, ldb.db)
+	val, err := getDbKey(key, batch)
 	if err != nil {
 		return err
 	}
@@ -508,7 +619,7 @@ func (ldb *LDataBase) undoModifyKv(old interface{}) error {
 		return err
 	}
 
-	return ldb.modifyKvToDb(&dst, &oldRef)
+	return modifyKvToDb(&dst, &oldRef, batch)
 }
 
 /*
@@ -517,7 +628,7 @@ The following are some specific implementations of the features that may change,
 
 */
 
-func saveKey(key, value []byte, tx *leveldb.DB) error {
+func saveKey(key, value []byte, tx kvReadWriter) error {
 	if ok, _ := tx.Has(key, nil); ok {
 		return ErrAlreadyExists
 	}
@@ -528,8 +639,8 @@ func saveKey(key, value []byte, tx *leveldb.DB) error {
 	return nil
 }
 
-func removeKey(key []byte, db *leveldb.DB) error {
-	if ok, _ := db.Has(key, nil); !ok {
+func removeKey(key []byte, db kvReadWriter) error {
+	if _, err := db.Get(key, nil); err != nil { /* the batch keeps the size of the value for the counters */
 		return ErrNotFound
 	}
 	err := db.Delete(key, nil)
@@ -555,7 +666,7 @@ func (ldb *LDataBase) Find(tagName string, in interface{}, out interface{}) erro
 	return find(tagName, in, out, ldb.db)
 }
 
-func find(tagName string, value interface{}, to interface{}, db *leveldb.DB) error {
+func find(tagName string, value interface{}, to interface{}, db kvStore) error {
 	// fieldName == tagName --> Just different nextId
 	fieldName := []byte(tagName)
 	fields, err := getFieldInfo(tagName, value)
@@ -588,7 +699,7 @@ func find(tagName string, value interface{}, to interface{}, db *leveldb.DB) err
 	return nil
 }
 
-func findNonUniqueFields(key, typeName []byte, to interface{}, db *leveldb.DB) error {
+func findNonUniqueFields(key, typeName []byte, to interface{}, db kvStore) error {
 	end := make([]byte, len(key))
 	copy(end, key)
 	end[len(end)-1] = end[len(end)-1] + 1
@@ -599,7 +710,7 @@ func findNonUniqueFields(key, typeName []byte, to interface{}, db *leveldb.DB) e
 	return findDbObject(it.Value(), []byte(typeName), to, db)
 }
 
-func findUniqueFields(key, typeName []byte, to interface{}, db *leveldb.DB) error {
+func findUniqueFields(key, typeName []byte, to interface{}, db kvStore) error {
 	v, err := getDbKey(key, db)
 	if err != nil {
 		return err
@@ -608,7 +719,7 @@ func findUniqueFields(key, typeName []byte, to interface{}, db *leveldb.DB) erro
 }
 
 // only key is id can be called
-func findDbObject(key, typeName []byte, to interface{}, db *leveldb.DB) error {
+func findDbObject(key, typeName []byte, to interface{}, db kvStore) error {
 
 	id := idKey(key, typeName)
 	val, err := getDbKey(id, db)
@@ -636,7 +747,7 @@ func (ldb *LDataBase) GetIndex(tagName string, in interface{}) (*multiIndex, err
 	return getIndex(tagName, in, ldb)
 }
 
-func getIndex(tagName string, value interface{}, db DataBase) (*multiIndex, error) {
+func getIndex(tagName string, value interface{}, db indexReader) (*multiIndex, error) {
 
 	// fieldName == tagName --> Just different nextId
 	fieldName := []byte(tagName)
@@ -670,7 +781,7 @@ from the db and returned to the caller
 which may not exist
 
 */
-func getDbKey(key []byte, db *leveldb.DB) ([]byte, error) {
+func getDbKey(key []byte, db kvReadWriter) ([]byte, error) {
 	exits, err := db.Has(key, nil)
 	if err != nil {
 		return nil, err
@@ -718,6 +829,11 @@ func (ldb *LDataBase) lowerBound(begin, end, fieldName []byte, data interface{},
 	return nil, ErrNotFound
 }
 
+func (ldb *LDataBase) newIterator(typeName, begin, end []byte, greater bool) *DbIterator {
+	it := ldb.db.NewIterator(&util.Range{Start: begin, Limit: end}, nil)
+	return &DbIterator{typeName: typeName, it: it, db: ldb.db, greater: greater}
+}
+
 func (ldb *LDataBase) Empty(begin, end, fieldName []byte) bool {
 
 	it := ldb.db.NewIterator(&util.Range{Start: begin, Limit: end}, nil)
@@ -757,50 +873,6 @@ func (ldb *LDataBase) enable() bool { /* Whether the database enables the undo f
 	return ldb.stack.Size() != 0
 }
 
-/* The following three functions are the undo functions provided by the database.*/
-
-func (ldb *LDataBase) undoInsert(in interface{}) {
-	if !ldb.enable() {
-		return
-	}
-
-	stack := ldb.getStack()
-	if stack == nil {
-		log.Println("undo session empty")
-		return
-	}
-	copy_ := cloneInterface(in)
-	stack.undoInsert(copy_)
-}
-
-func (ldb *LDataBase) undoModify(in interface{}) {
-	if !ldb.enable() {
-		return
-	}
-
-	stack := ldb.getStack()
-	if stack == nil {
-		log.Println("undo session empty")
-		return
-	}
-	copy_ := cloneInterface(in)
-	stack.undoModify(copy_)
-}
-
-func (ldb *LDataBase) undoRemove(in interface{}) {
-	if !ldb.enable() {
-		return
-	}
-
-	stack := ldb.getStack()
-	if stack == nil {
-		log.Println("undo session empty")
-		return
-	}
-	copy_ := cloneInterface(in)
-	stack.undoRemove(copy_)
-}
-
 /*
 
 The three functions here are the implementation of
@@ -847,58 +919,6 @@ func (ldb *LDataBase) getStack() *undoState {
 	return nil
 }
 
-/*
-
-When deleting or inserting an object
-if an error occurs before completion
-the following two functions are called to restore the database state
-The internal undo operation of the database is not used externally
-
-*/
-
-func (ldb *LDataBase) undoInsertKV(undoKeyValue *dbKeyValue) { /*	insert kv to db error --> undo kv */
-
-	if len(undoKeyValue.index) == 0{
-		return
-	}
-
-	for _, v := range undoKeyValue.index { /* 	undo index*/
-		err := removeKey(v.key, ldb.db)
-		if err != nil {
-			panic(err)
-		}
-	}
-	if len(undoKeyValue.id.key) == 0{
-		return
-	}
-	err := removeKey(undoKeyValue.id.key, ldb.db) /* 	undo id*/
-	if err != nil {
-		panic(err)
-	}
-}
-
-func (ldb *LDataBase) undoRemoveKV(undoKeyValue *dbKeyValue) { /*	remove kv from db error --> undo kv */
-
-	undoKeyValue.showDbKV()
-	if len(undoKeyValue.index) == 0 {
-		return
-	}
-	for _, v := range undoKeyValue.index { /* 	undo index*/
-		err := saveKey(v.key, v.value, ldb.db)
-		if err != nil {
-			ldb.PanicDb(err.Error())
-		}
-	}
-	if len(undoKeyValue.id.key) == 0 {
-		return
-	}
-
-	err := saveKey(undoKeyValue.id.key, undoKeyValue.id.value, ldb.db) /* undo id*/
-	if err != nil {
-		panic(err)
-	}
-}
-
 /*		database log	 */
 
 func (ldb *LDataBase) PanicDb(message string) {
@@ -1,12 +1,15 @@
 package database
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"github.com/eosspark/eos-go/crypto/rlp"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -147,7 +150,7 @@ func Test_modifyUndo(t *testing.T) {
 	}
 	it.Release()
 
-	session := db.StartSession()
+	session := startSession(db)
 	defer session.Undo()
 	obj := DbTableIdObject{ID: 4, Code: 21, Scope: 22, Table: 26, Payer: 27, Count: 25}
 	newobj := DbTableIdObject{ID: 4, Code: 200, Scope: 22, Table: 26, Payer: 27, Count: 25}
@@ -172,6 +175,59 @@ func Test_modifyUndo(t *testing.T) {
 	}
 }
 
+func Test_undoRepeatedChanges(t *testing.T) {
+	db, clo := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer clo()
+	objs, houses := Objects()
+	objs, _ = saveObjs(objs, houses, db)
+
+	// the undo state keeps the first value of an object changed more than once by a session
+	for i := 0; i < 10; i++ {
+		session := startSession(db)
+		modified, removed := objs[0], objs[1]
+		for _, code := range []AccountName{200, 300} {
+			code := code
+			if err := db.Modify(&modified, func(object *DbTableIdObject) { object.Code = code }); err != nil {
+				log.Fatalln(err)
+			}
+		}
+		if err := db.Modify(&removed, func(object *DbTableIdObject) { object.Count = 1000 }); err != nil {
+			log.Fatalln(err)
+		}
+		if err := db.Remove(&removed); err != nil {
+			log.Fatalln(err)
+		}
+		inserted := DbTableIdObject{Code: 500, Scope: 501, Table: 502, Payer: 503, Count: 504}
+		if err := db.Insert(&inserted); err != nil {
+			log.Fatalln(err)
+		}
+		if err := db.Modify(&inserted, func(object *DbTableIdObject) { object.Count = 505 }); err != nil {
+			log.Fatalln(err)
+		}
+		if err := db.Modify(&inserted, func(object *DbTableIdObject) { object.Count = 506 }); err != nil {
+			log.Fatalln(err)
+		}
+		if err := session.Undo(); err != nil {
+			log.Fatalln(err)
+		}
+
+		for _, obj := range objs {
+			tmp := DbTableIdObject{}
+			if err := db.Find("id", DbTableIdObject{ID: obj.ID}, &tmp); err != nil || tmp != obj {
+				logObj(tmp)
+				log.Fatalln("undo must restore the object as it was before the session")
+			}
+		}
+		tmp := DbTableIdObject{}
+		if db.Find("id", DbTableIdObject{ID: inserted.ID}, &tmp) == nil {
+			log.Fatalln("undo must remove the object inserted by the session")
+		}
+	}
+}
+
 func Test_undoInsert(t *testing.T) {
 	db, clo := openDb()
 	if db == nil {
@@ -181,7 +237,7 @@ func Test_undoInsert(t *testing.T) {
 
 	//////////////////////////////////////////////		Insert UNDO		///////////////////////////////////
 	db.SetRevision(10)
-	session := db.StartSession()
+	session := startSession(db)
 	objs, _ := Objects()
 	for i := 0; i < 3; i++ {
 		err := db.Insert(&objs[i])
@@ -203,7 +259,7 @@ func Test_undoInsert(t *testing.T) {
 
 	//////////////////////////////////////////////		COMMIT		///////////////////////////////////
 
-	session = db.StartSession()
+	session = startSession(db)
 	for i := 0; i < 3; i++ {
 		err := db.Insert(&objs[i])
 		if err != nil {
@@ -271,7 +327,7 @@ func Test_undoRemove(t *testing.T) {
 		}
 		i++
 	}
-	session := db.StartSession()
+	session := startSession(db)
 
 	err = db.Remove(&table)
 	if err != nil {
@@ -436,6 +492,862 @@ func Test_resourceLimitsObject(t *testing.T) {
 	}
 }
 
+func Test_atomicInsert(t *testing.T) {
+	db, clo := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer clo()
+
+	house := DbHouse{Area: 100, Name: "first", Carnivore: Carnivore{Lion: 5, Tiger: 5}}
+	if err := db.Insert(&house); err != nil {
+		log.Fatalln(err)
+	}
+
+	db.SetRevision(1)
+	session := startSession(db)
+	other := DbHouse{Area: 200, Name: "other", Carnivore: Carnivore{Lion: 6, Tiger: 6}}
+	if err := db.Insert(&other); err != nil {
+		log.Fatalln(err)
+	}
+	same := DbHouse{Area: 100, Name: "same area", Carnivore: Carnivore{Lion: 7, Tiger: 7}}
+	if err := db.Insert(&same); err != ErrAlreadyExists {
+		log.Fatalln("insert with an existing unique index must fail", err)
+	}
+
+	tmp := DbHouse{}
+	if err := db.Find("Lion", DbHouse{Carnivore: Carnivore{Lion: 7}}, &tmp); err != ErrNotFound {
+		log.Fatalln("a failed insert must not leave any index behind", err)
+	}
+
+	session.Undo()
+	if err := db.Find("Lion", DbHouse{Carnivore: Carnivore{Lion: 6}}, &tmp); err != ErrNotFound {
+		log.Fatalln("undo must remove every index of the session", err)
+	}
+	if err := db.Find("Area", DbHouse{Area: 100}, &tmp); err != nil || tmp != house {
+		log.Fatalln("undo must keep the objects outside of the session", err)
+	}
+}
+
+func Test_persistentUndo(t *testing.T) {
+	fileName := "./persistent"
+	reFn := func() {
+		errs := os.RemoveAll(fileName)
+		if errs != nil {
+			log.Fatalln(errs)
+		}
+	}
+	reFn()
+	defer reFn()
+
+	db, err := NewDataBase(fileName, false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	objs, _ := Objects()
+	kept, added := objs[0], objs[1]
+	if err = db.Insert(&kept); err != nil {
+		log.Fatalln(err)
+	}
+
+	db.SetRevision(5)
+	startSession(db)
+	if err = db.Insert(&added); err != nil {
+		log.Fatalln(err)
+	}
+	if err = db.Modify(&kept, func(obj *DbTableIdObject) { obj.Count = 99 }); err != nil {
+		log.Fatalln(err)
+	}
+	db.Close()
+
+	db, err = NewDataBase(fileName, false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if db.Revision() != 6 {
+		log.Fatalln("revision is not restored", db.Revision())
+	}
+	if err = db.Undo(); err != nil {
+		log.Fatalln(err)
+	}
+	if db.Revision() != 5 {
+		log.Fatalln("undo after restart must go back to revision 5", db.Revision())
+	}
+
+	tmp, expected := DbTableIdObject{}, objs[0]
+	expected.ID = kept.ID
+	if err = db.Find("id", DbTableIdObject{ID: kept.ID}, &tmp); err != nil || tmp != expected {
+		logObj(tmp)
+		log.Fatalln("modify is not undone after restart", err)
+	}
+	if err = db.Find("id", DbTableIdObject{ID: added.ID}, &tmp); err != ErrNotFound {
+		log.Fatalln("insert is not undone after restart", err)
+	}
+	db.Close()
+
+	db, err = NewDataBase(fileName, false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+	if db.Revision() != 5 {
+		log.Fatalln("revision after undo is not restored", db.Revision())
+	}
+	again := objs[1]
+	if err = db.Insert(&again); err != nil || again.ID != added.ID {
+		log.Fatalln("ids are not restored by undo", err, again.ID)
+	}
+}
+
+func Test_persistentSquash(t *testing.T) {
+	fileName := "./persistent"
+	reFn := func() {
+		errs := os.RemoveAll(fileName)
+		if errs != nil {
+			log.Fatalln(errs)
+		}
+	}
+	reFn()
+	defer reFn()
+
+	undoKeys := func(db DataBase, revision int64) int {
+		it := db.(*LDataBase).db.NewIterator(util.BytesPrefix(undoStateKey(revision)), nil)
+		defer it.Release()
+		count := 0
+		for it.Next() {
+			count++
+		}
+		return count
+	}
+
+	db, err := NewDataBase(fileName, false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	objs, _ := Objects()
+	first, second := objs[0], objs[1]
+	if err = db.SetRevision(5); err != nil {
+		log.Fatalln(err)
+	}
+	startSession(db)
+	if err = db.Insert(&first); err != nil {
+		log.Fatalln(err)
+	}
+	session := startSession(db)
+	if err = db.Modify(&first, func(obj *DbTableIdObject) { obj.Count = 99 }); err != nil {
+		log.Fatalln(err)
+	}
+	if err = db.Insert(&second); err != nil {
+		log.Fatalln(err)
+	}
+	// each mutation adds one entry after the header of its session
+	if undoKeys(db, 6) != 2 || undoKeys(db, 7) != 3 {
+		log.Fatalln("undo entries are not written per mutation", undoKeys(db, 6), undoKeys(db, 7))
+	}
+	if err = session.Squash(); err != nil {
+		log.Fatalln(err)
+	}
+	if undoKeys(db, 6) != 4 || undoKeys(db, 7) != 0 {
+		log.Fatalln("squash does not move the undo entries", undoKeys(db, 6), undoKeys(db, 7))
+	}
+	db.Close()
+
+	db, err = NewDataBase(fileName, false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+	if db.Revision() != 6 {
+		log.Fatalln("revision is not restored", db.Revision())
+	}
+	if err = db.Undo(); err != nil {
+		log.Fatalln(err)
+	}
+	tmp := DbTableIdObject{}
+	if err = db.Find("id", DbTableIdObject{ID: first.ID}, &tmp); err != ErrNotFound {
+		log.Fatalln("squashed session is not undone after restart", err)
+	}
+	if err = db.Find("id", DbTableIdObject{ID: second.ID}, &tmp); err != ErrNotFound {
+		log.Fatalln("squashed session is not undone after restart", err)
+	}
+	if db.Revision() != 5 || undoKeys(db, 6) != 0 {
+		log.Fatalln("undo does not drop the undo state", db.Revision(), undoKeys(db, 6))
+	}
+}
+
+func Test_iteratorPositions(t *testing.T) {
+	db, clo := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer clo()
+	objs, houses := Objects()
+	objs, houses = saveObjs(objs, houses, db)
+
+	idx, err := db.GetIndex("Code", DbTableIdObject{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	it := idx.BeginIterator()
+	tmp := DbTableIdObject{}
+	if !it.Next() || it.Data(&tmp) != nil || tmp != objs[0] {
+		log.Fatalln("begin iterator must start at the first object")
+	}
+	if !it.Last() || it.Data(&tmp) != nil || tmp != objs[len(objs)-1] {
+		log.Fatalln("last must move to the last object")
+	}
+	if !it.First() || !it.Next() || it.Data(&tmp) != nil || tmp != objs[1] {
+		log.Fatalln("next after first must move to the second object")
+	}
+	it.Release()
+
+	it = idx.End()
+	if !idx.CompareEnd(it) {
+		log.Fatalln("end must be past the last object")
+	}
+	for i := len(objs) - 1; i >= 0; i-- {
+		if !it.Prev() || it.Data(&tmp) != nil || tmp != objs[i] {
+			logObj(tmp)
+			log.Fatalln("reverse iteration from end failed at", i)
+		}
+	}
+	if it.Prev() {
+		log.Fatalln("reverse iteration must stop before the first object")
+	}
+	it.Release()
+
+	it = idx.IteratorTo(objs[4])
+	if !it.Next() || it.Data(&tmp) != nil || tmp != objs[4] {
+		log.Fatalln("iterator to must move to the object")
+	}
+	it.Release()
+	it = idx.IteratorTo(DbTableIdObject{ID: 100, Code: objs[4].Code})
+	if !idx.CompareEnd(it) {
+		log.Fatalln("iterator to a missing object must be end")
+	}
+	it.Release()
+
+	areaIdx, err := db.GetIndex("Area", DbHouse{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	house := DbHouse{}
+	it = areaIdx.BeginIterator()
+	if !it.Next() || it.Data(&house) != nil || house != houses[len(houses)-1] {
+		log.Fatalln("greater index must start at the largest area")
+	}
+	it.Release()
+	it = areaIdx.End()
+	if !it.Prev() || it.Data(&house) != nil || house != houses[0] {
+		log.Fatalln("greater index must end at the smallest area")
+	}
+	it.Release()
+}
+
+func Test_rangeIterator(t *testing.T) {
+	db, clo := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer clo()
+	objs, houses := Objects()
+	objs, houses = saveObjs(objs, houses, db)
+
+	check := func(it Iterator, want []DbTableIdObject, reverse bool) {
+		defer it.Release()
+		next := it.Next
+		if reverse {
+			next = it.Prev
+			if !it.Last() {
+				log.Fatalln("range must not be empty")
+			}
+		}
+		for i, obj := range want {
+			tmp := DbTableIdObject{}
+			if (i > 0 || !reverse) && !next() || it.Data(&tmp) != nil || tmp != obj {
+				logObj(tmp)
+				log.Fatalln("range iteration failed at", i)
+			}
+		}
+		if next() {
+			log.Fatalln("range must stop at its upper bound")
+		}
+	}
+
+	idx, err := db.GetIndex("Code", DbTableIdObject{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	it, err := idx.Range(DbTableIdObject{Code: objs[3].Code}, DbTableIdObject{Code: objs[6].Code})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	check(it, objs[3:6], false)
+	it, _ = idx.Range(DbTableIdObject{Code: objs[3].Code}, DbTableIdObject{Code: objs[6].Code})
+	check(it, []DbTableIdObject{objs[5], objs[4], objs[3]}, true)
+	it, _ = idx.Range(DbTableIdObject{Code: objs[6].Code}, nil)
+	check(it, objs[6:], false)
+
+	/* byTable is a composite greater index, the range runs from lower down to upper */
+	tableIdx, err := db.GetIndex("byTable", DbTableIdObject{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	it, err = tableIdx.Range(DbTableIdObject{Scope: objs[7].Scope, Table: objs[7].Table},
+		DbTableIdObject{Scope: objs[4].Scope, Table: objs[4].Table})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	check(it, []DbTableIdObject{objs[7], objs[6], objs[5]}, false)
+
+	areaIdx, err := db.GetIndex("Area", DbHouse{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	it, _ = areaIdx.Range(DbHouse{Area: houses[7].Area}, DbHouse{Area: houses[4].Area})
+	defer it.Release()
+	for i := 7; i > 4; i-- {
+		house := DbHouse{}
+		if !it.Next() || it.Data(&house) != nil || house != houses[i] {
+			log.Fatalln("greater range iteration failed at", i)
+		}
+	}
+	if it.Next() {
+		log.Fatalln("greater range must stop at its upper bound")
+	}
+}
+
+func Test_size(t *testing.T) {
+	db, _ := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer func() {
+		db.Close() // the database is opened again below
+		os.RemoveAll(openDbPath)
+	}()
+
+	counted := func() {
+		var recount uint64
+		for _, size := range countSizes(db.(*LDataBase).usage.kvStore) {
+			recount += uint64(size)
+		}
+		if recount != db.Size() {
+			log.Fatalln("size does not match the content of the database", recount, db.Size())
+		}
+		var total uint64
+		for _, size := range db.TableSizes() {
+			total += size
+		}
+		if total != db.Size() {
+			log.Fatalln("table sizes do not add up to the size", total, db.Size())
+		}
+	}
+
+	house := DbHouse{Area: 100, Name: "first", Carnivore: Carnivore{Lion: 5, Tiger: 5}}
+	if err := db.Insert(&house); err != nil {
+		log.Fatalln(err)
+	}
+	if db.TableSizes()["DbHouse"] == 0 {
+		log.Fatalln("table size of DbHouse is missing")
+	}
+	counted()
+
+	db.SetRevision(1)
+	session := startSession(db)
+	other := DbHouse{Area: 200, Name: "other", Carnivore: Carnivore{Lion: 6, Tiger: 6}}
+	if err := db.Insert(&other); err != nil {
+		log.Fatalln(err)
+	}
+	if err := db.Modify(&house, func(data *DbHouse) { data.Name = "first house" }); err != nil {
+		log.Fatalln(err)
+	}
+	counted()
+
+	session.Undo()
+	counted()
+
+	if err := db.Remove(&house); err != nil {
+		log.Fatalln(err)
+	}
+	if _, ok := db.TableSizes()["DbHouse"]; ok {
+		log.Fatalln("table size of DbHouse must be dropped with its last object")
+	}
+	counted()
+
+	// the counters are read back instead of counted again
+	size := db.Size()
+	db.Close()
+	var err error
+	if db, err = NewDataBase(openDbPath, false); err != nil {
+		log.Fatalln(err)
+	}
+	if db.Size() != size {
+		log.Fatalln("size is not read back", db.Size(), size)
+	}
+	counted()
+}
+
+func Test_view(t *testing.T) {
+	db, clo := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer clo()
+	objs, houses := Objects()
+	objs, houses = saveObjs(objs, houses, db)
+	db.SetRevision(3)
+
+	view, err := db.View()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer view.Release()
+
+	before := append([]DbTableIdObject{}, objs...)
+	session := startSession(db)
+	if err = db.Modify(&objs[0], func(data *DbTableIdObject) { data.Count = 100 }); err != nil {
+		log.Fatalln(err)
+	}
+	if err = db.Remove(&objs[1]); err != nil {
+		log.Fatalln(err)
+	}
+	session.Push()
+
+	if view.Revision() != 3 || db.Revision() != 4 {
+		log.Fatalln("view must keep the revision it was taken at", view.Revision(), db.Revision())
+	}
+
+	// the view is read while the database keeps changing
+	done := make(chan error)
+	go func() {
+		tmp := DbTableIdObject{}
+		if err := view.Find("id", DbTableIdObject{ID: objs[1].ID}, &tmp); err != nil || tmp != before[1] {
+			done <- fmt.Errorf("removed object must stay in the view: %v", err)
+			return
+		}
+		idx, err := view.GetIndex("id", DbTableIdObject{})
+		if err != nil {
+			done <- err
+			return
+		}
+		it := idx.BeginIterator()
+		defer it.Release()
+		for i := 0; i < len(before); i++ {
+			if !it.Next() || it.Data(&tmp) != nil || tmp != before[i] {
+				done <- fmt.Errorf("view iteration failed at %d", i)
+				return
+			}
+		}
+		done <- nil
+	}()
+	for i := 10; i < 20; i++ {
+		house := DbHouse{Area: uint64(1000 + i), Name: "other", Carnivore: Carnivore{Lion: i, Tiger: i}}
+		if err := db.Insert(&house); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if err = <-done; err != nil {
+		log.Fatalln(err)
+	}
+
+	tmp := DbTableIdObject{}
+	if err = db.Find("id", DbTableIdObject{ID: objs[0].ID}, &tmp); err != nil || tmp.Count != 100 {
+		log.Fatalln("database must have moved on", err)
+	}
+}
+
+func Test_check(t *testing.T) {
+	db, clo := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer clo()
+	objs, houses := Objects()
+	objs, houses = saveObjs(objs, houses, db)
+
+	// indexKey is the key obj has in the index tag
+	indexKey := func(obj interface{}, tag string) []byte {
+		cfg, err := parseObjectToCfg(obj)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		dbKV := &dbKeyValue{}
+		if err = structKV(obj, dbKV, cfg); err != nil {
+			log.Fatalln(err)
+		}
+		prefix := append(typeNameFieldName([]byte(cfg.Name), []byte(tag)), '_', '_')
+		for _, index := range dbKV.index {
+			if bytes.HasPrefix(index.key, prefix) {
+				return index.key
+			}
+		}
+		log.Fatalln("no key in index", tag)
+		return nil
+	}
+
+	for _, object := range []interface{}{DbTableIdObject{}, &DbHouse{}} {
+		report, err := db.Check(object, false)
+		if err != nil || !report.Ok() || report.Objects != len(objs) {
+			log.Fatalln("a consistent database must pass the check", err, report)
+		}
+	}
+
+	// index drift: a missing key, a key holding the id of another object and a key left behind
+	store := db.(*LDataBase).db
+	missing := indexKey(&objs[0], "Code")
+	wrong := indexKey(&objs[1], "byTable")
+	stale := houses[0]
+	stale.Area = 1000
+	orphaned := indexKey(&stale, "Area")
+	objId, _ := rlp.EncodeToBytes(objs[2].ID)
+	if store.Delete(missing, nil) != nil || store.Put(wrong, objId, nil) != nil || store.Put(orphaned, objId, nil) != nil {
+		log.Fatalln("unable to break the indexes")
+	}
+
+	expected := map[string]IndexProblem{string(missing): MissingIndexKey, string(wrong): WrongIndexValue}
+	report, err := db.Check(DbTableIdObject{}, true)
+	if err != nil || len(report.Issues) != len(expected) {
+		log.Fatalln("table objects check failed", err, report)
+	}
+	for _, issue := range report.Issues {
+		if problem, ok := expected[string(issue.Key)]; !ok || problem != issue.Problem || !issue.Repaired {
+			log.Fatalln("unexpected issue", issue)
+		}
+	}
+	report, err = db.Check(DbHouse{}, true)
+	if err != nil || len(report.Issues) != 1 || report.Issues[0].Problem != OrphanedIndexKey ||
+		report.Issues[0].Index != "Area" || !bytes.Equal(report.Issues[0].Key, orphaned) {
+		log.Fatalln("house check failed", err, report)
+	}
+
+	for _, object := range []interface{}{DbTableIdObject{}, DbHouse{}} {
+		if report, err = db.Check(object, false); err != nil || !report.Ok() {
+			log.Fatalln("a repaired database must pass the check", err, report)
+		}
+	}
+	tmp := DbTableIdObject{}
+	if err = db.Find("byTable", objs[1], &tmp); err != nil || tmp != objs[1] {
+		log.Fatalln("repaired index must find its object", err)
+	}
+}
+
+func Test_history(t *testing.T) {
+	db, clo := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer clo()
+	db.SetHistory(3)
+	db.SetRevision(10)
+
+	objs, houses := Objects()
+	session := startSession(db) // 11
+	objs, houses = saveObjs(objs, houses, db)
+	session.Push()
+	db.Commit(11)
+	before := append([]DbTableIdObject{}, objs...)
+
+	session = startSession(db) // 12
+	if err := db.Modify(&objs[0], func(data *DbTableIdObject) { data.Count = 100 }); err != nil {
+		log.Fatalln(err)
+	}
+	session.Push()
+	db.Commit(12)
+
+	session = startSession(db) // 13, still on the undo stack
+	if err := db.Remove(&objs[1]); err != nil {
+		log.Fatalln(err)
+	}
+	session.Push()
+
+	// count is the number of table objects the Code index of the view holds
+	count := func(view DataBaseView) int {
+		idx, err := view.GetIndex("Code", DbTableIdObject{})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		it := idx.BeginIterator()
+		defer it.Release()
+		n := 0
+		for it.Next() {
+			n++
+		}
+		return n
+	}
+	find := func(view DataBaseView, obj DbTableIdObject) (DbTableIdObject, error) {
+		tmp := DbTableIdObject{}
+		err := view.Find("byTable", obj, &tmp)
+		return tmp, err
+	}
+	for revision, expected := range map[int64]struct {
+		objects int
+		count   uint32
+		removed bool
+	}{
+		10: {0, 0, true},
+		11: {len(objs), before[0].Count, false},
+		12: {len(objs), 100, false},
+		13: {len(objs) - 1, 100, true},
+	} {
+		view, err := db.ViewAt(revision)
+		if err != nil {
+			log.Fatalln(revision, err)
+		}
+		if view.Revision() != revision || count(view) != expected.objects {
+			log.Fatalln("view has wrong objects at revision", revision, count(view))
+		}
+		if tmp, err := find(view, before[0]); expected.objects != 0 && (err != nil || tmp.Count != expected.count) {
+			log.Fatalln("view has wrong object at revision", revision, err)
+		}
+		if _, err = find(view, before[1]); (err != nil) != expected.removed {
+			log.Fatalln("view has wrong removed object at revision", revision, err)
+		}
+		house := DbHouse{}
+		if err = view.Find("Area", DbHouse{Area: houses[0].Area}, &house); (err != nil) != (revision == 10) {
+			log.Fatalln("view has wrong house at revision", revision, err)
+		}
+		view.Release()
+	}
+	if _, err := db.ViewAt(14); err != ErrRevisionUnavailable {
+		log.Fatalln("a future revision must not be available", err)
+	}
+	if tmp := (DbTableIdObject{}); db.Find("byTable", before[0], &tmp) != nil || tmp.Count != 100 {
+		log.Fatalln("views must leave the database as it is")
+	}
+
+	// only the last 3 revisions are kept
+	for i := 0; i < 3; i++ {
+		session = startSession(db)
+		session.Push()
+	}
+	db.Commit(db.Revision())
+	if _, err := db.ViewAt(12); err != ErrRevisionUnavailable {
+		log.Fatalln("revisions out of the history must not be available", err)
+	}
+	view, err := db.ViewAt(13)
+	if err != nil || count(view) != len(objs)-1 {
+		log.Fatalln("revision in the history must be available", err)
+	}
+	view.Release()
+}
+
+// orderedKeys are the index keys of cfg, integers big endian with the sign flipped and the rest rlp
+func orderedKeys(cfg *structInfo) map[string][]byte {
+	encode := func(v reflect.Value) []byte {
+		buf := make([]byte, 8)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int32:
+			binary.BigEndian.PutUint32(buf, uint32(v.Int())^1<<31)
+			return buf[:4]
+		case reflect.Int64:
+			binary.BigEndian.PutUint64(buf, uint64(v.Int())^1<<63)
+			return buf
+		case reflect.Uint32:
+			binary.BigEndian.PutUint32(buf, uint32(v.Uint()))
+			return buf[:4]
+		case reflect.Uint64:
+			binary.BigEndian.PutUint64(buf, v.Uint())
+			return buf
+		}
+		value, _ := rlp.EncodeToBytes(v.Interface())
+		return value
+	}
+
+	keys := make(map[string][]byte)
+	objId := encode(*cfg.Id)
+	for tag, fields := range cfg.Fields {
+		key := typeNameFieldName([]byte(cfg.Name), []byte(tag))
+		for _, v := range fields.fieldValue {
+			key = append(append(key, '_', '_'), encode(*v)...)
+		}
+		if !fields.unique && len(fields.fieldValue) == 1 {
+			key = append(key, objId...)
+		}
+		keys[string(key)] = objId
+	}
+	return keys
+}
+
+// rlpKeys are the index keys of cfg encoded through rlp only
+func rlpKeys(cfg *structInfo) map[string][]byte {
+	keys := make(map[string][]byte)
+	objId, _ := rlp.EncodeToBytes(cfg.Id.Interface())
+	for tag, fields := range cfg.Fields {
+		key := typeNameFieldName([]byte(cfg.Name), []byte(tag))
+		for _, v := range fields.fieldValue {
+			value, _ := rlp.EncodeToBytes(v.Interface())
+			key = append(append(key, '_', '_'), value...)
+		}
+		if !fields.unique && len(fields.fieldValue) == 1 {
+			key = append(key, objId...)
+		}
+		keys[string(key)] = objId
+	}
+	return keys
+}
+
+func Test_keyEncoders(t *testing.T) {
+	objs := []interface{}{
+		&DbTableIdObject{ID: 3, Code: 11, Scope: 12, Table: 13, Payer: 14, Count: 15},
+		&DbHouse{Id: 4, Area: 100, Name: "house", Carnivore: Carnivore{Lion: -5, Tiger: 6}},
+		&DbResourceLimitsObject{ID: 5, Pending: true, Owner: 21},
+		&DbEncodedHouse{Id: 6, Area: 200, Name: "encoded house"},
+	}
+	for _, obj := range objs {
+		cfg, err := parseObjectToCfg(obj)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		dbKV := &dbKeyValue{}
+		if err = structKV(obj, dbKV, cfg); err != nil {
+			log.Fatalln(err)
+		}
+
+		expected := orderedKeys(cfg)
+		if len(dbKV.index) != len(expected) {
+			log.Fatalln("index count does not match", cfg.Name)
+		}
+		for _, index := range dbKV.index {
+			objId, ok := expected[string(index.key)]
+			if !ok || !bytes.Equal(objId, index.value) {
+				log.Fatalln("key encoders must write ordered keys", cfg.Name, index.key)
+			}
+		}
+	}
+
+	cfg, _ := parseObjectToCfg(&DbEncodedHouse{})
+	if cfg.Fields["Area"].encoder == nil {
+		log.Fatalln("the key encoder of DbEncodedHouse is not used")
+	}
+}
+
+func Test_keyOrder(t *testing.T) {
+	db, clo := openDb()
+	if db == nil {
+		log.Fatalln("db open failed")
+	}
+	defer clo()
+
+	/* byte wise the little endian encoding of 256 sorts before the one of 1, -1 after every positive */
+	lions := []int{256, -1, 1, 70000, -300, 0}
+	for i, lion := range lions {
+		house := DbHouse{Area: uint64(1000 + i), Carnivore: Carnivore{Lion: lion}}
+		if err := db.Insert(&house); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	idx, err := db.GetIndex("Lion", DbHouse{})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	it := idx.BeginIterator()
+	defer it.Release()
+	want := []int{70000, 256, 1, 0, -1, -300}
+	for i, lion := range want {
+		house := DbHouse{}
+		/* rlp keeps an int in 4 bytes, the value read back is the uint32 of a negative lion */
+		if !it.Next() || it.Data(&house) != nil || int32(house.Carnivore.Lion) != int32(lion) {
+			log.Fatalln("greater index must sort by value at", i, house.Carnivore.Lion)
+		}
+	}
+}
+
+func Test_inMemory(t *testing.T) {
+	openDbPath = InMemory
+	defer func() { openDbPath = "./hello" }()
+
+	for name, test := range map[string]func(*testing.T){
+		"insert":     Test_insert,
+		"find":       Test_find,
+		"modifyUndo": Test_modifyUndo,
+		"undoInsert": Test_undoInsert,
+		"undoRemove": Test_undoRemove,
+		"undoRepeat": Test_undoRepeatedChanges,
+		"empty":      Test_empty,
+		"modify":     Test_modify,
+		"remove":     Test_remove,
+		"atomic":     Test_atomicInsert,
+		"positions":  Test_iteratorPositions,
+		"range":      Test_rangeIterator,
+		"size":       Test_size,
+		"view":       Test_view,
+		"check":      Test_check,
+		"history":    Test_history,
+	} {
+		t.Run(name, test)
+	}
+
+	_, err := os.Stat(InMemory)
+	if !os.IsNotExist(err) {
+		log.Fatalln("in memory database must not touch the disk")
+	}
+}
+
+func Test_memStoreVersions(t *testing.T) {
+	store := newMemStore()
+	defer store.Close()
+	store.Put([]byte("a"), []byte("1"), nil)
+	store.Put([]byte("b"), []byte("1"), nil)
+
+	snap, err := snapshotOf(store)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	store.Put([]byte("a"), []byte("2"), nil)
+	store.Delete([]byte("b"), nil)
+	store.Put([]byte("c"), []byte("2"), nil)
+
+	if val, err := snap.Get([]byte("a"), nil); err != nil || string(val) != "1" {
+		log.Fatalln("a snapshot must read the value it was taken at")
+	}
+	if ok, _ := snap.Has([]byte("b"), nil); !ok {
+		log.Fatalln("a snapshot must read a key deleted after it")
+	}
+	if ok, _ := snap.Has([]byte("c"), nil); ok {
+		log.Fatalln("a snapshot must not read a key written after it")
+	}
+	if val, err := store.Get([]byte("a"), nil); err != nil || string(val) != "2" {
+		log.Fatalln("the store must read the newest value")
+	}
+	if ok, _ := store.Has([]byte("b"), nil); ok {
+		log.Fatalln("the store must not read a deleted key")
+	}
+
+	keys := func(it interface {
+		Next() bool
+		Prev() bool
+		Last() bool
+		Key() []byte
+		Release()
+	}, backward bool) string {
+		defer it.Release()
+		found := ""
+		if backward {
+			for ok := it.Last(); ok; ok = it.Prev() {
+				found += string(it.Key())
+			}
+			return found
+		}
+		for it.Next() {
+			found += string(it.Key())
+		}
+		return found
+	}
+	if keys(snap.NewIterator(nil, nil), false) != "ab" || keys(snap.NewIterator(nil, nil), true) != "ba" {
+		log.Fatalln("a snapshot must iterate the keys it was taken at")
+	}
+	if keys(store.NewIterator(nil, nil), false) != "ac" || keys(store.NewIterator(nil, nil), true) != "ca" {
+		log.Fatalln("the store must iterate the newest keys")
+	}
+
+	snap.Close()
+	if store.db.Len() != 2 {
+		log.Fatalln("the versions must be dropped once no reader reads them, got", store.db.Len())
+	}
+}
+
 func Test_Increment(t *testing.T) {
 
 	
@@ -487,9 +1399,12 @@ func Test_Increment(t *testing.T) {
 	}
 }
 
+// openDbPath is the database openDb creates, Test_inMemory switches it to InMemory
+var openDbPath = "./hello"
+
 func openDb() (DataBase, func()) {
 
-	fileName := "./hello"
+	fileName := openDbPath
 	reFn := func() {
 		errs := os.RemoveAll(fileName)
 		if errs != nil {
@@ -513,6 +1428,14 @@ func openDb() (DataBase, func()) {
 	}
 }
 
+func startSession(db DataBase) *Session {
+	session, err := db.StartSession()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return session
+}
+
 func Objects() ([]DbTableIdObject, []DbHouse) {
 	objs := []DbTableIdObject{}
 	DbHouses := []DbHouse{}
@@ -814,3 +1737,61 @@ func MakeResourceLimitsObjects() []DbResourceLimitsObject {
 	}
 	return limits
 }
+
+// benchmarks of the keys of an insert, reflection parses the tags and encodes every field through
+// rlp each time as the database did before the layouts were kept
+
+func benchmarkHouse(i int) *DbHouse {
+	return &DbHouse{Id: uint64(i), Area: uint64(i), Name: "house", Carnivore: Carnivore{Lion: i, Tiger: i}}
+}
+
+func Benchmark_keysReflection(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		obj := benchmarkHouse(i)
+		ref := reflect.ValueOf(obj).Elem()
+		layout := &typeLayout{name: ref.Type().Name(), indexes: make(map[string]*indexLayout)}
+		if err := extractTypeLayout(ref.Type(), nil, layout); err != nil {
+			b.Fatal(err)
+		}
+		rlpKeys(layout.bind(&ref))
+	}
+}
+
+func Benchmark_keysLayout(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		obj := benchmarkHouse(i)
+		cfg, err := parseObjectToCfg(obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+		objId, _ := cfg.encodeId()
+		cfgToKV(objId, cfg, &dbKeyValue{})
+	}
+}
+
+func Benchmark_keysEncoder(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		obj := &DbEncodedHouse{Id: uint64(i), Area: uint64(i), Name: "house"}
+		cfg, err := parseObjectToCfg(obj)
+		if err != nil {
+			b.Fatal(err)
+		}
+		objId, _ := cfg.encodeId()
+		cfgToKV(objId, cfg, &dbKeyValue{})
+	}
+}
+
+func Benchmark_insert(b *testing.B) {
+	db, err := NewDataBase(InMemory)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = db.Insert(benchmarkHouse(i + 1)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -50,11 +50,29 @@ func (s *deque) PopFront() interface{} {
 }
 
 func (s *deque) LastSecond() interface{} {
-	last := s.PopFront()
-	second := s.PopFront()
-	s.Append(second)
-	s.Append(last)
-	return second
+	s.RLock()
+	defer s.RUnlock()
+
+	last := s.container.Back()
+	if last == nil || last.Prev() == nil {
+		return nil
+	}
+	return last.Prev().Value
+}
+
+// At is the i-th item from the front
+func (s *deque) At(i int) interface{} {
+	s.RLock()
+	defer s.RUnlock()
+
+	item := s.container.Front()
+	for ; item != nil && i > 0; i-- {
+		item = item.Next()
+	}
+	if item == nil {
+		return nil
+	}
+	return item.Value
 }
 
 func (s *deque) Pop() interface{} {
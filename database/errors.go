@@ -31,4 +31,8 @@ var (
 	ErrStructNeeded = errors.New("database : provided target must be a struct to a valid variable")
 
 	ErrNotFound = errors.New("database not found")
+
+	ErrReadOnly = errors.New("database : read only view can not be modified")
+
+	ErrRevisionUnavailable = errors.New("database : revision is not kept in the history")
 )
@@ -17,6 +17,10 @@ const (
 	tagGreater     = "greater"
 	tagInline      = "inline"
 	dbIncrement    = "db_increment"
+	dbRevision     = "db_revision"
+	dbUndoState    = "db_undo__"
+	dbHistoryState = "db_history__"
+	dbSize         = "db_size"
 )
 
 /*
@@ -35,6 +39,8 @@ type fieldInfo struct {
 	typeName   string
 	fieldName  []string
 	fieldValue []*reflect.Value
+	keys       []*keyField
+	encoder    func() KeyEncoder
 }
 
 /*
@@ -109,7 +115,11 @@ func parseObjectToCfg(in interface{}) (*structInfo, error) {
 	return cfg, nil
 }
 
-func extractObjectTagInfo(s *reflect.Value, mi ...*structInfo) (*structInfo, error) {
+/*
+extractObjectTagInfo binds the fields of the object to the index layout of its type,
+the tags of a type are parsed once by layoutOf
+*/
+func extractObjectTagInfo(s *reflect.Value) (*structInfo, error) {
 
 	if s.Kind() == reflect.Ptr {
 		e := s.Elem()
@@ -119,37 +129,37 @@ func extractObjectTagInfo(s *reflect.Value, mi ...*structInfo) (*structInfo, err
 		return nil, ErrBadType
 	}
 
-	typ := s.Type()
-	var m *structInfo
-	if len(mi) > 0 {
-		m = mi[0]
-	} else {
-		m = &structInfo{}
-		m.Fields = make(map[string]*fieldInfo)
+	layout, err := layoutOf(s.Type())
+	if err != nil {
+		return nil, err
 	}
+	return layout.bind(s), nil
+}
+
+func extractTypeLayout(typ reflect.Type, index []int, m *typeLayout) error {
 
-	if m.Name == "" {
-		m.Name = typ.Name()
+	if typ.Kind() != reflect.Struct {
+		return ErrBadType
 	}
 
-	numFields := s.NumField()
+	numFields := typ.NumField()
 	for i := 0; i < numFields; i++ {
 		field := typ.Field(i)
-		value := s.Field(i)
 
 		if field.PkgPath != "" {
 			continue
 		}
 
-		err := extractObjectFieldTag(&value, &field, m)
+		key := &keyField{name: field.Name, index: append(cloneIndex(index), i), encode: kindEncoderOf(field.Type)}
+		err := extractObjectFieldTag(key, &field, m)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
-	return m, nil
+	return nil
 }
 
-func extractObjectFieldTag(value *reflect.Value, field *reflect.StructField, m *structInfo) error {
+func extractObjectFieldTag(key *keyField, field *reflect.StructField, m *typeLayout) error {
 
 	tag := field.Tag.Get(tagPrefix)
 	if tag == "" {
@@ -159,7 +169,7 @@ func extractObjectFieldTag(value *reflect.Value, field *reflect.StructField, m *
 	tags := strings.Split(tag, ":")
 
 	for _, tag := range tags {
-		err := splitSubTag(field.Name, value, tag, m)
+		err := splitSubTag(field.Name, key, field.Type, tag, m)
 		if err != nil {
 			return err
 		}
@@ -167,30 +177,26 @@ func extractObjectFieldTag(value *reflect.Value, field *reflect.StructField, m *
 	return nil
 }
 
-func splitSubTag(fieldName string, fieldValue *reflect.Value, tag string, m *structInfo) error {
+func splitSubTag(fieldName string, key *keyField, fieldType reflect.Type, tag string, m *typeLayout) error {
 
 	tags := strings.Split(tag, ",")
 	tagPre := tags[0]
 	if tagPre == tagID {
 
-		return extractIdTag(tags, fieldValue, m)
+		return extractIdTag(tags, key, m)
 
 	} else if tagPre == tagUniqueIdx || tagPre == tagNoUniqueIdx {
 
-		return extractUniqueOrNoUniqueTag(tagPre, fieldName, tags, fieldValue, m)
+		return extractUniqueOrNoUniqueTag(tagPre, fieldName, tags, key, m)
 
 	} else if tagPre == tagInline {
 
-		_, err := extractObjectTagInfo(fieldValue, m)
-		if err != nil {
-			return err
-		}
-		return nil
+		return extractTypeLayout(fieldType, key.index, m)
 	}
-	return extractOtherTag(tagPre, fieldName, tags, fieldValue, m)
+	return extractOtherTag(tagPre, fieldName, tags, key, m)
 }
 
-func extractIdTag(tags []string, fieldValue *reflect.Value, m *structInfo) error {
+func extractIdTag(tags []string, key *keyField, m *typeLayout) error {
 	for _, subTag := range tags {
 		//fmt.Println(subTag)
 		if subTag == tagGreater || subTag == tagLess {
@@ -199,18 +205,18 @@ func extractIdTag(tags []string, fieldValue *reflect.Value, m *structInfo) error
 		if subTag == tagIncrement {
 			continue
 		}
-		f := fieldInfo{}
+		f := indexLayout{}
 		f.unique = true
-		m.Id = fieldValue
-		addFieldInfo(subTag, tagID, fieldValue, &f, m)
+		m.id = key
+		addFieldInfo(subTag, tagID, key, &f, m)
 
 	}
 	return nil
 }
 
-func extractUniqueOrNoUniqueTag(tagPre, fieldName string, tags []string, fieldValue *reflect.Value, m *structInfo) error {
+func extractUniqueOrNoUniqueTag(tagPre, fieldName string, tags []string, key *keyField, m *typeLayout) error {
 
-	f := fieldInfo{}
+	f := indexLayout{}
 	if tagPre == tagUniqueIdx {
 		f.unique = true
 	}
@@ -225,19 +231,18 @@ func extractUniqueOrNoUniqueTag(tagPre, fieldName string, tags []string, fieldVa
 			f.greater = true
 		}
 	}
-	addFieldInfo(subTag, fieldName, fieldValue, &f, m)
+	addFieldInfo(subTag, fieldName, key, &f, m)
 	return nil
 }
 
-func extractOtherTag(tagPre, fieldName string, tags []string, fieldValue *reflect.Value, m *structInfo) error {
+func extractOtherTag(tagPre, fieldName string, tags []string, key *keyField, m *typeLayout) error {
 
 	tagLen := len(tags)
 	if tagLen < 2 {
 		return ErrTagInvalid
 	}
 
-	f := fieldInfo{}
-	f.typeName = m.Name
+	f := indexLayout{}
 
 	if tagLen > 2 {
 		sor := tags[2]
@@ -256,19 +261,18 @@ func extractOtherTag(tagPre, fieldName string, tags []string, fieldValue *reflec
 	if tagIdx == tagUniqueIdx {
 		f.unique = true
 	}
-	addFieldInfo(tagPre, fieldName, fieldValue, &f, m)
+	addFieldInfo(tagPre, fieldName, key, &f, m)
 	return nil
 }
 
-func addFieldInfo(tag, fieldName string, fieldValue *reflect.Value, f *fieldInfo, m *structInfo) {
-	if v, ok := m.Fields[tag]; ok {
+func addFieldInfo(tag, fieldName string, key *keyField, f *indexLayout, m *typeLayout) {
+	if v, ok := m.indexes[tag]; ok {
 		v.fieldName = append(v.fieldName, fieldName)
-		v.fieldValue = append(v.fieldValue, fieldValue)
+		v.keys = append(v.keys, key)
 	} else {
-		f.typeName = m.Name
 		f.fieldName = append(f.fieldName, fieldName)
-		f.fieldValue = append(f.fieldValue, fieldValue)
-		m.Fields[tag] = f
+		f.keys = append(f.keys, key)
+		m.indexes[tag] = f
 	}
 
 }
@@ -324,7 +328,7 @@ func structKV(in interface{}, dbKV *dbKeyValue, cfg *structInfo) error {
 	if err != nil {
 		return err
 	}
-	objId, err := rlp.EncodeToBytes(cfg.Id.Interface())
+	objId, err := cfg.encodeId()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/comparer"
+	ldbiter "github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/memdb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+/*
+SetHistory keeps the undo states of the last revisions once they are committed, ViewAt rebuilds
+any of those revisions from them. 0, the default, drops the states at commit like before.
+The history is not part of the undo stack, Undo never reaches it
+*/
+func (ldb *LDataBase) SetHistory(revisions int64) {
+	if revisions < 0 {
+		revisions = 0
+	}
+	ldb.history = revisions
+}
+
+func historyStateKey(revision int64) []byte {
+	key := make([]byte, len(dbHistoryState)+8)
+	copy(key, dbHistoryState)
+	binary.BigEndian.PutUint64(key[len(dbHistoryState):], uint64(revision))
+	return key
+}
+
+func (ldb *LDataBase) putHistoryState(batch *dbBatch, state *undoState) error {
+	val, err := encodeUndoState(state)
+	if err != nil {
+		return err
+	}
+	batch.putNew(historyStateKey(state.reversion), val)
+	return nil
+}
+
+// pruneHistory drops the states of the history up to revision
+func (ldb *LDataBase) pruneHistory(batch *dbBatch, revision int64) {
+	if revision < 0 {
+		return
+	}
+	limit := historyStateKey(revision)
+	if revision < math.MaxInt64 {
+		limit = historyStateKey(revision + 1)
+	}
+	it := ldb.db.NewIterator(&util.Range{Start: []byte(dbHistoryState), Limit: limit}, nil)
+	defer it.Release()
+	for it.Next() {
+		batch.deleteStored(cloneByte(it.Key()), it.Value())
+	}
+}
+
+/*
+ViewAt takes a read only view of the database as it was at a past revision, the undo states of the
+revisions that followed are taken back in the view, the database is left as it is.
+The revision has to be between the oldest state of the history and the current revision
+
+@param revision 	-->		revision of the view
+
+@return
+success 			-->		view, Release it once done
+error 				-->		ErrRevisionUnavailable when the history does not go that far
+*/
+func (ldb *LDataBase) ViewAt(revision int64) (DataBaseView, error) {
+	view, err := ldb.View()
+	if err != nil {
+		return nil, err
+	}
+	v := view.(*dbView)
+	head := v.ldb.revision
+	if revision == head {
+		return view, nil
+	}
+	if revision > head || revision < 0 {
+		view.Release()
+		return nil, ErrRevisionUnavailable
+	}
+
+	states, err := statesAfter(v.ldb.db, revision, head)
+	if err != nil {
+		view.Release()
+		return nil, err
+	}
+
+	overlay := newOverlayStore(v.ldb.db)
+	v.ldb.db = overlay
+	for _, state := range states {
+		if err = v.ldb.undoObjects(overlay, state); err != nil {
+			view.Release()
+			return nil, err
+		}
+	}
+	v.ldb.revision = revision
+	return view, nil
+}
+
+// statesAfter reads the undo states from head down to the one after revision, in the stack or in the history
+func statesAfter(store kvStore, revision, head int64) ([]*undoState, error) {
+	stacked, err := readUndoStates(store, revision+1, head+1)
+	if err != nil {
+		return nil, err
+	}
+	onStack := make(map[int64]*undoState, len(stacked))
+	for _, state := range stacked {
+		onStack[state.reversion] = state
+	}
+
+	states := make([]*undoState, 0, head-revision)
+	for r := head; r > revision; r-- {
+		if state, ok := onStack[r]; ok {
+			states = append(states, state)
+			continue
+		}
+		val, err := store.Get(historyStateKey(r), nil)
+		if err == leveldb.ErrNotFound {
+			return nil, ErrRevisionUnavailable
+		}
+		if err != nil {
+			return nil, err
+		}
+		state, err := decodeUndoState(val)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+/*
+overlayStore holds the writes made over a read only store in memory, the store under it is
+not touched. Its iterators merge the keys written over the store with those it still shows
+*/
+type overlayStore struct {
+	base    kvStore
+	writes  *memdb.DB
+	changed map[string]bool // keys put or deleted over base
+}
+
+func newOverlayStore(base kvStore) *overlayStore {
+	return &overlayStore{base: base, writes: memdb.New(comparer.DefaultComparer, 0), changed: make(map[string]bool)}
+}
+
+func (o *overlayStore) Has(key []byte, ro *opt.ReadOptions) (bool, error) {
+	if o.changed[string(key)] {
+		return o.writes.Contains(key), nil
+	}
+	return o.base.Has(key, ro)
+}
+
+func (o *overlayStore) Get(key []byte, ro *opt.ReadOptions) ([]byte, error) {
+	if o.changed[string(key)] {
+		return o.writes.Get(key)
+	}
+	return o.base.Get(key, ro)
+}
+
+func (o *overlayStore) Put(key, value []byte, wo *opt.WriteOptions) error {
+	o.changed[string(key)] = true
+	return o.writes.Put(key, value)
+}
+
+func (o *overlayStore) Delete(key []byte, wo *opt.WriteOptions) error {
+	o.changed[string(key)] = true
+	o.writes.Delete(key) // the key may only be in base
+	return nil
+}
+
+func (o *overlayStore) Write(batch *leveldb.Batch, wo *opt.WriteOptions) error {
+	return batch.Replay(overlayReplay{o})
+}
+
+func (o *overlayStore) NewIterator(slice *util.Range, ro *opt.ReadOptions) ldbiter.Iterator {
+	base := &hidingIterator{Iterator: o.base.NewIterator(slice, ro), hidden: o.changed}
+	return ldbiter.NewMergedIterator([]ldbiter.Iterator{o.writes.NewIterator(slice), base}, comparer.DefaultComparer, true)
+}
+
+func (o *overlayStore) Close() error {
+	o.writes.Reset()
+	return o.base.Close()
+}
+
+type overlayReplay struct {
+	o *overlayStore
+}
+
+func (r overlayReplay) Put(key, value []byte) {
+	r.o.Put(key, value, nil)
+}
+
+func (r overlayReplay) Delete(key []byte) {
+	r.o.Delete(key, nil)
+}
+
+// hidingIterator skips the keys of base the overlay wrote over, the merged iterator finds them in the writes
+type hidingIterator struct {
+	ldbiter.Iterator
+	hidden map[string]bool
+}
+
+func (it *hidingIterator) skip(move func() bool) bool {
+	for it.Iterator.Valid() && it.hidden[string(it.Iterator.Key())] {
+		if !move() {
+			return false
+		}
+	}
+	return it.Iterator.Valid()
+}
+
+func (it *hidingIterator) First() bool {
+	it.Iterator.First()
+	return it.skip(it.Iterator.Next)
+}
+
+func (it *hidingIterator) Last() bool {
+	it.Iterator.Last()
+	return it.skip(it.Iterator.Prev)
+}
+
+func (it *hidingIterator) Seek(key []byte) bool {
+	it.Iterator.Seek(key)
+	return it.skip(it.Iterator.Next)
+}
+
+func (it *hidingIterator) Next() bool {
+	it.Iterator.Next()
+	return it.skip(it.Iterator.Next)
+}
+
+func (it *hidingIterator) Prev() bool {
+	it.Iterator.Prev()
+	return it.skip(it.Iterator.Prev)
+}
@@ -5,6 +5,8 @@ type DataBase interface {
 
 	Insert(in interface{}) error
 
+	Restore(in interface{}) error
+
 	Find(tagName string, in interface{}, out interface{}) error
 
 	Empty(begin, end, fieldName []byte) bool
@@ -17,21 +19,60 @@ type DataBase interface {
 
 	Remove(data interface{}) error
 
-	Undo()
+	Undo() error
+
+	UndoAll() error
+
+	StartSession() (*Session, error)
+
+	Commit(revision int64) error
+
+	SetRevision(revision int64) error
+
+	Revision() int64
+
+	Size() uint64
+
+	TableSizes() map[string]uint64
+
+	View() (DataBaseView, error)
+
+	SetHistory(revisions int64)
+
+	ViewAt(revision int64) (DataBaseView, error)
+
+	Check(object interface{}, repair bool) (*CheckReport, error)
 
-	UndoAll()
+	lowerBound(key, value, typeName []byte, in interface{}, greater bool) (*DbIterator, error)
+
+	upperBound(key, value, typeName []byte, in interface{}, greater bool) (*DbIterator, error)
 
-	StartSession() *Session
+	squash() error
 
-	Commit(revision int64)
+	newIterator(typeName, begin, end []byte, greater bool) *DbIterator
+}
 
-	SetRevision(revision int64)
+// DataBaseView is a read only state of a DataBase, it does not change while the database is modified
+// and can be read from any goroutine. Release has to be called once the view is not needed
+type DataBaseView interface {
+	Find(tagName string, in interface{}, out interface{}) error
+
+	GetIndex(tagName string, in interface{}) (*multiIndex, error)
 
 	Revision() int64
 
+	Release()
+}
+
+// indexReader is what a multiIndex reads the objects through, a DataBase or a DataBaseView
+type indexReader interface {
+	Find(tagName string, in interface{}, out interface{}) error
+
+	Empty(begin, end, fieldName []byte) bool
+
 	lowerBound(key, value, typeName []byte, in interface{}, greater bool) (*DbIterator, error)
 
 	upperBound(key, value, typeName []byte, in interface{}, greater bool) (*DbIterator, error)
 
-	squash()
+	newIterator(typeName, begin, end []byte, greater bool) *DbIterator
 }
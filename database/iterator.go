@@ -2,7 +2,6 @@ package database
 
 import (
 	"github.com/eosspark/eos-go/crypto/rlp"
-	"github.com/syndtr/goleveldb/leveldb"
 	"reflect"
 )
 
@@ -40,14 +39,14 @@ type DbIterator struct {
 	value    []byte
 	begin    []byte
 	typeName []byte
-	db       *leveldb.DB // TODO interface
+	db       kvStore
 	it       iterator
 	greater  bool
 	first    bool
 }
 
 //Do not use the functions in this file
-func newDbIterator(typeName []byte, it iterator, db *leveldb.DB, greater bool) (*DbIterator, error) {
+func newDbIterator(typeName []byte, it iterator, db kvStore, greater bool) (*DbIterator, error) {
 	if greater {
 		if it.Last() {
 			idx := &DbIterator{typeName: typeName, it: it, db: db, greater: greater}
@@ -191,14 +190,73 @@ func (index *DbIterator) Value() []byte {
 	return index.value
 }
 
-func (index *DbIterator) Last() bool { // TODO
-	return index.it.Last()
+/*
+
+First, Last and Seek move the iterator on an object, Value holds it when true is returned
+and Next or Prev continue from there in the order of the index
+
+*/
+
+func (index *DbIterator) First() bool {
+	index.first = false
+	if index.greater {
+		return index.load(index.it.Last())
+	}
+	return index.load(index.it.First())
+}
+
+func (index *DbIterator) Last() bool {
+	index.first = false
+	if index.greater {
+		return index.load(index.it.First())
+	}
+	return index.load(index.it.Last())
+}
+
+// Seek moves to the first index key that is not less than key
+func (index *DbIterator) Seek(key []byte) bool {
+	index.first = false
+	return index.load(index.it.Seek(key))
+}
+
+func (index *DbIterator) load(ok bool) bool {
+	if !ok {
+		index.key = nil
+		index.value = nil
+		return false
+	}
+	return index.keyValue(index.it.Value()) == nil
 }
 
-func (index *DbIterator) First() bool { // TODO
-	return index.it.First()
+/*
+
+toEnd moves the iterator past the last object of the index,
+Value is nil (idx.CompareEnd) and Prev returns the last object
+
+*/
+func (index *DbIterator) toEnd() *DbIterator {
+	if index.greater {
+		index.it.First()
+		index.it.Prev()
+	} else {
+		index.it.Last()
+		index.it.Next()
+	}
+	index.first = false
+	index.key = nil
+	index.value = nil
+	return index
 }
 
-func (index *DbIterator) Seek(key []byte) bool { // TODO
-	return index.it.Seek(key)
+/*
+
+toFirst moves the iterator on the first object of the index without consuming it,
+the first Next returns it as with LowerBound
+
+*/
+func (index *DbIterator) toFirst() *DbIterator {
+	if index.First() {
+		index.copyBeginValue(index.value)
+	}
+	return index
 }
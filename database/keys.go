@@ -1,7 +1,6 @@
 package database
 
 import (
-	"github.com/eosspark/eos-go/crypto/rlp"
 	"reflect"
 )
 
@@ -49,7 +48,7 @@ func getNonUniqueFieldValue(info *fieldInfo) ([]byte, []byte) { /* non unique fi
 	prefix := []byte{}
 	//regexp := []byte{40,46,42,41}
 	count := 0
-	for _, v := range info.fieldValue {
+	for i, v := range info.fieldValue {
 		values = append(values, '_')
 		values = append(values, '_')
 		if v.Kind() != reflect.Bool && isZero(v) {
@@ -58,7 +57,7 @@ func getNonUniqueFieldValue(info *fieldInfo) ([]byte, []byte) { /* non unique fi
 			return prefix, prefix
 			continue
 		}
-		re, err := rlp.EncodeToBytes(v.Interface())
+		re, err := info.encodeField(i)
 		if err != nil {
 			return nil, nil
 		}
@@ -94,10 +93,10 @@ func getNonUniqueEnd(key []byte) []byte { /* non unique fields --> regexp*/
 
 func fieldValueToByte(key []byte, info *fieldInfo) []byte { /* fieldValue[0]__fieldValue[1]... */
 	cloneKey := cloneByte(key)
-	for _, v := range info.fieldValue { // typeName__tag__fieldValue...
+	for i := range info.fieldValue { // typeName__tag__fieldValue...
 		cloneKey = append(cloneKey, '_')
 		cloneKey = append(cloneKey, '_')
-		value, err := rlp.EncodeToBytes(v.Interface())
+		value, err := info.encodeField(i)
 		if err != nil {
 			return nil
 		}
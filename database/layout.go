@@ -0,0 +1,198 @@
+package database
+
+import (
+	"encoding/binary"
+	"reflect"
+	"sync"
+
+	"github.com/eosspark/eos-go/crypto/rlp"
+)
+
+/*
+KeyEncoder is implemented by the objects that encode the fields of their indexes by hand or with
+generated code, the keys of the object are then built without reflection and rlp.
+EncodeKeyField returns the key encoding of the field named fieldName, false hands the field
+back to the encoders of the database. The bytes have to be those the database writes for the
+field, the objects found through a struct value that does not implement KeyEncoder use them
+*/
+type KeyEncoder interface {
+	EncodeKeyField(fieldName string) ([]byte, bool)
+}
+
+var keyEncoderType = reflect.TypeOf((*KeyEncoder)(nil)).Elem()
+
+/*
+typeLayout is the index layout of an object type, the multiIndex tags of a type are parsed once
+and every structInfo of the type is bound from its layout
+
+	name 			--> TypeName
+	id 				--> id field
+	indexes			--> tag-indexLayout tag-indexLayout tag-indexLayout
+*/
+type typeLayout struct {
+	name         string
+	id           *keyField
+	indexes      map[string]*indexLayout
+	valueEncoder bool // the struct implements KeyEncoder
+	ptrEncoder   bool // the pointer to the struct implements KeyEncoder
+}
+
+type indexLayout struct {
+	unique    bool
+	greater   bool
+	fieldName []string
+	keys      []*keyField
+}
+
+// keyField is a field of an index, encode is nil for the kinds rlp has to encode
+type keyField struct {
+	name   string
+	index  []int
+	encode kindEncoder
+}
+
+type layoutEntry struct {
+	layout *typeLayout
+	err    error
+}
+
+var layouts sync.Map // reflect.Type --> *layoutEntry
+
+func layoutOf(typ reflect.Type) (*typeLayout, error) {
+	if entry, ok := layouts.Load(typ); ok {
+		return entry.(*layoutEntry).layout, entry.(*layoutEntry).err
+	}
+
+	layout := &typeLayout{
+		name:         typ.Name(),
+		indexes:      make(map[string]*indexLayout),
+		valueEncoder: typ.Implements(keyEncoderType),
+		ptrEncoder:   reflect.PtrTo(typ).Implements(keyEncoderType),
+	}
+	err := extractTypeLayout(typ, nil, layout)
+	if err != nil {
+		layout = nil
+	}
+	entry, _ := layouts.LoadOrStore(typ, &layoutEntry{layout: layout, err: err})
+	return entry.(*layoutEntry).layout, entry.(*layoutEntry).err
+}
+
+func (l *typeLayout) bind(s *reflect.Value) *structInfo {
+	m := &structInfo{Name: l.name, Fields: make(map[string]*fieldInfo, len(l.indexes))}
+
+	// the encoder is taken when a key is built, the id of the object is only set by then
+	var encoder func() KeyEncoder
+	if object := *s; l.ptrEncoder && object.CanAddr() {
+		encoder = func() KeyEncoder { return object.Addr().Interface().(KeyEncoder) }
+	} else if l.valueEncoder {
+		encoder = func() KeyEncoder { return object.Interface().(KeyEncoder) }
+	}
+
+	if l.id != nil {
+		id := s.FieldByIndex(l.id.index)
+		m.Id = &id
+	}
+	for tag, index := range l.indexes {
+		f := &fieldInfo{
+			unique:     index.unique,
+			greater:    index.greater,
+			typeName:   l.name,
+			fieldName:  index.fieldName,
+			fieldValue: make([]*reflect.Value, len(index.keys)),
+			keys:       index.keys,
+			encoder:    encoder,
+		}
+		for i, key := range index.keys {
+			v := s.FieldByIndex(key.index)
+			f.fieldValue[i] = &v
+		}
+		m.Fields[tag] = f
+	}
+	return m
+}
+
+// encodeField encodes the i-th field of the index with the encoder of the object, of its kind, or rlp
+func (f *fieldInfo) encodeField(i int) ([]byte, error) {
+	key := f.keys[i]
+	if f.encoder != nil {
+		if value, ok := f.encoder().EncodeKeyField(key.name); ok {
+			return value, nil
+		}
+	}
+	if key.encode != nil {
+		return key.encode(*f.fieldValue[i]), nil
+	}
+	return rlp.EncodeToBytes(f.fieldValue[i].Interface())
+}
+
+func (s *structInfo) encodeId() ([]byte, error) {
+	f, ok := s.Fields[tagID]
+	if !ok {
+		return nil, ErrNoID
+	}
+	return f.encodeField(0)
+}
+
+func cloneIndex(index []int) []int {
+	dst := make([]int, len(index), len(index)+1)
+	copy(dst, index)
+	return dst
+}
+
+// kindEncoder writes a value of an integer kind big endian, signed ones with the sign bit flipped,
+// so that the keys of an ordered index sort as the values do
+type kindEncoder func(v reflect.Value) []byte
+
+func kindEncoderOf(typ reflect.Type) kindEncoder {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return func(v reflect.Value) []byte {
+			if v.Bool() {
+				return []byte{1}
+			}
+			return []byte{0}
+		}
+	case reflect.Int8:
+		return func(v reflect.Value) []byte { return []byte{byte(v.Int()) ^ 0x80} }
+	case reflect.Uint8:
+		return func(v reflect.Value) []byte { return []byte{byte(v.Uint())} }
+	case reflect.Int16:
+		return func(v reflect.Value) []byte { return encodeUint16(uint16(v.Int()) ^ 1<<15) }
+	case reflect.Uint16:
+		return func(v reflect.Value) []byte { return encodeUint16(uint16(v.Uint())) }
+	case reflect.Int32, reflect.Int:
+		return func(v reflect.Value) []byte { return encodeUint32(uint32(v.Int()) ^ 1<<31) }
+	case reflect.Uint32, reflect.Uint:
+		return func(v reflect.Value) []byte { return encodeUint32(uint32(v.Uint())) }
+	case reflect.Int64:
+		return func(v reflect.Value) []byte { return encodeUint64(uint64(v.Int()) ^ 1<<63) }
+	case reflect.Uint64:
+		return func(v reflect.Value) []byte { return encodeUint64(v.Uint()) }
+	case reflect.String:
+		return func(v reflect.Value) []byte {
+			str := v.String()
+			buf := make([]byte, binary.MaxVarintLen64+len(str))
+			l := binary.PutUvarint(buf, uint64(len(str)))
+			return append(buf[:l], str...)
+		}
+	}
+	return nil
+}
+
+func encodeUint16(i uint16) []byte {
+	buf := make([]byte, 2)
+	binary.BigEndian.PutUint16(buf, i)
+	return buf
+}
+
+func encodeUint32(i uint32) []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, i)
+	return buf
+}
+
+func encodeUint64(i uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, i)
+	return buf
+}
@@ -0,0 +1,407 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/comparer"
+	ldbiter "github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/memdb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+/*
+memStore keeps the keys sorted with the same comparer as leveldb, so the multiIndex layout and
+the iterators behave exactly as on disk. Every write adds a new version of the keys it writes,
+snapshots and iterators read the versions up to the one they were taken at, as leveldb does.
+The versions no reader needs any more are dropped once the key is written again or the readers
+are released, so nothing is copied to take a view
+*/
+type memStore struct {
+	db      *memdb.DB
+	mutex   sync.RWMutex // a batch is applied as a whole before it is read
+	version uint64
+	readers map[uint64]int  // the versions snapshots and iterators read at
+	kept    map[string]bool // the keys with older versions kept for the readers
+}
+
+const (
+	memDeleted byte = 0
+	memValue   byte = 1
+)
+
+func newMemStore() *memStore {
+	return &memStore{db: memdb.New(versionComparer{}, 0), readers: make(map[uint64]int), kept: make(map[string]bool)}
+}
+
+// versionedKey is key followed by version, the newer versions of a key sort first
+func versionedKey(key []byte, version uint64) []byte {
+	vk := make([]byte, len(key)+8)
+	copy(vk, key)
+	binary.BigEndian.PutUint64(vk[len(key):], math.MaxUint64-version)
+	return vk
+}
+
+func splitVersionedKey(vk []byte) ([]byte, uint64) {
+	n := len(vk) - 8
+	return vk[:n], math.MaxUint64 - binary.BigEndian.Uint64(vk[n:])
+}
+
+type versionComparer struct{}
+
+func (versionComparer) Compare(a, b []byte) int {
+	if c := comparer.DefaultComparer.Compare(a[:len(a)-8], b[:len(b)-8]); c != 0 {
+		return c
+	}
+	return bytes.Compare(a[len(a)-8:], b[len(b)-8:])
+}
+
+// get reads the newest version of key up to version
+func (m *memStore) get(key []byte, version uint64) ([]byte, error) {
+	vk, value, err := m.db.Find(versionedKey(key, version))
+	if err != nil {
+		return nil, leveldb.ErrNotFound
+	}
+	if k, _ := splitVersionedKey(vk); !bytes.Equal(k, key) || value[0] == memDeleted {
+		return nil, leveldb.ErrNotFound
+	}
+	return cloneByte(value[1:]), nil
+}
+
+func (m *memStore) Has(key []byte, ro *opt.ReadOptions) (bool, error) {
+	_, err := m.Get(key, ro)
+	return err == nil, nil
+}
+
+func (m *memStore) Get(key []byte, ro *opt.ReadOptions) ([]byte, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.get(key, m.version)
+}
+
+func (m *memStore) Put(key, value []byte, wo *opt.WriteOptions) error {
+	return m.apply(func(w leveldb.BatchReplay) error {
+		w.Put(key, value)
+		return nil
+	})
+}
+
+func (m *memStore) Delete(key []byte, wo *opt.WriteOptions) error {
+	return m.apply(func(w leveldb.BatchReplay) error {
+		w.Delete(key)
+		return nil
+	})
+}
+
+func (m *memStore) Write(batch *leveldb.Batch, wo *opt.WriteOptions) error {
+	return m.apply(batch.Replay)
+}
+
+// apply writes every key of replay at the next version, the readers see them once they are all written
+func (m *memStore) apply(replay func(w leveldb.BatchReplay) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	w := &memWrite{db: m.db, version: m.version + 1, keys: make(map[string]bool)}
+	if err := replay(w); err != nil {
+		for key := range w.keys {
+			m.db.Delete(versionedKey([]byte(key), w.version))
+		}
+		return err
+	}
+	m.version = w.version
+	for key := range w.keys {
+		m.prune([]byte(key))
+	}
+	return nil
+}
+
+type memWrite struct {
+	db      *memdb.DB
+	version uint64
+	keys    map[string]bool
+}
+
+func (w *memWrite) Put(key, value []byte) {
+	w.keys[string(key)] = true
+	w.db.Put(versionedKey(key, w.version), append([]byte{memValue}, value...))
+}
+
+func (w *memWrite) Delete(key []byte) {
+	w.keys[string(key)] = true
+	w.db.Put(versionedKey(key, w.version), []byte{memDeleted})
+}
+
+// prune drops the versions of key no reader reads, the newest one is what the store holds
+func (m *memStore) prune(key []byte) {
+	it := m.db.NewIterator(&util.Range{Start: versionedKey(key, math.MaxUint64), Limit: versionedKey(key, 0)})
+	drop := make([][]byte, 0)
+	kept, newer := 0, uint64(math.MaxUint64)
+	var newestDeleted []byte
+	for it.Next() {
+		_, version := splitVersionedKey(it.Key())
+		if kept == 0 {
+			if it.Value()[0] == memDeleted {
+				newestDeleted = cloneByte(it.Key())
+			}
+			kept++
+		} else if m.reads(version, newer) {
+			kept++
+		} else {
+			drop = append(drop, cloneByte(it.Key()))
+		}
+		newer = version
+	}
+	it.Release()
+
+	if kept == 1 && newestDeleted != nil {
+		drop = append(drop, newestDeleted)
+	}
+	for _, vk := range drop {
+		m.db.Delete(vk)
+	}
+	if kept > 1 {
+		m.kept[string(key)] = true
+	} else {
+		delete(m.kept, string(key))
+	}
+}
+
+// reads tells whether a reader reads the version of a key whose next version is newer
+func (m *memStore) reads(version, newer uint64) bool {
+	for reader := range m.readers {
+		if version <= reader && reader < newer {
+			return true
+		}
+	}
+	return false
+}
+
+// hold keeps the versions read at version until it is released
+func (m *memStore) hold(version uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.readers[version]++
+}
+
+func (m *memStore) current() uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.readers[m.version]++
+	return m.version
+}
+
+func (m *memStore) release(version uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.readers[version]--; m.readers[version] <= 0 {
+		delete(m.readers, version)
+	}
+	for key := range m.kept {
+		m.prune([]byte(key))
+	}
+}
+
+func (m *memStore) NewIterator(slice *util.Range, ro *opt.ReadOptions) ldbiter.Iterator {
+	return newMemIterator(m, m.current(), slice)
+}
+
+func (m *memStore) Close() error {
+	m.db.Reset()
+	return nil
+}
+
+// snapshot is a read only store of what m holds now
+func (m *memStore) snapshot() kvStore {
+	version := m.current()
+	return &readOnlyStore{kvReader: &memSnapshot{m: m, version: version}, release: func() { m.release(version) }}
+}
+
+// memSnapshot reads a memStore at a version held for it
+type memSnapshot struct {
+	m       *memStore
+	version uint64
+}
+
+func (s *memSnapshot) Has(key []byte, ro *opt.ReadOptions) (bool, error) {
+	_, err := s.m.get(key, s.version)
+	return err == nil, nil
+}
+
+func (s *memSnapshot) Get(key []byte, ro *opt.ReadOptions) ([]byte, error) {
+	return s.m.get(key, s.version)
+}
+
+func (s *memSnapshot) NewIterator(slice *util.Range, ro *opt.ReadOptions) ldbiter.Iterator {
+	s.m.hold(s.version)
+	return newMemIterator(s.m, s.version, slice)
+}
+
+const (
+	memIterBeforeFirst  = -1
+	memIterUnpositioned = 0
+	memIterValid        = 1
+	memIterAfterLast    = 2
+)
+
+// memIterator walks the newest version of the keys up to its version, the version is held until it is released
+type memIterator struct {
+	m        *memStore
+	raw      ldbiter.Iterator
+	version  uint64
+	key      []byte
+	value    []byte
+	pos      int
+	released bool
+	releaser util.Releaser
+}
+
+func newMemIterator(m *memStore, version uint64, slice *util.Range) *memIterator {
+	var raw *util.Range
+	if slice != nil {
+		raw = &util.Range{}
+		if slice.Start != nil {
+			raw.Start = versionedKey(slice.Start, math.MaxUint64)
+		}
+		if slice.Limit != nil {
+			raw.Limit = versionedKey(slice.Limit, math.MaxUint64)
+		}
+	}
+	return &memIterator{m: m, raw: m.db.NewIterator(raw), version: version}
+}
+
+func (it *memIterator) found(key, value []byte) bool {
+	it.key, it.value, it.pos = cloneByte(key), cloneByte(value), memIterValid
+	return true
+}
+
+func (it *memIterator) exhausted(pos int) bool {
+	it.key, it.value, it.pos = nil, nil, pos
+	return false
+}
+
+// forward stops at the first key from the raw position with a value at the version
+func (it *memIterator) forward() bool {
+	for it.raw.Valid() {
+		key, version := splitVersionedKey(it.raw.Key())
+		if version > it.version {
+			it.raw.Next()
+			continue
+		}
+		if value := it.raw.Value(); value[0] == memValue {
+			return it.found(key, value[1:])
+		}
+		it.skip(key)
+	}
+	return it.exhausted(memIterAfterLast)
+}
+
+// backward stops at the first key before the raw position with a value at the version
+func (it *memIterator) backward() bool {
+	for it.raw.Valid() {
+		key, _ := splitVersionedKey(it.raw.Key())
+		var value []byte
+		for it.raw.Valid() {
+			k, version := splitVersionedKey(it.raw.Key())
+			if !bytes.Equal(k, key) {
+				break
+			}
+			if version <= it.version {
+				value = it.raw.Value()
+			}
+			it.raw.Prev()
+		}
+		if value != nil && value[0] == memValue {
+			return it.found(key, value[1:])
+		}
+	}
+	return it.exhausted(memIterBeforeFirst)
+}
+
+// skip moves the raw iterator past the versions of key
+func (it *memIterator) skip(key []byte) {
+	for it.raw.Valid() {
+		if k, _ := splitVersionedKey(it.raw.Key()); !bytes.Equal(k, key) {
+			return
+		}
+		it.raw.Next()
+	}
+}
+
+func (it *memIterator) First() bool {
+	it.raw.First()
+	return it.forward()
+}
+
+func (it *memIterator) Last() bool {
+	it.raw.Last()
+	return it.backward()
+}
+
+func (it *memIterator) Seek(key []byte) bool {
+	it.raw.Seek(versionedKey(key, math.MaxUint64))
+	return it.forward()
+}
+
+func (it *memIterator) Next() bool {
+	switch it.pos {
+	case memIterUnpositioned, memIterBeforeFirst:
+		return it.First()
+	case memIterAfterLast:
+		return false
+	}
+	it.raw.Seek(versionedKey(it.key, math.MaxUint64))
+	it.skip(it.key)
+	return it.forward()
+}
+
+func (it *memIterator) Prev() bool {
+	switch it.pos {
+	case memIterUnpositioned, memIterAfterLast:
+		return it.Last()
+	case memIterBeforeFirst:
+		return false
+	}
+	if it.raw.Seek(versionedKey(it.key, math.MaxUint64)) {
+		it.raw.Prev()
+	} else {
+		it.raw.Last()
+	}
+	return it.backward()
+}
+
+func (it *memIterator) Valid() bool {
+	return it.pos == memIterValid
+}
+
+func (it *memIterator) Key() []byte {
+	return it.key
+}
+
+func (it *memIterator) Value() []byte {
+	return it.value
+}
+
+func (it *memIterator) Error() error {
+	return it.raw.Error()
+}
+
+func (it *memIterator) Release() {
+	if it.released {
+		return
+	}
+	it.released = true
+	it.raw.Release()
+	it.m.release(it.version)
+	if it.releaser != nil {
+		it.releaser.Release()
+		it.releaser = nil
+	}
+}
+
+func (it *memIterator) SetReleaser(releaser util.Releaser) {
+	it.releaser = releaser
+}
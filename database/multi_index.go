@@ -2,6 +2,8 @@ package database
 
 import (
 	"bytes"
+	"reflect"
+
 	"github.com/eosspark/eos-go/crypto/rlp"
 )
 
@@ -12,12 +14,12 @@ type multiIndex struct {
 	itEnd     []byte
 	typeName  []byte
 	fieldName []byte
-	db        DataBase
+	db        indexReader
 	it        DbIterator
 	greater   bool
 }
 
-func newMultiIndex(typeName, fieldName, begin, end []byte, greater bool, db DataBase) *multiIndex {
+func newMultiIndex(typeName, fieldName, begin, end []byte, greater bool, db indexReader) *multiIndex {
 	return &multiIndex{typeName: typeName, fieldName: fieldName, begin: begin, end: end, greater: greater, db: db}
 }
 
@@ -132,61 +134,126 @@ func (index *multiIndex) CompareEnd(in Iterator) bool {
 
 /*
 
-@return 			-->		Iterator
+--> idx.end() <--
+
+@return 			-->		Iterator after the last object, Prev returns the last object
 
 */
 
 func (index *multiIndex) End() Iterator {
-	// TODO
-	return nil
+	return index.db.newIterator(index.typeName, index.begin, index.end, index.greater).toEnd()
 }
 
 /*
 
-@param in 			--> 	object
+--> idx.begin() <--
 
-@return
-success 			-->		Iterator
-error 				-->		nil
+@return 			-->		Iterator, Next returns the first object
 
 */
 
 func (index *multiIndex) BeginIterator() Iterator {
-	// TODO
-	if len(index.typeName) == 0 {
-		return nil
-	}
+	return index.db.newIterator(index.typeName, index.begin, index.end, index.greater).toFirst()
+}
+
+/*
+
+--> idx.iterator_to(obj) <--
+
+@param in 			--> 	object(struct) holding the id and the fields of the index
+
+@return
+success 			-->		Iterator, Next returns the object
+error 				-->		End()
 
-	key := append(index.typeName, '_')
-	key = append(key, '_')
-	// typeName__tag__
-	key = append(key, index.fieldName...)
+*/
 
-	if index.it.Seek(key) {
-		return nil
+func (index *multiIndex) IteratorTo(in interface{}) Iterator {
+	it := index.db.newIterator(index.typeName, index.begin, index.end, index.greater)
+	key, err := index.objectKey(in)
+	if err != nil || !it.it.Seek(key) || !bytes.Equal(it.it.Key(), key) {
+		return it.toEnd()
 	}
-	return &index.it
+	it.first = true
+	it.keyValue(it.it.Value())
+	return it
 }
 
-func (index *multiIndex) IteratorTo(in interface{}) Iterator {
-	// TODO
-	if len(index.typeName) == 0 {
-		return nil
+/*
+
+Range iterates over the objects from lower (included) to upper (excluded) in the order of the index,
+the bounds are objects(struct) holding every field of the index, composite ones too. nil stands
+for the begin or the end of the index
+
+@param lower 		--> 	object or nil
+@param upper 		--> 	object or nil
+
+@return
+success 			-->		Iterator, Next returns the first object and Last then Prev go backwards
+error 				-->		error
+
+*/
+
+func (index *multiIndex) Range(lower, upper interface{}) (Iterator, error) {
+	begin, end := index.begin, index.end
+	if lower != nil {
+		key, err := index.boundKey(lower)
+		if err != nil {
+			return nil, err
+		}
+		if index.greater {
+			end = getNonUniqueEnd(key)
+		} else {
+			begin = key
+		}
 	}
+	if upper != nil {
+		key, err := index.boundKey(upper)
+		if err != nil {
+			return nil, err
+		}
+		if index.greater {
+			begin = getNonUniqueEnd(key)
+		} else {
+			end = key
+		}
+	}
+	return index.db.newIterator(index.typeName, begin, end, index.greater).toFirst(), nil
+}
+
+// boundKey is the index key of the fields of in without the id non unique keys end with
+func (index *multiIndex) boundKey(in interface{}) ([]byte, error) {
 	fields, err := getFieldInfo(string(index.fieldName), in)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	key := fieldValueToByte(typeNameFieldName(index.typeName, index.fieldName), fields)
+	if key == nil {
+		return nil, ErrTagInvalid
+	}
+	return key, nil
+}
+
+// objectKey is the index key of in as written by cfgToKV
+func (index *multiIndex) objectKey(in interface{}) ([]byte, error) {
+	ref := reflect.ValueOf(in)
+	cfg, err := extractObjectTagInfo(&ref)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := cfg.Fields[string(index.fieldName)]
+	if !ok || cfg.Id == nil {
+		return nil, ErrNotFound
 	}
-	key := append(index.typeName, '_')
-	key = append(key, '_')
-	// typeName__tag__
-	key = append(key, index.fieldName...)
-	key = fieldValueToByte(key, fields)
-
-	if index.it.Seek(key) {
-		return nil
+	key := fieldValueToByte(typeNameFieldName(index.typeName, index.fieldName), fields)
+	if !fields.unique && len(fields.fieldValue) == 1 {
+		objId, err := cfg.encodeId()
+		if err != nil {
+			return nil, err
+		}
+		key = append(key, objId...)
 	}
-	return &index.it
+	return key, nil
 }
 
 func (index *multiIndex) Empty() bool {
@@ -23,24 +23,30 @@ func (session *Session) Push() {
 	session.apply = false
 	session.db = nil
 }
-func (session *Session) Squash() {
+func (session *Session) Squash() error {
 	if session.db == nil || !session.apply {
-		return
+		return nil
 	}
 
-	session.db.squash()
+	if err := session.db.squash(); err != nil {
+		return err
+	}
 	session.db = nil
 	session.apply = false
+	return nil
 }
 
-func (session *Session) Undo() {
+func (session *Session) Undo() error {
 	if session.db == nil || !session.apply {
-		return
+		return nil
 	}
 
-	session.db.Undo()
+	if err := session.db.Undo(); err != nil {
+		return err
+	}
 	session.db = nil
 	session.apply = false
+	return nil
 }
 
 func (session *Session) Revision() int64 {
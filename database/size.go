@@ -0,0 +1,186 @@
+package database
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/eosspark/eos-go/crypto/rlp"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+/*
+sizedStore keeps count of the bytes of the keys and values held by the store it wraps, for every
+table and overall. A table is the type name the keys of an object and of its indexes start with,
+the undo states and the other keys of the database are counted under their own name, the
+bookkeeping keys are left out. The counters are written under dbSize with every batch.
+The numbers are those of the encoded objects, the files on disk are compressed and may be smaller
+*/
+type sizedStore struct {
+	kvStore
+	mutex  sync.RWMutex
+	tables map[string]int64
+	total  int64
+}
+
+// newSizedStore reads the counters of the store, a store written before they were kept is counted once
+func newSizedStore(store kvStore) (*sizedStore, error) {
+	s := &sizedStore{kvStore: store}
+	val, err := store.Get([]byte(dbSize), nil)
+	switch {
+	case err == leveldb.ErrNotFound:
+		s.tables = countSizes(store)
+		if len(s.tables) != 0 {
+			if err = s.putSizes(s.tables); err != nil {
+				return nil, err
+			}
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err = rlp.DecodeBytes(val, &s.tables); err != nil {
+			return nil, err
+		}
+	}
+	for _, size := range s.tables {
+		s.total += size
+	}
+	return s, nil
+}
+
+// countSizes scans the store for the size of every table
+func countSizes(store kvStore) map[string]int64 {
+	tables := make(map[string]int64)
+	it := store.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if !isBookkeeping(string(it.Key())) {
+			addSize(tables, it.Key(), int64(len(it.Key())+len(it.Value())))
+		}
+	}
+	return tables
+}
+
+func (s *sizedStore) putSizes(tables map[string]int64) error {
+	val, err := rlp.EncodeToBytes(tables)
+	if err != nil {
+		return err
+	}
+	return s.kvStore.Put([]byte(dbSize), val, nil)
+}
+
+// isBookkeeping tells the keys of the revision, the next ids and the counters, they are not counted
+func isBookkeeping(key string) bool {
+	return key == dbSize || key == dbRevision || key == dbIncrement
+}
+
+// tableOf is the part of key before the first separator
+func tableOf(key []byte) string {
+	if i := bytes.Index(key, []byte("__")); i >= 0 {
+		return string(key[:i])
+	}
+	return string(key)
+}
+
+func addSize(tables map[string]int64, key []byte, delta int64) {
+	if delta == 0 {
+		return
+	}
+	table := tableOf(key)
+	tables[table] += delta
+	if tables[table] == 0 {
+		delete(tables, table)
+	}
+}
+
+// stored is the size the key takes in the store, 0 when it is not there
+func (s *sizedStore) stored(key []byte) int64 {
+	if value, err := s.kvStore.Get(key, nil); err == nil {
+		return int64(len(key) + len(value))
+	}
+	return 0
+}
+
+/*
+write applies batch with the counters updated by it. sizes holds the size every key written by
+the batch takes once it is applied, stored the size those keys take before as far as the writer
+read them, only the other keys are looked up
+*/
+func (s *sizedStore) write(batch *leveldb.Batch, sizes batchSizes, stored map[string]int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tables := make(map[string]int64, len(s.tables))
+	for table, size := range s.tables {
+		tables[table] = size
+	}
+	total := s.total
+	for key, size := range sizes {
+		if isBookkeeping(key) {
+			continue
+		}
+		old, ok := stored[key]
+		if !ok {
+			old = s.stored([]byte(key))
+		}
+		addSize(tables, []byte(key), size-old)
+		total += size - old
+	}
+
+	val, err := rlp.EncodeToBytes(tables)
+	if err != nil {
+		return err
+	}
+	batch.Put([]byte(dbSize), val)
+	if err = s.kvStore.Write(batch, nil); err != nil {
+		return err
+	}
+	s.tables, s.total = tables, total
+	return nil
+}
+
+func (s *sizedStore) Put(key, value []byte, wo *opt.WriteOptions) error {
+	batch := new(leveldb.Batch)
+	batch.Put(key, value)
+	return s.write(batch, batchSizes{string(key): int64(len(key) + len(value))}, nil)
+}
+
+func (s *sizedStore) Delete(key []byte, wo *opt.WriteOptions) error {
+	batch := new(leveldb.Batch)
+	batch.Delete(key)
+	return s.write(batch, batchSizes{string(key): 0}, nil)
+}
+
+func (s *sizedStore) Write(batch *leveldb.Batch, wo *opt.WriteOptions) error {
+	sizes := batchSizes(make(map[string]int64))
+	if err := batch.Replay(sizes); err != nil {
+		return err
+	}
+	return s.write(batch, sizes, nil)
+}
+
+func (s *sizedStore) size() uint64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return uint64(s.total)
+}
+
+func (s *sizedStore) tableSizes() map[string]uint64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	tables := make(map[string]uint64, len(s.tables))
+	for table, size := range s.tables {
+		tables[table] = uint64(size)
+	}
+	return tables
+}
+
+// batchSizes replays a batch to find the size every key written by it takes once it is applied
+type batchSizes map[string]int64
+
+func (b batchSizes) Put(key, value []byte) {
+	b[string(key)] = int64(len(key) + len(value))
+}
+
+func (b batchSizes) Delete(key []byte) {
+	b[string(key)] = 0
+}
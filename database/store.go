@@ -0,0 +1,86 @@
+package database
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	ldbiter "github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// InMemory passed as path to NewDataBase keeps every object, index and undo state in memory,
+// nothing is written to disk and the content is dropped once the database is closed
+const InMemory = ":memory:"
+
+// kvReadWriter is the part of kvStore an object mutation works on, the store itself or a dbBatch
+type kvReadWriter interface {
+	Has(key []byte, ro *opt.ReadOptions) (bool, error)
+
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+
+	Put(key, value []byte, wo *opt.WriteOptions) error
+
+	Delete(key []byte, wo *opt.WriteOptions) error
+}
+
+/*
+kvStore is the ordered key value storage under LDataBase,
+*leveldb.DB for a database on disk and memStore for InMemory
+*/
+type kvStore interface {
+	kvReadWriter
+
+	Write(batch *leveldb.Batch, wo *opt.WriteOptions) error
+
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) ldbiter.Iterator
+
+	Close() error
+}
+
+// kvReader is the read part of a kvStore, a leveldb snapshot provides it
+type kvReader interface {
+	Has(key []byte, ro *opt.ReadOptions) (bool, error)
+
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) ldbiter.Iterator
+}
+
+// readOnlyStore is the kvStore of a view, writes fail and Close releases the state it reads
+type readOnlyStore struct {
+	kvReader
+	release func()
+}
+
+func (r *readOnlyStore) Put(key, value []byte, wo *opt.WriteOptions) error {
+	return ErrReadOnly
+}
+
+func (r *readOnlyStore) Delete(key []byte, wo *opt.WriteOptions) error {
+	return ErrReadOnly
+}
+
+func (r *readOnlyStore) Write(batch *leveldb.Batch, wo *opt.WriteOptions) error {
+	return ErrReadOnly
+}
+
+func (r *readOnlyStore) Close() error {
+	r.release()
+	return nil
+}
+
+// snapshotOf takes the current state of the store, a leveldb snapshot on disk and a version in memory
+func snapshotOf(store kvStore) (kvStore, error) {
+	switch s := store.(type) {
+	case *sizedStore:
+		return snapshotOf(s.kvStore)
+	case *leveldb.DB:
+		snapshot, err := s.GetSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		return &readOnlyStore{kvReader: snapshot, release: snapshot.Release}, nil
+	case *memStore:
+		return s.snapshot(), nil
+	}
+	return nil, ErrBadType
+}
@@ -44,6 +44,23 @@ type DbResourceLimitsObject struct {
 	RamBytes  int64
 }
 
+// DbEncodedHouse encodes the keys of its indexes by hand, Name is left to the database
+type DbEncodedHouse struct {
+	Id   uint64 `multiIndex:"id,increment"`
+	Area uint64 `multiIndex:"orderedUnique,greater"`
+	Name string `multiIndex:"orderedNonUnique"`
+}
+
+func (h *DbEncodedHouse) EncodeKeyField(fieldName string) ([]byte, bool) {
+	switch fieldName {
+	case "Id":
+		return encodeUint64(h.Id), true
+	case "Area":
+		return encodeUint64(h.Area), true
+	}
+	return nil, false
+}
+
 func logObj(data interface{}) {
 	space := "	"
 	ref := reflect.ValueOf(data)
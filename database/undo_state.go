@@ -1,7 +1,6 @@
 package database
 
 import (
-	"fmt"
 	"reflect"
 )
 
@@ -12,6 +11,8 @@ type undoState struct {
 	OldValue    map[interface{}]int64
 	oldIds      map[string]int64
 	reversion   int64
+	entries     int64                  // undo entries written for the session
+	identities  map[interface{}]string // the id keys of the values above
 }
 
 func newUndoState(reversion int64, oldIds map[string]int64) *undoState {
@@ -26,6 +27,7 @@ func newUndoState(reversion int64, oldIds map[string]int64) *undoState {
 		OldValue:    make(map[interface{}]int64),
 		oldIds:      oldIds_,
 		reversion:   reversion,
+		identities:  make(map[interface{}]string),
 	}
 }
 
@@ -33,50 +35,57 @@ func (stack *undoState) undoInsert(data interface{}) {
 	stack.NewValue[data] = stack.reversion
 }
 
+// undoRemove keeps the value the object had before the session, nothing is kept for an object the session inserted
 func (stack *undoState) undoRemove(data interface{}) {
-	_, ok := stack.NewValue[data]
-	if ok {
-		undoMapRemove(stack.NewValue, data)
+	if key := stack.find(stack.NewValue, data); key != nil {
+		delete(stack.NewValue, key)
 		return
 	}
-	_, ok = stack.OldValue[data]
-	if ok {
-		stack.RemoveValue[data] = stack.reversion
-		undoMapRemove(stack.OldValue, data)
+	if key := stack.find(stack.OldValue, data); key != nil {
+		stack.RemoveValue[key] = stack.OldValue[key]
+		delete(stack.OldValue, key)
 		return
 	}
-
-	_, ok = stack.RemoveValue[data]
-	if ok {
+	if stack.find(stack.RemoveValue, data) != nil {
 		return
 	}
 	stack.RemoveValue[data] = stack.reversion
 }
 
+// undoModify keeps the value the object had before its first change in the session
 func (stack *undoState) undoModify(data interface{}) {
-	key := undoEqual(stack.NewValue, data)
-	if key != nil {
-		return
-	}
-	key = undoEqual(stack.OldValue, data)
-	if key != nil {
+	if stack.find(stack.NewValue, data) != nil || stack.find(stack.OldValue, data) != nil {
 		return
 	}
-	stack.reversion++
 	stack.OldValue[data] = stack.reversion
 }
 
-func undoEqual(m map[interface{}]int64, data interface{}) interface{} {
-	for key, value := range m {
-		if reflect.DeepEqual(key, data) {
-			fmt.Println(value)
+// find returns the key of m that is a value of the same object as data, of the same type and id
+func (stack *undoState) find(m map[interface{}]int64, data interface{}) interface{} {
+	id := stack.identity(data)
+	for key := range m {
+		if stack.identity(key) == id {
 			return key
 		}
 	}
 	return nil
 }
 
-func undoMapRemove(m map[interface{}]int64, data interface{}) {
-	key := undoEqual(m, data)
-	delete(m, key)
+// identity is the id key of obj, the identities of the values kept by the state are computed once
+func (stack *undoState) identity(obj interface{}) string {
+	if id, ok := stack.identities[obj]; ok {
+		return id
+	}
+	ref := reflect.ValueOf(resolveObject(obj))
+	cfg, err := extractObjectTagInfo(&ref)
+	if err != nil {
+		panic("database undo failed : " + err.Error())
+	}
+	id, err := cfg.encodeId()
+	if err != nil {
+		panic("database undo failed : " + err.Error())
+	}
+	key := string(idKey(id, []byte(cfg.Name)))
+	stack.identities[obj] = key
+	return key
 }
@@ -0,0 +1,327 @@
+package database
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/eosspark/eos-go/crypto/rlp"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+/*
+
+The undo states are written to the store next to the data they belong to, so the database reopens
+with the undo stack and the revision it had when it stopped. A session writes its revision and the
+next ids under db_undo__<revision> when it starts, each object mutation adds one entry under
+db_undo__<revision><sequence> in the same batch as the mutation, reading the entries back in order
+rebuilds the undo state. The history keeps whole states under db_history__<revision>
+
+*/
+
+var undoTypes sync.Map // type name --> reflect.Type
+
+/*
+RegisterType makes the types of the objects known to the undo states read back from disk and
+records the index layout of each type, the types a session records are registered by themselves
+
+@param objs 		--> 	objects(pointer or struct)
+*/
+func RegisterType(objs ...interface{}) {
+	for _, obj := range objs {
+		typ := reflect.TypeOf(obj)
+		if typ == nil {
+			continue
+		}
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		undoTypes.Store(typ.Name(), typ)
+		layoutOf(typ)
+	}
+}
+
+// storedObject is an object of an undo state read back from disk, it is decoded when the undo needs it
+type storedObject struct {
+	typeName string
+	data     []byte
+}
+
+const (
+	undoOpInsert uint8 = iota
+	undoOpModify
+	undoOpRemove
+)
+
+// undoEntry is the object of one mutation made in a session
+type undoEntry struct {
+	Op       uint8
+	TypeName string
+	Data     []byte
+}
+
+// undoHeader is written when a session starts, the entries of its mutations follow it
+type undoHeader struct {
+	Reversion int64
+	OldIds    map[string]int64
+}
+
+type undoObject struct {
+	TypeName string
+	Data     []byte
+	Revision int64
+}
+
+type undoRecord struct {
+	Reversion   int64
+	OldIds      map[string]int64
+	NewValue    []undoObject
+	RemoveValue []undoObject
+	OldValue    []undoObject
+}
+
+func undoStateKey(revision int64) []byte {
+	key := make([]byte, len(dbUndoState)+8)
+	copy(key, dbUndoState)
+	binary.BigEndian.PutUint64(key[len(dbUndoState):], uint64(revision))
+	return key
+}
+
+func undoEntryKey(revision int64, sequence int64) []byte {
+	key := make([]byte, len(dbUndoState)+16)
+	copy(key, undoStateKey(revision))
+	binary.BigEndian.PutUint64(key[len(dbUndoState)+8:], uint64(sequence))
+	return key
+}
+
+// typeNameOf is the name the undo entries record obj under, the type is registered the first time
+func typeNameOf(obj interface{}) string {
+	typ := reflect.TypeOf(obj)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if _, ok := undoTypes.Load(typ.Name()); !ok {
+		undoTypes.Store(typ.Name(), typ)
+	}
+	return typ.Name()
+}
+
+func encodeUndoObjects(m map[interface{}]int64) ([]undoObject, error) {
+	objs := make([]undoObject, 0, len(m))
+	for obj, revision := range m {
+		if stored, ok := obj.(*storedObject); ok {
+			objs = append(objs, undoObject{TypeName: stored.typeName, Data: stored.data, Revision: revision})
+			continue
+		}
+		data, err := rlp.EncodeToBytes(obj)
+		if err != nil {
+			return nil, err
+		}
+		typeName := reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+		objs = append(objs, undoObject{TypeName: typeName, Data: data, Revision: revision})
+	}
+	return objs, nil
+}
+
+func decodeUndoObjects(objs []undoObject) map[interface{}]int64 {
+	m := make(map[interface{}]int64, len(objs))
+	for _, obj := range objs {
+		m[&storedObject{typeName: obj.TypeName, data: obj.Data}] = obj.Revision
+	}
+	return m
+}
+
+/*
+resolveObject turns an object read back from disk into a pointer to its registered type
+*/
+func resolveObject(obj interface{}) interface{} {
+	stored, ok := obj.(*storedObject)
+	if !ok {
+		return obj
+	}
+	typ, ok := undoTypes.Load(stored.typeName)
+	if !ok {
+		panic(fmt.Sprintf("database undo failed : type %s is not registered", stored.typeName))
+	}
+	dst := reflect.New(typ.(reflect.Type))
+	if err := rlp.DecodeBytes(stored.data, dst.Interface()); err != nil {
+		panic("database undo failed : " + err.Error())
+	}
+	return dst.Interface()
+}
+
+// putUndoHeader writes the revision and the next ids a new session starts from
+func putUndoHeader(batch *dbBatch, state *undoState) error {
+	val, err := rlp.EncodeToBytes(&undoHeader{Reversion: state.reversion, OldIds: state.oldIds})
+	if err != nil {
+		return err
+	}
+	batch.putNew(undoStateKey(state.reversion), val)
+	return nil
+}
+
+// putUndoEntry writes the entry of a mutation after the entries the state already has
+func putUndoEntry(batch *dbBatch, state *undoState, op uint8, obj interface{}) error {
+	data, err := rlp.EncodeToBytes(obj)
+	if err != nil {
+		return err
+	}
+	val, err := rlp.EncodeToBytes(&undoEntry{Op: op, TypeName: typeNameOf(obj), Data: data})
+	if err != nil {
+		return err
+	}
+	batch.putNew(undoEntryKey(state.reversion, state.entries), val)
+	return nil
+}
+
+// deleteUndoState drops the header and the entries of the state of revision
+func (ldb *LDataBase) deleteUndoState(batch *dbBatch, revision int64) {
+	it := ldb.db.NewIterator(util.BytesPrefix(undoStateKey(revision)), nil)
+	defer it.Release()
+	for it.Next() {
+		batch.deleteStored(cloneByte(it.Key()), it.Value())
+	}
+}
+
+// moveUndoEntries appends the entries of the state of revision to those of state
+func (ldb *LDataBase) moveUndoEntries(batch *dbBatch, revision int64, state *undoState) error {
+	it := ldb.db.NewIterator(util.BytesPrefix(undoStateKey(revision)), nil)
+	defer it.Release()
+	sequence := state.entries
+	for it.Next() {
+		key := cloneByte(it.Key())
+		batch.deleteStored(key, it.Value())
+		if len(key) == len(dbUndoState)+8 {
+			continue
+		}
+		batch.putNew(undoEntryKey(state.reversion, sequence), cloneByte(it.Value()))
+		sequence++
+	}
+	return it.Error()
+}
+
+// apply takes the mutation of an entry into the state
+func (stack *undoState) apply(op uint8, obj interface{}) {
+	switch op {
+	case undoOpInsert:
+		stack.undoInsert(obj)
+	case undoOpModify:
+		stack.undoModify(obj)
+	case undoOpRemove:
+		stack.undoRemove(obj)
+	}
+	stack.entries++
+}
+
+/*
+readUndoStates rebuilds the undo states of the revisions in [begin, end) from their headers and entries
+*/
+func readUndoStates(store kvStore, begin, end int64) ([]*undoState, error) {
+	states := make([]*undoState, 0)
+	it := store.NewIterator(&util.Range{Start: undoStateKey(begin), Limit: undoStateKey(end)}, nil)
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) == len(dbUndoState)+8 {
+			header := undoHeader{}
+			if err := rlp.DecodeBytes(it.Value(), &header); err != nil {
+				return nil, err
+			}
+			states = append(states, newUndoState(header.Reversion, header.OldIds))
+			continue
+		}
+		if len(states) == 0 {
+			return nil, ErrRevisionUnavailable
+		}
+		entry := undoEntry{}
+		if err := rlp.DecodeBytes(it.Value(), &entry); err != nil {
+			return nil, err
+		}
+		states[len(states)-1].apply(entry.Op, &storedObject{typeName: entry.TypeName, data: entry.Data})
+	}
+	return states, it.Error()
+}
+
+func encodeUndoState(state *undoState) ([]byte, error) {
+	record := undoRecord{Reversion: state.reversion, OldIds: state.oldIds}
+	var err error
+	if record.NewValue, err = encodeUndoObjects(state.NewValue); err != nil {
+		return nil, err
+	}
+	if record.RemoveValue, err = encodeUndoObjects(state.RemoveValue); err != nil {
+		return nil, err
+	}
+	if record.OldValue, err = encodeUndoObjects(state.OldValue); err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(&record)
+}
+
+func decodeUndoState(val []byte) (*undoState, error) {
+	record := undoRecord{}
+	if err := rlp.DecodeBytes(val, &record); err != nil {
+		return nil, err
+	}
+	state := newUndoState(record.Reversion, record.OldIds)
+	state.NewValue = decodeUndoObjects(record.NewValue)
+	state.RemoveValue = decodeUndoObjects(record.RemoveValue)
+	state.OldValue = decodeUndoObjects(record.OldValue)
+	return state, nil
+}
+
+func putRevision(batch kvReadWriter, revision int64) error {
+	val, err := rlp.EncodeToBytes(revision)
+	if err != nil {
+		return err
+	}
+	return batch.Put([]byte(dbRevision), val, nil)
+}
+
+/*
+commitMutation writes the batch of an object mutation with the next ids and, in a session, the undo
+entry of obj. The undo state only takes the mutation once the batch is written
+*/
+func (ldb *LDataBase) commitMutation(batch *dbBatch, op uint8, obj interface{}) error {
+	if err := putIncrement(batch, ldb.nextId); err != nil {
+		return err
+	}
+	stack := ldb.getStack()
+	if stack == nil {
+		return batch.commit()
+	}
+	copy_ := cloneInterface(obj)
+	if err := putUndoEntry(batch, stack, op, copy_); err != nil {
+		return err
+	}
+	if err := batch.commit(); err != nil {
+		return err
+	}
+	stack.apply(op, copy_)
+	return nil
+}
+
+/*
+loadUndo reads the revision and the undo stack written by a previous run
+*/
+func (ldb *LDataBase) loadUndo() error {
+	val, err := ldb.db.Get([]byte(dbRevision), nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return err
+	}
+	if err == nil {
+		if err = rlp.DecodeBytes(val, &ldb.revision); err != nil {
+			return err
+		}
+	}
+
+	states, err := readUndoStates(ldb.db, 0, math.MaxInt64)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		ldb.stack.Append(state)
+	}
+	return nil
+}
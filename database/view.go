@@ -0,0 +1,57 @@
+package database
+
+import (
+	"github.com/eosspark/eos-go/crypto/rlp"
+)
+
+/*
+dbView reads a snapshot of the store through the read functions of an LDataBase,
+nothing the view is given can write to the snapshot
+*/
+type dbView struct {
+	ldb *LDataBase
+}
+
+/*
+View takes a read only view of the database as it is now, the objects modified afterwards keep
+their old content in the view. Each object mutation is written at once so the view never holds
+half an object, it is up to the caller to take it at a revision that is consistent for it
+
+@return
+success 			-->		view, Release it once done
+error 				-->		error
+*/
+func (ldb *LDataBase) View() (DataBaseView, error) {
+	store, err := snapshotOf(ldb.db)
+	if err != nil {
+		return nil, err
+	}
+
+	// the revision is read from the snapshot, the database may have moved on already
+	var revision int64
+	if val, err := store.Get([]byte(dbRevision), nil); err == nil {
+		if err = rlp.DecodeBytes(val, &revision); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+
+	view := &LDataBase{db: store, stack: newDeque(), path: ldb.path, revision: revision, nextId: make(map[string]int64)}
+	return &dbView{ldb: view}, nil
+}
+
+func (v *dbView) Find(tagName string, in interface{}, out interface{}) error {
+	return v.ldb.Find(tagName, in, out)
+}
+
+func (v *dbView) GetIndex(tagName string, in interface{}) (*multiIndex, error) {
+	return getIndex(tagName, in, v.ldb)
+}
+
+func (v *dbView) Revision() int64 {
+	return v.ldb.revision
+}
+
+func (v *dbView) Release() {
+	v.ldb.db.Close()
+}
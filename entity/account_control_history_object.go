@@ -0,0 +1,13 @@
+package entity
+
+import (
+	"github.com/eosspark/eos-go/common"
+)
+
+// the fields of an index are keyed in declaration order, ID comes last to make byControlling unique
+type AccountControlHistoryObject struct {
+	ControlledAccount    common.AccountName    `multiIndex:"byControlledAuthority,orderedNonUnique"`
+	ControlledPermission common.PermissionName `multiIndex:"byControlledAuthority,orderedNonUnique"`
+	ControllingAccount   common.AccountName    `multiIndex:"byControlling,orderedNonUnique:byControlledAuthority,orderedNonUnique"`
+	ID                   common.IdType         `multiIndex:"id,increment,byControlling"`
+}
@@ -5,19 +5,19 @@ import (
 )
 
 type AccountHistoryObject struct {
-	ID                 common.IdType 		`multiIndex:"id,increment"`
-	Account            common.AccountName	`multiIndex:"byAccountActionSeq,orderedNonUnique"`
+	ID                 common.IdType      `multiIndex:"id,increment"`
+	Account            common.AccountName `multiIndex:"byAccountActionSeq,orderedNonUnique"`
 	ActionSequenceNum  uint64
-	AccountSequenceNum int32				`multiIndex:"byAccountActionSeq,orderedNonUnique"`
+	AccountSequenceNum int32 `multiIndex:"byAccountActionSeq,orderedNonUnique"`
 }
 
 type ActionHistoryObject struct {
-	ID                common.IdType			   `multiIndex:"id,increment"`
-	ActionSequenceNum uint64        		   `multiIndex:"byActionSequenceNum,orderedUnique:byTrxId,orderedUnique"`
+	ID                common.IdType            `multiIndex:"id,increment"`
+	TrxId             common.TransactionIdType `multiIndex:"byTrxId,orderedNonUnique"`
+	ActionSequenceNum uint64                   `multiIndex:"byActionSequenceNum,orderedUnique:byTrxId,orderedNonUnique"`
 	PackedActionTrace common.HexBytes
 	BlockNum          uint32
 	BlockTime         common.BlockTimeStamp
-	TrxId             common.TransactionIdType `multiIndex:"byTrxId,orderedUnique"`
 }
 
 //type FilterEntry struct {
@@ -5,7 +5,6 @@ import (
 	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
 	"github.com/eosspark/eos-go/crypto"
-	"github.com/eosspark/eos-go/crypto/rlp"
 	"github.com/eosspark/eos-go/exception"
 )
 
@@ -32,18 +31,16 @@ type AccountSequenceObject struct {
 }
 
 func (a *AccountObject) SetAbi(ad types.AbiDef) {
-	d, _ := rlp.EncodeToBytes(ad)
+	d, _ := types.EncodeAbiDef(&ad)
 	a.Abi = d
 }
 
 func (a *AccountObject) GetAbi() types.AbiDef {
-	abiDef := types.AbiDef{}
-	if len(a.Abi) != 0 {
-		exception.EosAssert(len(a.Abi) != 0, &exception.AbiNotFoundException{}, "No ABI set on account :", a.Name)
-	}
-	err := rlp.DecodeBytes(a.Abi, abiDef)
+	exception.EosAssert(len(a.Abi) != 0, &exception.AbiNotFoundException{}, "No ABI set on account %s", a.Name)
+	abiDef, err := types.DecodeAbiDef(a.Abi)
 	if err != nil {
 		fmt.Println("account_object GetAbi DecodeBytes is error:", err.Error())
+		return types.AbiDef{}
 	}
-	return abiDef
+	return *abiDef
 }
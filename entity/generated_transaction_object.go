@@ -8,13 +8,13 @@ import (
 )
 
 type GeneratedTransactionObject struct {
-	Id         common.IdType            `multiIndex:"id,increment,byExpiration,byDelay"`
+	Id         common.IdType            `multiIndex:"id,increment"`
 	TrxId      common.TransactionIdType `multiIndex:"byTrxId,orderedUnique"`
 	Sender     common.AccountName       `multiIndex:"bySenderId,orderedUnique"`
 	SenderId   arithmetic.Uint128       `multiIndex:"bySenderId,orderedUnique"`
 	Payer      common.AccountName
-	DelayUntil common.TimePoint         `multiIndex:"byDelay,orderedUnique"`
-	Expiration common.TimePoint         `multiIndex:"byExpiration,orderedUnique"`
+	DelayUntil common.TimePoint         `multiIndex:"byDelay,orderedNonUnique"`
+	Expiration common.TimePoint         `multiIndex:"byExpiration,orderedNonUnique"`
 	Published  common.TimePoint
 	PackedTrx  common.HexBytes //c++ shared_string
 }
@@ -5,9 +5,11 @@ import (
 	"github.com/eosspark/eos-go/crypto/ecc"
 )
 
+// the fields of an index are keyed in declaration order, so ID comes last to only make
+// byAccountPermission unique, as publicKey+id and accountPermission+id in c++
 type PublicKeyHistoryObject struct {
-	ID         common.IdType         `multiIndex:"id,increment,byPubKey,byAccountPermission"`
-	PublicKey  ecc.PublicKey         `multiIndex:"byPubKey,orderedNonUnique"`                      //c++ publicKey+id unique
-	Name       common.AccountName    `multiIndex:"byAccountPermission,orderedNonUnique"` //c++ ByAccountPermission+id unique
-	Permission common.PermissionName `multiIndex:"byAccountPermission,orderedNonUnique"` //c++ ByAccountPermission+id unique
+	PublicKey  ecc.PublicKey         `multiIndex:"byPubKey,orderedNonUnique"`
+	Name       common.AccountName    `multiIndex:"byPubKey,orderedNonUnique:byAccountPermission,orderedNonUnique"`
+	Permission common.PermissionName `multiIndex:"byPubKey,orderedNonUnique:byAccountPermission,orderedNonUnique"`
+	ID         common.IdType         `multiIndex:"id,increment,byAccountPermission"`
 }
@@ -23,6 +23,6 @@ func (rbo *ReversibleBlockObject) SetBlock(b *types.SignedBlock) {
 
 func (rbo *ReversibleBlockObject) GetBlock() *types.SignedBlock {
 	result := types.SignedBlock{}
-	rlp.DecodeBytes(rbo.PackedBlock, result)
+	rlp.DecodeBytes(rbo.PackedBlock, &result)
 	return &result
 }
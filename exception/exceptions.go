@@ -38,6 +38,7 @@ func (ChainException) What() string     { return "blockchain exception" }
  *   |- resource_limit_exception		 >3210000
  *   |- mongo_db_exception 				 >3220000
  *   |- contract_api_exception  		 >3230000
+ *   |- snapshot_exception  			 >3240000
  */
 
 /**
@@ -234,3 +235,11 @@ type ContractApiExceptions interface {
 	ChainExceptions
 	ContractApiExceptions()
 }
+
+/**
+ * snapshot_exception
+ */
+type SnapshotExceptions interface {
+	ChainExceptions
+	SnapshotExceptions()
+}
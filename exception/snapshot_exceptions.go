@@ -0,0 +1,15 @@
+package exception
+
+type SnapshotException struct{ logMessage }
+
+func (SnapshotException) ChainExceptions()    {}
+func (SnapshotException) SnapshotExceptions() {}
+func (SnapshotException) Code() ExcTypes      { return 3240000 }
+func (SnapshotException) What() string        { return "Snapshot exception" }
+
+type SnapshotValidationException struct{ logMessage }
+
+func (SnapshotValidationException) ChainExceptions()    {}
+func (SnapshotValidationException) SnapshotExceptions() {}
+func (SnapshotValidationException) Code() ExcTypes      { return 3240001 }
+func (SnapshotValidationException) What() string        { return "Snapshot Validation Exception" }
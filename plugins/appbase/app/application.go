@@ -1,11 +1,12 @@
 package app
 
 import (
-	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
-	"gopkg.in/urfave/cli.v1"
-	. "github.com/eosspark/eos-go/exception"
 	"fmt"
+	. "github.com/eosspark/eos-go/exception"
 	"github.com/eosspark/eos-go/exception/try"
+	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	"github.com/eosspark/eos-go/plugins/appbase/asio"
+	"gopkg.in/urfave/cli.v1"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -26,7 +27,7 @@ type application struct {
 	Plugins            map[string]Plugin //< all registered plugins
 	initializedPlugins []Plugin          //< stored in the order they were started running
 	runningPlugins     []Plugin          //<  stored in the order they were started running
-
+	ioServ             *asio.IoContext   //< main loop, work that must not race block production is posted to it
 }
 
 //app public methods
@@ -43,7 +44,7 @@ type application struct {
 
 var appImpl = &applicationImpl{Version, cli.NewApp(), "", ""}
 
-var App *application = &application{appImpl, make(map[string]Plugin), make([]Plugin, 0), make([]Plugin, 0)}
+var App *application = &application{appImpl, make(map[string]Plugin), make([]Plugin, 0), make([]Plugin, 0), asio.NewIoContext()}
 
 func (app *application) RegisterPlugin(plugin Plugin) Plugin {
 	if p, existing := app.Plugins[plugin.GetName()]; existing {
@@ -97,7 +98,7 @@ func (app *application) Initialize(basicPlugin []string) bool {
 	var AbstractPlugins []Plugin
 	for i := 0; i < len(basicPlugin); i++ {
 		if p := FindPlugin(basicPlugin[i]); p != nil {
-			AbstractPlugins = append(AbstractPlugins, p)
+			AbstractPlugins = append(AbstractPlugins, *p)
 		}
 	}
 
@@ -106,7 +107,11 @@ func (app *application) Initialize(basicPlugin []string) bool {
 
 func (app *application) InitializeImpl(a []Plugin) (r bool) {
 	setProgramOptions()
+	for i := 0; i < len(a); i++ {
+		a[i].SetProgramOptions()
+	}
 
+	parsed := false
 	app.My.Options.Action = func(c *cli.Context) error {
 		if c.String("data-dir") != "" {
 			app.My.DateDir = homeDir() + c.String("data-dir")
@@ -114,15 +119,20 @@ func (app *application) InitializeImpl(a []Plugin) (r bool) {
 		if c.String("config-dir") != "" {
 			app.My.ConfigDir = homeDir() + c.String("config-dir")
 		}
-
+		parsed = true
 		return nil
 	}
 
+	if err := app.My.Options.Run(os.Args); err != nil || !parsed {
+		return false
+	}
+
 	defer try.HandleReturn()
 	try.Try(func() {
 		for i := 0; i < len(a); i++ {
 			if a[i].GetState() == Registered {
 				a[i].Initialize(app.My.Options)
+				app.initializedPlugins = append(app.initializedPlugins, a[i])
 			}
 		}
 	}).Catch(func(e Exception) {
@@ -136,25 +146,41 @@ func (app *application) InitializeImpl(a []Plugin) (r bool) {
 }
 
 func (app *application) StartUp() {
-	for _, v := range app.Plugins {
-		v.PluginStartUp()
+	for _, v := range app.initializedPlugins {
+		v.StartUp()
+		app.runningPlugins = append(app.runningPlugins, v)
 	}
 }
 
 func (app *application) ShutDown() {
-	for _, v := range app.Plugins {
-		v.PluginShutDown()
+	for i := len(app.runningPlugins) - 1; i >= 0; i-- {
+		app.runningPlugins[i].ShutDown()
 	}
 	app.runningPlugins = app.runningPlugins[:0]
-	app.runningPlugins = app.initializedPlugins[:0]
+	app.initializedPlugins = app.initializedPlugins[:0]
 
-	for k, v := range app.Plugins {
-		v.PluginShutDown()
+	for k := range app.Plugins {
 		delete(app.Plugins, k)
 	}
 
 }
 
+// GetIoService is the main loop of the application, see: appbase application::get_io_service
+func (app *application) GetIoService() *asio.IoContext {
+	return app.ioServ
+}
+
+// Exec runs the main loop until it is stopped, then shuts the plugins down
+func (app *application) Exec() {
+	app.ioServ.Run()
+	app.ShutDown()
+}
+
+// Quit stops the main loop, Exec returns once the running handler is done
+func (app *application) Quit() {
+	app.ioServ.Stop()
+}
+
 func FindPlugin(name string) (plugin *Plugin) {
 	if v, ok := App.Plugins[name]; ok {
 		return &v
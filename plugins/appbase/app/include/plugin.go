@@ -2,7 +2,6 @@ package include
 
 import (
 	"gopkg.in/urfave/cli.v1"
-	"github.com/eosspark/eos-go/plugins/appbase/app"
 )
 
 /** these notifications get called from the plugin when their state changes so that
@@ -16,10 +15,11 @@ type Plugin interface {
 	PluginStartUp()
 	PluginShutDown()
 
-
 	GetName() string
 	GetState() State
 	Initialize(options *cli.App)
+	StartUp()
+	ShutDown()
 }
 
 type State int
@@ -37,13 +37,26 @@ type AbstractPlugin struct {
 	State State
 }
 
+// Initialize runs PluginInitialize of the concrete plugin once, the application keeps
+// track of the initialized order itself so include never has to import app
 func (a *AbstractPlugin) Initialize(options *cli.App) {
-
-
 	if a.State == Registered {
-		a.PluginInitialize()
+		a.State = Initialized
+		a.Plugin.PluginInitialize()
+	}
+}
 
-		app.App.PluginInitialized(a.Plugin)
+func (a *AbstractPlugin) StartUp() {
+	if a.State == Initialized {
+		a.State = Started
+		a.Plugin.PluginStartUp()
+	}
+}
+
+func (a *AbstractPlugin) ShutDown() {
+	if a.State == Started {
+		a.State = Stopped
+		a.Plugin.PluginShutDown()
 	}
 }
 
@@ -54,5 +67,3 @@ func (a *AbstractPlugin) GetName() string {
 func (a *AbstractPlugin) GetState() State {
 	return a.State
 }
-
-
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"github.com/eosspark/eos-go/exception/try"
+	. "github.com/eosspark/eos-go/plugins/appbase/app"
+	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	_ "github.com/eosspark/eos-go/plugins/appbase/plugin/chain_plugin"
+	_ "github.com/eosspark/eos-go/plugins/appbase/plugin/http_plugin"
 	_ "github.com/eosspark/eos-go/plugins/appbase/plugin/net_plugin"
 	_ "github.com/eosspark/eos-go/plugins/appbase/plugin/producer_plugin"
-	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
-	. "github.com/eosspark/eos-go/plugins/appbase/app"
-	"github.com/eosspark/eos-go/exception/try"
+	_ "github.com/eosspark/eos-go/plugins/chain_api_plugin"
+	_ "github.com/eosspark/eos-go/plugins/history_api_plugin"
+	_ "github.com/eosspark/eos-go/plugins/history_plugin"
 	"os"
 	"os/signal"
 )
@@ -27,9 +32,7 @@ var (
 	//Age int
 )
 
-
-var basicPlugin  = []string{"ProducerPlugin","ChainPlugin","NetPlugin","HttpPlugin"}
-
+var basicPlugin = []string{"ProducerPlugin", "ChainPlugin", "NetPlugin", "HttpPlugin", "ChainApiPlugin", "NetApiPlugin", "HistoryPlugin", "HistoryApiPlugin"}
 
 //var pro producer_plugin.Producer_plugin
 //var net net_plugin.Net_plugin
@@ -37,23 +40,23 @@ var basicPlugin  = []string{"ProducerPlugin","ChainPlugin","NetPlugin","HttpPlug
 func main() {
 	defer try.HandleReturn()
 	try.Try(func() {
-			App.SetVersion(Version)
-			App.SetDefaultDataDir()
-			App.SetDefaultConfigDir()
-			App.My.Options.Run(os.Args)
-
-			if !App.Initialize(basicPlugin) {
-				try.Return()
-			}
-			App.StartUp()
-
-			sigChan := make(chan os.Signal, 1)
-			signal.Notify(sigChan, os.Interrupt)
-			select {
-				case <-sigChan:
-					App.ShutDown()
-			}
-		}).Catch(func() {
-
-		}).End()
+		App.SetVersion(Version)
+		App.SetDefaultDataDir()
+		App.SetDefaultConfigDir()
+
+		if !App.Initialize(basicPlugin) {
+			try.Return()
+		}
+		App.StartUp()
+
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, os.Interrupt)
+		go func() {
+			<-sigChan
+			App.Quit()
+		}()
+		App.Exec()
+	}).Catch(func() {
+
+	}).End()
 }
@@ -1,32 +1,176 @@
 package chain_plugin
 
-import  (
-	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/database"
+	"github.com/eosspark/eos-go/entity"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/log"
 	"github.com/eosspark/eos-go/plugins/appbase/app"
+	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	"gopkg.in/urfave/cli.v1"
 )
 
 type ChainPlugin struct {
 	AbstractPlugin
+	replay          bool
+	hardReplay      bool
+	truncateAtBlock uint
+	snapshot        string
+
+	replayProgressInterval uint
+
+	stateSizeMb           uint
+	stateGuardSizeMb      uint
+	reversibleSizeMb      uint
+	reversibleGuardSizeMb uint
+	stateHistoryBlocks    uint
+	wasmCodeCacheSize     uint
+	wasmInstructionLimit  uint64
 }
 
 func init() {
 	var chainPlugin = new(ChainPlugin)
-	//chainPlugin.Plugin = chainPlugin
+	chainPlugin.Plugin = chainPlugin
 	chainPlugin.Name = "ChainPlugin"
 	chainPlugin.State = Registered
 	app.App.RegisterPlugin(chainPlugin)
 }
 
-func (chainPlugin *ChainPlugin)SetProgramOptions() {
-
+func (chainPlugin *ChainPlugin) SetProgramOptions() {
+	app.App.My.Options.Flags = append(app.App.My.Options.Flags,
+		cli.BoolFlag{
+			Name:        "replay-blockchain",
+			Usage:       "clear chain state database and replay all blocks",
+			Destination: &chainPlugin.replay,
+		},
+		cli.BoolFlag{
+			Name:        "hard-replay-blockchain",
+			Usage:       "clear chain state database, recover as many blocks as possible from the block log, and then replay those blocks",
+			Destination: &chainPlugin.hardReplay,
+		},
+		cli.UintFlag{
+			Name:        "truncate-at-block",
+			Usage:       "stop hard replay / block log recovery at this block number (if set to non-zero number)",
+			Destination: &chainPlugin.truncateAtBlock,
+		},
+		cli.UintFlag{
+			Name:        "replay-progress-interval",
+			Usage:       "Number of blocks between two progress reports while the block log is replayed",
+			Value:       uint(chain.ReplayProgressInterval),
+			Destination: &chainPlugin.replayProgressInterval,
+		},
+		cli.UintFlag{
+			Name:        "chain-state-db-size-mb",
+			Usage:       "Maximum size (in MiB) of the chain state database",
+			Value:       uint(common.DefaultConfig.DefaultStateSize / 1024 / 1024),
+			Destination: &chainPlugin.stateSizeMb,
+		},
+		cli.UintFlag{
+			Name:        "chain-state-db-guard-size-mb",
+			Usage:       "Safely shut down node when free space remaining in the chain state database drops below this size (in MiB).",
+			Value:       uint(common.DefaultConfig.DefaultStateGuardSize / 1024 / 1024),
+			Destination: &chainPlugin.stateGuardSizeMb,
+		},
+		cli.UintFlag{
+			Name:        "reversible-blocks-db-size-mb",
+			Usage:       "Maximum size (in MiB) of the reversible blocks database",
+			Value:       uint(common.DefaultConfig.DefaultReversibleCacheSize / 1024 / 1024),
+			Destination: &chainPlugin.reversibleSizeMb,
+		},
+		cli.UintFlag{
+			Name:        "reversible-blocks-db-guard-size-mb",
+			Usage:       "Safely shut down node when free space remaining in the reversible blocks database drops below this size (in MiB).",
+			Value:       uint(common.DefaultConfig.DefaultReversibleGuardSize / 1024 / 1024),
+			Destination: &chainPlugin.reversibleGuardSizeMb,
+		},
+		cli.UintFlag{
+			Name:        "state-history-blocks",
+			Usage:       "Number of irreversible blocks whose state the chain API can still query with block_num (0 keeps none)",
+			Destination: &chainPlugin.stateHistoryBlocks,
+		},
+		cli.UintFlag{
+			Name:        "wasm-code-cache-size",
+			Usage:       "Number of compiled contracts kept in memory, the least recently used are compiled again",
+			Value:       uint(chain.WasmCodeCacheSize),
+			Destination: &chainPlugin.wasmCodeCacheSize,
+		},
+		cli.Uint64Flag{
+			Name:        "wasm-instruction-limit",
+			Usage:       "Number of wasm instructions an action may execute (0 leaves them unmetered, only the deadline applies)",
+			Destination: &chainPlugin.wasmInstructionLimit,
+		},
+		cli.StringFlag{
+			Name:        "snapshot",
+			Usage:       "File to read Snapshot State from",
+			Destination: &chainPlugin.snapshot,
+		},
+		cli.StringFlag{
+			Name:        "snapshots-dir",
+			Usage:       "the location of the snapshots directory written by create_snapshot",
+			Value:       common.DefaultConfig.DefaultSnapshotsDirName,
+			Destination: &common.DefaultConfig.DefaultSnapshotsDirName,
+		},
+	)
 }
+
+// PluginInitialize sets the database sizes and prepares the data directories for a replay, the controller replays the block log
+// or loads the snapshot when it is created and finds no fork database
 func (chainPlugin *ChainPlugin) PluginInitialize() {
+	blocksDir := common.DefaultConfig.DefaultBlocksDirName
+	common.DefaultConfig.DefaultStateSize = uint64(chainPlugin.stateSizeMb) * 1024 * 1024
+	common.DefaultConfig.DefaultStateGuardSize = uint64(chainPlugin.stateGuardSizeMb) * 1024 * 1024
+	common.DefaultConfig.DefaultReversibleCacheSize = uint64(chainPlugin.reversibleSizeMb) * 1024 * 1024
+	common.DefaultConfig.DefaultReversibleGuardSize = uint64(chainPlugin.reversibleGuardSizeMb) * 1024 * 1024
+	chain.StateHistoryBlocks = uint32(chainPlugin.stateHistoryBlocks)
+	chain.WasmCodeCacheSize = uint32(chainPlugin.wasmCodeCacheSize)
+	chain.WasmInstructionLimit = chainPlugin.wasmInstructionLimit
+	EosAssert(chainPlugin.replayProgressInterval > 0, &PluginConfigException{}, "--replay-progress-interval must be greater than 0")
+	chain.ReplayProgressInterval = uint32(chainPlugin.replayProgressInterval)
 
+	if chainPlugin.snapshot != "" {
+		EosAssert(!chainPlugin.replay && !chainPlugin.hardReplay, &PluginConfigException{},
+			"--snapshot is incompatible with --replay-blockchain and --hard-replay-blockchain")
+		_, err := os.Stat(chainPlugin.snapshot)
+		EosAssert(err == nil, &PluginConfigException{}, "Cannot load snapshot, %s does not exist", chainPlugin.snapshot)
+		states, _ := filepath.Glob(filepath.Join(common.DefaultConfig.DefaultStateDirName, "*"))
+		_, err = os.Stat(filepath.Join(blocksDir, common.DefaultConfig.ForkDBName))
+		EosAssert(len(states) == 0 && os.IsNotExist(err), &PluginConfigException{},
+			"Snapshot can only be used to initialize an empty database, remove %s and %s",
+			common.DefaultConfig.DefaultStateDirName, filepath.Join(blocksDir, common.DefaultConfig.ForkDBName))
+		chain.SnapshotFile = chainPlugin.snapshot
+	} else if chainPlugin.hardReplay {
+		log.Info("Hard replay requested: deleting state database")
+		clearDirectoryContents(common.DefaultConfig.DefaultStateDirName)
+		backupDir := chain.RepairLog(blocksDir, uint32(chainPlugin.truncateAtBlock))
+		reversibleDir := filepath.Clean(common.DefaultConfig.DefaultReversibleBlocksDirName)
+		if _, err := os.Stat(reversibleDir); err == nil {
+			reversibleBackup := filepath.Join(backupDir, filepath.Base(reversibleDir))
+			err = os.Rename(reversibleDir, reversibleBackup)
+			EosAssert(err == nil, &PluginConfigException{}, "unable to move reversible blocks directory to '%s': %s", reversibleBackup, err)
+			recoverReversibleBlocks(reversibleBackup, reversibleDir, uint32(chainPlugin.truncateAtBlock))
+		}
+		log.Info(fmt.Sprintf("backup of the blocks and reversible blocks directories and the fork database are left in '%s'", backupDir))
+	} else if chainPlugin.replay {
+		log.Info("Replay requested: deleting state database")
+		if chainPlugin.truncateAtBlock > 0 {
+			log.Warn("The --truncate-at-block option does not work for a regular replay of the blockchain.")
+		}
+		clearDirectoryContents(common.DefaultConfig.DefaultStateDirName)
+		os.Remove(filepath.Join(blocksDir, common.DefaultConfig.ForkDBName))
+	} else if chainPlugin.truncateAtBlock > 0 {
+		log.Warn("The --truncate-at-block option can only be used with --hard-replay-blockchain.")
+	}
 }
 func (chainPlugin *ChainPlugin) PluginStartUp() {
 
 }
-func (chainPlugin *ChainPlugin)PluginShutDown() {
+func (chainPlugin *ChainPlugin) PluginShutDown() {
 
 }
 
@@ -36,4 +180,73 @@ func (chainPlugin *ChainPlugin) GetName() string {
 func (chainPlugin *ChainPlugin) GetState() State {
 	return chainPlugin.State
 
-}
\ No newline at end of file
+}
+
+func clearDirectoryContents(dir string) {
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		if err := os.RemoveAll(name); err != nil {
+			log.Warn("remove file is error,detail:", err)
+		}
+	}
+}
+
+// recoverReversibleBlocks copies the reversible blocks of the database in backupDir to a new database in dir. The copy
+// stops at the first gap in the block numbers or after truncateAtBlock, if that is set
+func recoverReversibleBlocks(backupDir string, dir string, truncateAtBlock uint32) {
+	log.Info(fmt.Sprintf("Reconstructing '%s' from backed up reversible directory", dir))
+	oldReversible, err := database.NewDataBase(backupDir)
+	EosAssert(err == nil, &PluginConfigException{}, "unable to open reversible blocks database in '%s': %s", backupDir, err)
+	defer oldReversible.Close()
+	newReversible, err := database.NewDataBase(dir)
+	EosAssert(err == nil, &PluginConfigException{}, "unable to create reversible blocks database in '%s': %s", dir, err)
+	defer newReversible.Close()
+
+	idx, err := oldReversible.GetIndex("byNum", entity.ReversibleBlockObject{})
+	EosAssert(err == nil, &PluginConfigException{}, "unable to read reversible blocks database in '%s': %s", backupDir, err)
+	it := idx.BeginIterator()
+	defer it.Release()
+
+	var num, start, end uint32
+	for it.Next() {
+		obj := entity.ReversibleBlockObject{}
+		if err := it.Data(&obj); err != nil {
+			log.Warn("read reversible block is error,detail:", err)
+			break
+		}
+		if num == 0 {
+			start, end = obj.BlockNum, obj.BlockNum-1
+			if truncateAtBlock > 0 && start > truncateAtBlock {
+				log.Info(fmt.Sprintf("Did not recover any reversible blocks since the specified block number to stop at (%d) "+
+					"is less than first block in the reversible database (%d).", truncateAtBlock, start))
+				return
+			}
+		}
+		if obj.BlockNum != end+1 {
+			log.Warn(fmt.Sprintf("gap in reversible block database between %d and %d", end, obj.BlockNum))
+			break
+		}
+		ubo := entity.ReversibleBlockObject{BlockNum: obj.BlockNum}
+		// decoding and encoding the block rather than copying the packed data validates it
+		ubo.SetBlock(obj.GetBlock())
+		if err := newReversible.Insert(&ubo); err != nil {
+			log.Warn("insert reversible block is error,detail:", err)
+			break
+		}
+		end = obj.BlockNum
+		num++
+		if end == truncateAtBlock {
+			log.Info(fmt.Sprintf("Stopped recovery of reversible blocks early at specified block number: %d", truncateAtBlock))
+			break
+		}
+	}
+
+	if num == 0 {
+		log.Info("There were no recoverable blocks in the reversible block database")
+	} else {
+		log.Info(fmt.Sprintf("Recovered %d blocks from reversible block database: blocks %d to %d", num, start, end))
+	}
+}
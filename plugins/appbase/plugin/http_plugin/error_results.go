@@ -0,0 +1,115 @@
+package http_plugin
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/log"
+)
+
+// see: plugins/http_plugin/include/eosio/http_plugin/http_plugin.hpp error_results
+
+const maxLimitedMessage = 256
+
+// verboseHttpErrors is set from the verbose-http-errors option, error details carry the whole
+// log message when it is on
+var verboseHttpErrors = false
+
+type ErrorResults struct {
+	Code    uint16    `json:"code"`
+	Message string    `json:"message"`
+	Error   ErrorInfo `json:"error"`
+}
+
+type ErrorInfo struct {
+	Code    int64         `json:"code"`
+	Name    string        `json:"name"`
+	What    string        `json:"what"`
+	Details []ErrorDetail `json:"details"`
+}
+
+type ErrorDetail struct {
+	Message    string `json:"message"`
+	File       string `json:"file"`
+	LineNumber uint64 `json:"line_number"`
+	Method     string `json:"method"`
+}
+
+func newErrorResults(code int, message string, info ErrorInfo) ErrorResults {
+	return ErrorResults{Code: uint16(code), Message: message, Error: info}
+}
+
+func newErrorInfo(e Exception, includeFullLog bool) ErrorInfo {
+	message := e.Message()
+	if !includeFullLog && len(message) > maxLimitedMessage {
+		message = message[:maxLimitedMessage] + "..."
+	}
+	return ErrorInfo{
+		Code:    int64(e.Code()),
+		Name:    ExceptionName(e),
+		What:    e.What(),
+		Details: []ErrorDetail{{Message: message}},
+	}
+}
+
+// newUnspecifiedErrorInfo describes an error that did not come from an Exception, the same
+// way upstream wraps it into a plain fc::exception
+func newUnspecifiedErrorInfo(message string) ErrorInfo {
+	return ErrorInfo{
+		Code:    int64(UnspecifiedExceptionCode),
+		Name:    "exception",
+		What:    "unspecified",
+		Details: []ErrorDetail{{Message: message}},
+	}
+}
+
+// HandleException answers cb with the upstream error object for e, it is meant to be called
+// from the Catch of an api call
+func HandleException(e interface{}, apiName string, callName string, body string, cb UrlResponseCallback) {
+	switch exc := e.(type) {
+	case *UnknownBlockException:
+		cb(http.StatusBadRequest, newErrorResults(http.StatusBadRequest, "Unknown Block", newErrorInfo(exc, verboseHttpErrors)))
+	case *UnsatisfiedAuthorization:
+		cb(http.StatusUnauthorized, newErrorResults(http.StatusUnauthorized, "UnAuthorized", newErrorInfo(exc, verboseHttpErrors)))
+	case *TxDuplicate:
+		cb(http.StatusConflict, newErrorResults(http.StatusConflict, "Conflict", newErrorInfo(exc, verboseHttpErrors)))
+	case *EofException:
+		cb(http.StatusUnprocessableEntity, newErrorResults(http.StatusUnprocessableEntity, "Unprocessable Entity", newErrorInfo(exc, verboseHttpErrors)))
+		log.Error("Unable to parse arguments to " + apiName + "." + callName)
+		log.Debug("Bad arguments:", body)
+	case Exception:
+		cb(http.StatusInternalServerError, newErrorResults(http.StatusInternalServerError, "Internal Service Error", newErrorInfo(exc, verboseHttpErrors)))
+		log.Error("Exception encountered while processing "+apiName+"."+callName+",detail:", exc.Message())
+		log.Debug("Exception Details:", exc.Message())
+	case error:
+		cb(http.StatusInternalServerError, newErrorResults(http.StatusInternalServerError, "Internal Service Error", newUnspecifiedErrorInfo(exc.Error())))
+		log.Error("STD Exception encountered while processing "+apiName+"."+callName+",detail:", exc.Error())
+	default:
+		cb(http.StatusInternalServerError, newErrorResults(http.StatusInternalServerError, "Internal Service Error", newUnspecifiedErrorInfo("Unknown Exception")))
+		log.Error("Unknown Exception encountered while processing "+apiName+"."+callName+",detail:", fmt.Sprint(e))
+	}
+}
+
+// ExceptionName turns the exception type into its upstream name, AbiNotFoundException is abi_not_found_exception
+func ExceptionName(e Exception) string {
+	t := reflect.TypeOf(e)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var name strings.Builder
+	for i, r := range t.Name() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				name.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		name.WriteRune(r)
+	}
+	return name.String()
+}
@@ -1,36 +1,150 @@
 package http_plugin
 
 import (
-	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/eosspark/eos-go/log"
 	"github.com/eosspark/eos-go/plugins/appbase/app"
+	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	"gopkg.in/urfave/cli.v1"
 )
 
+// UrlResponseCallback sends the status code and the body of a response, body is written as json
+type UrlResponseCallback func(code int, body interface{})
+
+// UrlHandler serves a request made to one url, source is the url and body the raw request body
+type UrlHandler func(source string, body string, cb UrlResponseCallback)
+
+// ApiDescription maps urls to their handlers
+type ApiDescription map[string]UrlHandler
 
 type HttpPlugin struct {
 	AbstractPlugin
+	my *httpPluginImpl
 }
 
-func init () {
-	var httpPlugin HttpPlugin
-	//httpPlugin.Plugin = httpPlugin
+var httpPlugin *HttpPlugin
+
+func init() {
+	httpPlugin = new(HttpPlugin)
+	httpPlugin.Plugin = httpPlugin
 	httpPlugin.State = Registered
 	httpPlugin.Name = "HttpPlugin"
-	app.App.RegisterPlugin(&httpPlugin)
+	httpPlugin.my = newHttpPluginImpl()
+	app.App.RegisterPlugin(httpPlugin)
+}
 
+func GetInstance() *HttpPlugin {
+	return httpPlugin
 }
 
-func (httpPlugin *HttpPlugin) SetProgramOptions() {
+// SetDefaults changes the default listen address and unix socket, it has to be called before
+// the program options are set, keosd uses it to listen on its own port
+func (httpPlugin *HttpPlugin) SetDefaults(listenStr string, unixSocketPath string) {
+	httpPlugin.my.ListenStr = listenStr
+	httpPlugin.my.UnixSocketPath = unixSocketPath
+}
 
+func (httpPlugin *HttpPlugin) SetProgramOptions() {
+	my := httpPlugin.my
+	app.App.My.Options.Flags = append(app.App.My.Options.Flags,
+		cli.StringFlag{
+			Name:        "http-server-address",
+			Usage:       "The local IP and port to listen for incoming http connections; set blank to disable.",
+			Value:       my.ListenStr,
+			Destination: &my.ListenStr,
+		},
+		cli.StringFlag{
+			Name:        "unix-socket-path",
+			Usage:       "The filename (relative to data-dir) to create a unix socket for HTTP RPC; set blank to disable.",
+			Value:       my.UnixSocketPath,
+			Destination: &my.UnixSocketPath,
+		},
+		cli.StringFlag{
+			Name:        "access-control-allow-origin",
+			Usage:       "Specify the Access-Control-Allow-Origin to be returned on each request.",
+			Destination: &my.AccessControlAllowOrigin,
+		},
+		cli.StringFlag{
+			Name:        "access-control-allow-headers",
+			Usage:       "Specify the Access-Control-Allow-Headers to be returned on each request.",
+			Destination: &my.AccessControlAllowHeaders,
+		},
+		cli.StringFlag{
+			Name:        "access-control-max-age",
+			Usage:       "Specify the Access-Control-Max-Age to be returned on each request.",
+			Destination: &my.AccessControlMaxAge,
+		},
+		cli.BoolFlag{
+			Name:        "access-control-allow-credentials",
+			Usage:       "Specify if Access-Control-Allow-Credentials: true should be returned on each request.",
+			Destination: &my.AccessControlAllowCredentials,
+		},
+		cli.Int64Flag{
+			Name:        "max-body-size",
+			Usage:       "The maximum body size in bytes allowed for incoming RPC requests",
+			Value:       my.MaxBodySize,
+			Destination: &my.MaxBodySize,
+		},
+		cli.Int64Flag{
+			Name:        "http-max-response-time-ms",
+			Usage:       "Maximum time for processing a request.",
+			Value:       my.MaxResponseTimeMs,
+			Destination: &my.MaxResponseTimeMs,
+		},
+		cli.BoolFlag{
+			Name:        "verbose-http-errors",
+			Usage:       "Append the error log to HTTP responses",
+			Destination: &my.VerboseHttpErrors,
+		},
+	)
 }
 
 func (httpPlugin *HttpPlugin) PluginInitialize() {
-
+	my := httpPlugin.my
+	if len(my.ListenStr) > 0 {
+		_, err := net.ResolveTCPAddr("tcp", my.ListenStr)
+		EosAssert(err == nil, &PluginConfigException{}, "failed to configure http to listen on %s: %s", my.ListenStr, err)
+	}
+	EosAssert(my.MaxBodySize > 0, &PluginConfigException{}, "max-body-size must be positive, got %d", my.MaxBodySize)
+	verboseHttpErrors = my.VerboseHttpErrors
 }
+
 func (httpPlugin *HttpPlugin) PluginStartUp() {
+	my := httpPlugin.my
+	if len(my.ListenStr) > 0 {
+		listener, err := net.Listen("tcp", my.ListenStr)
+		EosAssert(err == nil, &PluginConfigException{}, "http service failed to start: %s", err)
+		log.Info("start listening for http requests on " + my.ListenStr)
+		my.serve(listener)
+	}
 
+	if len(my.UnixSocketPath) > 0 {
+		my.removeUnixSocket()
+		listener, err := net.Listen("unix", my.UnixSocketPath)
+		EosAssert(err == nil, &PluginConfigException{}, "unix socket service failed to start: %s", err)
+		log.Info("start listening for http requests on unix socket " + my.UnixSocketPath)
+		my.serve(listener)
+	}
 }
-func (httpPlugin *HttpPlugin) PluginShutDown() {
 
+func (httpPlugin *HttpPlugin) PluginShutDown() {
+	my := httpPlugin.my
+	for _, server := range my.servers {
+		if err := server.Close(); err != nil {
+			log.Warn("http plugin shutdown is error,detail:", err)
+		}
+	}
+	my.servers = my.servers[:0]
+	if len(my.UnixSocketPath) > 0 {
+		my.removeUnixSocket()
+	}
 }
 
 func (httpPlugin *HttpPlugin) GetName() string {
@@ -39,4 +153,71 @@ func (httpPlugin *HttpPlugin) GetName() string {
 
 func (httpPlugin *HttpPlugin) GetState() State {
 	return httpPlugin.State
-}
\ No newline at end of file
+}
+
+// AddHandler registers handler for url, replacing any handler already there
+func (httpPlugin *HttpPlugin) AddHandler(url string, handler UrlHandler) {
+	log.Info("add api url: " + url)
+	httpPlugin.my.addHandler(url, httpPlugin.my.urlHandler(url, handler))
+}
+
+// AddHttpHandler registers a plain net/http handler for url, it still gets the cors headers and
+// the body limit of the plugin, the handler writes its response itself without the response time limit
+func (httpPlugin *HttpPlugin) AddHttpHandler(url string, handler http.Handler) {
+	log.Info("add api url: " + url)
+	httpPlugin.my.addHandler(url, handler)
+}
+
+func (httpPlugin *HttpPlugin) AddApi(api ApiDescription) {
+	for url, handler := range api {
+		httpPlugin.AddHandler(url, handler)
+	}
+}
+
+// CallApi answers with code and the result of api, exceptions become the upstream error object,
+// apiName is the plugin the call belongs to as in the CALL macro of the upstream api plugins
+func CallApi(apiName string, code int, api func(body string) interface{}) UrlHandler {
+	return func(source string, body string, cb UrlResponseCallback) {
+		try.Try(func() {
+			if len(strings.TrimSpace(body)) == 0 {
+				body = "{}"
+			}
+			result := api(body)
+			if s, ok := result.(string); ok {
+				result = mustMarshal(s) // a string answer is a json string, not the raw body
+			}
+			cb(code, result)
+		}).Catch(func(e interface{}) {
+			HandleException(e, apiName, path.Base(source), body, cb)
+		}).End()
+	}
+}
+
+// ParseParams decodes the json body of a call into params, numbers are kept as json.Number
+func ParseParams(body string, params interface{}) {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(params)
+	EosAssert(err == nil, &ParseErrorException{}, "Unable to parse valid input from POST body: %s", err)
+}
+
+// IsOnLoopback reports whether the plugin only listens on loopback addresses or a unix socket
+func (httpPlugin *HttpPlugin) IsOnLoopback() bool {
+	if len(httpPlugin.my.ListenStr) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(httpPlugin.my.ListenStr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Handler returns the http.Handler serving every registered url
+func (httpPlugin *HttpPlugin) Handler() http.Handler {
+	return httpPlugin.my
+}
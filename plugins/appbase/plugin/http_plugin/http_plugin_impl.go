@@ -0,0 +1,191 @@
+package http_plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/eosspark/eos-go/log"
+)
+
+const (
+	defaultListenStr         = "127.0.0.1:8888"
+	defaultMaxBodySize       = 1024 * 1024
+	defaultMaxResponseTimeMs = 30 // covers turning the result into json, not the api call itself
+)
+
+type httpPluginImpl struct {
+	ListenStr                     string
+	UnixSocketPath                string
+	AccessControlAllowOrigin      string
+	AccessControlAllowHeaders     string
+	AccessControlMaxAge           string
+	AccessControlAllowCredentials bool
+	MaxBodySize                   int64
+	MaxResponseTimeMs             int64
+	VerboseHttpErrors             bool
+
+	lock     sync.RWMutex
+	handlers map[string]http.Handler
+	servers  []*http.Server
+}
+
+func newHttpPluginImpl() *httpPluginImpl {
+	return &httpPluginImpl{
+		ListenStr:         defaultListenStr,
+		MaxBodySize:       defaultMaxBodySize,
+		MaxResponseTimeMs: defaultMaxResponseTimeMs,
+		handlers:          make(map[string]http.Handler),
+	}
+}
+
+func (my *httpPluginImpl) addHandler(url string, handler http.Handler) {
+	my.lock.Lock()
+	defer my.lock.Unlock()
+	my.handlers[url] = handler
+}
+
+func (my *httpPluginImpl) findHandler(url string) (http.Handler, bool) {
+	my.lock.RLock()
+	defer my.lock.RUnlock()
+	handler, ok := my.handlers[url]
+	return handler, ok
+}
+
+func (my *httpPluginImpl) serve(listener net.Listener) {
+	server := &http.Server{Handler: my}
+	my.servers = append(my.servers, server)
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("http service is error,detail:", err)
+		}
+	}()
+}
+
+func (my *httpPluginImpl) removeUnixSocket() {
+	if err := os.Remove(my.UnixSocketPath); err != nil && !os.IsNotExist(err) {
+		log.Warn("remove unix socket is error,detail:", err)
+	}
+}
+
+func (my *httpPluginImpl) addCorsHeaders(w http.ResponseWriter) {
+	header := w.Header()
+	if len(my.AccessControlAllowOrigin) > 0 {
+		header.Set("Access-Control-Allow-Origin", my.AccessControlAllowOrigin)
+	}
+	if len(my.AccessControlAllowHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", my.AccessControlAllowHeaders)
+	}
+	if len(my.AccessControlMaxAge) > 0 {
+		header.Set("Access-Control-Max-Age", my.AccessControlMaxAge)
+	}
+	if my.AccessControlAllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (my *httpPluginImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	my.addCorsHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	handler, ok := my.findHandler(r.URL.Path)
+	if !ok {
+		log.Warn("404 - not found: " + r.URL.Path)
+		writeResponse(w, http.StatusNotFound, newErrorResults(http.StatusNotFound, "Not Found",
+			newUnspecifiedErrorInfo("Unknown Endpoint")))
+		return
+	}
+
+	if r.ContentLength > my.MaxBodySize {
+		writeResponse(w, http.StatusRequestEntityTooLarge, newErrorResults(http.StatusRequestEntityTooLarge,
+			"Request Entity Too Large", newUnspecifiedErrorInfo(
+				fmt.Sprintf("request body of %d bytes exceeds max-body-size %d", r.ContentLength, my.MaxBodySize))))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, my.MaxBodySize)
+
+	my.serveHandler(w, r, handler)
+}
+
+// serveHandler runs handler, an exception that escapes it is answered as the api calls answer theirs
+func (my *httpPluginImpl) serveHandler(w http.ResponseWriter, r *http.Request, handler http.Handler) {
+	try.Try(func() {
+		handler.ServeHTTP(w, r)
+	}).Catch(func(e interface{}) {
+		HandleException(e, "http", r.URL.Path, "", func(code int, body interface{}) {
+			writeResponse(w, code, body)
+		})
+	}).End()
+}
+
+// urlHandler adapts an UrlHandler to net/http, the body is read under the max-body-size limit.
+// As in upstream http-max-response-time-ms limits the time taken to turn the result into json, the
+// call itself may take longer as push_transaction does while it waits for the main loop
+func (my *httpPluginImpl) urlHandler(url string, handler UrlHandler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		cb := func(code int, body interface{}) {
+			start := time.Now()
+			code, data := encodeResponse(code, body)
+			if my.MaxResponseTimeMs > 0 && time.Since(start) > time.Duration(my.MaxResponseTimeMs)*time.Millisecond {
+				log.Warn("http response timeout: " + url)
+				info := newErrorInfo(&TimeoutException{}, verboseHttpErrors)
+				info.Details = []ErrorDetail{{Message: "Response time exceeded"}}
+				code, data = encodeResponse(http.StatusInternalServerError, newErrorResults(http.StatusInternalServerError,
+					"Internal Service Error", info))
+			}
+			w.WriteHeader(code)
+			w.Write(data)
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			cb(http.StatusRequestEntityTooLarge, newErrorResults(http.StatusRequestEntityTooLarge,
+				"Request Entity Too Large", newUnspecifiedErrorInfo(err.Error())))
+			return
+		}
+		if r.Context().Err() != nil {
+			return // nobody waits for the answer any more
+		}
+		handler(url, string(body), cb)
+	}
+	return http.HandlerFunc(fn)
+}
+
+func writeResponse(w http.ResponseWriter, code int, body interface{}) {
+	code, data := encodeResponse(code, body)
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// encodeResponse turns body into json, a string or []byte body is json already
+func encodeResponse(code int, body interface{}) (int, []byte) {
+	switch b := body.(type) {
+	case string:
+		return code, []byte(b)
+	case []byte:
+		return code, b
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		log.Error("http response marshal is error,detail:", err)
+		return http.StatusInternalServerError, mustMarshal(newErrorResults(http.StatusInternalServerError,
+			"Internal Service Error", newUnspecifiedErrorInfo(err.Error())))
+	}
+	return code, data
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
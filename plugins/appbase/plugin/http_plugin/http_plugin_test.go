@@ -0,0 +1,160 @@
+package http_plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/stretchr/testify/assert"
+)
+
+func request(my *httpPluginImpl, method string, url string, body string) (*httptest.ResponseRecorder, ErrorResults) {
+	w := httptest.NewRecorder()
+	my.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+	result := ErrorResults{}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return w, result
+}
+
+func TestExceptionName(t *testing.T) {
+	assert.Equal(t, "abi_not_found_exception", ExceptionName(&AbiNotFoundException{}))
+	assert.Equal(t, "tx_duplicate", ExceptionName(&TxDuplicate{}))
+	assert.Equal(t, "unknown_block_exception", ExceptionName(&UnknownBlockException{}))
+}
+
+func TestUnknownEndpoint(t *testing.T) {
+	my := newHttpPluginImpl()
+	w, result := request(my, "POST", "/v1/chain/get_nothing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, uint16(404), result.Code)
+	assert.Equal(t, "Not Found", result.Message)
+	assert.Equal(t, "exception", result.Error.Name)
+	assert.Equal(t, "Unknown Endpoint", result.Error.Details[0].Message)
+}
+
+func TestCors(t *testing.T) {
+	my := newHttpPluginImpl()
+	my.AccessControlAllowOrigin = "*"
+	my.AccessControlAllowHeaders = "Content-Type"
+	my.AccessControlAllowCredentials = true
+
+	w, _ := request(my, "OPTIONS", "/v1/chain/get_info", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "", w.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestUrlHandler(t *testing.T) {
+	my := newHttpPluginImpl()
+	my.addHandler("/v1/test/echo", my.urlHandler("/v1/test/echo", func(source string, body string, cb UrlResponseCallback) {
+		cb(201, map[string]string{"source": source, "body": body})
+	}))
+	my.addHandler("/v1/test/fail", my.urlHandler("/v1/test/fail", func(source string, body string, cb UrlResponseCallback) {
+		EosThrow(&UnknownBlockException{}, "Could not find block: %s", body)
+	}))
+
+	w := httptest.NewRecorder()
+	my.ServeHTTP(w, httptest.NewRequest("POST", "/v1/test/echo", strings.NewReader(`{"a":1}`)))
+	assert.Equal(t, 201, w.Code)
+	assert.JSONEq(t, `{"source":"/v1/test/echo","body":"{\"a\":1}"}`, w.Body.String())
+
+	w, result := request(my, "POST", "/v1/test/fail", "100")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Unknown Block", result.Message)
+	assert.Equal(t, "unknown_block_exception", result.Error.Name)
+	assert.Equal(t, int64(UnknownBlockException{}.Code()), result.Error.Code)
+	assert.Equal(t, "Could not find block: 100", result.Error.Details[0].Message)
+
+	my.MaxBodySize = 4
+	w, result = request(my, "POST", "/v1/test/echo", `{"a":1}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, uint16(http.StatusRequestEntityTooLarge), result.Code)
+}
+
+// slowJson takes its time to turn into json
+type slowJson struct{}
+
+func (slowJson) MarshalJSON() ([]byte, error) {
+	time.Sleep(50 * time.Millisecond)
+	return []byte("{}"), nil
+}
+
+func TestResponseTimeout(t *testing.T) {
+	my := newHttpPluginImpl()
+	my.MaxResponseTimeMs = 10
+
+	// the limit is on the json of the result, not on the call that waits for it
+	my.addHandler("/v1/test/slow_call", my.urlHandler("/v1/test/slow_call", func(source string, body string, cb UrlResponseCallback) {
+		time.Sleep(50 * time.Millisecond)
+		cb(202, map[string]string{"transaction_id": "00"})
+	}))
+	w := httptest.NewRecorder()
+	my.ServeHTTP(w, httptest.NewRequest("POST", "/v1/test/slow_call", strings.NewReader("")))
+	assert.Equal(t, 202, w.Code)
+	assert.JSONEq(t, `{"transaction_id":"00"}`, w.Body.String())
+
+	my.addHandler("/v1/test/slow_json", my.urlHandler("/v1/test/slow_json", func(source string, body string, cb UrlResponseCallback) {
+		cb(200, slowJson{})
+	}))
+	w, result := request(my, "POST", "/v1/test/slow_json", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "timeout_exception", result.Error.Name)
+	assert.Equal(t, "Response time exceeded", result.Error.Details[0].Message)
+
+	my.MaxResponseTimeMs = 0
+	w, _ = request(my, "POST", "/v1/test/slow_json", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCallApi(t *testing.T) {
+	handler := CallApi("chain", 200, func(body string) interface{} {
+		params := struct {
+			BlockNumOrId string `json:"block_num_or_id"`
+		}{}
+		ParseParams(body, &params)
+		EosAssert(len(params.BlockNumOrId) > 0, &UnknownBlockException{}, "Could not find block: %s", params.BlockNumOrId)
+		return params
+	})
+
+	var code int
+	var result interface{}
+	cb := func(c int, body interface{}) { code, result = c, body }
+
+	handler("/v1/chain/get_block", `{"block_num_or_id":"100"}`, cb)
+	assert.Equal(t, 200, code)
+
+	handler("/v1/chain/get_block", "", cb)
+	assert.Equal(t, 400, code)
+	assert.Equal(t, "unknown_block_exception", result.(ErrorResults).Error.Name)
+
+	handler("/v1/chain/get_block", `{"block_num_or_id":`, cb)
+	assert.Equal(t, 500, code)
+	assert.Equal(t, "parse_error_exception", result.(ErrorResults).Error.Name)
+
+	CallApi("net", 201, func(body string) interface{} {
+		return "added connection"
+	})("/v1/net/connect", `"127.0.0.1:9876"`, cb)
+	assert.Equal(t, 201, code)
+	assert.Equal(t, []byte(`"added connection"`), result)
+}
+
+func TestHandleException(t *testing.T) {
+	var code int
+	var body interface{}
+	cb := func(c int, b interface{}) { code, body = c, b }
+
+	HandleException(&TxDuplicate{}, "chain", "push_transaction", "", cb)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "Conflict", body.(ErrorResults).Message)
+
+	HandleException("boom", "chain", "push_transaction", "", cb)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "Unknown Exception", body.(ErrorResults).Error.Details[0].Message)
+}
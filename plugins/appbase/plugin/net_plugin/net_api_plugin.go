@@ -0,0 +1,78 @@
+package net_plugin
+
+import (
+	"encoding/json"
+
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/log"
+	"github.com/eosspark/eos-go/plugins/appbase/app"
+	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	"github.com/eosspark/eos-go/plugins/appbase/plugin/http_plugin"
+)
+
+const (
+	netFuncBase    string = "/v1/net"
+	netConnect     string = netFuncBase + "/connect"
+	netDisconnect  string = netFuncBase + "/disconnect"
+	netStatus      string = netFuncBase + "/status"
+	netConnections string = netFuncBase + "/connections"
+)
+
+type NetApiPlugin struct {
+	AbstractPlugin
+}
+
+var netApiPlugin *NetApiPlugin
+
+func init() {
+	netApiPlugin = new(NetApiPlugin)
+	netApiPlugin.Plugin = netApiPlugin
+	netApiPlugin.Name = "NetApiPlugin"
+	netApiPlugin.State = Registered
+	app.App.RegisterPlugin(netApiPlugin)
+}
+
+func (n *NetApiPlugin) SetProgramOptions() {}
+
+func (n *NetApiPlugin) PluginInitialize() {
+	if !http_plugin.GetInstance().IsOnLoopback() {
+		log.Warn("\n" +
+			"**********SECURITY WARNING**********\n" +
+			"*                                  *\n" +
+			"* --         Net API            -- *\n" +
+			"* - EXPOSED to the LOCAL NETWORK - *\n" +
+			"* - USE ONLY ON SECURE NETWORKS! - *\n" +
+			"*                                  *\n" +
+			"************************************\n")
+	}
+}
+
+// PluginStartUp mounts the /v1/net endpoints of the net plugin on the http plugin
+func (n *NetApiPlugin) PluginStartUp() {
+	log.Info("starting net_api_plugin")
+	np := GetInstance()
+	http_plugin.GetInstance().AddApi(http_plugin.ApiDescription{
+		netConnect: http_plugin.CallApi("net", 201, func(body string) interface{} {
+			return np.connect(parseHost(body))
+		}),
+		netDisconnect: http_plugin.CallApi("net", 201, func(body string) interface{} {
+			return np.disconnect(parseHost(body))
+		}),
+		netStatus: http_plugin.CallApi("net", 201, func(body string) interface{} {
+			return np.status(parseHost(body))
+		}),
+		netConnections: http_plugin.CallApi("net", 201, func(body string) interface{} {
+			return np.connections()
+		}),
+	})
+}
+
+func (n *NetApiPlugin) PluginShutDown() {}
+
+// parseHost reads the json string "host:port" the net endpoints take as argument
+func parseHost(body string) string {
+	var host string
+	err := json.Unmarshal([]byte(body), &host)
+	EosAssert(err == nil, &ParseErrorException{}, "Unable to parse valid input from POST body: %s", err)
+	return host
+}
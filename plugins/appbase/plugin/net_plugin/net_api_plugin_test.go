@@ -0,0 +1,59 @@
+package net_plugin
+
+import (
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eosspark/eos-go/plugins/appbase/app"
+	"github.com/eosspark/eos-go/plugins/appbase/plugin/http_plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func request(t *testing.T, url string, body string, result interface{}) int {
+	w := httptest.NewRecorder()
+	http_plugin.GetInstance().Handler().ServeHTTP(w, httptest.NewRequest("POST", url, strings.NewReader(body)))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result), w.Body.String())
+	return w.Code
+}
+
+func TestNetApiPlugin(t *testing.T) {
+	_, registered := app.App.Plugins["NetApiPlugin"]
+	assert.True(t, registered)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	host := `"` + listener.Addr().String() + `"`
+
+	netApiPlugin.PluginStartUp()
+	defer netPlugin.PluginShutDown()
+
+	var message string
+	assert.Equal(t, 201, request(t, netConnect, host, &message))
+	assert.Equal(t, "added connection", message)
+	assert.Equal(t, 201, request(t, netConnect, host, &message))
+	assert.Equal(t, "already connected", message)
+
+	status := &ConnectionStatus{}
+	assert.Equal(t, 201, request(t, netStatus, host, &status))
+	assert.Equal(t, listener.Addr().String(), status.Peer)
+
+	var connections []ConnectionStatus
+	assert.Equal(t, 201, request(t, netConnections, "", &connections))
+	assert.Equal(t, []ConnectionStatus{{Peer: listener.Addr().String()}}, connections)
+
+	assert.Equal(t, 201, request(t, netDisconnect, host, &message))
+	assert.Equal(t, "connection removed", message)
+	assert.Equal(t, 201, request(t, netDisconnect, host, &message))
+	assert.Equal(t, "no known connection for host", message)
+
+	assert.Equal(t, 201, request(t, netStatus, host, &status))
+	assert.Nil(t, status)
+
+	failed := http_plugin.ErrorResults{}
+	assert.Equal(t, 500, request(t, netConnect, `{"host":`, &failed))
+	assert.Equal(t, "parse_error_exception", failed.Error.Name)
+}
@@ -3,11 +3,24 @@ package net_plugin
 import (
 	"github.com/eosspark/eos-go/plugins/appbase/app"
 	"fmt"
+	"net"
+	"sort"
+	"sync"
 	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
 )
 
 type NetPlugin struct {
 	AbstractPlugin
+
+	mu    sync.Mutex
+	peers map[string]net.Conn //< connections opened through connect, by host:port
+}
+
+// ConnectionStatus is the state of a connection as net_api_plugin reports it
+type ConnectionStatus struct {
+	Peer       string `json:"peer"`
+	Connecting bool   `json:"connecting"`
+	Syncing    bool   `json:"syncing"`
 }
 
 var netPlugin *NetPlugin
@@ -16,6 +29,7 @@ func init() {
 	netPlugin = new(NetPlugin)
 	netPlugin.Plugin = netPlugin
 	netPlugin.Name = "NetPlugin"
+	netPlugin.peers = make(map[string]net.Conn)
 	netPlugin.State = Registered
 	app.App.RegisterPlugin(netPlugin)
 }
@@ -50,9 +64,68 @@ func (netPlugin *NetPlugin) PluginStartUp() {
 
 func (netPlugin *NetPlugin) PluginShutDown() {
 	loop = false
+	netPlugin.mu.Lock()
+	for host, conn := range netPlugin.peers {
+		conn.Close()
+		delete(netPlugin.peers, host)
+	}
+	netPlugin.mu.Unlock()
 	fmt.Println("NetPlugin PluginShutDown")
 }
 
+// GetInstance is the net plugin of the node, the one net_api_plugin serves
+func GetInstance() *NetPlugin {
+	return netPlugin
+}
+
+func (netPlugin *NetPlugin) connect(host string) string {
+	netPlugin.mu.Lock()
+	defer netPlugin.mu.Unlock()
+	if _, ok := netPlugin.peers[host]; ok {
+		return "already connected"
+	}
+
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		return err.Error()
+	}
+	netPlugin.peers[host] = conn
+	return "added connection"
+}
+
+func (netPlugin *NetPlugin) disconnect(host string) string {
+	netPlugin.mu.Lock()
+	defer netPlugin.mu.Unlock()
+	conn, ok := netPlugin.peers[host]
+	if !ok {
+		return "no known connection for host"
+	}
+	conn.Close()
+	delete(netPlugin.peers, host)
+	return "connection removed"
+}
+
+// status is the state of the connection to host, nil when there is none
+func (netPlugin *NetPlugin) status(host string) *ConnectionStatus {
+	netPlugin.mu.Lock()
+	defer netPlugin.mu.Unlock()
+	if _, ok := netPlugin.peers[host]; !ok {
+		return nil
+	}
+	return &ConnectionStatus{Peer: host}
+}
+
+func (netPlugin *NetPlugin) connections() []ConnectionStatus {
+	netPlugin.mu.Lock()
+	defer netPlugin.mu.Unlock()
+	result := make([]ConnectionStatus, 0, len(netPlugin.peers))
+	for host := range netPlugin.peers {
+		result = append(result, ConnectionStatus{Peer: host})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Peer < result[j].Peer })
+	return result
+}
+
 //var Net = NewNet_Plugin()
 
 //func NewNet_Plugin() *NetPlugin {
@@ -0,0 +1,153 @@
+package chain_api_plugin
+
+import (
+	"github.com/eosspark/eos-go/log"
+	"github.com/eosspark/eos-go/plugins/appbase/app"
+	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	"github.com/eosspark/eos-go/plugins/appbase/plugin/http_plugin"
+	"github.com/eosspark/eos-go/plugins/chain_plugin"
+)
+
+const (
+	chainFuncBase           string = "/v1/chain"
+	getInfoFunc             string = chainFuncBase + "/get_info"
+	getBlockFunc            string = chainFuncBase + "/get_block"
+	getBlockHeaderStateFunc string = chainFuncBase + "/get_block_header_state"
+	getAccountFunc          string = chainFuncBase + "/get_account"
+	getCodeFunc             string = chainFuncBase + "/get_code"
+	getAbiFunc              string = chainFuncBase + "/get_abi"
+	getRawCodeAndAbiFunc    string = chainFuncBase + "/get_raw_code_and_abi"
+	getTableFunc            string = chainFuncBase + "/get_table_rows"
+	getCurrencyBalanceFunc  string = chainFuncBase + "/get_currency_balance"
+	getCurrencyStatsFunc    string = chainFuncBase + "/get_currency_stats"
+	getProducersFunc        string = chainFuncBase + "/get_producers"
+	getScheduleFunc         string = chainFuncBase + "/get_producer_schedule"
+	getRequiredKeysFunc     string = chainFuncBase + "/get_required_keys"
+	jsonToBinFunc           string = chainFuncBase + "/abi_json_to_bin"
+	binToJsonFunc           string = chainFuncBase + "/abi_bin_to_json"
+	pushTxnFunc             string = chainFuncBase + "/push_transaction"
+	pushTxnsFunc            string = chainFuncBase + "/push_transactions"
+
+	// there is no producer_api_plugin, the snapshot is written by the chain plugin
+	producerFuncBase   string = "/v1/producer"
+	createSnapshotFunc string = producerFuncBase + "/create_snapshot"
+)
+
+type ChainApiPlugin struct {
+	AbstractPlugin
+}
+
+var chainApiPlugin *ChainApiPlugin
+
+func init() {
+	chainApiPlugin = new(ChainApiPlugin)
+	chainApiPlugin.Plugin = chainApiPlugin
+	chainApiPlugin.Name = "ChainApiPlugin"
+	chainApiPlugin.State = Registered
+	app.App.RegisterPlugin(chainApiPlugin)
+}
+
+func (c *ChainApiPlugin) SetProgramOptions() {}
+
+func (c *ChainApiPlugin) PluginInitialize() {}
+
+// PluginStartUp mounts every /v1/chain endpoint of the chain plugin on the http plugin
+func (c *ChainApiPlugin) PluginStartUp() {
+	log.Info("starting chain_api_plugin")
+	chain := chain_plugin.GetInstance()
+	ro := chain.GetReadOnlyApi()
+	rw := chain.GetReadWriteApi()
+
+	http_plugin.GetInstance().AddApi(http_plugin.ApiDescription{
+		getInfoFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetInfoParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetInfo(params)
+		}),
+		getBlockFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetBlockParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetBlock(params)
+		}),
+		getBlockHeaderStateFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetBlockParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetBlockHeaderState(params)
+		}),
+		getAccountFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetAccountParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetAccount(params)
+		}),
+		getCodeFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetCodeParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetCode(params)
+		}),
+		getAbiFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetAbiParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetAbi(params)
+		}),
+		getRawCodeAndAbiFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetRawCodeAndAbiParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetRawCodeAndAbi(params)
+		}),
+		getTableFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.NewGetTableRowsParams()
+			http_plugin.ParseParams(body, &params)
+			return ro.GetTableRows(params)
+		}),
+		getCurrencyBalanceFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetCurrencyBalanceParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetCurrencyBalance(params)
+		}),
+		getCurrencyStatsFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetCurrencyStatsParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetCurrencyStats(params)
+		}),
+		getProducersFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.NewGetProducersParams()
+			http_plugin.ParseParams(body, &params)
+			return ro.GetProducers(params)
+		}),
+		getScheduleFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetProducerScheduleParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetProducerSchedule(params)
+		}),
+		getRequiredKeysFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.GetRequiredKeysParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetRequiredKeys(params)
+		}),
+		jsonToBinFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.AbiJsonToBinParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.AbiJsonToBin(params)
+		}),
+		binToJsonFunc: http_plugin.CallApi("chain", 200, func(body string) interface{} {
+			params := chain_plugin.AbiBinToJsonParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.AbiBinToJson(params)
+		}),
+		pushTxnFunc: http_plugin.CallApi("chain", 202, func(body string) interface{} {
+			params := chain_plugin.PushTransactionParams{}
+			http_plugin.ParseParams(body, &params)
+			return rw.PushTransaction(params)
+		}),
+		pushTxnsFunc: http_plugin.CallApi("chain", 202, func(body string) interface{} {
+			params := chain_plugin.PushTransactionsParams{}
+			http_plugin.ParseParams(body, &params)
+			return rw.PushTransactions(params)
+		}),
+		createSnapshotFunc: http_plugin.CallApi("producer", 201, func(body string) interface{} {
+			return rw.CreateSnapshot()
+		}),
+	})
+}
+
+func (c *ChainApiPlugin) PluginShutDown() {}
@@ -0,0 +1,227 @@
+package chain_api_plugin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto"
+	"github.com/eosspark/eos-go/crypto/ecc"
+	"github.com/eosspark/eos-go/entity"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/plugins/appbase/app"
+	"github.com/eosspark/eos-go/plugins/appbase/plugin/http_plugin"
+	"github.com/eosspark/eos-go/plugins/chain_plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func request(t *testing.T, url string, body string, result interface{}) int {
+	w := httptest.NewRecorder()
+	http_plugin.GetInstance().Handler().ServeHTTP(w, httptest.NewRequest("POST", url, strings.NewReader(body)))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), result), w.Body.String())
+	return w.Code
+}
+
+// newTestContract creates the account tester with some code, an abi with the table owners and the rows
+// alice, bob, carol and dave in the scope 1 of that table, in the block following the genesis block
+func newTestContract(t *testing.T, control *chain.Controller, code []byte) {
+	key, err := ecc.NewPrivateKey("5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3")
+	assert.NoError(t, err)
+
+	tester := common.AccountName(common.N("tester"))
+	control.StartBlock(control.Head.Header.Timestamp+1, 0)
+	control.CreateNativeAccount(tester, types.Authority{Threshold: 1}, types.Authority{Threshold: 1}, false)
+
+	account := entity.AccountObject{Name: tester}
+	assert.NoError(t, control.DB.Find("byName", account, &account))
+	assert.NoError(t, control.DB.Modify(&account, func(a *entity.AccountObject) {
+		digest := sha256.Sum256(code)
+		a.Code = code
+		a.CodeVersion = *crypto.NewSha256Byte(digest[:])
+		a.SetAbi(types.AbiDef{
+			Version: "eosio::abi/1.0",
+			Structs: []types.StructDef{{Name: "owner", Fields: []types.FieldDef{{Name: "owner", Type: "name"}}}},
+			Tables:  []types.TableDef{{Name: common.TableName(common.N("owners")), IndexType: "i64", Type: "owner"}},
+		})
+	}))
+
+	owners := []string{"alice", "bob", "carol", "dave"}
+	tab := entity.TableIdObject{Code: tester, Scope: 1, Table: common.TableName(common.N("owners")), Payer: tester, Count: uint32(len(owners))}
+	assert.NoError(t, control.DB.Insert(&tab))
+	for _, owner := range owners {
+		value := make([]byte, 8)
+		binary.LittleEndian.PutUint64(value, common.N(owner))
+		assert.NoError(t, control.DB.Insert(&entity.KeyValueObject{TId: tab.ID, PrimaryKey: common.N(owner), Payer: tester, Value: value}))
+	}
+
+	control.FinalizeBlock()
+	control.SignBlock(func(digest crypto.Sha256) ecc.Signature {
+		sig, _ := key.Sign(digest.Bytes())
+		return sig
+	})
+	control.CommitBlock(true)
+}
+
+func TestChainApi(t *testing.T) {
+	os.RemoveAll("/tmp/data")
+	defer os.RemoveAll("/tmp/data")
+
+	// push_transaction and create_snapshot run their work on the main loop, its reactor is made before it runs
+	io := app.App.GetIoService()
+	io.GetService()
+	go io.Run()
+	defer io.Stop()
+
+	control := chain.GetControllerInstance()
+	defer control.Close()
+	code := []byte("\x00asm\x01\x00\x00\x00")
+	newTestContract(t, control, code)
+	chainApiPlugin.PluginStartUp()
+
+	t.Run("get_info", func(t *testing.T) {
+		result := chain_plugin.GetInfoResult{}
+		assert.Equal(t, 200, request(t, getInfoFunc, "", &result))
+		assert.Equal(t, control.GetChainId(), result.ChainID)
+		assert.Equal(t, uint32(2), result.HeadBlockNum)
+		assert.Equal(t, control.HeadBlockId(), result.HeadBlockID)
+		assert.Equal(t, control.LastIrreversibleBlockNum(), result.LastIrreversibleBlockNum)
+		assert.Equal(t, control.GetMutableResourceLimitsManager().GetBlockCpuLimit(), result.BlockCpuLimit)
+	})
+
+	t.Run("get_account", func(t *testing.T) {
+		result := chain_plugin.GetAccountResult{}
+		assert.Equal(t, 200, request(t, getAccountFunc, `{"account_name":"tester"}`, &result))
+		assert.Equal(t, common.AccountName(common.N("tester")), result.AccountName)
+		assert.Equal(t, control.GetMutableResourceLimitsManager().GetAccountRamUsage(result.AccountName), result.RamUsage)
+		assert.True(t, result.RamUsage > 0)
+		assert.Equal(t, 2, len(result.Permissions))
+
+		failed := http_plugin.ErrorResults{}
+		assert.Equal(t, 500, request(t, getAccountFunc, `{"account_name":"tester","block_num":1}`, &failed))
+		assert.Equal(t, "account_query_exception", failed.Error.Name)
+	})
+
+	t.Run("get_code", func(t *testing.T) {
+		result := struct {
+			AccountName string `json:"account_name"`
+			Wasm        string `json:"wasm"`
+			CodeHash    string `json:"code_hash"`
+		}{}
+		assert.Equal(t, 200, request(t, getCodeFunc, `{"account_name":"tester","code_as_wasm":true}`, &result))
+		digest := sha256.Sum256(code)
+		assert.Equal(t, hex.EncodeToString(digest[:]), result.CodeHash)
+		assert.Equal(t, string(code), result.Wasm)
+
+		failed := http_plugin.ErrorResults{}
+		assert.Equal(t, 500, request(t, getCodeFunc, `{"account_name":"tester"}`, &failed))
+		assert.Equal(t, "unsupported_feature", failed.Error.Name)
+	})
+
+	t.Run("get_table_rows", func(t *testing.T) {
+		owners := func(body string) ([]string, bool) {
+			result := struct {
+				Rows []struct {
+					Owner string `json:"owner"`
+				} `json:"rows"`
+				More bool `json:"more"`
+			}{}
+			assert.Equal(t, 200, request(t, getTableFunc, body, &result), body)
+			names := make([]string, 0, len(result.Rows))
+			for _, row := range result.Rows {
+				names = append(names, row.Owner)
+			}
+			return names, result.More
+		}
+
+		rows, more := owners(`{"code":"tester","scope":"1","table":"owners","json":true}`)
+		assert.Equal(t, []string{"alice", "bob", "carol", "dave"}, rows)
+		assert.False(t, more)
+
+		rows, more = owners(`{"code":"tester","scope":"1","table":"owners","json":true,"limit":2}`)
+		assert.Equal(t, []string{"alice", "bob"}, rows)
+		assert.True(t, more)
+
+		rows, more = owners(`{"code":"tester","scope":"1","table":"owners","json":true,"lower_bound":"bob","upper_bound":"carol","key_type":"name"}`)
+		assert.Equal(t, []string{"bob", "carol"}, rows)
+		assert.False(t, more)
+
+		lower := strconv.FormatUint(common.N("carol"), 10)
+		rows, _ = owners(`{"code":"tester","scope":"1","table":"owners","json":true,"lower_bound":"` + lower + `","key_type":"i64"}`)
+		assert.Equal(t, []string{"carol", "dave"}, rows)
+		rows, _ = owners(`{"code":"tester","scope":"1","table":"owners","json":true,"lower_bound":"` + lower + `"}`)
+		assert.Equal(t, []string{"carol", "dave"}, rows)
+
+		// the scope is the number 1, not the name 1
+		rows, _ = owners(`{"code":"tester","scope":"` + strconv.FormatUint(common.N("1"), 10) + `","table":"owners","json":true}`)
+		assert.Equal(t, []string{}, rows)
+
+		hexRows := struct {
+			Rows []string `json:"rows"`
+		}{}
+		assert.Equal(t, 200, request(t, getTableFunc, `{"code":"tester","scope":"1","table":"owners","limit":1}`, &hexRows))
+		alice := make([]byte, 8)
+		binary.LittleEndian.PutUint64(alice, common.N("alice"))
+		assert.Equal(t, []string{hex.EncodeToString(alice)}, hexRows.Rows)
+
+		// the abi is read from the same block as the rows, tester had no abi yet in block 1
+		failed := http_plugin.ErrorResults{}
+		assert.Equal(t, 500, request(t, getTableFunc, `{"code":"tester","scope":"1","table":"owners","block_num":1}`, &failed))
+		assert.Equal(t, "abi_not_found_exception", failed.Error.Name)
+		rows, _ = owners(`{"code":"tester","scope":"1","table":"owners","json":true,"limit":1,"block_num":2}`)
+		assert.Equal(t, []string{"alice"}, rows)
+
+		assert.Equal(t, 500, request(t, getTableFunc, `{"code":"tester","scope":"1","table":"nothing"}`, &failed))
+		assert.Equal(t, "contract_table_query_exception", failed.Error.Name)
+		assert.Equal(t, 500, request(t, getTableFunc, `{"code":"tester","scope":"1","table":"owners","lower_bound":"bob","key_type":"i64"}`, &failed))
+		assert.Equal(t, "chain_type_exception", failed.Error.Name)
+	})
+
+	t.Run("push_transaction", func(t *testing.T) {
+		trx := `{"signatures":[],"compression":"none","packed_context_free_data":"","packed_trx":""}`
+
+		// without a producer the transaction goes to the pending block of the controller, there is none
+		failed := http_plugin.ErrorResults{}
+		assert.Equal(t, 500, request(t, pushTxnFunc, trx, &failed))
+		assert.Equal(t, "transaction_exception", failed.Error.Name)
+
+		assert.Equal(t, 500, request(t, pushTxnFunc, `{"packed_trx":`, &failed))
+		assert.Equal(t, "parse_error_exception", failed.Error.Name)
+
+		chainPlugin := chain_plugin.GetInstance()
+		defer func() {
+			chainPlugin.IncomingTransactionAsync = nil
+			chainApiPlugin.PluginStartUp()
+		}()
+		for _, c := range []struct {
+			result interface{}
+			code   int
+			name   string
+		}{
+			{&TxDuplicate{}, http.StatusConflict, "tx_duplicate"},
+			{&UnsatisfiedAuthorization{}, http.StatusUnauthorized, "unsatisfied_authorization"},
+			{&ExpiredTxException{}, http.StatusInternalServerError, "expired_tx_exception"},
+			{errors.New("rejected"), http.StatusInternalServerError, "transaction_exception"},
+		} {
+			result := c.result
+			chainPlugin.IncomingTransactionAsync = func(trx *types.PackedTransaction, persistUntilExpired bool, next func(interface{})) {
+				next(result)
+			}
+			chainApiPlugin.PluginStartUp()
+
+			failed := http_plugin.ErrorResults{}
+			assert.Equal(t, c.code, request(t, pushTxnFunc, trx, &failed))
+			assert.Equal(t, c.name, failed.Error.Name)
+		}
+	})
+}
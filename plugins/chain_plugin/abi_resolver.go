@@ -0,0 +1,122 @@
+package chain_plugin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+
+	Chain "github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/eosspark/eos-go/log"
+)
+
+// abiResolver converts the actions found in a variant between their binary and abi form,
+// the same job as abi_serializer::to_variant/from_variant with a resolver in upstream
+type abiResolver struct {
+	db                   *Chain.Controller
+	abiSerializerMaxTime common.Microseconds
+	cache                map[common.AccountName]*types.AbiSerializer
+}
+
+func newAbiResolver(db *Chain.Controller, abiSerializerMaxTime common.Microseconds) *abiResolver {
+	return &abiResolver{
+		db:                   db,
+		abiSerializerMaxTime: abiSerializerMaxTime,
+		cache:                make(map[common.AccountName]*types.AbiSerializer),
+	}
+}
+
+func (r *abiResolver) resolve(account common.AccountName) *types.AbiSerializer {
+	if abis, ok := r.cache[account]; ok {
+		return abis
+	}
+	abis := r.db.GetAbiSerializer(account, r.abiSerializerMaxTime)
+	r.cache[account] = abis
+	return abis
+}
+
+// toVariant turns obj into its json variant, the data of every action is replaced by the
+// decoded arguments and kept in hex_data
+func (r *abiResolver) toVariant(obj interface{}) interface{} {
+	data, err := json.Marshal(obj)
+	EosAssert(err == nil, &ParseErrorException{}, "Failed to convert %T to variant: %s", obj, err)
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&v)
+	EosAssert(err == nil, &ParseErrorException{}, "Failed to convert %T to variant: %s", obj, err)
+
+	r.walk(v, r.decodeAction)
+	return v
+}
+
+// fromVariant packs the action arguments given as objects so v can be read into chain types
+func (r *abiResolver) fromVariant(v interface{}) interface{} {
+	r.walk(v, r.encodeAction)
+	return v
+}
+
+func (r *abiResolver) walk(v interface{}, action func(obj map[string]interface{})) {
+	switch obj := v.(type) {
+	case map[string]interface{}:
+		_, hasData := obj["data"]
+		account, isAccount := obj["account"].(string)
+		name, isName := obj["name"].(string)
+		if hasData && isAccount && isName && len(account) != 0 && len(name) != 0 {
+			action(obj)
+			return
+		}
+		for _, value := range obj {
+			r.walk(value, action)
+		}
+	case []interface{}:
+		for _, value := range obj {
+			r.walk(value, action)
+		}
+	}
+}
+
+func (r *abiResolver) actionType(obj map[string]interface{}) (*types.AbiSerializer, types.TypeName) {
+	abis := r.resolve(common.AccountName(common.N(obj["account"].(string))))
+	if abis == nil {
+		return nil, ""
+	}
+	return abis, abis.GetActionType(common.ActionName(common.N(obj["name"].(string))))
+}
+
+func (r *abiResolver) decodeAction(obj map[string]interface{}) {
+	hexData, ok := obj["data"].(string)
+	if !ok {
+		return
+	}
+	abis, actionType := r.actionType(obj)
+	if len(actionType) == 0 {
+		return
+	}
+	binary, err := hex.DecodeString(hexData)
+	if err != nil {
+		return
+	}
+
+	try.Try(func() {
+		obj["data"] = abis.BinaryToVariant(actionType, binary, r.abiSerializerMaxTime)
+		obj["hex_data"] = hexData
+	}).Catch(func(e Exception) {
+		log.Warn("abiResolver decode action data is error,detail:", e.Message())
+	}).End()
+}
+
+func (r *abiResolver) encodeAction(obj map[string]interface{}) {
+	if _, ok := obj["data"].(string); ok {
+		return
+	}
+	abis, actionType := r.actionType(obj)
+	EosAssert(len(actionType) != 0, &InvalidActionArgsException{}, "Unable to find action %s in contract %s",
+		obj["name"], obj["account"])
+
+	obj["data"] = hex.EncodeToString(abis.VariantToBinary(actionType, obj["data"], r.abiSerializerMaxTime))
+}
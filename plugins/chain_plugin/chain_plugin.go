@@ -1,12 +1,26 @@
 package chain_plugin
 
-import "github.com/eosspark/eos-go/common"
+import (
+	Chain "github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/plugins/appbase/app"
+	"github.com/eosspark/eos-go/plugins/appbase/asio"
+)
 
 var IsActive bool = false
 var chain *ChainPlugin
 
+// IncomingTransactionFunc matches the producer's incoming transaction entry point, next receives
+// either the transaction trace or the exception/error that rejected the transaction
+type IncomingTransactionFunc func(trx *types.PackedTransaction, persistUntilExpired bool, next func(interface{}))
+
 type ChainPlugin struct {
 	AbiSerializerMaxTimeMs common.Microseconds
+
+	// IncomingTransactionAsync is installed by the producer plugin, when it is nil transactions
+	// are pushed straight into the pending block of the controller
+	IncomingTransactionAsync IncomingTransactionFunc
 }
 
 func GetInstance() *ChainPlugin {
@@ -30,3 +44,41 @@ func (chain *ChainPlugin) GetAbiSerializerMaxTime() common.Microseconds {
 func (chain *ChainPlugin) Init() {
 	chain.AbiSerializerMaxTimeMs = 1000 //TODO tmp value
 }
+
+func (chain *ChainPlugin) Chain() *Chain.Controller {
+	return Chain.GetControllerInstance()
+}
+
+func (chain *ChainPlugin) GetChainId() common.ChainIdType {
+	return chain.Chain().GetChainId()
+}
+
+func (chain *ChainPlugin) GetReadOnlyApi() *ReadOnly {
+	return NewReadOnly(chain.Chain(), chain.GetAbiSerializerMaxTime())
+}
+
+// GetReadWriteApi pushes the transactions from the main loop of the application so that they do not
+// race the blocks being produced or applied
+func (chain *ChainPlugin) GetReadWriteApi() *ReadWrite {
+	incoming := chain.IncomingTransactionAsync
+	if incoming == nil {
+		incoming = pushToController(chain.Chain())
+	}
+	io := app.App.GetIoService()
+	return NewReadWrite(chain.Chain(), chain.GetAbiSerializerMaxTime(), postTo(io, incoming), io)
+}
+
+// postTo runs incoming on io instead of the goroutine of the caller
+func postTo(io *asio.IoContext, incoming IncomingTransactionFunc) IncomingTransactionFunc {
+	return func(trx *types.PackedTransaction, persistUntilExpired bool, next func(interface{})) {
+		io.Post(func() {
+			incoming(trx, persistUntilExpired, next)
+		})
+	}
+}
+
+// ToVariantWithAbi turns obj into its json variant with the action data decoded by the abi of
+// each contract, as controller::to_variant_with_abi in upstream
+func (chain *ChainPlugin) ToVariantWithAbi(obj interface{}) interface{} {
+	return newAbiResolver(chain.Chain(), chain.GetAbiSerializerMaxTime()).toVariant(obj)
+}
@@ -0,0 +1,800 @@
+package chain_plugin
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	Chain "github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto"
+	"github.com/eosspark/eos-go/crypto/ecc"
+	"github.com/eosspark/eos-go/database"
+	"github.com/eosspark/eos-go/entity"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/eosspark/eos-go/plugins/appbase/app"
+)
+
+// see: plugins/chain_plugin/chain_plugin.cpp read_only
+
+const (
+	tableRowsQueryTime = 10 * 1000 // microseconds spent walking a table before answering with more=true
+	defaultTableLimit  = 10
+	defaultProducerMax = 50
+)
+
+type ReadOnly struct {
+	db                   *Chain.Controller
+	abiSerializerMaxTime common.Microseconds
+}
+
+func NewReadOnly(db *Chain.Controller, abiSerializerMaxTime common.Microseconds) *ReadOnly {
+	return &ReadOnly{db: db, abiSerializerMaxTime: abiSerializerMaxTime}
+}
+
+type GetInfoParams struct{}
+
+type GetInfoResult struct {
+	ServerVersion            string             `json:"server_version"`
+	ChainID                  common.ChainIdType `json:"chain_id"`
+	HeadBlockNum             uint32             `json:"head_block_num"`
+	LastIrreversibleBlockNum uint32             `json:"last_irreversible_block_num"`
+	LastIrreversibleBlockID  common.BlockIdType `json:"last_irreversible_block_id"`
+	HeadBlockID              common.BlockIdType `json:"head_block_id"`
+	HeadBlockTime            common.TimePoint   `json:"head_block_time"`
+	HeadBlockProducer        common.AccountName `json:"head_block_producer"`
+	VirtualBlockCpuLimit     uint64             `json:"virtual_block_cpu_limit"`
+	VirtualBlockNetLimit     uint64             `json:"virtual_block_net_limit"`
+	BlockCpuLimit            uint64             `json:"block_cpu_limit"`
+	BlockNetLimit            uint64             `json:"block_net_limit"`
+	ServerVersionString      string             `json:"server_version_string"`
+}
+
+func (ro *ReadOnly) GetInfo(params GetInfoParams) GetInfoResult {
+	db := ro.db.StateView()
+	defer db.Release()
+
+	rm := Chain.NewResourceLimitsView(db)
+	return GetInfoResult{
+		ServerVersion:            fmt.Sprintf("%08x", app.GetVersion()),
+		ChainID:                  ro.db.GetChainId(),
+		HeadBlockNum:             ro.db.HeadBlockNum(),
+		LastIrreversibleBlockNum: ro.db.LastIrreversibleBlockNum(),
+		LastIrreversibleBlockID:  ro.db.LastIrreversibleBlockId(),
+		HeadBlockID:              ro.db.HeadBlockId(),
+		HeadBlockTime:            ro.db.HeadBlockTime(),
+		HeadBlockProducer:        ro.db.HeadBlockProducer(),
+		VirtualBlockCpuLimit:     rm.GetVirtualBlockCpuLimit(),
+		VirtualBlockNetLimit:     rm.GetVirtualBlockNetLimit(),
+		BlockCpuLimit:            rm.GetBlockCpuLimit(),
+		BlockNetLimit:            rm.GetBlockNetLimit(),
+		ServerVersionString:      fmt.Sprintf("v%08x", app.GetVersion()),
+	}
+}
+
+type GetBlockParams struct {
+	BlockNumOrId string `json:"block_num_or_id"`
+}
+
+type GetBlockResult struct {
+	types.SignedBlockHeader
+	Transactions    []blockTransaction `json:"transactions"`
+	BlockExtensions []*types.Extension `json:"block_extensions"`
+	ID              common.BlockIdType `json:"id"`
+	BlockNum        uint32             `json:"block_num"`
+	RefBlockPrefix  uint32             `json:"ref_block_prefix"`
+}
+
+// blockTransaction is a transaction receipt whose trx is either a transaction id or a packed transaction
+type blockTransaction struct {
+	types.TransactionReceiptHeader
+	Trx interface{} `json:"trx"`
+}
+
+type packedTransactionVariant struct {
+	ID                    common.TransactionIdType `json:"id"`
+	Signatures            []ecc.Signature          `json:"signatures"`
+	Compression           common.CompressionType   `json:"compression"`
+	PackedContextFreeData common.HexBytes          `json:"packed_context_free_data"`
+	ContextFreeData       []common.HexBytes        `json:"context_free_data"`
+	PackedTrx             common.HexBytes          `json:"packed_trx"`
+	Transaction           *types.Transaction       `json:"transaction"`
+}
+
+// GetBlock returns the block with the action data of every transaction decoded through the contract abi
+func (ro *ReadOnly) GetBlock(params GetBlockParams) interface{} {
+	EosAssert(len(params.BlockNumOrId) != 0 && len(params.BlockNumOrId) <= 64, &BlockIdTypeException{},
+		"Invalid Block number or ID, must be greater than 0 and less than 64 characters")
+
+	var block *types.SignedBlock
+	if len(params.BlockNumOrId) == 64 {
+		block = ro.db.FetchBlockById(parseBlockId(params.BlockNumOrId))
+	} else {
+		num, err := strconv.ParseUint(params.BlockNumOrId, 10, 32)
+		EosAssert(err == nil, &BlockIdTypeException{}, "Invalid Block number: %s", params.BlockNumOrId)
+		block = ro.db.FetchBlockByNumber(uint32(num))
+	}
+	EosAssert(block != nil && block.BlockNumber() != 0, &UnknownBlockException{}, "Could not find block: %s", params.BlockNumOrId)
+
+	id := block.BlockID()
+	result := GetBlockResult{
+		SignedBlockHeader: block.SignedBlockHeader,
+		Transactions:      make([]blockTransaction, 0, len(block.Transactions)),
+		BlockExtensions:   block.BlockExtensions,
+		ID:                id,
+		BlockNum:          block.BlockNumber(),
+		RefBlockPrefix:    uint32(id.Hash[1]),
+	}
+	for _, receipt := range block.Transactions {
+		trx := blockTransaction{TransactionReceiptHeader: receipt.TransactionReceiptHeader}
+		if ptrx := receipt.Trx.PackedTransaction; ptrx != nil {
+			trx.Trx = packedTransactionVariant{
+				ID:                    ptrx.ID(),
+				Signatures:            ptrx.Signatures,
+				Compression:           ptrx.Compression,
+				PackedContextFreeData: ptrx.PackedContextFreeData,
+				ContextFreeData:       ptrx.GetContextFreeData(),
+				PackedTrx:             ptrx.PackedTrx,
+				Transaction:           ptrx.GetTransaction(),
+			}
+		} else {
+			trx.Trx = receipt.Trx.TransactionID
+		}
+		result.Transactions = append(result.Transactions, trx)
+	}
+
+	return ro.toVariantWithAbi(result)
+}
+
+func (ro *ReadOnly) GetBlockHeaderState(params GetBlockParams) *types.BlockHeaderState {
+	EosAssert(len(params.BlockNumOrId) != 0 && len(params.BlockNumOrId) <= 64, &BlockIdTypeException{},
+		"Invalid Block number or ID, must be greater than 0 and less than 64 characters")
+
+	var state *types.BlockState
+	if len(params.BlockNumOrId) == 64 {
+		state = ro.db.FetchBlockStateById(parseBlockId(params.BlockNumOrId))
+	} else {
+		num, err := strconv.ParseUint(params.BlockNumOrId, 10, 32)
+		EosAssert(err == nil, &BlockIdTypeException{}, "Invalid Block number: %s", params.BlockNumOrId)
+		state = ro.db.FetchBlockStateByNumber(uint32(num))
+	}
+	EosAssert(state != nil, &UnknownBlockException{}, "Could not find reversible block: %s", params.BlockNumOrId)
+
+	return &state.BlockHeaderState
+}
+
+type GetAccountParams struct {
+	AccountName        common.AccountName `json:"account_name"`
+	ExpectedCoreSymbol string             `json:"expected_core_symbol"`
+	BlockNum           uint32             `json:"block_num"` // a past block kept by --state-history-blocks, 0 is the head
+}
+
+type GetAccountResult struct {
+	AccountName       common.AccountName `json:"account_name"`
+	HeadBlockNum      uint32             `json:"head_block_num"`
+	HeadBlockTime     common.TimePoint   `json:"head_block_time"`
+	Privileged        bool               `json:"privileged"`
+	LastCodeUpdate    common.TimePoint   `json:"last_code_update"`
+	Created           common.TimePoint   `json:"created"`
+	CoreLiquidBalance *common.Asset      `json:"core_liquid_balance,omitempty"`
+
+	RamQuota  int64                      `json:"ram_quota"`
+	NetWeight int64                      `json:"net_weight"`
+	CpuWeight int64                      `json:"cpu_weight"`
+	NetLimit  Chain.AccountResourceLimit `json:"net_limit"`
+	CpuLimit  Chain.AccountResourceLimit `json:"cpu_limit"`
+	RamUsage  int64                      `json:"ram_usage"`
+
+	Permissions            []types.Permission `json:"permissions"`
+	TotalResources         interface{}        `json:"total_resources"`
+	SelfDelegatedBandwidth interface{}        `json:"self_delegated_bandwidth"`
+	RefundRequest          interface{}        `json:"refund_request"`
+	VoterInfo              interface{}        `json:"voter_info"`
+}
+
+func (ro *ReadOnly) GetAccount(params GetAccountParams) GetAccountResult {
+	db := ro.stateView(params.BlockNum)
+	defer db.Release()
+
+	result := GetAccountResult{
+		AccountName:   params.AccountName,
+		HeadBlockNum:  ro.db.HeadBlockNum(),
+		HeadBlockTime: ro.db.HeadBlockTime(),
+	}
+
+	account := entity.AccountObject{Name: params.AccountName}
+	err := db.Find("byName", account, &account)
+	EosAssert(err == nil, &AccountQueryException{}, "Fail to retrieve account for %s", params.AccountName)
+
+	result.Privileged = account.Privileged
+	result.LastCodeUpdate = account.LastCodeUpdate
+	result.Created = account.CreationDate.ToTimePoint()
+
+	rm := Chain.NewResourceLimitsView(db)
+	rm.GetAccountLimits(params.AccountName, &result.RamQuota, &result.NetWeight, &result.CpuWeight)
+	result.NetLimit = rm.GetAccountNetLimitEx(params.AccountName, true)
+	result.CpuLimit = rm.GetAccountCpuLimitEx(params.AccountName, true)
+	result.RamUsage = rm.GetAccountRamUsage(params.AccountName)
+
+	result.Permissions = ro.getPermissions(db, params.AccountName)
+
+	coreSymbol := params.ExpectedCoreSymbol
+	if len(coreSymbol) == 0 {
+		coreSymbol = common.EOSSymbol.Symbol
+	}
+	token := common.AccountName(common.N("eosio.token"))
+	if abis := ro.abiSerializer(db, token); abis != nil {
+		ro.walkTable(db, token, uint64(params.AccountName), common.TableName(common.N("accounts")), symbolCode(coreSymbol),
+			func(kv *entity.KeyValueObject) bool {
+				if kv.PrimaryKey == symbolCode(coreSymbol) {
+					balance, err := common.NewAsset(abis.BinaryToVariant("asset", kv.Value, ro.abiSerializerMaxTime).(string))
+					if err == nil {
+						result.CoreLiquidBalance = &balance
+					}
+				}
+				return false
+			})
+	}
+
+	system := common.AccountName(common.DefaultConfig.SystemAccountName)
+	if abis := ro.abiSerializer(db, system); abis != nil {
+		scope, key := uint64(params.AccountName), uint64(params.AccountName)
+		result.TotalResources = ro.getTableRow(db, abis, system, scope, common.TableName(common.N("userres")), key)
+		result.SelfDelegatedBandwidth = ro.getTableRow(db, abis, system, scope, common.TableName(common.N("delband")), key)
+		result.RefundRequest = ro.getTableRow(db, abis, system, scope, common.TableName(common.N("refunds")), key)
+		result.VoterInfo = ro.getTableRow(db, abis, system, uint64(system), common.TableName(common.N("voters")), key)
+	}
+
+	return result
+}
+
+func (ro *ReadOnly) getPermissions(db database.DataBaseView, owner common.AccountName) []types.Permission {
+	permissions := make([]types.Permission, 0)
+
+	perm := entity.PermissionObject{Owner: owner}
+	idx, err := db.GetIndex("byOwner", perm)
+	if err != nil {
+		return permissions
+	}
+	itr, err := idx.LowerBound(perm)
+	if err != nil {
+		return permissions
+	}
+	defer itr.Release()
+
+	for itr.Next() {
+		po := entity.PermissionObject{}
+		if itr.Data(&po) != nil || po.Owner != owner {
+			break
+		}
+
+		parent := ""
+		if po.Parent != 0 {
+			parentObj := entity.PermissionObject{ID: po.Parent}
+			if db.Find("id", parentObj, &parentObj) == nil {
+				parent = parentObj.Name.String()
+			}
+		}
+
+		permissions = append(permissions, types.Permission{
+			PermName: po.Name.String(),
+			Parent:   parent,
+			RequiredAuth: types.Authority{
+				Threshold: po.Auth.Threshold,
+				Keys:      po.Auth.Keys,
+				Accounts:  po.Auth.Accounts,
+				Waits:     po.Auth.Waits,
+			},
+		})
+	}
+	return permissions
+}
+
+type GetAbiParams struct {
+	AccountName common.AccountName `json:"account_name"`
+}
+
+type GetAbiResult struct {
+	AccountName common.AccountName `json:"account_name"`
+	Abi         *types.AbiDef      `json:"abi,omitempty"`
+}
+
+func (ro *ReadOnly) GetAbi(params GetAbiParams) GetAbiResult {
+	db := ro.db.StateView()
+	defer db.Release()
+
+	result := GetAbiResult{AccountName: params.AccountName}
+
+	account := ro.getAccountObject(db, params.AccountName)
+	if len(account.Abi) > 0 {
+		abi := account.GetAbi()
+		result.Abi = &abi
+	}
+	return result
+}
+
+type GetCodeParams struct {
+	AccountName common.AccountName `json:"account_name"`
+	CodeAsWasm  bool               `json:"code_as_wasm"`
+}
+
+type GetCodeResult struct {
+	AccountName common.AccountName `json:"account_name"`
+	Wast        string             `json:"wast"`
+	Wasm        string             `json:"wasm"`
+	CodeHash    crypto.Sha256      `json:"code_hash"`
+	Abi         *types.AbiDef      `json:"abi,omitempty"`
+}
+
+// GetCode returns the code as wasm, there is no wasm to wast printer so code_as_wasm must be set
+// as in upstream once wast was dropped
+func (ro *ReadOnly) GetCode(params GetCodeParams) GetCodeResult {
+	db := ro.db.StateView()
+	defer db.Release()
+
+	result := GetCodeResult{AccountName: params.AccountName}
+
+	account := ro.getAccountObject(db, params.AccountName)
+	if len(account.Code) > 0 {
+		EosAssert(params.CodeAsWasm, &UnsupportedFeature{}, "Returning WAST from get_code is not supported, set code_as_wasm")
+		result.Wasm = string(account.Code)
+		result.CodeHash = account.CodeVersion
+	}
+	if len(account.Abi) > 0 {
+		abi := account.GetAbi()
+		result.Abi = &abi
+	}
+	return result
+}
+
+type GetRawCodeAndAbiParams struct {
+	AccountName common.AccountName `json:"account_name"`
+}
+
+type GetRawCodeAndAbiResult struct {
+	AccountName common.AccountName `json:"account_name"`
+	Wasm        []byte             `json:"wasm"`
+	Abi         []byte             `json:"abi"`
+}
+
+func (ro *ReadOnly) GetRawCodeAndAbi(params GetRawCodeAndAbiParams) GetRawCodeAndAbiResult {
+	db := ro.db.StateView()
+	defer db.Release()
+
+	account := ro.getAccountObject(db, params.AccountName)
+	return GetRawCodeAndAbiResult{
+		AccountName: params.AccountName,
+		Wasm:        account.Code,
+		Abi:         account.Abi,
+	}
+}
+
+type GetTableRowsParams struct {
+	Json          bool               `json:"json"`
+	Code          common.AccountName `json:"code"`
+	Scope         string             `json:"scope"`
+	Table         common.TableName   `json:"table"`
+	TableKey      string             `json:"table_key"`
+	LowerBound    string             `json:"lower_bound"`
+	UpperBound    string             `json:"upper_bound"`
+	Limit         uint32             `json:"limit"`
+	KeyType       string             `json:"key_type"`
+	IndexPosition string             `json:"index_position"`
+	EncodeType    string             `json:"encode_type"`
+	BlockNum      uint32             `json:"block_num"` // a past block kept by --state-history-blocks, 0 is the head
+}
+
+func NewGetTableRowsParams() GetTableRowsParams {
+	return GetTableRowsParams{Limit: defaultTableLimit}
+}
+
+type GetTableRowsResult struct {
+	Rows []interface{} `json:"rows"`
+	More bool          `json:"more"`
+}
+
+// GetTableRows walks the primary index of a contract table, rows are returned as hex unless json is set.
+// The rows are read from a view of the state so they are consistent while blocks are being applied.
+// The chain keeps no secondary index yet, so index_position and key_type may only name the primary key
+func (ro *ReadOnly) GetTableRows(params GetTableRowsParams) GetTableRowsResult {
+	EosAssert(params.IndexPosition == "" || params.IndexPosition == "primary" || params.IndexPosition == "first" ||
+		params.IndexPosition == "1", &UnsupportedFeature{}, "Secondary index %s is not supported", params.IndexPosition)
+	EosAssert(params.KeyType == "" || params.KeyType == "i64" || params.KeyType == "name",
+		&UnsupportedFeature{}, "Key type %s is not supported by the primary index", params.KeyType)
+
+	db := ro.stateView(params.BlockNum)
+	defer db.Release()
+
+	abis := ro.abiSerializer(db, params.Code)
+	EosAssert(abis != nil, &AbiNotFoundException{}, "No ABI found for %s", params.Code)
+	tableType := abis.GetTableType(params.Table)
+	EosAssert(len(tableType) != 0, &ContractTableQueryException{}, "Table %s is not specified in the ABI", params.Table)
+
+	scope := convertToUint64(params.Scope, "scope")
+	lower := uint64(0)
+	if len(params.LowerBound) != 0 {
+		lower = convertToPrimaryKey(params.LowerBound, params.KeyType, "lower_bound")
+	}
+	upper := uint64(math.MaxUint64)
+	if len(params.UpperBound) != 0 {
+		upper = convertToPrimaryKey(params.UpperBound, params.KeyType, "upper_bound")
+	}
+
+	result := GetTableRowsResult{Rows: make([]interface{}, 0)}
+	deadline := common.Now().AddUs(tableRowsQueryTime)
+	ro.walkTable(db, params.Code, scope, params.Table, lower, func(kv *entity.KeyValueObject) bool {
+		if kv.PrimaryKey > upper {
+			return false
+		}
+		if uint32(len(result.Rows)) >= params.Limit || common.Now() > deadline {
+			result.More = true
+			return false
+		}
+		if params.Json {
+			result.Rows = append(result.Rows, abis.BinaryToVariant(tableType, kv.Value, ro.abiSerializerMaxTime))
+		} else {
+			result.Rows = append(result.Rows, kv.Value)
+		}
+		return true
+	})
+	return result
+}
+
+type GetCurrencyBalanceParams struct {
+	Code    common.AccountName `json:"code"`
+	Account common.AccountName `json:"account"`
+	Symbol  string             `json:"symbol"`
+}
+
+func (ro *ReadOnly) GetCurrencyBalance(params GetCurrencyBalanceParams) []common.Asset {
+	db := ro.db.StateView()
+	defer db.Release()
+
+	abis := ro.abiSerializer(db, params.Code)
+	EosAssert(abis != nil, &AbiNotFoundException{}, "No ABI found for %s", params.Code)
+	table := common.TableName(common.N("accounts"))
+	EosAssert(len(abis.GetTableType(table)) != 0, &ContractTableQueryException{}, "Table %s is not specified in the ABI", table)
+
+	results := make([]common.Asset, 0)
+	ro.walkTable(db, params.Code, uint64(params.Account), table, 0, func(kv *entity.KeyValueObject) bool {
+		balance, err := common.NewAsset(abis.BinaryToVariant("asset", kv.Value, ro.abiSerializerMaxTime).(string))
+		EosAssert(err == nil, &AssetTypeException{}, "Invalid balance in %s: %s", params.Code, err)
+		if len(params.Symbol) == 0 || balance.Symbol.Symbol == params.Symbol {
+			results = append(results, balance)
+		}
+		return true
+	})
+	return results
+}
+
+type GetCurrencyStatsParams struct {
+	Code   common.AccountName `json:"code"`
+	Symbol string             `json:"symbol"`
+}
+
+type GetCurrencyStatsResult struct {
+	Supply    interface{} `json:"supply"`
+	MaxSupply interface{} `json:"max_supply"`
+	Issuer    interface{} `json:"issuer"`
+}
+
+func (ro *ReadOnly) GetCurrencyStats(params GetCurrencyStatsParams) map[string]GetCurrencyStatsResult {
+	db := ro.db.StateView()
+	defer db.Release()
+
+	abis := ro.abiSerializer(db, params.Code)
+	EosAssert(abis != nil, &AbiNotFoundException{}, "No ABI found for %s", params.Code)
+	table := common.TableName(common.N("stat"))
+	tableType := abis.GetTableType(table)
+	EosAssert(len(tableType) != 0, &ContractTableQueryException{}, "Table %s is not specified in the ABI", table)
+
+	results := make(map[string]GetCurrencyStatsResult)
+	ro.walkTable(db, params.Code, symbolCode(params.Symbol), table, 0, func(kv *entity.KeyValueObject) bool {
+		stats, ok := abis.BinaryToVariant(tableType, kv.Value, ro.abiSerializerMaxTime).(common.Variants)
+		if ok {
+			results[params.Symbol] = GetCurrencyStatsResult{
+				Supply:    stats["supply"],
+				MaxSupply: stats["max_supply"],
+				Issuer:    stats["issuer"],
+			}
+		}
+		return true
+	})
+	return results
+}
+
+type GetProducersParams struct {
+	Json       bool   `json:"json"`
+	LowerBound string `json:"lower_bound"`
+	Limit      uint32 `json:"limit"`
+}
+
+func NewGetProducersParams() GetProducersParams {
+	return GetProducersParams{Limit: defaultProducerMax}
+}
+
+type GetProducersResult struct {
+	Rows                    []interface{} `json:"rows"`
+	TotalProducerVoteWeight string        `json:"total_producer_vote_weight"`
+	More                    string        `json:"more"`
+}
+
+// GetProducers lists the registered producers ordered by votes, falling back to the active
+// schedule when the system contract is not deployed
+func (ro *ReadOnly) GetProducers(params GetProducersParams) GetProducersResult {
+	db := ro.db.StateView()
+	defer db.Release()
+
+	system := common.AccountName(common.DefaultConfig.SystemAccountName)
+	result := GetProducersResult{Rows: make([]interface{}, 0), TotalProducerVoteWeight: "0.00000000000000000"}
+
+	abis := ro.abiSerializer(db, system)
+	table := common.TableName(common.N("producers"))
+	if abis == nil || len(abis.GetTableType(table)) == 0 {
+		for _, producer := range ro.db.ActiveProducers().Producers {
+			result.Rows = append(result.Rows, common.Variants{
+				"owner":        producer.AccountName,
+				"producer_key": producer.BlockSigningKey,
+				"url":          "",
+				"total_votes":  "0.00000000000000000",
+			})
+		}
+		return result
+	}
+
+	type producerRow struct {
+		owner string
+		votes float64
+		value interface{}
+	}
+	rows := make([]producerRow, 0)
+	tableType := abis.GetTableType(table)
+	ro.walkTable(db, system, uint64(system), table, 0, func(kv *entity.KeyValueObject) bool {
+		v, _ := abis.BinaryToVariant(tableType, kv.Value, ro.abiSerializerMaxTime).(common.Variants)
+		row := producerRow{owner: fmt.Sprint(v["owner"]), value: v}
+		row.votes, _ = strconv.ParseFloat(fmt.Sprint(v["total_votes"]), 64)
+		if !params.Json {
+			row.value = kv.Value
+		}
+		rows = append(rows, row)
+		return true
+	})
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].votes > rows[j].votes })
+
+	start := 0
+	if len(params.LowerBound) != 0 {
+		for start < len(rows) && rows[start].owner != params.LowerBound {
+			start++
+		}
+	}
+	for i := start; i < len(rows); i++ {
+		if uint32(len(result.Rows)) >= params.Limit {
+			result.More = rows[i].owner
+			break
+		}
+		result.Rows = append(result.Rows, rows[i].value)
+	}
+
+	global := ro.getTableRow(db, abis, system, uint64(system), common.TableName(common.N("global")), uint64(common.N("global")))
+	if g, ok := global.(common.Variants); ok && g["total_producer_vote_weight"] != nil {
+		result.TotalProducerVoteWeight = fmt.Sprint(g["total_producer_vote_weight"])
+	}
+	return result
+}
+
+type GetProducerScheduleParams struct{}
+
+type GetProducerScheduleResult struct {
+	Active   *types.ProducerScheduleType `json:"active"`
+	Pending  *types.ProducerScheduleType `json:"pending"`
+	Proposed *types.ProducerScheduleType `json:"proposed"`
+}
+
+func (ro *ReadOnly) GetProducerSchedule(params GetProducerScheduleParams) GetProducerScheduleResult {
+	proposed := ro.db.ProposedProducers()
+	return GetProducerScheduleResult{
+		Active:   ro.db.ActiveProducers(),
+		Pending:  ro.db.PendingProducers(),
+		Proposed: &proposed,
+	}
+}
+
+type GetRequiredKeysParams struct {
+	Transaction   interface{}     `json:"transaction"`
+	AvailableKeys []ecc.PublicKey `json:"available_keys"`
+}
+
+type GetRequiredKeysResult struct {
+	RequiredKeys []ecc.PublicKey `json:"required_keys"`
+}
+
+func (ro *ReadOnly) GetRequiredKeys(params GetRequiredKeysParams) GetRequiredKeysResult {
+	trx := types.Transaction{}
+	data, err := json.Marshal(ro.fromVariantWithAbi(params.Transaction))
+	if err == nil {
+		err = json.Unmarshal(data, &trx)
+	}
+	EosAssert(err == nil, &TransactionTypeException{}, "Invalid transaction: %s", err)
+
+	candidateKeys := make([]*ecc.PublicKey, 0, len(params.AvailableKeys))
+	for i := range params.AvailableKeys {
+		candidateKeys = append(candidateKeys, &params.AvailableKeys[i])
+	}
+
+	keys := ro.db.GetAuthorizationManager().GetRequiredKeys(&trx, candidateKeys, common.Seconds(int64(trx.DelaySec)))
+	return GetRequiredKeysResult{RequiredKeys: keys}
+}
+
+type AbiJsonToBinParams struct {
+	Code   common.AccountName `json:"code"`
+	Action common.ActionName  `json:"action"`
+	Args   json.RawMessage    `json:"args"`
+}
+
+type AbiJsonToBinResult struct {
+	Binargs common.HexBytes `json:"binargs"`
+}
+
+func (ro *ReadOnly) AbiJsonToBin(params AbiJsonToBinParams) AbiJsonToBinResult {
+	abis := ro.db.GetAbiSerializer(params.Code, ro.abiSerializerMaxTime)
+	EosAssert(abis != nil, &AbiNotFoundException{}, "No ABI found for %s", params.Code)
+	actionType := abis.GetActionType(params.Action)
+	EosAssert(len(actionType) != 0, &InvalidActionArgsException{}, "Unable to find action %s in contract %s", params.Action, params.Code)
+
+	var binargs []byte
+	try.Try(func() {
+		binargs = abis.JsonToBinary(actionType, params.Args, ro.abiSerializerMaxTime)
+	}).Catch(func(e Exception) {
+		EosThrow(&InvalidActionArgsException{}, "'%s' is invalid args for action '%s' code '%s'. expected '%s': %s",
+			params.Args, params.Action, params.Code, actionType, e.Message())
+	}).End()
+	return AbiJsonToBinResult{Binargs: binargs}
+}
+
+type AbiBinToJsonParams struct {
+	Code    common.AccountName `json:"code"`
+	Action  common.ActionName  `json:"action"`
+	Binargs common.HexBytes    `json:"binargs"`
+}
+
+type AbiBinToJsonResult struct {
+	Args interface{} `json:"args"`
+}
+
+func (ro *ReadOnly) AbiBinToJson(params AbiBinToJsonParams) AbiBinToJsonResult {
+	abis := ro.db.GetAbiSerializer(params.Code, ro.abiSerializerMaxTime)
+	EosAssert(abis != nil, &AbiNotFoundException{}, "No ABI found for %s", params.Code)
+	actionType := abis.GetActionType(params.Action)
+	EosAssert(len(actionType) != 0, &InvalidActionArgsException{}, "Unable to find action %s in contract %s", params.Action, params.Code)
+
+	return AbiBinToJsonResult{Args: abis.BinaryToVariant(actionType, params.Binargs, ro.abiSerializerMaxTime)}
+}
+
+// stateView is the state as of blockNum, or the current one when blockNum is 0
+func (ro *ReadOnly) stateView(blockNum uint32) database.DataBaseView {
+	if blockNum == 0 {
+		return ro.db.StateView()
+	}
+	return ro.db.StateViewAt(blockNum)
+}
+
+// abiSerializer reads the abi of the account from db, nil when the account has none
+func (ro *ReadOnly) abiSerializer(db database.DataBaseView, name common.AccountName) *types.AbiSerializer {
+	account := entity.AccountObject{Name: name}
+	if name.Empty() || db.Find("byName", account, &account) != nil || len(account.Abi) == 0 {
+		return nil
+	}
+	abi := account.GetAbi()
+	return types.NewAbiSerializer(&abi, ro.abiSerializerMaxTime)
+}
+
+func (ro *ReadOnly) getAccountObject(db database.DataBaseView, name common.AccountName) *entity.AccountObject {
+	account := entity.AccountObject{Name: name}
+	err := db.Find("byName", account, &account)
+	EosAssert(err == nil, &AccountQueryException{}, "Fail to retrieve account for %s", name)
+	return &account
+}
+
+// walkTable visits the rows of code/scope/table starting at the primary key lower until f returns false.
+// The index can only seek by the table id, the rows before lower are skipped
+func (ro *ReadOnly) walkTable(db database.DataBaseView, code common.AccountName, scope uint64, table common.TableName,
+	lower uint64, f func(kv *entity.KeyValueObject) bool) {
+	tab := entity.TableIdObject{Code: code, Scope: common.ScopeName(scope), Table: table}
+	if err := db.Find("byCodeScopeTable", tab, &tab); err != nil {
+		return
+	}
+
+	obj := entity.KeyValueObject{TId: tab.ID}
+	idx, err := db.GetIndex("byScopePrimary", obj)
+	if err != nil {
+		return
+	}
+	itr, err := idx.LowerBound(obj)
+	if err != nil {
+		return
+	}
+	defer itr.Release()
+
+	for itr.Next() {
+		kv := entity.KeyValueObject{}
+		if itr.Data(&kv) != nil || kv.TId != tab.ID {
+			return
+		}
+		if kv.PrimaryKey < lower {
+			continue
+		}
+		if !f(&kv) {
+			return
+		}
+	}
+}
+
+// getTableRow returns the decoded row with the primary key, or nil when it does not exist
+func (ro *ReadOnly) getTableRow(db database.DataBaseView, abis *types.AbiSerializer, code common.AccountName, scope uint64,
+	table common.TableName, key uint64) (row interface{}) {
+	tableType := abis.GetTableType(table)
+	if len(tableType) == 0 {
+		return nil
+	}
+	ro.walkTable(db, code, scope, table, key, func(kv *entity.KeyValueObject) bool {
+		if kv.PrimaryKey == key {
+			row = abis.BinaryToVariant(tableType, kv.Value, ro.abiSerializerMaxTime)
+		}
+		return false
+	})
+	return
+}
+
+func (ro *ReadOnly) toVariantWithAbi(obj interface{}) interface{} {
+	return newAbiResolver(ro.db, ro.abiSerializerMaxTime).toVariant(obj)
+}
+
+func (ro *ReadOnly) fromVariantWithAbi(v interface{}) interface{} {
+	return newAbiResolver(ro.db, ro.abiSerializerMaxTime).fromVariant(v)
+}
+
+func parseBlockId(s string) common.BlockIdType {
+	data, err := hex.DecodeString(s)
+	EosAssert(err == nil && len(data) == 32, &BlockIdTypeException{}, "Invalid block ID: %s", s)
+	return common.BlockIdType(*crypto.NewSha256Byte(data))
+}
+
+// convertToUint64 reads a table key given either as a number or as a name, a string of digits is a number
+// even when it is also a valid name
+func convertToUint64(s string, desc string) uint64 {
+	if value, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64); err == nil {
+		return value
+	}
+	EosAssert(len(s) <= 13 && common.S(common.N(s)) == s, &ChainTypeException{}, "Could not convert %s string '%s' to key type.", desc, s)
+	return common.N(s)
+}
+
+// convertToPrimaryKey reads a bound of the primary key as the name or the number key_type asks for,
+// either when it is not given
+func convertToPrimaryKey(s string, keyType string, desc string) uint64 {
+	switch keyType {
+	case "name":
+		EosAssert(len(s) <= 13 && common.S(common.N(s)) == s, &ChainTypeException{}, "Could not convert %s string '%s' to name.", desc, s)
+		return common.N(s)
+	case "i64":
+		value, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+		EosAssert(err == nil, &ChainTypeException{}, "Could not convert %s string '%s' to i64.", desc, s)
+		return value
+	}
+	return convertToUint64(s, desc)
+}
+
+// symbolCode packs a symbol name like "EOS" the way it is used as scope and primary key by token contracts
+func symbolCode(s string) uint64 {
+	var code uint64
+	for i := 0; i < len(s) && i < 7; i++ {
+		code |= uint64(s[i]) << uint(8*i)
+	}
+	return code
+}
@@ -0,0 +1,44 @@
+package chain_plugin
+
+import (
+	"testing"
+
+	"github.com/eosspark/eos-go/common"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToUint64(t *testing.T) {
+	// digits 1 to 5 are valid in names too, a number is still read as a number
+	assert.Equal(t, uint64(1), convertToUint64("1", "scope"))
+	assert.Equal(t, uint64(12345), convertToUint64("12345", "scope"))
+	assert.Equal(t, uint64(18446744073709551615), convertToUint64("18446744073709551615", "scope"))
+	assert.Equal(t, common.N("alice"), convertToUint64("alice", "scope"))
+	assert.Equal(t, common.N("eosio.token"), convertToUint64("eosio.token", "scope"))
+
+	for _, s := range []string{"Alice", "toolongname123", "-1"} {
+		returned := false
+		try.Try(func() {
+			convertToUint64(s, "scope")
+		}).Catch(func(e *ChainTypeException) {
+			returned = true
+		}).End()
+		assert.True(t, returned, s)
+	}
+}
+
+func TestConvertToPrimaryKey(t *testing.T) {
+	assert.Equal(t, common.N("12345"), convertToPrimaryKey("12345", "name", "lower_bound"))
+	assert.Equal(t, uint64(12345), convertToPrimaryKey("12345", "i64", "lower_bound"))
+	assert.Equal(t, uint64(12345), convertToPrimaryKey("12345", "", "lower_bound"))
+	assert.Equal(t, common.N("bob"), convertToPrimaryKey("bob", "", "lower_bound"))
+
+	returned := false
+	try.Try(func() {
+		convertToPrimaryKey("bob", "i64", "lower_bound")
+	}).Catch(func(e *ChainTypeException) {
+		returned = true
+	}).End()
+	assert.True(t, returned)
+}
@@ -0,0 +1,135 @@
+package chain_plugin
+
+import (
+	"errors"
+	"fmt"
+
+	Chain "github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/eosspark/eos-go/plugins/appbase/asio"
+)
+
+// see: plugins/chain_plugin/chain_plugin.cpp read_write
+
+const (
+	maxTransactionsAtOnce   = 1000
+	defaultMaxTransactionUs = 30 * 1000
+)
+
+type ReadWrite struct {
+	db                   *Chain.Controller
+	abiSerializerMaxTime common.Microseconds
+	incoming             IncomingTransactionFunc
+	io                   *asio.IoContext
+}
+
+func NewReadWrite(db *Chain.Controller, abiSerializerMaxTime common.Microseconds, incoming IncomingTransactionFunc, io *asio.IoContext) *ReadWrite {
+	return &ReadWrite{db: db, abiSerializerMaxTime: abiSerializerMaxTime, incoming: incoming, io: io}
+}
+
+type PushTransactionParams = types.PackedTransaction
+
+type PushTransactionResults struct {
+	TransactionId common.TransactionIdType `json:"transaction_id"`
+	Processed     interface{}              `json:"processed"`
+}
+
+// PushTransaction hands the transaction to the producer and waits for its trace
+func (rw *ReadWrite) PushTransaction(params PushTransactionParams) PushTransactionResults {
+	trx := params
+	done := make(chan interface{}, 1)
+	rw.incoming(&trx, true, func(result interface{}) { done <- result })
+
+	switch result := (<-done).(type) {
+	case Exception:
+		try.Throw(result)
+	case error:
+		EosThrow(&TransactionException{}, "%s", result.Error())
+	case *types.TransactionTrace:
+		return PushTransactionResults{
+			TransactionId: result.ID,
+			Processed:     newAbiResolver(rw.db, rw.abiSerializerMaxTime).toVariant(result),
+		}
+	}
+	EosThrow(&TransactionException{}, "Unexpected push transaction result")
+	return PushTransactionResults{}
+}
+
+type PushTransactionsParams = []PushTransactionParams
+
+type PushTransactionsResults = []PushTransactionResults
+
+// PushTransactions pushes each transaction in turn, a failed one is reported in its processed field
+func (rw *ReadWrite) PushTransactions(params PushTransactionsParams) PushTransactionsResults {
+	EosAssert(len(params) <= maxTransactionsAtOnce, &TooManyTxAtOnce{}, "Attempt to push too many transactions at once")
+
+	results := make(PushTransactionsResults, 0, len(params))
+	for _, trx := range params {
+		try.Try(func() {
+			results = append(results, rw.PushTransaction(trx))
+		}).Catch(func(e Exception) {
+			results = append(results, PushTransactionResults{Processed: common.Variants{"error": e.Message()}})
+		}).Catch(func(e interface{}) {
+			results = append(results, PushTransactionResults{Processed: common.Variants{"error": fmt.Sprint(e)}})
+		}).End()
+	}
+	return results
+}
+
+// pushToController is used when no producer took over incoming transactions, it applies them
+// to the pending block of the controller and must run on the main loop like block production
+func pushToController(db *Chain.Controller) IncomingTransactionFunc {
+	return func(trx *types.PackedTransaction, persistUntilExpired bool, next func(interface{})) {
+		try.Try(func() {
+			if db.Pending == nil || !db.Pending.Valid {
+				next(errors.New("no pending block to push the transaction into"))
+				return
+			}
+			deadline := common.Now().AddUs(defaultMaxTransactionUs)
+			trace := db.PushTransaction(*types.NewTransactionMetadata(trx), deadline, 0, false)
+			if trace.Except != nil {
+				next(trace.Except)
+			} else {
+				next(trace)
+			}
+		}).Catch(func(e Exception) {
+			next(e)
+		}).Catch(func(e interface{}) {
+			next(fmt.Errorf("%v", e))
+		}).End()
+	}
+}
+
+type CreateSnapshotResults struct {
+	HeadBlockId  common.BlockIdType `json:"head_block_id"`
+	SnapshotName string             `json:"snapshot_name"`
+}
+
+// CreateSnapshot writes a snapshot of the head block to the snapshots directory, it runs on the main
+// loop as the pending block is aborted first
+func (rw *ReadWrite) CreateSnapshot() CreateSnapshotResults {
+	done := make(chan interface{}, 1)
+	rw.io.Post(func() {
+		try.Try(func() {
+			name := rw.db.CreateSnapshot(common.DefaultConfig.DefaultSnapshotsDirName)
+			done <- CreateSnapshotResults{HeadBlockId: rw.db.HeadBlockId(), SnapshotName: name}
+		}).Catch(func(e Exception) {
+			done <- e
+		}).Catch(func(e interface{}) {
+			done <- fmt.Errorf("%v", e)
+		}).End()
+	})
+
+	switch result := (<-done).(type) {
+	case Exception:
+		try.Throw(result)
+	case error:
+		EosThrow(&SnapshotException{}, "%s", result.Error())
+	case CreateSnapshotResults:
+		return result
+	}
+	return CreateSnapshotResults{}
+}
@@ -0,0 +1,66 @@
+package history_api_plugin
+
+import (
+	"github.com/eosspark/eos-go/log"
+	"github.com/eosspark/eos-go/plugins/appbase/app"
+	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	"github.com/eosspark/eos-go/plugins/appbase/plugin/http_plugin"
+	"github.com/eosspark/eos-go/plugins/history_plugin"
+)
+
+const (
+	historyFuncBase           string = "/v1/history"
+	getActionsFunc            string = historyFuncBase + "/get_actions"
+	getTransactionFunc        string = historyFuncBase + "/get_transaction"
+	getKeyAccountsFunc        string = historyFuncBase + "/get_key_accounts"
+	getControlledAccountsFunc string = historyFuncBase + "/get_controlled_accounts"
+)
+
+type HistoryApiPlugin struct {
+	AbstractPlugin
+}
+
+var historyApiPlugin *HistoryApiPlugin
+
+func init() {
+	historyApiPlugin = new(HistoryApiPlugin)
+	historyApiPlugin.Plugin = historyApiPlugin
+	historyApiPlugin.Name = "HistoryApiPlugin"
+	historyApiPlugin.State = Registered
+	app.App.RegisterPlugin(historyApiPlugin)
+}
+
+func (h *HistoryApiPlugin) SetProgramOptions() {}
+
+func (h *HistoryApiPlugin) PluginInitialize() {}
+
+// PluginStartUp mounts every /v1/history endpoint of the history plugin on the http plugin
+func (h *HistoryApiPlugin) PluginStartUp() {
+	log.Info("starting history_api_plugin")
+	ro := history_plugin.GetInstance().GetReadOnlyApi()
+
+	http_plugin.GetInstance().AddApi(http_plugin.ApiDescription{
+		getActionsFunc: http_plugin.CallApi("history", 200, func(body string) interface{} {
+			params := history_plugin.GetActionsParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetActions(params)
+		}),
+		getTransactionFunc: http_plugin.CallApi("history", 200, func(body string) interface{} {
+			params := history_plugin.GetTransactionParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetTransaction(params)
+		}),
+		getKeyAccountsFunc: http_plugin.CallApi("history", 200, func(body string) interface{} {
+			params := history_plugin.GetKeyAccountsParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetKeyAccounts(params)
+		}),
+		getControlledAccountsFunc: http_plugin.CallApi("history", 200, func(body string) interface{} {
+			params := history_plugin.GetControlledAccountsParams{}
+			http_plugin.ParseParams(body, &params)
+			return ro.GetControlledAccounts(params)
+		}),
+	})
+}
+
+func (h *HistoryApiPlugin) PluginShutDown() {}
@@ -1,160 +1,353 @@
 package history_plugin
 
-//type AccountHistoryObject struct {
-//	ID                 types.IdType       `storm:"id,increment" json:"id"`
-//	Account            common.AccountName `storm:"index"`
-//	ActionSequenceNum  uint64
-//	AccountSequenceNum int32
-//	ByAccountActionSeq ByAccountActionSeq `storm:"index"`
-//	/*ByAccountActionSeq struct {
-//		Account            common.AccountName
-//		AccountSequenceNum int32
-//	}`storm:"index"`*/
-//}
-//
-///*search condition*/
-//type ByAccountActionSeq struct {
-//	Account            common.AccountName
-//	AccountSequenceNum int32
-//}
-//
-//type ActionHistoryObject struct {
-//	ID                types.IdType `storm:"id,increment"`
-//	ActionSequenceNum uint64       `storm:"by_action_sequence_num unique"`
-//	PackedActionTrace common.HexBytes
-//	BlockNum          uint32
-//	BlockTime         common.BlockTimeStamp
-//	TrxId             common.TransactionIdType
-//	//c++ no param
-//	ByTrxId struct {
-//		TrxId             common.TransactionIdType
-//		ActionSequenceNum uint64
-//	} `storm:"unique"`
-//}
-//
-//func AddAccountHistoryObject(db *eosiodb.DataBase, aho *AccountHistoryObject) {
-//	//fmt.Println("params:", aho)
-//	err := db.Insert(&aho)
-//	defer db.Close()
-//	if err != nil {
-//		fmt.Println(err.Error())
-//		return
-//	}
-//}
-//
-//func GetAccountHistoryObjectById(db *eosiodb.DataBase, id types.IdType) {
-//	result := AccountHistoryObject{}
-//	err := db.Find("ById", id, &result)
-//	defer db.Close()
-//	if err != nil {
-//		fmt.Println(err.Error())
-//		return
-//	}
-//	//fmt.Println("GetAccountHistoryObjectById result :", result)
-//}
-//
-//func GetAccountByAccountActionSeq(db *eosiodb.DataBase, asNum int32, account common.AccountName) {
-//
-//	aho := AccountHistoryObject{}
-//	aho.ByAccountActionSeq.AccountSequenceNum = asNum
-//	aho.ByAccountActionSeq.Account = account
-//	result := AccountHistoryObject{}
-//	err := db.Find("condition", aho.ByAccountActionSeq, &result)
-//	defer db.Close()
-//	if err != nil {
-//		fmt.Println(err.Error())
-//		return
-//	}
-//	//fmt.Println("GetAccountByAccActSeq result:", result)
-//}
-//
-//func GetActionHistoryObjectByActSeqNum(db *eosiodb.DataBase, asNum uint64) *ActionHistoryObject {
-//	result := ActionHistoryObject{}
-//	err := db.Find("ActionSequenceNum", asNum, &result)
-//	defer db.Close()
-//	if err != nil {
-//		fmt.Println("GetActionHistoryObjectByActSeqNum is error detail :", err.Error())
-//	}
-//	return &result
-//}
-//
-//func GetActionHistoryByAccActSeq(db *eosiodb.DataBase, asNum uint64, trxId common.TransactionIdType) {
-//	result := ActionHistoryObject{}
-//	result.ByTrxId.TrxId = trxId
-//	result.ByTrxId.ActionSequenceNum = asNum
-//	err := db.Find("ByAccountActionSeq", result.ByTrxId, &result)
-//	defer db.Close()
-//	if err != nil {
-//		fmt.Println(err.Error())
-//		return
-//	}
-//	//fmt.Println("result:", result)
-//}
-//
-//func GetAccountHistoryObjectByAccount(db *eosiodb.DataBase, account common.AccountName) *[]AccountHistoryObject {
-//	aho := AccountHistoryObject{}
-//	aho.Account = account
-//	//aho.ByAccountActionSeq.AccountSequenceNum = 4
-//	fmt.Println("+++++++++++++++++:", aho.ByAccountActionSeq.Account)
-//	var result []AccountHistoryObject
-//	err := db.Get("Account", aho.Account, &result)
-//	defer db.Close()
-//	if err != nil {
-//		fmt.Println(err.Error())
-//		return nil
-//	}
-//	//fmt.Print("result :", result)
-//	return &result
-//}
-//
-//type GetActionParam struct {
-//	AccountName common.AccountName
-//	Pos         int32
-//	Offset      int32
-//}
-//
-//type OrderedActionResult struct {
-//	GlobalActionSeq  uint64
-//	AccountActionSeq int32
-//	BlockNum         uint32
-//	BlockTime        common.BlockTimeStamp
-//	ActionTrace      types.ActionTrace
-//}
-//type GetActionResult struct {
-//	Actions                []OrderedActionResult
-//	LastIrreversibleBlock  uint32
-//	TimeLimitExceededError bool
-//}
-//
-//func GetActions(params *GetActionParam) *[]GetActionResult {
-//	//control := chain.GetControlInstance()
-//	//db := control.DB()
-//	//chain.get_abi_serializer_max_time
-//	abiSerializerMaxTime := chain_plugin.GetInstance().GetAbiSerializerMaxTime()
-//
-//	db, err := eosiodb.NewDataBase("./", "shared_memory.bin", true)
-//	if err != nil {
-//		fmt.Println("Test_GetAccountHistoryObject is error detail:", err.Error())
-//	}
-//	defer db.Close()
-//
-//	ahoList := *GetAccountHistoryObjectByAccount(db, params.AccountName)
-//	var pageNum, start, end int32
-//	start = params.Pos
-//	if start == 0 {
-//		end = params.Offset //data count per page
-//	}
-//	//end = params.Offset+5
-//	if len(ahoList) > 0 {
-//		pageNum = int32(len(ahoList)) / end
-//	}
-//	//db.Find("ByAccountActionSeq",AccountHistoryObject,[]AccountHistoryObject)
-//
-//	array := ahoList[0:3]
-//	fmt.Println(array)
-//
-//	fmt.Println(abiSerializerMaxTime, pageNum, start, end)
-//
-//	//return &array
-//	return nil
-//}
+import (
+	"math"
+	"strings"
+
+	"github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto/rlp"
+	"github.com/eosspark/eos-go/database"
+	"github.com/eosspark/eos-go/entity"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/log"
+	"github.com/eosspark/eos-go/plugins/appbase/app"
+	. "github.com/eosspark/eos-go/plugins/appbase/app/include"
+	"github.com/eosspark/eos-go/plugins/chain_plugin"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// see: plugins/history_plugin/history_plugin.cpp
+
+type HistoryPlugin struct {
+	AbstractPlugin
+	my *historyPluginImpl
+}
+
+var historyPlugin *HistoryPlugin
+
+func init() {
+	historyPlugin = new(HistoryPlugin)
+	historyPlugin.Plugin = historyPlugin
+	historyPlugin.Name = "HistoryPlugin"
+	historyPlugin.State = Registered
+	historyPlugin.my = newHistoryPluginImpl()
+	app.App.RegisterPlugin(historyPlugin)
+}
+
+func GetInstance() *HistoryPlugin {
+	return historyPlugin
+}
+
+func (h *HistoryPlugin) SetProgramOptions() {
+	my := h.my
+	app.App.My.Options.Flags = append(app.App.My.Options.Flags,
+		cli.StringSliceFlag{
+			Name:  "filter-on",
+			Usage: "Track actions which match receiver:action:actor. Actor may be blank to include all. Action and Actor both blank allows all from Receiver. Receiver may not be blank.",
+			Value: &my.filterOnOptions,
+		},
+		cli.StringSliceFlag{
+			Name:  "filter-out",
+			Usage: "Do not track actions which match receiver:action:actor. Action and Actor both blank excludes all from Receiver. Actor blank excludes all from reciever:action. Receiver may not be blank.",
+			Value: &my.filterOutOptions,
+		},
+	)
+}
+
+func (h *HistoryPlugin) PluginInitialize() {
+	my := h.my
+	for _, s := range my.filterOnOptions {
+		if s == "*" {
+			my.bypassFilter = true
+			log.Warn("--filter-on * enabled. This can fill shared_mem, causing nodeos to stop.")
+			break
+		}
+		fe := parseFilterEntry(s, "--filter-on")
+		my.filterOn[fe] = struct{}{}
+	}
+	for _, s := range my.filterOutOptions {
+		fe := parseFilterEntry(s, "--filter-out")
+		my.filterOut[fe] = struct{}{}
+	}
+
+	chain := chain_plugin.GetInstance().Chain()
+	my.db = chain.DataBase()
+	chain.AppliedTransaction.Connect(func(trace *types.TransactionTrace) {
+		pending := chain.PendingBlockState()
+		my.onAppliedTransaction(trace, pending.BlockNum, common.NewBlockTimeStamp(chain.PendingBlockTime()))
+	})
+}
+
+func (h *HistoryPlugin) PluginStartUp() {}
+
+func (h *HistoryPlugin) PluginShutDown() {}
+
+func (h *HistoryPlugin) GetName() string {
+	return h.Name
+}
+
+func (h *HistoryPlugin) GetState() State {
+	return h.State
+}
+
+func (h *HistoryPlugin) GetReadOnlyApi() *ReadOnly {
+	return NewReadOnly(h.my, chain_plugin.GetInstance())
+}
+
+// parseFilterEntry reads receiver:action:actor, only the receiver is required
+func parseFilterEntry(s string, option string) filterEntry {
+	v := strings.Split(s, ":")
+	EosAssert(len(v) == 3, &InvalidArgException{}, "Invalid value %s for %s", s, option)
+	fe := filterEntry{
+		Receiver: common.Name(common.N(v[0])),
+		Action:   common.Name(common.N(v[1])),
+		Actor:    common.Name(common.N(v[2])),
+	}
+	EosAssert(fe.Receiver != 0, &InvalidArgException{}, "Invalid value %s for %s", s, option)
+	return fe
+}
+
+type filterEntry struct {
+	Receiver common.Name
+	Action   common.Name
+	Actor    common.Name
+}
+
+type historyPluginImpl struct {
+	bypassFilter     bool
+	filterOn         map[filterEntry]struct{}
+	filterOut        map[filterEntry]struct{}
+	filterOnOptions  cli.StringSlice
+	filterOutOptions cli.StringSlice
+	db               database.DataBase
+}
+
+func newHistoryPluginImpl() *historyPluginImpl {
+	return &historyPluginImpl{
+		filterOn:  make(map[filterEntry]struct{}),
+		filterOut: make(map[filterEntry]struct{}),
+	}
+}
+
+func (my *historyPluginImpl) matches(set map[filterEntry]struct{}, receiver, action, actor common.Name) bool {
+	_, ok := set[filterEntry{Receiver: receiver, Action: action, Actor: actor}]
+	return ok
+}
+
+func (my *historyPluginImpl) filter(act *types.ActionTrace) bool {
+	receiver := common.Name(act.Receipt.Receiver)
+	name := common.Name(act.Act.Name)
+
+	passOn := my.bypassFilter ||
+		my.matches(my.filterOn, receiver, 0, 0) ||
+		my.matches(my.filterOn, receiver, name, 0)
+	for _, a := range act.Act.Authorization {
+		actor := common.Name(a.Actor)
+		if my.matches(my.filterOn, receiver, 0, actor) || my.matches(my.filterOn, receiver, name, actor) {
+			passOn = true
+		}
+	}
+	if !passOn {
+		return false
+	}
+
+	if my.matches(my.filterOut, receiver, 0, 0) || my.matches(my.filterOut, receiver, name, 0) {
+		return false
+	}
+	for _, a := range act.Act.Authorization {
+		actor := common.Name(a.Actor)
+		if my.matches(my.filterOut, receiver, 0, actor) || my.matches(my.filterOut, receiver, name, actor) {
+			return false
+		}
+	}
+	return true
+}
+
+// accountSet is the receiver plus every authorizer that passes the filters, they all get
+// the action in their history
+func (my *historyPluginImpl) accountSet(act *types.ActionTrace) []common.AccountName {
+	receiver := common.Name(act.Receipt.Receiver)
+	name := common.Name(act.Act.Name)
+
+	result := []common.AccountName{act.Receipt.Receiver}
+	seen := map[common.AccountName]bool{act.Receipt.Receiver: true}
+	for _, a := range act.Act.Authorization {
+		actor := common.Name(a.Actor)
+		if my.bypassFilter ||
+			my.matches(my.filterOn, receiver, 0, 0) ||
+			my.matches(my.filterOn, receiver, 0, actor) ||
+			my.matches(my.filterOn, receiver, name, 0) ||
+			my.matches(my.filterOn, receiver, name, actor) {
+			if !my.matches(my.filterOut, receiver, 0, 0) &&
+				!my.matches(my.filterOut, receiver, 0, actor) &&
+				!my.matches(my.filterOut, receiver, name, 0) &&
+				!my.matches(my.filterOut, receiver, name, actor) && !seen[a.Actor] {
+				seen[a.Actor] = true
+				result = append(result, a.Actor)
+			}
+		}
+	}
+	return result
+}
+
+// lastAccountSequence is the highest account sequence recorded for account, the index is seeked
+// before the first sequence that can not exist and stepped back once
+func (my *historyPluginImpl) lastAccountSequence(account common.AccountName) (int32, bool) {
+	idx, err := my.db.GetIndex("byAccountActionSeq", entity.AccountHistoryObject{})
+	EosAssert(err == nil, &DatabaseException{}, "get account history index is error: %s", err)
+	it, err := idx.Range(entity.AccountHistoryObject{Account: account},
+		entity.AccountHistoryObject{Account: account, AccountSequenceNum: math.MaxInt32})
+	EosAssert(err == nil, &DatabaseException{}, "get account history range is error: %s", err)
+	defer it.Release()
+
+	obj := entity.AccountHistoryObject{}
+	if !it.Last() || it.Data(&obj) != nil {
+		return 0, false
+	}
+	return obj.AccountSequenceNum, true
+}
+
+func (my *historyPluginImpl) recordAccountAction(n common.AccountName, act *types.ActionTrace) {
+	asn := int32(0)
+	if last, ok := my.lastAccountSequence(n); ok {
+		asn = last + 1
+	}
+
+	obj := entity.AccountHistoryObject{
+		Account:            n,
+		ActionSequenceNum:  act.Receipt.GlobalSequence,
+		AccountSequenceNum: asn,
+	}
+	err := my.db.Insert(&obj)
+	EosAssert(err == nil, &DatabaseException{}, "insert account history is error: %s", err)
+}
+
+func (my *historyPluginImpl) addKeys(keys []types.KeyWeight, name common.AccountName, permission common.PermissionName) {
+	for _, k := range keys {
+		obj := entity.PublicKeyHistoryObject{PublicKey: k.Key, Name: name, Permission: permission}
+		err := my.db.Insert(&obj)
+		EosAssert(err == nil, &DatabaseException{}, "insert public key history is error: %s", err)
+	}
+}
+
+func (my *historyPluginImpl) addAccounts(accounts []types.PermissionLevelWeight, name common.AccountName, permission common.PermissionName) {
+	for _, a := range accounts {
+		obj := entity.AccountControlHistoryObject{
+			ControlledAccount:    name,
+			ControlledPermission: permission,
+			ControllingAccount:   a.Permission.Actor,
+		}
+		err := my.db.Insert(&obj)
+		EosAssert(err == nil, &DatabaseException{}, "insert account control history is error: %s", err)
+	}
+}
+
+func (my *historyPluginImpl) removeAuthority(name common.AccountName, permission common.PermissionName) {
+	keys := make([]entity.PublicKeyHistoryObject, 0)
+	if idx, err := my.db.GetIndex("byAccountPermission", entity.PublicKeyHistoryObject{}); err == nil {
+		if it, err := idx.LowerBound(entity.PublicKeyHistoryObject{Name: name, Permission: permission}); err == nil {
+			for it.Next() {
+				obj := entity.PublicKeyHistoryObject{}
+				if it.Data(&obj) != nil || obj.Name != name || obj.Permission != permission {
+					break
+				}
+				keys = append(keys, obj)
+			}
+			it.Release()
+		}
+	}
+	for i := range keys {
+		EosAssert(my.db.Remove(&keys[i]) == nil, &DatabaseException{}, "remove public key history is error")
+	}
+
+	controls := make([]entity.AccountControlHistoryObject, 0)
+	if idx, err := my.db.GetIndex("byControlledAuthority", entity.AccountControlHistoryObject{}); err == nil {
+		if it, err := idx.LowerBound(entity.AccountControlHistoryObject{ControlledAccount: name, ControlledPermission: permission}); err == nil {
+			for it.Next() {
+				obj := entity.AccountControlHistoryObject{}
+				if it.Data(&obj) != nil || obj.ControlledAccount != name || obj.ControlledPermission != permission {
+					break
+				}
+				controls = append(controls, obj)
+			}
+			it.Release()
+		}
+	}
+	for i := range controls {
+		EosAssert(my.db.Remove(&controls[i]) == nil, &DatabaseException{}, "remove account control history is error")
+	}
+}
+
+// onSystemAction keeps the key and controlling account indexes in step with eosio auth actions
+func (my *historyPluginImpl) onSystemAction(at *types.ActionTrace) {
+	switch at.Act.Name {
+	case common.ActionName(common.N("newaccount")):
+		create := chain.NewAccount{}
+		if err := rlp.DecodeBytes(at.Act.Data, &create); err != nil {
+			log.Error("history plugin decode newaccount is error,detail:", err)
+			return
+		}
+		owner, active := common.PermissionName(common.N("owner")), common.PermissionName(common.N("active"))
+		my.addKeys(create.Owner.Keys, create.Name, owner)
+		my.addKeys(create.Active.Keys, create.Name, active)
+		my.addAccounts(create.Owner.Accounts, create.Name, owner)
+		my.addAccounts(create.Active.Accounts, create.Name, active)
+
+	case common.ActionName(common.N("updateauth")):
+		update := chain.UpdateAuth{}
+		if err := rlp.DecodeBytes(at.Act.Data, &update); err != nil {
+			log.Error("history plugin decode updateauth is error,detail:", err)
+			return
+		}
+		my.removeAuthority(update.Account, update.Permission)
+		my.addKeys(update.Auth.Keys, update.Account, update.Permission)
+		my.addAccounts(update.Auth.Accounts, update.Account, update.Permission)
+
+	case common.ActionName(common.N("deleteauth")):
+		del := chain.DeleteAuth{}
+		if err := rlp.DecodeBytes(at.Act.Data, &del); err != nil {
+			log.Error("history plugin decode deleteauth is error,detail:", err)
+			return
+		}
+		my.removeAuthority(del.Account, del.Permission)
+	}
+}
+
+func (my *historyPluginImpl) onActionTrace(at *types.ActionTrace, blockNum uint32, blockTime common.BlockTimeStamp) {
+	if my.filter(at) {
+		packed, err := rlp.EncodeToBytes(at)
+		EosAssert(err == nil, &DatabaseException{}, "pack action trace is error: %s", err)
+
+		aho := entity.ActionHistoryObject{
+			TrxId:             at.TrxId,
+			ActionSequenceNum: at.Receipt.GlobalSequence,
+			PackedActionTrace: packed,
+			BlockNum:          blockNum,
+			BlockTime:         blockTime,
+		}
+		err = my.db.Insert(&aho)
+		EosAssert(err == nil, &DatabaseException{}, "insert action history is error: %s", err)
+
+		for _, a := range my.accountSet(at) {
+			my.recordAccountAction(a, at)
+		}
+	}
+	if at.Receipt.Receiver == common.DefaultConfig.SystemAccountName {
+		my.onSystemAction(at)
+	}
+	for i := range at.InlineTraces {
+		my.onActionTrace(&at.InlineTraces[i], blockNum, blockTime)
+	}
+}
+
+func (my *historyPluginImpl) onAppliedTransaction(trace *types.TransactionTrace, blockNum uint32, blockTime common.BlockTimeStamp) {
+	if trace.Except != nil ||
+		(trace.Receipt.Status != types.TransactionStatusExecuted && trace.Receipt.Status != types.TransactionStatusSoftFail) {
+		return
+	}
+	for i := range trace.ActionTraces {
+		my.onActionTrace(&trace.ActionTraces[i], blockNum, blockTime)
+	}
+}
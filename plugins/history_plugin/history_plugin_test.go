@@ -1,159 +1,229 @@
 package history_plugin
 
 import (
-	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 
+	"github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto/ecc"
+	"github.com/eosspark/eos-go/crypto/rlp"
 	"github.com/eosspark/eos-go/database"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/stretchr/testify/assert"
 )
 
-func Test_AddAccountHistoryObject(t *testing.T) {
-	db, err := eosiodb.NewDataBase("./", "shared_memory.bin", true)
-	if err != nil {
-		fmt.Println("Test_AddAccountHistoryObject is error detail:", err.Error())
+func newTestHistory(t *testing.T, filterOn ...string) (*historyPluginImpl, func()) {
+	dir, err := ioutil.TempDir("", "history_plugin")
+	assert.NoError(t, err)
+	db, err := database.NewDataBase(dir)
+	assert.NoError(t, err)
+
+	my := newHistoryPluginImpl()
+	my.db = db
+	for _, s := range filterOn {
+		if s == "*" {
+			my.bypassFilter = true
+			continue
+		}
+		my.filterOn[parseFilterEntry(s, "--filter-on")] = struct{}{}
 	}
-	defer db.Close()
-
-	aho := AccountHistoryObject{}
-	acount := common.AccountName(common.N("tuanhuo"))
-	num := int32(66)
-	aho.Account = acount
-	aho.AccountSequenceNum = num
-	aho.ByAccountActionSeq.Account = acount
-	aho.ByAccountActionSeq.AccountSequenceNum = num
-	aho.ActionSequenceNum = uint64(aho.ByAccountActionSeq.AccountSequenceNum)
-	c := ByAccountActionSeq{}
-	c.Account = aho.ByAccountActionSeq.Account
-	c.AccountSequenceNum = aho.ByAccountActionSeq.AccountSequenceNum
-	err = db.Insert(&aho)
-	if err != nil {
-		fmt.Println(err.Error())
-		return
+	return my, func() {
+		db.Close()
+		os.RemoveAll(dir)
 	}
-	fmt.Println("Test_AddAccountHistoryObject finished")
-
-	fmt.Println("*******************************************")
-	result := []AccountHistoryObject{}
-	db.All(&result)
-	fmt.Print("all context:", result)
 }
 
-/*func Test_AddAccountHistorys(t *testing.T) {
-
-	fmt.Println(time.Now())
-	db, err := eosiodb.NewDataBase("./", "shared_memory.bin", true)
-	if err != nil {
-		fmt.Println("Test_AddAccountHistoryIndex is error detail:",err.Error())
+func newActionTrace(globalSequence uint64, receiver string, action string, actors ...string) types.ActionTrace {
+	at := types.ActionTrace{}
+	at.Receipt.Receiver = common.AccountName(common.N(receiver))
+	at.Receipt.GlobalSequence = globalSequence
+	at.Act.Account = at.Receipt.Receiver
+	at.Act.Name = common.ActionName(common.N(action))
+	for _, actor := range actors {
+		at.Act.Authorization = append(at.Act.Authorization, types.PermissionLevel{
+			Actor:      common.AccountName(common.N(actor)),
+			Permission: common.PermissionName(common.N("active")),
+		})
 	}
-	defer db.Close()
+	at.TrxId = common.TransactionIdType{}
+	at.TrxId.Hash[0] = globalSequence
+	return at
+}
 
-	results := []AccountHistoryObject{}
-	err = db.All(&results)
-	if err != nil{
-		fmt.Println("db.all() is error",err.Error())
-	}
-	fmt.Println("db.all() result:",results)
-	aho := AccountHistoryObject{}
-	aho.ID = 1
-	var h AccountHistoryObject
-	err = db.Find("ID",&aho.ID,&h)
-	if err != nil{
-		fmt.Println(err.Error())
-		return
-	}
-	fmt.Println(h)
-	AddAccountHistoryObject(db, &h)
-
-	for i :=0;i<1000;i++ {
-		aho := AccountHistoryObject{}
-		aho.Account = (h.Account+common.AccountName(i))
-		aho.AccountSequenceNum = int32(i+1)
-		aho.ActionSequenceNum = uint64(aho.AccountSequenceNum)
-		aho.condition.account = aho.Account
-		aho.condition.accountSequenceNum = aho.AccountSequenceNum
-		AddAccountHistoryObject(db, &aho)
+func TestParseFilterEntry(t *testing.T) {
+	fe := parseFilterEntry("eosio.token:transfer:", "--filter-on")
+	assert.Equal(t, common.Name(common.N("eosio.token")), fe.Receiver)
+	assert.Equal(t, common.Name(common.N("transfer")), fe.Action)
+	assert.Equal(t, common.Name(0), fe.Actor)
+
+	for _, s := range []string{"eosio.token", ":transfer:alice", "a:b:c:d"} {
+		returned := false
+		try.Try(func() {
+			parseFilterEntry(s, "--filter-on")
+		}).Catch(func(e *InvalidArgException) {
+			returned = true
+		}).End()
+		assert.True(t, returned, s)
 	}
+}
 
+func TestFilter(t *testing.T) {
+	my, closer := newTestHistory(t, "eosio.token::", "eosio:newaccount:")
+	defer closer()
+	my.filterOut[parseFilterEntry("eosio.token::spammer", "--filter-out")] = struct{}{}
 
-	fmt.Println(time.Now())
-}*/
+	transfer := newActionTrace(1, "eosio.token", "transfer", "alice")
+	assert.True(t, my.filter(&transfer))
+	assert.Equal(t, []common.AccountName{common.AccountName(common.N("eosio.token")), common.AccountName(common.N("alice"))}, my.accountSet(&transfer))
 
-func Test_GetAccountHistoryObject(t *testing.T) {
-	db, err := eosiodb.NewDataBase("./", "shared_memory.bin", true)
-	if err != nil {
-		fmt.Println("Test_GetAccountHistoryObject is error detail:", err.Error())
-	}
-	defer db.Close()
-
-	result := AccountHistoryObject{}
-	result.ID = 2
-	err = db.Find("ID", result.ID, &result)
-	if err != nil {
-		fmt.Println("Test_GetAccountHistoryObject is error detail:", err.Error())
-		return
-	}
-	fmt.Println("object:", result)
+	spam := newActionTrace(2, "eosio.token", "transfer", "spammer")
+	assert.False(t, my.filter(&spam))
 
-	//results := []AccountHistoryIndex{}
-	var results []AccountHistoryObject
+	newaccount := newActionTrace(3, "eosio", "newaccount", "eosio")
+	assert.True(t, my.filter(&newaccount))
 
-	err = db.All(&results)
-	if err != nil {
-		fmt.Println(err.Error())
-	}
-	fmt.Println("query all data:", results)
+	setcode := newActionTrace(4, "eosio", "setcode", "eosio")
+	assert.False(t, my.filter(&setcode))
+
+	my.bypassFilter = true
+	assert.True(t, my.filter(&setcode))
 }
 
-func Test_UpdateAccountHistoryObject(t *testing.T) {
-	db, err := eosiodb.NewDataBase("./", "shared_memory.bin", true)
-	if err != nil {
-		fmt.Println("Test_UpdateAccountHistoryObject is error detail:", err.Error())
+func TestGetActions(t *testing.T) {
+	my, closer := newTestHistory(t, "*")
+	defer closer()
+
+	trace := &types.TransactionTrace{}
+	for i := uint64(1); i <= 5; i++ {
+		at := newActionTrace(i*10, "alice", "transfer", "alice")
+		if i == 5 {
+			inline := newActionTrace(i*10+1, "bob", "transfer", "alice")
+			at.InlineTraces = append(at.InlineTraces, inline)
+		}
+		trace.ActionTraces = append(trace.ActionTraces, at)
 	}
-	defer db.Close()
-
-	var results []AccountHistoryObject
-	err = db.All(&results)
-	if err != nil {
-		fmt.Println("update is err :", err.Error())
-		return
+	my.onAppliedTransaction(trace, 7, common.BlockTimeStamp(8))
+
+	failed := &types.TransactionTrace{ActionTraces: []types.ActionTrace{newActionTrace(99, "alice", "transfer")}}
+	failed.Receipt.Status = types.TransactionStatusHardFail
+	my.onAppliedTransaction(failed, 7, common.BlockTimeStamp(8))
+
+	identity := func(v interface{}) interface{} { return v }
+	sequences := func(result GetActionsResult) []uint64 {
+		seqs := make([]uint64, 0)
+		for _, a := range result.Actions {
+			seqs = append(seqs, a.GlobalActionSeq)
+		}
+		return seqs
 	}
+	i32 := func(v int32) *int32 { return &v }
 
-	//updateObject All properties can not be empty.
-
-	//param :=AccountHistoryObject{}
-	param := results[0]
-	aho := AccountHistoryObject{}
-	aho.ID = param.ID
-	//aho.Condition.AccountSequenceNum = param.ByAccountActionSeq.AccountSequenceNum+5
-	//aho.Condition.Account = param.ByAccountActionSeq.Account
-	fmt.Println("modify object param:", param)
-	err = db.UpdateObject(&param, &aho)
-	if err != nil {
-		fmt.Println("modify object is error:", err.Error())
-		return
-	}
+	alice := common.AccountName(common.N("alice"))
+	result := my.getActions(GetActionsParams{AccountName: alice}, identity)
+	assert.Equal(t, []uint64{10, 20, 30, 40, 50, 51}, sequences(result))
+	assert.Equal(t, uint32(7), result.Actions[0].BlockNum)
+	assert.Equal(t, common.BlockTimeStamp(8), result.Actions[0].BlockTime)
+	assert.Equal(t, int32(5), result.Actions[5].AccountActionSeq)
+
+	at := result.Actions[5].ActionTrace.(*types.ActionTrace)
+	assert.Equal(t, common.AccountName(common.N("bob")), at.Receipt.Receiver)
+
+	result = my.getActions(GetActionsParams{AccountName: alice, Pos: i32(-1), Offset: i32(-2)}, identity)
+	assert.Equal(t, []uint64{50, 51}, sequences(result))
+
+	result = my.getActions(GetActionsParams{AccountName: alice, Pos: i32(1), Offset: i32(1)}, identity)
+	assert.Equal(t, []uint64{20, 30}, sequences(result))
+
+	result = my.getActions(GetActionsParams{AccountName: common.AccountName(common.N("bob"))}, identity)
+	assert.Equal(t, []uint64{51}, sequences(result))
+
+	result = my.getActions(GetActionsParams{AccountName: common.AccountName(common.N("carol"))}, identity)
+	assert.Equal(t, 0, len(result.Actions))
+
+	actions := my.transactionActions(newActionTrace(30, "alice", "transfer").TrxId.String()[:8])
+	assert.Equal(t, 1, len(actions))
+	assert.Equal(t, uint64(30), actions[0].ActionSequenceNum)
 }
 
-func Test_GetAccountHistoryObjectsByAccount(t *testing.T) {
-	db, err := eosiodb.NewDataBase("./", "shared_memory.bin", true)
-	if err != nil {
-		fmt.Println("Test_GetAccountHistoryObjectByAccount is error detail:", err.Error())
-		return
+func TestGetActionsLongHistory(t *testing.T) {
+	my, closer := newTestHistory(t, "*")
+	defer closer()
+
+	trace := &types.TransactionTrace{}
+	for i := uint64(1); i <= 300; i++ {
+		trace.ActionTraces = append(trace.ActionTraces, newActionTrace(i, "alice", "transfer"))
+	}
+	my.onAppliedTransaction(trace, 7, common.BlockTimeStamp(8))
+
+	alice := common.AccountName(common.N("alice"))
+	last, ok := my.lastAccountSequence(alice)
+	assert.True(t, ok)
+	assert.Equal(t, int32(299), last)
+	_, ok = my.lastAccountSequence(common.AccountName(common.N("bob")))
+	assert.False(t, ok)
+
+	identity := func(v interface{}) interface{} { return v }
+	i32 := func(v int32) *int32 { return &v }
+	result := my.getActions(GetActionsParams{AccountName: alice}, identity)
+	assert.Equal(t, 20, len(result.Actions))
+	for i, a := range result.Actions {
+		assert.Equal(t, int32(280+i), a.AccountActionSeq)
+		assert.Equal(t, uint64(281+i), a.GlobalActionSeq)
 	}
-	defer db.Close()
 
-	result := GetAccountHistoryObjectByAccount(db, common.AccountName(common.N("tuanhuo2")))
-	fmt.Print("Query many data :")
-	fmt.Println(result)
-}
+	result = my.getActions(GetActionsParams{AccountName: alice, Pos: i32(255), Offset: i32(2)}, identity)
+	assert.Equal(t, 3, len(result.Actions))
+	assert.Equal(t, int32(255), result.Actions[0].AccountActionSeq)
+	assert.Equal(t, int32(257), result.Actions[2].AccountActionSeq)
 
-func Test_GetActions(t *testing.T) {
-	param := GetActionParam{}
-	param.Pos = 0
-	param.Offset = 3
-	param.AccountName = common.AccountName(common.N("tuanhuo"))
+	result = my.getActions(GetActionsParams{AccountName: alice, Pos: i32(3), Offset: i32(-10)}, identity)
+	assert.Equal(t, 4, len(result.Actions))
+	assert.Equal(t, int32(0), result.Actions[0].AccountActionSeq)
 
-	result := GetActions(&param)
-	fmt.Println("Test_GetActions result :", result)
+	trxId := newActionTrace(300, "alice", "transfer").TrxId.String()
+	actions := my.transactionActions(trxId)
+	assert.Equal(t, 1, len(actions))
+	assert.Equal(t, uint64(300), actions[0].ActionSequenceNum)
+	assert.Equal(t, 0, len(my.transactionActions("ffffffff")))
+}
+
+func TestKeyAndControlledAccounts(t *testing.T) {
+	my, closer := newTestHistory(t)
+	defer closer()
+
+	privateKey, err := ecc.NewRandomPrivateKey()
+	assert.NoError(t, err)
+	key := privateKey.PublicKey()
+
+	alice := common.AccountName(common.N("alice"))
+	bob := common.AccountName(common.N("bob"))
+	create := chain.NewAccount{
+		Creator: common.DefaultConfig.SystemAccountName,
+		Name:    bob,
+		Owner: types.Authority{Threshold: 1, Accounts: []types.PermissionLevelWeight{{
+			Permission: types.PermissionLevel{Actor: alice, Permission: common.PermissionName(common.N("active"))},
+			Weight:     1,
+		}}},
+		Active: types.Authority{Threshold: 1, Keys: []types.KeyWeight{{Key: key, Weight: 1}}},
+	}
+	at := newActionTrace(1, "eosio", "newaccount", "eosio")
+	at.Act.Data, err = rlp.EncodeToBytes(&create)
+	assert.NoError(t, err)
+	my.onActionTrace(&at, 1, 0)
+
+	assert.Equal(t, []common.AccountName{bob}, my.keyAccounts(key))
+	assert.Equal(t, []common.AccountName{bob}, my.controlledAccounts(alice))
+
+	del := chain.DeleteAuth{Account: bob, Permission: common.PermissionName(common.N("active"))}
+	at = newActionTrace(2, "eosio", "deleteauth", "bob")
+	at.Act.Data, err = rlp.EncodeToBytes(&del)
+	assert.NoError(t, err)
+	my.onActionTrace(&at, 2, 0)
+
+	assert.Equal(t, 0, len(my.keyAccounts(key)))
+	assert.Equal(t, []common.AccountName{bob}, my.controlledAccounts(alice))
 }
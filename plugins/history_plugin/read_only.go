@@ -0,0 +1,314 @@
+package history_plugin
+
+import (
+	"encoding/hex"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/crypto"
+	"github.com/eosspark/eos-go/crypto/ecc"
+	"github.com/eosspark/eos-go/crypto/rlp"
+	"github.com/eosspark/eos-go/entity"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/plugins/chain_plugin"
+)
+
+// see: plugins/history_plugin/history_plugin.cpp read_only
+
+const getActionsTimeLimit = common.Microseconds(100000)
+
+type ReadOnly struct {
+	history *historyPluginImpl
+	chain   *chain_plugin.ChainPlugin
+}
+
+func NewReadOnly(history *historyPluginImpl, chain *chain_plugin.ChainPlugin) *ReadOnly {
+	return &ReadOnly{history: history, chain: chain}
+}
+
+type GetActionsParams struct {
+	AccountName common.AccountName `json:"account_name"`
+	Pos         *int32             `json:"pos"`    ///< a absolute sequence positon -1 is the end/last action
+	Offset      *int32             `json:"offset"` ///< the number of actions relative to pos, negative numbers return [pos-offset,pos), positive numbers return [pos,pos+offset)
+}
+
+type OrderedActionResult struct {
+	GlobalActionSeq  uint64                `json:"global_action_seq"`
+	AccountActionSeq int32                 `json:"account_action_seq"`
+	BlockNum         uint32                `json:"block_num"`
+	BlockTime        common.BlockTimeStamp `json:"block_time"`
+	ActionTrace      interface{}           `json:"action_trace"`
+}
+
+type GetActionsResult struct {
+	Actions                []OrderedActionResult `json:"actions"`
+	LastIrreversibleBlock  uint32                `json:"last_irreversible_block"`
+	TimeLimitExceededError bool                  `json:"time_limit_exceeded_error,omitempty"`
+}
+
+func (ro *ReadOnly) GetActions(params GetActionsParams) GetActionsResult {
+	result := ro.history.getActions(params, ro.chain.ToVariantWithAbi)
+	result.LastIrreversibleBlock = ro.chain.Chain().LastIrreversibleBlockNum()
+	return result
+}
+
+func (my *historyPluginImpl) getActions(params GetActionsParams, toVariant func(interface{}) interface{}) GetActionsResult {
+	pos, offset := int64(-1), int64(-20)
+	if params.Pos != nil {
+		pos = int64(*params.Pos)
+	}
+	if params.Offset != nil {
+		offset = int64(*params.Offset)
+	}
+	if pos == -1 {
+		if last, ok := my.lastAccountSequence(params.AccountName); ok {
+			pos = int64(last) + 1
+		} else {
+			pos = 0xfffffff
+		}
+	}
+
+	var start, end int64
+	if offset > 0 {
+		start = pos
+		end = start + offset
+	} else {
+		start = pos + offset
+		if start > pos {
+			start = 0
+		}
+		end = pos
+	}
+	EosAssert(end >= start, &PluginException{}, "end position is earlier than start position")
+	if start < 0 {
+		start = 0
+	}
+	if end >= math.MaxInt32 {
+		end = math.MaxInt32 - 1
+	}
+
+	result := GetActionsResult{Actions: make([]OrderedActionResult, 0)}
+	if end < start {
+		return result
+	}
+
+	idx, err := my.db.GetIndex("byAccountActionSeq", entity.AccountHistoryObject{})
+	EosAssert(err == nil, &DatabaseException{}, "get account history index is error: %s", err)
+	it, err := idx.Range(entity.AccountHistoryObject{Account: params.AccountName, AccountSequenceNum: int32(start)},
+		entity.AccountHistoryObject{Account: params.AccountName, AccountSequenceNum: int32(end + 1)})
+	EosAssert(err == nil, &DatabaseException{}, "get account history range is error: %s", err)
+	defer it.Release()
+
+	startTime := common.Now()
+	for it.Next() {
+		obj := entity.AccountHistoryObject{}
+		EosAssert(it.Data(&obj) == nil, &DatabaseException{}, "account history of %s is not readable", params.AccountName)
+
+		a := entity.ActionHistoryObject{ActionSequenceNum: obj.ActionSequenceNum}
+		err := my.db.Find("byActionSequenceNum", a, &a)
+		EosAssert(err == nil, &DatabaseException{}, "action %d of %s is not in history", obj.ActionSequenceNum, params.AccountName)
+
+		result.Actions = append(result.Actions, OrderedActionResult{
+			GlobalActionSeq:  obj.ActionSequenceNum,
+			AccountActionSeq: obj.AccountSequenceNum,
+			BlockNum:         a.BlockNum,
+			BlockTime:        a.BlockTime,
+			ActionTrace:      toVariant(unpackActionTrace(a.PackedActionTrace)),
+		})
+
+		if common.Now().Sub(startTime) > getActionsTimeLimit {
+			result.TimeLimitExceededError = true
+			break
+		}
+	}
+	return result
+}
+
+type GetTransactionParams struct {
+	ID           string  `json:"id"`
+	BlockNumHint *uint32 `json:"block_num_hint"`
+}
+
+type GetTransactionResult struct {
+	ID                    common.TransactionIdType `json:"id"`
+	Trx                   interface{}              `json:"trx"`
+	BlockTime             common.BlockTimeStamp    `json:"block_time"`
+	BlockNum              uint32                   `json:"block_num"`
+	LastIrreversibleBlock uint32                   `json:"last_irreversible_block"`
+	Traces                []interface{}            `json:"traces"`
+}
+
+// GetTransaction accepts the leading 8 to 64 hex characters of an id, the transaction is looked
+// up in the action history first and in the block given by block_num_hint otherwise
+func (ro *ReadOnly) GetTransaction(params GetTransactionParams) GetTransactionResult {
+	id := strings.ToLower(params.ID)
+	EosAssert(len(id) <= 64, &TransactionIdTypeException{}, "hex string is too long")
+	EosAssert(len(id) >= 8, &TransactionIdTypeException{}, "hex string too short")
+	_, err := hex.DecodeString(id[:len(id)&^1])
+	EosAssert(err == nil, &TransactionIdTypeException{}, "hex string is invalid: %s", params.ID)
+
+	chain := ro.chain.Chain()
+	result := GetTransactionResult{Traces: make([]interface{}, 0), LastIrreversibleBlock: chain.LastIrreversibleBlockNum()}
+	matched := func(trxId common.TransactionIdType) bool {
+		return strings.HasPrefix(trxId.String(), id)
+	}
+
+	actions := ro.history.transactionActions(id)
+	if len(actions) == 0 {
+		EosAssert(params.BlockNumHint != nil, &TxNotFound{},
+			"Transaction %s not found in history and no block hint was given", params.ID)
+
+		blk := chain.FetchBlockByNumber(*params.BlockNumHint)
+		if blk != nil {
+			for _, receipt := range blk.Transactions {
+				trxId, trx := receiptTransaction(&receipt)
+				if matched(trxId) {
+					result.ID = trxId
+					result.BlockNum = *params.BlockNumHint
+					result.BlockTime = blk.Timestamp
+					result.Trx = ro.chain.ToVariantWithAbi(trx)
+					return result
+				}
+			}
+		}
+		EosThrow(&TxNotFound{}, "Transaction %s not found in history or in block number %d", params.ID, *params.BlockNumHint)
+	}
+
+	result.ID = actions[0].TrxId
+	result.BlockNum = actions[0].BlockNum
+	result.BlockTime = actions[0].BlockTime
+	for _, a := range actions {
+		result.Traces = append(result.Traces, ro.chain.ToVariantWithAbi(unpackActionTrace(a.PackedActionTrace)))
+	}
+
+	blk := chain.FetchBlockByNumber(result.BlockNum)
+	if blk == nil {
+		if pending := chain.PendingBlockState(); pending != nil {
+			blk = pending.SignedBlock
+		}
+	}
+	if blk != nil {
+		for _, receipt := range blk.Transactions {
+			trxId, trx := receiptTransaction(&receipt)
+			if trxId == result.ID {
+				result.Trx = ro.chain.ToVariantWithAbi(trx)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// transactionActions returns the recorded actions of the transaction starting with id, sorted
+// by their global sequence. A short id is padded with zeros, the first transaction id not less
+// than it is the one that starts with id if any does
+func (my *historyPluginImpl) transactionActions(id string) []entity.ActionHistoryObject {
+	result := make([]entity.ActionHistoryObject, 0)
+	idx, err := my.db.GetIndex("byTrxId", entity.ActionHistoryObject{})
+	EosAssert(err == nil, &DatabaseException{}, "get action history index is error: %s", err)
+	it, err := idx.LowerBound(entity.ActionHistoryObject{TrxId: common.TransactionIdType(*crypto.NewSha256String(id + strings.Repeat("0", 64-len(id))))})
+	if err != nil {
+		return result
+	}
+	defer it.Release()
+
+	for it.Next() {
+		obj := entity.ActionHistoryObject{}
+		if it.Data(&obj) != nil {
+			break
+		}
+		if len(result) == 0 && !strings.HasPrefix(obj.TrxId.String(), id) {
+			break
+		}
+		if len(result) > 0 && obj.TrxId != result[0].TrxId {
+			break
+		}
+		result = append(result, obj)
+	}
+	return result
+}
+
+func receiptTransaction(receipt *types.TransactionReceipt) (common.TransactionIdType, interface{}) {
+	if receipt.Trx.PackedTransaction != nil {
+		pt := receipt.Trx.PackedTransaction
+		return pt.ID(), common.Variants{"receipt": receipt, "trx": pt.GetSignedTransaction()}
+	}
+	return receipt.Trx.TransactionID, common.Variants{"receipt": receipt}
+}
+
+func unpackActionTrace(packed common.HexBytes) *types.ActionTrace {
+	trace := types.ActionTrace{}
+	err := rlp.DecodeBytes(packed, &trace)
+	EosAssert(err == nil, &DatabaseException{}, "unpack action trace is error: %s", err)
+	return &trace
+}
+
+type GetKeyAccountsParams struct {
+	PublicKey ecc.PublicKey `json:"public_key"`
+}
+
+type GetKeyAccountsResults struct {
+	AccountNames []common.AccountName `json:"account_names"`
+}
+
+func (ro *ReadOnly) GetKeyAccounts(params GetKeyAccountsParams) GetKeyAccountsResults {
+	return GetKeyAccountsResults{AccountNames: ro.history.keyAccounts(params.PublicKey)}
+}
+
+func (my *historyPluginImpl) keyAccounts(key ecc.PublicKey) []common.AccountName {
+	names := make(map[common.AccountName]bool)
+	idx, err := my.db.GetIndex("byPubKey", entity.PublicKeyHistoryObject{})
+	EosAssert(err == nil, &DatabaseException{}, "get public key history index is error: %s", err)
+	if it, err := idx.LowerBound(entity.PublicKeyHistoryObject{PublicKey: key}); err == nil {
+		for it.Next() {
+			obj := entity.PublicKeyHistoryObject{}
+			if it.Data(&obj) != nil || obj.PublicKey != key {
+				break
+			}
+			names[obj.Name] = true
+		}
+		it.Release()
+	}
+	return sortedNames(names)
+}
+
+type GetControlledAccountsParams struct {
+	ControllingAccount common.AccountName `json:"controlling_account"`
+}
+
+type GetControlledAccountsResults struct {
+	ControlledAccounts []common.AccountName `json:"controlled_accounts"`
+}
+
+func (ro *ReadOnly) GetControlledAccounts(params GetControlledAccountsParams) GetControlledAccountsResults {
+	return GetControlledAccountsResults{ControlledAccounts: ro.history.controlledAccounts(params.ControllingAccount)}
+}
+
+func (my *historyPluginImpl) controlledAccounts(controlling common.AccountName) []common.AccountName {
+	names := make(map[common.AccountName]bool)
+	idx, err := my.db.GetIndex("byControlling", entity.AccountControlHistoryObject{})
+	EosAssert(err == nil, &DatabaseException{}, "get account control history index is error: %s", err)
+	if it, err := idx.LowerBound(entity.AccountControlHistoryObject{ControllingAccount: controlling}); err == nil {
+		for it.Next() {
+			obj := entity.AccountControlHistoryObject{}
+			if it.Data(&obj) != nil || obj.ControllingAccount != controlling {
+				break
+			}
+			names[obj.ControlledAccount] = true
+		}
+		it.Release()
+	}
+	return sortedNames(names)
+}
+
+func sortedNames(names map[common.AccountName]bool) []common.AccountName {
+	result := make([]common.AccountName, 0, len(names))
+	for n := range names {
+		result = append(result, n)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
@@ -7,6 +7,7 @@ import (
 
 	"encoding/hex"
 	"encoding/json"
+	"github.com/eosspark/eos-go/chain"
 	"github.com/eosspark/eos-go/exception"
 	"time"
 
@@ -233,15 +234,13 @@ func (np *NetPlugin) PluginStartup() {
 	go np.my.startConnTimer()
 	go np.my.startTxnTimer()
 
-	//chain::controller&cc = my->chain_plug->chain();
-	//	{
-	//		cc.accepted_block_header.connect( boost::bind(&net_plugin_impl::accepted_block_header, my.get(), _1));
-	//		cc.accepted_block.connect(  boost::bind(&net_plugin_impl::accepted_block, my.get(), _1));
-	//		cc.irreversible_block.connect( boost::bind(&net_plugin_impl::irreversible_block, my.get(), _1));
-	//		cc.accepted_transaction.connect( boost::bind(&net_plugin_impl::accepted_transaction, my.get(), _1));
-	//		cc.applied_transaction.connect( boost::bind(&net_plugin_impl::applied_transaction, my.get(), _1));
-	//		cc.accepted_confirmation.connect( boost::bind(&net_plugin_impl::accepted_confirmation, my.get(), _1));
-	//	}
+	cc := chain.GetControllerInstance()
+	cc.AcceptedBlockHeader.Connect(np.my.AcceptedBlockHeader)
+	cc.AcceptedBlock.Connect(np.my.AcceptedBlock)
+	cc.IrreversibleBlock.Connect(np.my.IrreversibleBlock)
+	cc.AcceptedTransaction.Connect(np.my.AcceptedTransaction)
+	cc.AppliedTransaction.Connect(np.my.AppliedTransaction)
+	cc.AcceptedConfirmation.Connect(np.my.AcceptedConfirmation)
 	//	my->incoming_transaction_ack_subscription = app().get_channel<channels::transaction_ack>().subscribe(boost::bind(&net_plugin_impl::transaction_ack, my.get(), _1));
 	//	if( cc.get_read_mode() == chain::db_read_mode::READ_ONLY ) {
 	//	my->max_nodes_per_host = 0;
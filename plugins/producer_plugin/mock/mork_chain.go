@@ -2,6 +2,7 @@ package mock
 
 import (
 	"fmt"
+	Chain "github.com/eosspark/eos-go/chain"
 	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
 	"github.com/eosspark/eos-go/crypto"
@@ -33,10 +34,16 @@ var initPubKey2 = initPriKey2.PublicKey()
 var eosio = common.AccountName(common.N("eosio"))
 var yuanc = common.AccountName(common.N("yuanc"))
 
+type Connection = Chain.Connection
+
 type Controller struct {
 	head    *types.BlockState
 	pending *types.BlockState
 	forkDb  forkDatabase
+	lib     uint32
+
+	AcceptedBlock     Chain.BlockStateSignal
+	IrreversibleBlock Chain.BlockStateSignal
 }
 
 func GetControllerInstance() *Controller {
@@ -107,35 +114,35 @@ func Initialize() {
 	fmt.Println("init", genHeader.Header.Timestamp.ToTimePoint())
 }
 
-func (c Controller) LastIrreversibleBlockNum() uint32 {
+func (c *Controller) LastIrreversibleBlockNum() uint32 {
 	return c.head.DposIrreversibleBlocknum
 }
 
-func (c Controller) HeadBlockState() *types.BlockState {
+func (c *Controller) HeadBlockState() *types.BlockState {
 	return c.head
 
 }
-func (c Controller) HeadBlockTime() common.TimePoint {
+func (c *Controller) HeadBlockTime() common.TimePoint {
 	return c.head.Header.Timestamp.ToTimePoint()
 }
 
-func (c Controller) PendingBlockTime() common.TimePoint {
+func (c *Controller) PendingBlockTime() common.TimePoint {
 	return c.pending.Header.Timestamp.ToTimePoint()
 }
 
-func (c Controller) HeadBlockNum() uint32 {
+func (c *Controller) HeadBlockNum() uint32 {
 	return c.head.BlockNum
 }
 
-func (c Controller) PendingBlockState() *types.BlockState {
+func (c *Controller) PendingBlockState() *types.BlockState {
 	return c.pending
 }
 
-func (c Controller) GetUnappliedTransactions() []*types.TransactionMetadata {
+func (c *Controller) GetUnappliedTransactions() []*types.TransactionMetadata {
 	return make([]*types.TransactionMetadata, 0)
 }
 
-func (c Controller) GetScheduledTransactions() []common.TransactionIdType {
+func (c *Controller) GetScheduledTransactions() []common.TransactionIdType {
 	return make([]common.TransactionIdType, 0)
 }
 
@@ -174,10 +181,22 @@ func (c *Controller) CommitBlock(addToForkDb bool) {
 		c.forkDb.add(c.pending)
 		c.head = c.forkDb.head
 	}
+	c.AcceptedBlock.Emit(c.pending)
+	c.onIrreversible()
 
 	//c.pending = nil
 }
 
+// onIrreversible emits IrreversibleBlock for every block that became irreversible with the current head.
+func (c *Controller) onIrreversible() {
+	for newLib := c.head.DposIrreversibleBlocknum; c.lib < newLib; {
+		c.lib++
+		if state := c.forkDb.findByNum(c.lib); state != nil {
+			c.IrreversibleBlock.Emit(state)
+		}
+	}
+}
+
 func (c *Controller) PushTransaction(trx *types.TransactionMetadata, deadline common.TimePoint) *types.TransactionTrace {
 	//c.pending.SignedBlock.Transactions = append(c.pending.SignedBlock.Transactions, )
 	c.pending.Trxs = append(c.pending.Trxs, trx)
@@ -206,6 +225,7 @@ func (c *Controller) MaybeSwitchForks() {
 	if newHead.Header.Previous == c.head.BlockId {
 		c.ApplyBlock(newHead.SignedBlock)
 		c.head = newHead
+		c.onIrreversible()
 
 	} else if newHead.ID != c.head.ID {
 		fmt.Println(" newHead.ID != c.head.ID ")
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	Chain "github.com/eosspark/eos-go/plugins/producer_plugin/mock" /*test model*/
 	//Chain "github.com/eosspark/eos-go/chain" /*real chain*/
+	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
 	"github.com/eosspark/eos-go/crypto"
 	"github.com/eosspark/eos-go/crypto/ecc"
@@ -227,9 +228,12 @@ func (p *ProducerPlugin) PluginStartup() {
 	EosAssert(len(p.my.Producers) == 0 || chain.GetValidationMode() == Chain.ValidationMode(Chain.FULL), &PluginConfigException{},
 		"node cannot have any producer-name configured because block production is not safe when validation_mode is not \"full\"" )
 
-	//TODO if
-	// my->_accepted_block_connection.emplace(chain.accepted_block.connect( [this]( const auto& bsp ){ my->on_block( bsp ); } ));
-	// my->_irreversible_block_connection.emplace(chain.irreversible_block.connect( [this]( const auto& bsp ){ my->on_irreversible_block( bsp->block ); } ));
+	p.my.AcceptedBlockConnection = chain.AcceptedBlock.Connect(func(bsp *types.BlockState) {
+		p.my.OnBlock(bsp)
+	})
+	p.my.IrreversibleBlockConnection = chain.IrreversibleBlock.Connect(func(bsp *types.BlockState) {
+		p.my.OnIrreversibleBlock(bsp.SignedBlock)
+	})
 
 	libNum := chain.LastIrreversibleBlockNum()
 	lib := chain.FetchBlockByNumber(libNum)
@@ -256,6 +260,8 @@ func (p *ProducerPlugin) PluginStartup() {
 
 func (p *ProducerPlugin) PluginShutdown() {
 	p.my.Timer.Cancel()
+	p.my.AcceptedBlockConnection.Disconnect()
+	p.my.IrreversibleBlockConnection.Disconnect()
 }
 
 func (p *ProducerPlugin) Pause() {
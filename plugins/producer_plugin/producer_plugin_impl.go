@@ -59,6 +59,9 @@ type ProducerPluginImpl struct {
 	// keep a expected ratio between defer txn and incoming txn
 	IncomingTrxWeight  float64
 	IncomingDeferRadio float64
+
+	AcceptedBlockConnection     Chain.Connection
+	IrreversibleBlockConnection Chain.Connection
 }
 
 type EnumStartBlockRusult int
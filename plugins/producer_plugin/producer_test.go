@@ -168,6 +168,10 @@ func TestProducerPlugin_PluginStartup(t *testing.T) {
 	}()
 
 	exec()
+
+	lib := chain.FetchBlockByNumber(chain.LastIrreversibleBlockNum())
+	assert.NotNil(t, lib)
+	assert.Equal(t, lib.Timestamp.ToTimePoint(), plugin.my.IrreversibleBlockTime)
 }
 
 func TestProducerPlugin_Pause(t *testing.T) {
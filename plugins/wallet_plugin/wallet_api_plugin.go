@@ -1 +1,60 @@
 package walletPlugin
+
+import (
+	"github.com/eosspark/eos-go/log"
+	"github.com/eosspark/eos-go/plugins/appbase/plugin/http_plugin"
+)
+
+const (
+	walletFuncBase       string = "/v1/wallet"
+	walletCreateFunc     string = walletFuncBase + "/create"
+	walletOpenFunc       string = walletFuncBase + "/open"
+	walletListFunc       string = walletFuncBase + "/list_wallets"
+	walletListKeysFunc   string = walletFuncBase + "/list_keys"
+	walletPublicKeysFunc string = walletFuncBase + "/get_public_keys"
+	walletLockFunc       string = walletFuncBase + "/lock"
+	walletLockAllFunc    string = walletFuncBase + "/lock_all"
+	walletUnlockFunc     string = walletFuncBase + "/unlock"
+	walletImportKeyFunc  string = walletFuncBase + "/import_key"
+	walletRemoveKeyFunc  string = walletFuncBase + "/remove_key"
+	walletCreateKeyFunc  string = walletFuncBase + "/create_key"
+	walletSignTrxFunc    string = walletFuncBase + "/sign_transaction"
+
+	walletSignDigestFunc string = walletFuncBase + "/sign_digest"
+	walletSetTimeOutFunc string = walletFuncBase + "/set_timeout"
+)
+
+// PluginStartUp mounts the /v1/wallet endpoints on the http plugin
+func PluginStartUp() {
+	log.Info("starting wallet_api_plugin")
+	http := http_plugin.GetInstance()
+	// http.AddHttpHandler(walletSetTimeOutFunc, SetTimeOut())
+	http.AddHttpHandler(walletSignTrxFunc, SignTransaction())
+	http.AddHttpHandler(walletSignDigestFunc, SignDigest())
+	http.AddHttpHandler(walletCreateFunc, Create())
+	http.AddHttpHandler(walletOpenFunc, Open())
+	http.AddHttpHandler(walletLockAllFunc, LockAllwallets())
+	http.AddHttpHandler(walletLockFunc, Lock())
+	http.AddHttpHandler(walletUnlockFunc, UnLock())
+	http.AddHttpHandler(walletImportKeyFunc, ImportKey())
+	http.AddHttpHandler(walletRemoveKeyFunc, RemoveKey())
+	http.AddHttpHandler(walletCreateKeyFunc, CreateKey())
+	http.AddHttpHandler(walletListFunc, ListWallets())
+	http.AddHttpHandler(walletListKeysFunc, ListKeys())
+	http.AddHttpHandler(walletPublicKeysFunc, GetPublicKeys())
+}
+
+func PluginInitialize() {
+	if !http_plugin.GetInstance().IsOnLoopback() {
+		log.Warn("\n" +
+			"**********SECURITY WARNING**********\n" +
+			"*                                  *\n" +
+			"* --        Wallet API          -- *\n" +
+			"* - EXPOSED to the LOCAL NETWORK - *\n" +
+			"* - HTTP RPC is NOT encrypted    - *\n" +
+			"* - Password and/or Private Keys - *\n" +
+			"* - are at risk of being leaked  - *\n" +
+			"*                                  *\n" +
+			"************************************\n")
+	}
+}
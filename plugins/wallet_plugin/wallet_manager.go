@@ -449,7 +449,7 @@ func SignTransaction() http.Handler {
 
 			for _, wallet := range wallets {
 				if !wallet.isLocked() {
-					sig := wallet.trySignDigest(tx.SigDigest(chainID, tx.ContextFreeData), key)
+					sig := wallet.trySignDigest(tx.SigDigest(&chainID, tx.ContextFreeData), key)
 					if !common.Empty(sig) {
 						tx.Signatures = append(tx.Signatures, *sig)
 						found = true
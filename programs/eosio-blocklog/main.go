@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/eosspark/eos-go/chain"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	blocksDirFlag = cli.StringFlag{
+		Name:  "blocks-dir",
+		Value: "blocks",
+		Usage: "the location of the blocks directory (absolute path or relative to the current directory)",
+	}
+	outputFileFlag = cli.StringFlag{
+		Name:  "output-file, o",
+		Usage: "the file to write the blocks to, stdout when not set",
+	}
+	outputDirFlag = cli.StringFlag{
+		Name:  "output-dir",
+		Usage: "the directory the trimmed block log is written to",
+	}
+	firstFlag = cli.UintFlag{
+		Name:  "first, f",
+		Value: 1,
+		Usage: "the first block number to export",
+	}
+	lastFlag = cli.UintFlag{
+		Name:  "last, l",
+		Value: math.MaxUint32,
+		Usage: "the last block number to export or to keep in a trimmed block log",
+	}
+	asJsonArrayFlag = cli.BoolFlag{
+		Name:  "as-json-array",
+		Usage: "print out the blocks as a json array instead of one block per line",
+	}
+	truncateAtBlockFlag = cli.UintFlag{
+		Name:  "truncate-at-block",
+		Usage: "stop the repair after this block, 0 keeps every good block",
+	}
+)
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "eosio-blocklog"
+	app.Usage = "inspect, repair and export a block log"
+	app.Commands = []cli.Command{
+		{
+			Name:   "export",
+			Usage:  "Export a range of blocks as json",
+			Action: run(exportBlocks),
+			Flags:  []cli.Flag{blocksDirFlag, outputFileFlag, firstFlag, lastFlag, asJsonArrayFlag},
+		},
+		{
+			Name:   "trim",
+			Usage:  "Write the blocks up to --last to a new block log",
+			Action: run(trimBlocks),
+			Flags:  []cli.Flag{blocksDirFlag, outputDirFlag, lastFlag},
+		},
+		{
+			Name:   "repair",
+			Usage:  "Truncate the block log at its first corrupt block, or at --truncate-at-block, and rebuild the index",
+			Action: run(repairBlocks),
+			Flags:  []cli.Flag{blocksDirFlag, truncateAtBlockFlag},
+		},
+		{
+			Name:   "make-index",
+			Usage:  "Rebuild blocks.index from blocks.log",
+			Action: run(makeIndex),
+			Flags:  []cli.Flag{blocksDirFlag},
+		},
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run turns an exception thrown by the block log into the error of the command
+func run(action func(c *cli.Context)) func(c *cli.Context) error {
+	return func(c *cli.Context) (err error) {
+		try.Try(func() {
+			action(c)
+		}).Catch(func(e Exception) {
+			err = errors.New(e.Message())
+		}).End()
+		return
+	}
+}
+
+func openBlockLog(c *cli.Context) *chain.BlockLog {
+	dir := c.String(blocksDirFlag.Name)
+	_, err := os.Stat(filepath.Join(dir, "blocks.log"))
+	EosAssert(err == nil, &BlockLogNotFound{}, "Block log not found in '%s'", dir)
+	return chain.NewBlockLog(dir)
+}
+
+func exportBlocks(c *cli.Context) {
+	blockLog := openBlockLog(c)
+	defer blockLog.Close()
+
+	var out io.Writer = os.Stdout
+	if name := c.String("output-file"); name != "" {
+		file, err := os.Create(name)
+		EosAssert(err == nil, &BlockLogException{}, "unable to create output file %s: %s", name, err)
+		defer file.Close()
+		out = file
+	}
+
+	first, last := uint32(c.Uint(firstFlag.Name)), uint32(c.Uint(lastFlag.Name))
+	if head := blockLog.Head(); head == nil {
+		last = 0
+	} else if head.BlockNumber() < last {
+		last = head.BlockNumber()
+	}
+
+	asArray := c.Bool(asJsonArrayFlag.Name)
+	if asArray {
+		fmt.Fprint(out, "[")
+	}
+	for num := first; num <= last; num++ {
+		block := blockLog.ReadBlockByNum(num)
+		EosAssert(block != nil, &BlockLogException{}, "block %d can not be read from the block log", num)
+		data, err := json.Marshal(block)
+		EosAssert(err == nil, &BlockLogException{}, "unable to convert block %d to json: %s", num, err)
+		if asArray && num > first {
+			fmt.Fprint(out, ",")
+		}
+		fmt.Fprintf(out, "%s", data)
+		if !asArray {
+			fmt.Fprintln(out)
+		}
+	}
+	if asArray {
+		fmt.Fprintln(out, "]")
+	}
+}
+
+func trimBlocks(c *cli.Context) {
+	outDir := c.String(outputDirFlag.Name)
+	EosAssert(outDir != "", &BlockLogException{}, "--output-dir is required")
+	last := chain.TrimLog(c.String(blocksDirFlag.Name), outDir, uint32(c.Uint(lastFlag.Name)))
+	fmt.Printf("wrote blocks 1 to %d to %s\n", last, outDir)
+}
+
+func repairBlocks(c *cli.Context) {
+	backupDir := chain.RepairLog(c.String(blocksDirFlag.Name), uint32(c.Uint(truncateAtBlockFlag.Name)))
+	fmt.Printf("the original blocks directory was moved to %s\n", backupDir)
+}
+
+func makeIndex(c *cli.Context) {
+	blockLog := openBlockLog(c)
+	defer blockLog.Close()
+	blockLog.ConstructIndex()
+}
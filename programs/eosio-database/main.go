@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/database"
+	. "github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	stateDirFlag = cli.StringFlag{
+		Name:  "state-dir",
+		Value: "state",
+		Usage: "the location of the state directory (absolute path or relative to the current directory)",
+	}
+	typeFlag = cli.StringFlag{
+		Name:  "type, t",
+		Usage: "only check the objects of this type, e.g. AccountObject",
+	}
+	repairFlag = cli.BoolFlag{
+		Name:  "repair",
+		Usage: "put the missing index keys and delete the orphaned ones",
+	}
+	verboseFlag = cli.BoolFlag{
+		Name:  "verbose, v",
+		Usage: "print every key found out of sync",
+	}
+)
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "eosio-database"
+	app.Usage = "inspect and repair a state database"
+	app.Commands = []cli.Command{
+		{
+			Name:   "check",
+			Usage:  "Check that the index keys of every object type point to existing objects with matching fields",
+			Action: run(checkDatabase),
+			Flags:  []cli.Flag{stateDirFlag, typeFlag, repairFlag, verboseFlag},
+		},
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run turns an exception thrown by the database into the error of the command
+func run(action func(c *cli.Context) error) func(c *cli.Context) error {
+	return func(c *cli.Context) (err error) {
+		try.Try(func() {
+			err = action(c)
+		}).Catch(func(e Exception) {
+			err = errors.New(e.Message())
+		}).End()
+		return
+	}
+}
+
+func openDatabase(c *cli.Context) database.DataBase {
+	dir := c.String(stateDirFlag.Name)
+	_, err := os.Stat(dir)
+	EosAssert(err == nil, &DatabaseException{}, "State database not found in '%s'", dir)
+
+	database.RegisterType(chain.StateObjects...)
+	db, err := database.NewDataBase(dir)
+	EosAssert(err == nil, &DatabaseException{}, "unable to open state database %s: %s", dir, err)
+	return db
+}
+
+func checkDatabase(c *cli.Context) error {
+	objects := chain.StateObjects
+	if name := c.String("type"); name != "" {
+		objects = nil
+		for _, object := range chain.StateObjects {
+			if reflect.TypeOf(object).Elem().Name() == name {
+				objects = append(objects, object)
+			}
+		}
+		EosAssert(len(objects) != 0, &DatabaseException{}, "unknown object type %s", name)
+	}
+
+	db := openDatabase(c)
+	defer db.Close()
+
+	repair, verbose := c.Bool(repairFlag.Name), c.Bool("verbose")
+	unrepaired := 0
+	for _, object := range objects {
+		report, err := db.Check(object, repair)
+		EosAssert(err == nil, &DatabaseException{}, "unable to check %s: %s", reflect.TypeOf(object).Elem().Name(), err)
+
+		fmt.Printf("%-28s %8d objects %10d keys %6d issues\n", report.Type, report.Objects, report.Keys, len(report.Issues))
+		for _, issue := range report.Issues {
+			if verbose {
+				fmt.Printf("    %s\n", issue)
+			}
+			if !issue.Repaired {
+				unrepaired++
+			}
+		}
+	}
+	if unrepaired != 0 && !repair {
+		return fmt.Errorf("%d keys are out of sync, run with --repair to fix the index keys", unrepaired)
+	} else if unrepaired != 0 {
+		return fmt.Errorf("%d keys are out of sync and can not be repaired, they have to be looked at by hand", unrepaired)
+	}
+	return nil
+}
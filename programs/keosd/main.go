@@ -3,55 +3,30 @@ package main
 import (
 	"flag"
 	"fmt"
-	"github.com/eosspark/eos-go/plugins/wallet_plugin"
-	"log"
-	"net/http"
-)
-
-const (
-	walletFuncBase       string = "/v1/wallet"
-	walletCreateFunc     string = walletFuncBase + "/create"
-	walletOpenFunc       string = walletFuncBase + "/open"
-	walletListFunc       string = walletFuncBase + "/list_wallets"
-	walletListKeysFunc   string = walletFuncBase + "/list_keys"
-	walletPublicKeysFunc string = walletFuncBase + "/get_public_keys"
-	walletLockFunc       string = walletFuncBase + "/lock"
-	walletLockAllFunc    string = walletFuncBase + "/lock_all"
-	walletUnlockFunc     string = walletFuncBase + "/unlock"
-	walletImportKeyFunc  string = walletFuncBase + "/import_key"
-	walletRemoveKeyFunc  string = walletFuncBase + "/remove_key"
-	walletCreateKeyFunc  string = walletFuncBase + "/create_key"
-	walletSignTrxFunc    string = walletFuncBase + "/sign_transaction"
+	"os"
+	"os/signal"
 
-	walletSignDigestFunc string = walletFuncBase + "/sign_digest"
-	walletSetTimeOutFunc string = walletFuncBase + "/set_timeout"
+	"github.com/eosspark/eos-go/plugins/appbase/plugin/http_plugin"
+	"github.com/eosspark/eos-go/plugins/wallet_plugin"
 )
 
 var walletlistenAddress = flag.String("wallet-listen-address", "127.0.0.1:8000", "The local IP and port to listen for incoming http connections;")
+var walletUnixSocketPath = flag.String("unix-socket-path", "", "The filename to create a unix socket for wallet RPC; set blank to disable.")
 
 func main() {
 	flag.Parse()
-	done := make(chan bool)
-	wallet := http.NewServeMux()
-	// wallet.Handle(walletSetTimeOutFunc, walletPlugin.SetTimeOut())
-	wallet.Handle(walletSignTrxFunc, walletPlugin.SignTransaction())
-	wallet.Handle(walletSignDigestFunc, walletPlugin.SignDigest())
-	wallet.Handle(walletCreateFunc, walletPlugin.Create())
-	wallet.Handle(walletOpenFunc, walletPlugin.Open())
-	wallet.Handle(walletLockAllFunc, walletPlugin.LockAllwallets())
-	wallet.Handle(walletLockFunc, walletPlugin.Lock())
-	wallet.Handle(walletUnlockFunc, walletPlugin.UnLock())
-	wallet.Handle(walletImportKeyFunc, walletPlugin.ImportKey())
-	wallet.Handle(walletRemoveKeyFunc, walletPlugin.RemoveKey())
-	wallet.Handle(walletCreateKeyFunc, walletPlugin.CreateKey())
-	wallet.Handle(walletListFunc, walletPlugin.ListWallets())
-	wallet.Handle(walletListKeysFunc, walletPlugin.ListKeys())
-	wallet.Handle(walletPublicKeysFunc, walletPlugin.GetPublicKeys())
 
+	http := http_plugin.GetInstance()
+	http.SetDefaults(*walletlistenAddress, *walletUnixSocketPath)
+	http.Initialize(nil)
+	walletPlugin.PluginInitialize()
+
+	walletPlugin.PluginStartUp()
+	http.StartUp()
 	fmt.Printf("Listening for wallet operations on %s\n", *walletlistenAddress)
-	err := http.ListenAndServe(*walletlistenAddress, wallet)
-	if err != nil {
-		log.Println("Litsening failed:", err)
-	}
-	<-done
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	<-sigChan
+	http.ShutDown()
 }
@@ -5,7 +5,6 @@ import (
 	"github.com/eosspark/eos-go/chain"
 	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
-	"github.com/eosspark/eos-go/crypto"
 	"github.com/eosspark/eos-go/crypto/rlp"
 	"github.com/eosspark/eos-go/wasmgo"
 	"io/ioutil"
@@ -31,8 +30,7 @@ func main() {
 		},
 	}
 
-	codeVersion := crypto.NewSha256Byte([]byte(code))
-	wasmgo.Apply(codeVersion, code, applyContext)
+	wasmgo.Apply(nil, 0, 0, code, applyContext)
 
 	//print "hello, walker"
 	fmt.Println(applyContext.PendingConsoleOutput)
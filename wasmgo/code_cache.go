@@ -0,0 +1,112 @@
+package wasmgo
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/eosspark/eos-go/crypto"
+	"github.com/eosspark/eos-go/wasmgo/wagon/exec"
+)
+
+// DefaultCodeCacheSize is the number of compiled contracts kept when the size is not configured
+const DefaultCodeCacheSize = 64
+
+// codeKey identifies the code of a contract, see: libraries/chain/wasm_interface.cpp get_instantiated_module
+type codeKey struct {
+	codeId    crypto.Sha256
+	vmType    uint8
+	vmVersion uint8
+}
+
+// CodeCacheMetrics are the counters of the compiled contract cache since the node started
+type CodeCacheMetrics struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+	Capacity      int    `json:"capacity"`
+}
+
+/*
+codeCache keeps the compiled modules of the contracts run last, the least recently used one is
+evicted once it is full. A compiled module is never modified by the VMs created from it so it is
+shared by every execution of the contract
+*/
+type codeCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[codeKey]*list.Element
+	lru      *list.List // front is the most recently used
+	metrics  CodeCacheMetrics
+}
+
+type codeCacheEntry struct {
+	key    codeKey
+	module *exec.CompiledModule
+}
+
+func newCodeCache(capacity int) *codeCache {
+	return &codeCache{capacity: capacity, entries: make(map[codeKey]*list.Element), lru: list.New()}
+}
+
+func (c *codeCache) get(key codeKey) *exec.CompiledModule {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.metrics.Hits++
+		c.lru.MoveToFront(e)
+		return e.Value.(*codeCacheEntry).module
+	}
+	c.metrics.Misses++
+	return nil
+}
+
+func (c *codeCache) put(key codeKey, module *exec.CompiledModule) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*codeCacheEntry).module = module
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&codeCacheEntry{key: key, module: module})
+	c.evict()
+}
+
+// invalidate drops the compiled modules of codeId for every vm
+func (c *codeCache) invalidate(codeId crypto.Sha256) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, e := range c.entries {
+		if key.codeId == codeId {
+			c.lru.Remove(e)
+			delete(c.entries, key)
+			c.metrics.Invalidations++
+		}
+	}
+}
+
+func (c *codeCache) resize(capacity int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.capacity = capacity
+	c.evict()
+}
+
+func (c *codeCache) evict() {
+	for c.lru.Len() > c.capacity {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*codeCacheEntry).key)
+		c.metrics.Evictions++
+	}
+}
+
+func (c *codeCache) stats() CodeCacheMetrics {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	metrics := c.metrics
+	metrics.Entries, metrics.Capacity = c.lru.Len(), c.capacity
+	return metrics
+}
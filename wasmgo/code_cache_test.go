@@ -0,0 +1,41 @@
+package wasmgo
+
+import (
+	"testing"
+
+	"github.com/eosspark/eos-go/crypto"
+	"github.com/eosspark/eos-go/wasmgo/wagon/exec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeCache(t *testing.T) {
+	cache := newCodeCache(2)
+	keys := []codeKey{
+		{codeId: crypto.Hash256("token")},
+		{codeId: crypto.Hash256("system")},
+		{codeId: crypto.Hash256("msig")},
+	}
+	modules := []*exec.CompiledModule{{}, {}, {}}
+
+	assert.Nil(t, cache.get(keys[0]))
+	cache.put(keys[0], modules[0])
+	cache.put(keys[1], modules[1])
+	assert.True(t, cache.get(keys[0]) == modules[0])
+
+	// keys[1] is the least recently used
+	cache.put(keys[2], modules[2])
+	assert.Nil(t, cache.get(keys[1]))
+	assert.True(t, cache.get(keys[2]) == modules[2])
+
+	// another vm version is another module of the same code
+	cache.resize(3)
+	other := codeKey{codeId: keys[0].codeId, vmVersion: 1}
+	cache.put(other, modules[1])
+	cache.invalidate(keys[0].codeId)
+	assert.Nil(t, cache.get(keys[0]))
+	assert.Nil(t, cache.get(other))
+
+	cache.resize(0)
+	assert.Nil(t, cache.get(keys[2]))
+	assert.Equal(t, CodeCacheMetrics{Hits: 2, Misses: 5, Evictions: 2, Invalidations: 2, Entries: 0, Capacity: 0}, cache.stats())
+}
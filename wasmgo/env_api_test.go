@@ -12,7 +12,6 @@ import (
 	"github.com/eosspark/eos-go/chain"
 	"github.com/eosspark/eos-go/chain/types"
 	"github.com/eosspark/eos-go/common"
-	"github.com/eosspark/eos-go/crypto"
 	"github.com/eosspark/eos-go/crypto/ecc"
 	"github.com/eosspark/eos-go/exception"
 	"github.com/eosspark/eos-go/exception/try"
@@ -55,8 +54,7 @@ func TestContextApis(t *testing.T) {
 					},
 				}
 
-				codeVersion := crypto.NewSha256Byte([]byte(code))
-				wasm.Apply(codeVersion, code, applyContext)
+				wasm.Apply(nil, 0, 0, code, applyContext)
 
 				//print "hello,walker"
 				//fmt.Println(applyContext.PendingConsoleOutput)
@@ -284,8 +282,7 @@ func TestContextAuth(t *testing.T) {
 			UsedAuthorizations: make([]bool, 1),
 		}
 
-		codeVersion := crypto.NewSha256Byte([]byte(code))
-		wasm.Apply(codeVersion, code, applyContext)
+		wasm.Apply(nil, 0, 0, code, applyContext)
 
 		result := fmt.Sprintf("%v", applyContext.PendingConsoleOutput)
 		assert.Equal(t, result, "walker has authorization,walker is account")
@@ -430,8 +427,7 @@ func callTestFunction(code []byte, cls string, method string, payload []byte) *c
 	applyContext := chain.NewApplyContext(control, nil, &act, 0)
 
 	fmt.Println(cls, method, action)
-	codeVersion := crypto.NewSha256Byte([]byte(code))
-	wasm.Apply(codeVersion, code, applyContext)
+	wasm.Apply(nil, 0, 0, code, applyContext)
 
 	control.Close()
 	control.Clean()
@@ -465,12 +461,11 @@ func callTestFunctionCheckException(code []byte, cls string, method string, payl
 	//	},
 	//}
 
-	codeVersion := crypto.NewSha256Byte([]byte(code))
 
 	//ret := false
 	defer try.HandleReturn()
 	try.Try(func() {
-		wasm.Apply(codeVersion, code, applyContext)
+		wasm.Apply(nil, 0, 0, code, applyContext)
 	}).Catch(func(e exception.Exception) {
 		if e.Code() == errCode {
 			fmt.Println(errMsg)
@@ -15,10 +15,14 @@ var (
 	// an invalid index to the module's table space is used as an operand to
 	// call_indirect
 	ErrUndefinedElementIndex = errors.New("exec: undefined element index")
+	// ErrCallStackExhausted is the error value used while trapping the VM
+	// when a call goes deeper than the VM's MaxCallDepth.
+	ErrCallStackExhausted = errors.New("exec: call stack exhausted")
 )
 
 func (vm *VM) call() {
 	index := vm.fetchUint32()
+	vm.checkTime()
 
 	vm.funcs[index].call(vm, int64(index))
 }
@@ -32,6 +36,7 @@ func (vm *VM) callIndirect() {
 		panic(ErrUndefinedElementIndex)
 	}
 	elemIndex := vm.module.TableIndexSpace[0][tableIndex]
+	vm.checkTime()
 	fnActual := vm.module.FunctionIndexSpace[elemIndex]
 
 	if len(fnExpect.ParamTypes) != len(fnActual.Sig.ParamTypes) {
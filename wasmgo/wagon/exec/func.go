@@ -113,6 +113,12 @@ func (fn goFunction) call(vm *VM, index int64) {
 }
 
 func (compiled compiledFunction) call(vm *VM, index int64) {
+	vm.callDepth++
+	if vm.MaxCallDepth != 0 && vm.callDepth > vm.MaxCallDepth {
+		panic(ErrCallStackExhausted)
+	}
+	defer func() { vm.callDepth-- }()
+
 	newStack := make([]uint64, compiled.maxDepth)
 	locals := make([]uint64, compiled.totalLocalVars)
 
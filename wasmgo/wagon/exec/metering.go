@@ -0,0 +1,34 @@
+package exec
+
+import "errors"
+
+// ErrInstructionLimitExceeded is the error value used while trapping the VM
+// when an execution runs more instructions than its InstructionLimit.
+var ErrInstructionLimitExceeded = errors.New("exec: instruction limit exceeded")
+
+// Instructions returns the number of instructions run by the last call to
+// `ExecCode`.
+func (vm *VM) Instructions() uint64 {
+	return vm.instructions
+}
+
+func (vm *VM) meter() {
+	vm.instructions++
+	if vm.InstructionLimit != 0 && vm.instructions > vm.InstructionLimit {
+		panic(ErrInstructionLimitExceeded)
+	}
+}
+
+func (vm *VM) checkTime() {
+	if vm.CheckTime != nil {
+		vm.CheckTime()
+	}
+}
+
+// jump moves to target, a jump back is the back-edge of a loop.
+func (vm *VM) jump(target int64) {
+	if target < vm.ctx.pc {
+		vm.checkTime()
+	}
+	vm.ctx.pc = target
+}
@@ -0,0 +1,71 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/eosspark/eos-go/wasmgo/wagon/wasm"
+)
+
+// (module (func (export "loop") (loop (br 0))))
+var infiniteLoop = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	0x01, 0x04, 0x01, 0x60, 0x00, 0x00,
+	0x03, 0x02, 0x01, 0x00,
+	0x07, 0x08, 0x01, 0x04, 'l', 'o', 'o', 'p', 0x00, 0x00,
+	0x0a, 0x09, 0x01, 0x07, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b,
+}
+
+var errDeadline = errors.New("deadline exceeded")
+
+func newLoopVM(t *testing.T) *VM {
+	m, err := wasm.ReadModule(bytes.NewReader(infiniteLoop), nil)
+	if err != nil {
+		t.Fatalf("could not read module: %v", err)
+	}
+	vm, err := NewVM(m, nil)
+	if err != nil {
+		t.Fatalf("could not create VM: %v", err)
+	}
+	vm.RecoverPanic = true
+	return vm
+}
+
+func TestCheckTime(t *testing.T) {
+	vm := newLoopVM(t)
+	checks := 0
+	vm.CheckTime = func() {
+		checks++
+		if checks == 100 {
+			panic(errDeadline)
+		}
+	}
+
+	_, err := vm.ExecCode(0)
+	if err != errDeadline {
+		t.Fatalf("expected %v, got %v", errDeadline, err)
+	}
+	if checks != 100 {
+		t.Fatalf("expected 100 checks, got %d", checks)
+	}
+}
+
+func TestInstructionLimit(t *testing.T) {
+	vm := newLoopVM(t)
+	vm.InstructionLimit = 1000
+
+	_, err := vm.ExecCode(0)
+	if err != ErrInstructionLimitExceeded {
+		t.Fatalf("expected %v, got %v", ErrInstructionLimitExceeded, err)
+	}
+	if vm.Instructions() != 1001 {
+		t.Fatalf("expected 1001 instructions, got %d", vm.Instructions())
+	}
+
+	// the count starts over at every call
+	_, err = vm.ExecCode(0)
+	if err != ErrInstructionLimitExceeded || vm.Instructions() != 1001 {
+		t.Fatalf("expected the limit to be hit again after 1001 instructions, got %v after %d", err, vm.Instructions())
+	}
+}
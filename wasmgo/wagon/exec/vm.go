@@ -72,6 +72,24 @@ type VM struct {
 	// A panic can occur either when executing an invalid VM
 	// or encountering an invalid instruction, e.g. `unreachable`.
 	RecoverPanic bool
+
+	// CheckTime, when set, is called at every loop back-edge and function
+	// call. The host aborts an execution past its deadline by panicking
+	// from it.
+	CheckTime func()
+
+	// InstructionLimit, when non-zero, is the number of instructions a call
+	// to `ExecCode` may execute before it traps with
+	// ErrInstructionLimitExceeded.
+	InstructionLimit uint64
+	instructions     uint64
+
+	// MaxCallDepth, when non-zero, is the number of functions that may
+	// be called in one another before the VM traps with
+	// ErrCallStackExhausted, so that a deep recursion does not overflow
+	// the stack of the host.
+	MaxCallDepth int
+	callDepth    int
 }
 
 // As per the WebAssembly spec: https://github.com/WebAssembly/design/blob/27ac254c854994103c24834a994be16f74f54186/Semantics.md#linear-memory
@@ -79,29 +97,21 @@ const wasmPageSize = 65536 // (64 KB)
 
 var endianess = binary.LittleEndian
 
-// NewVM creates a new VM from a given module. If the module defines a
-// start function, it will be executed.
-func NewVM(module *wasm.Module, wasmGo interface{}) (*VM, error) {
-	var vm VM
-
-	//vm.WasmInterface = wasmInterface
-	vm.WasmGo = wasmGo
+// CompiledModule is a module whose functions are disassembled and compiled,
+// any number of VMs can be created from it, they do not modify it.
+type CompiledModule struct {
+	module *wasm.Module
+	funcs  []function
+}
 
-	if module.Memory != nil && len(module.Memory.Entries) != 0 {
-		if len(module.Memory.Entries) > 1 {
-			return nil, ErrMultipleLinearMemories
-		}
-		//module.Memory.Entries[0].Limits.Initial = 16
-		vm.memory = make([]byte, uint(module.Memory.Entries[0].Limits.Initial)*wasmPageSize)
-		//vm.memory = make([]byte, uint(15)*wasmPageSize)
-		copy(vm.memory, module.LinearMemoryIndexSpace[0])
+// CompileModule compiles the functions of a module once for all the VMs
+// created from it.
+func CompileModule(module *wasm.Module) (*CompiledModule, error) {
+	if module.Memory != nil && len(module.Memory.Entries) > 1 {
+		return nil, ErrMultipleLinearMemories
 	}
 
-	vm.funcs = make([]function, len(module.FunctionIndexSpace))
-	vm.globals = make([]uint64, len(module.GlobalIndexSpace))
-	vm.newFuncTable()
-	vm.module = module
-
+	funcs := make([]function, len(module.FunctionIndexSpace))
 	nNatives := 0
 	for i, fn := range module.FunctionIndexSpace {
 		// Skip native methods as they need not be
@@ -111,7 +121,7 @@ func NewVM(module *wasm.Module, wasmGo interface{}) (*VM, error) {
 		// section of:
 		// https://webassembly.github.io/spec/core/exec/modules.html#allocation
 		if fn.IsHost() {
-			vm.funcs[i] = goFunction{
+			funcs[i] = goFunction{
 				typ: fn.Host.Type(),
 				val: fn.Host,
 			}
@@ -130,7 +140,7 @@ func NewVM(module *wasm.Module, wasmGo interface{}) (*VM, error) {
 			totalLocalVars += int(entry.Count)
 		}
 		code, table := compile.Compile(disassembly.Code)
-		vm.funcs[i] = compiledFunction{
+		funcs[i] = compiledFunction{
 			code:           code,
 			branchTables:   table,
 			maxDepth:       disassembly.MaxDepth,
@@ -139,6 +149,44 @@ func NewVM(module *wasm.Module, wasmGo interface{}) (*VM, error) {
 			returns:        len(fn.Sig.ReturnTypes) != 0,
 		}
 	}
+	return &CompiledModule{module: module, funcs: funcs}, nil
+}
+
+// Module returns the module that was compiled.
+func (c *CompiledModule) Module() *wasm.Module {
+	return c.module
+}
+
+// NewVM creates a new VM from a given module. If the module defines a
+// start function, it will be executed.
+func NewVM(module *wasm.Module, wasmGo interface{}) (*VM, error) {
+	compiled, err := CompileModule(module)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.NewVM(wasmGo)
+}
+
+// NewVM creates a new VM with its own memory and globals from the compiled
+// module. If the module defines a start function, it will be executed.
+func (c *CompiledModule) NewVM(wasmGo interface{}) (*VM, error) {
+	var vm VM
+	module := c.module
+
+	//vm.WasmInterface = wasmInterface
+	vm.WasmGo = wasmGo
+
+	if module.Memory != nil && len(module.Memory.Entries) != 0 {
+		//module.Memory.Entries[0].Limits.Initial = 16
+		vm.memory = make([]byte, uint(module.Memory.Entries[0].Limits.Initial)*wasmPageSize)
+		//vm.memory = make([]byte, uint(15)*wasmPageSize)
+		copy(vm.memory, module.LinearMemoryIndexSpace[0])
+	}
+
+	vm.funcs = c.funcs
+	vm.globals = make([]uint64, len(module.GlobalIndexSpace))
+	vm.newFuncTable()
+	vm.module = module
 
 	for i, global := range module.GlobalIndexSpace {
 		val, err := module.ExecInitExpr(global.Init)
@@ -303,6 +351,8 @@ func (vm *VM) ExecCode(fnIndex int64, args ...uint64) (rtrn interface{}, err err
 	vm.ctx.pc = 0
 	vm.ctx.code = compiled.code
 	vm.ctx.curFunc = fnIndex
+	vm.instructions = 0
+	vm.callDepth = 0
 
 	for i, arg := range args {
 		vm.ctx.locals[i] = arg
@@ -331,13 +381,15 @@ func (vm *VM) ExecCode(fnIndex int64, args ...uint64) (rtrn interface{}, err err
 func (vm *VM) execCode(compiled compiledFunction) uint64 {
 outer:
 	for int(vm.ctx.pc) < len(vm.ctx.code) {
+		vm.meter()
 		op := vm.ctx.code[vm.ctx.pc]
 		vm.ctx.pc++
 		switch op {
 		case ops.Return:
 			break outer
 		case compile.OpJmp:
-			vm.ctx.pc = vm.fetchInt64()
+			target := vm.fetchInt64()
+			vm.jump(target)
 			continue
 		case compile.OpJmpZ:
 			target := vm.fetchInt64()
@@ -350,7 +402,7 @@ outer:
 			preserveTop := vm.fetchBool()
 			discard := vm.fetchInt64()
 			if vm.popUint32() != 0 {
-				vm.ctx.pc = target
+				vm.jump(target)
 				var top uint64
 				if preserveTop {
 					top = vm.ctx.stack[len(vm.ctx.stack)-1]
@@ -379,7 +431,7 @@ outer:
 			if target.Return {
 				break outer
 			}
-			vm.ctx.pc = target.Addr
+			vm.jump(target.Addr)
 			var top uint64
 			if target.PreserveTop {
 				top = vm.ctx.stack[len(vm.ctx.stack)-1]
@@ -0,0 +1,182 @@
+package wasmgo
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/wasmgo/wagon/disasm"
+	"github.com/eosspark/eos-go/wasmgo/wagon/validate"
+	"github.com/eosspark/eos-go/wasmgo/wagon/wasm"
+	ops "github.com/eosspark/eos-go/wasmgo/wagon/wasm/operators"
+)
+
+// Limits of a contract, see: libraries/chain/include/eosio/chain/wasm_eosio_constraints.hpp
+const (
+	MaximumLinearMemory     = 33 * 1024 * 1024 // bytes
+	MaximumLinearMemoryInit = 64 * 1024        // bytes
+	MaximumTableElements    = 1024             // elements
+	MaximumNestingDepth     = 1024             // blocks, loops and ifs in one another
+	MaximumCallDepth        = 250              // functions called in one another
+
+	wasmPageSize = 64 * 1024
+)
+
+/*
+Validate refuses the code of a contract the chain must not run, setcode calls it before the code is
+stored. The module has to pass the structural checks of the wagon validator, then the rules of eosio:
+only the registered env intrinsics are imported with their own signatures, there is no floating point
+operation, memory, data and table are bounded, blocks are not nested too deep, apply(i64,i64,i64) is
+exported and there is no start function.
+see: libraries/chain/wasm_interface.cpp validate, libraries/chain/wasm_eosio_validation.cpp
+
+A wasm_serialization_error is thrown when the code can not be read or is not well formed, a
+wasm_exception when it imports what the chain does not provide and a wasm_execution_error when it
+breaks the other rules
+*/
+func (w *WasmGo) Validate(code []byte) {
+	m, err := wasm.ReadModule(bytes.NewReader(code), nil)
+	exception.EosAssert(err == nil, &exception.WasmSerializationError{}, "wasm deserialization error: %v", err)
+	w.validateImports(m)
+
+	// read again with the intrinsics so that calls to them are checked as well
+	m, err = wasm.ReadModule(bytes.NewReader(code), w.importer)
+	exception.EosAssert(err == nil, &exception.WasmSerializationError{}, "wasm deserialization error: %v", err)
+	err = validate.VerifyModule(m)
+	exception.EosAssert(err == nil, &exception.WasmSerializationError{}, "wasm validation error: %v", err)
+
+	validateMemory(m)
+	validateDataSegments(m)
+	validateTables(m)
+	validateApplyExported(m)
+	exception.EosAssert(m.Start == nil, &exception.WasmExecutionError{}, "Smart contract can not have a start function")
+
+	for i := range m.FunctionIndexSpace {
+		if fn := m.FunctionIndexSpace[i]; !fn.IsHost() {
+			validateFunction(fn, m)
+		}
+	}
+}
+
+func (w *WasmGo) validateImports(m *wasm.Module) {
+	if m.Import == nil {
+		return
+	}
+	for _, entry := range m.Import.Entries {
+		fi, ok := entry.Type.(wasm.FuncImport)
+		exception.EosAssert(entry.ModuleName == "env" && ok, &exception.WasmException{},
+			"%s.%s unresolveable, only the functions of env can be imported", entry.ModuleName, entry.FieldName)
+
+		handler := w.GetHandle(entry.FieldName)
+		exception.EosAssert(handler != nil, &exception.WasmException{}, "%s.%s unresolveable", entry.ModuleName, entry.FieldName)
+
+		exception.EosAssert(int(fi.Type) < len(m.Types.Entries), &exception.WasmSerializationError{},
+			"%s.%s has no type %d", entry.ModuleName, entry.FieldName, fi.Type)
+		sig, host := m.Types.Entries[fi.Type], hostSig(handler)
+		exception.EosAssert(sameTypes(sig.ParamTypes, host.ParamTypes) && sameTypes(sig.ReturnTypes, host.ReturnTypes),
+			&exception.WasmException{}, "%s.%s imported as %s, the intrinsic is %s", entry.ModuleName, entry.FieldName, sig, host)
+	}
+}
+
+func validateMemory(m *wasm.Module) {
+	if m.Memory == nil {
+		return
+	}
+	for _, memory := range m.Memory.Entries {
+		exception.EosAssert(uint64(memory.Limits.Initial)*wasmPageSize <= MaximumLinearMemory, &exception.WasmExecutionError{},
+			"Smart contract initial memory size must be less than or equal to %dKiB", MaximumLinearMemory/1024)
+	}
+}
+
+func validateDataSegments(m *wasm.Module) {
+	if m.Data == nil {
+		return
+	}
+	for _, segment := range m.Data.Entries {
+		val, err := m.ExecInitExpr(segment.Offset)
+		offset, ok := val.(int32)
+		exception.EosAssert(err == nil && ok, &exception.WasmExecutionError{}, "Smart contract data segments must have an i32 offset")
+		exception.EosAssert(offset >= 0 && int64(offset)+int64(len(segment.Data)) <= MaximumLinearMemoryInit, &exception.WasmExecutionError{},
+			"Smart contract data segments must lie in first %dKiB", MaximumLinearMemoryInit/1024)
+	}
+}
+
+func validateTables(m *wasm.Module) {
+	if m.Table == nil {
+		return
+	}
+	for _, table := range m.Table.Entries {
+		exception.EosAssert(table.Limits.Initial <= MaximumTableElements, &exception.WasmExecutionError{},
+			"Smart contract table limited to %d elements", MaximumTableElements)
+	}
+}
+
+func validateApplyExported(m *wasm.Module) {
+	found := false
+	if m.Export != nil {
+		if e, ok := m.Export.Entries["apply"]; ok && e.Kind == wasm.ExternalFunction {
+			if fn := m.GetFunction(int(e.Index)); fn != nil {
+				i64 := []wasm.ValueType{wasm.ValueTypeI64, wasm.ValueTypeI64, wasm.ValueTypeI64}
+				found = sameTypes(fn.Sig.ParamTypes, i64) && len(fn.Sig.ReturnTypes) == 0
+			}
+		}
+	}
+	exception.EosAssert(found, &exception.WasmExecutionError{}, "Smart contract's apply function not exported; non-existent; or wrong type")
+}
+
+// validateFunction checks the nesting depth and that there is no floating point operation in the body of fn
+func validateFunction(fn wasm.Function, m *wasm.Module) {
+	d, err := disasm.Disassemble(fn, m)
+	exception.EosAssert(err == nil, &exception.WasmSerializationError{}, "wasm disassembly error in %s: %v", fn.Name, err)
+
+	depth := 0
+	for _, instr := range d.Code {
+		if instr.Block != nil && instr.Block.Start {
+			depth++
+			exception.EosAssert(depth <= MaximumNestingDepth, &exception.WasmExecutionError{}, "Nested depth exceeded")
+		} else if instr.Op.Code == ops.End {
+			depth--
+		}
+		exception.EosAssert(!isFloatOp(instr.Op), &exception.WasmExecutionError{},
+			"Smart contract uses the floating point operation %s", instr.Op.Name)
+	}
+}
+
+func isFloatOp(op ops.Op) bool {
+	isFloat := func(t wasm.ValueType) bool { return t == wasm.ValueTypeF32 || t == wasm.ValueTypeF64 }
+	if isFloat(op.Returns) {
+		return true
+	}
+	for _, arg := range op.Args {
+		if isFloat(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostSig is the signature of an intrinsic, its first parameter is the *WasmGo it is called with
+func hostSig(handler interface{}) wasm.FunctionSig {
+	t := reflect.TypeOf(handler)
+	args := make([]wasm.ValueType, t.NumIn()-1)
+	for i := range args {
+		args[i] = reflect2wasm(t.In(i + 1).Kind())
+	}
+	rtrns := make([]wasm.ValueType, t.NumOut())
+	for i := range rtrns {
+		rtrns[i] = reflect2wasm(t.Out(i).Kind())
+	}
+	return wasm.FunctionSig{ParamTypes: args, ReturnTypes: rtrns}
+}
+
+func sameTypes(a, b []wasm.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
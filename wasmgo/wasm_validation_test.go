@@ -0,0 +1,118 @@
+package wasmgo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/stretchr/testify/assert"
+)
+
+func leb(n int) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func vec(items ...[]byte) []byte {
+	return append(leb(len(items)), bytes.Join(items, nil)...)
+}
+
+func rawSection(id byte, payload []byte) []byte {
+	return append(append([]byte{id}, leb(len(payload))...), payload...)
+}
+
+func section(id byte, items ...[]byte) []byte {
+	return rawSection(id, vec(items...))
+}
+
+func name(s string) []byte {
+	return append(leb(len(s)), s...)
+}
+
+// body is a function without locals
+func body(code ...byte) []byte {
+	b := append(append([]byte{0x00}, code...), 0x0b)
+	return append(leb(len(b)), b...)
+}
+
+func module(sections ...[]byte) []byte {
+	return append([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}, bytes.Join(sections, nil)...)
+}
+
+var (
+	applyType = []byte{0x60, 0x03, 0x7e, 0x7e, 0x7e, 0x00} // (i64, i64, i64) -> ()
+	voidType  = []byte{0x60, 0x00, 0x00}                   // () -> ()
+
+	typeSection = section(1, applyType, voidType)
+	applyFunc   = section(3, []byte{0x00})
+	exportApply = section(7, append(name("apply"), 0x00, 0x00))
+	emptyBody   = section(10, body())
+)
+
+func importFunc(moduleName, field string, typ byte) []byte {
+	return section(2, append(append(name(moduleName), name(field)...), 0x00, typ))
+}
+
+// validateError is the code of the exception Validate throws, 0 when the code is valid
+func validateError(code []byte) (excType exception.ExcTypes) {
+	try.Try(func() {
+		NewWasmGo().Validate(code)
+	}).Catch(func(e exception.Exception) {
+		excType = e.Code()
+	}).End()
+	return
+}
+
+func TestValidate(t *testing.T) {
+	wasmException := exception.WasmException{}.Code()
+	executionError := exception.WasmExecutionError{}.Code()
+	serializationError := exception.WasmSerializationError{}.Code()
+
+	nested := append(bytes.Repeat([]byte{0x02, 0x40}, MaximumNestingDepth+1), bytes.Repeat([]byte{0x0b}, MaximumNestingDepth+1)...)
+
+	tests := []struct {
+		name string
+		code []byte
+		err  exception.ExcTypes
+	}{
+		{"valid", module(typeSection, applyFunc, exportApply, emptyBody), 0},
+		{"intrinsic", module(typeSection, importFunc("env", "checktime", 1), applyFunc,
+			section(7, append(name("apply"), 0x00, 0x01)), section(10, body(0x10, 0x00))), 0},
+		{"garbage", []byte("not wasm"), serializationError},
+		{"malformed", module(typeSection, applyFunc, exportApply, section(10, body(0x1a))), serializationError},
+		{"other module", module(typeSection, importFunc("libc", "checktime", 1), applyFunc, exportApply, emptyBody), wasmException},
+		{"unknown intrinsic", module(typeSection, importFunc("env", "no_such_intrinsic", 1), applyFunc, exportApply, emptyBody), wasmException},
+		{"intrinsic signature", module(typeSection, importFunc("env", "checktime", 0), applyFunc, exportApply, emptyBody), wasmException},
+		{"no apply", module(typeSection, applyFunc, section(7, append(name("main"), 0x00, 0x00)), emptyBody), executionError},
+		{"apply type", module(typeSection, section(3, []byte{0x01}), exportApply, emptyBody), executionError},
+		{"start", module(typeSection, section(3, []byte{0x00}, []byte{0x01}), exportApply, rawSection(8, []byte{0x01}),
+			section(10, body(), body())), executionError},
+		{"float", module(typeSection, applyFunc, exportApply, section(10, body(0x43, 0x00, 0x00, 0x80, 0x3f, 0x1a))), executionError},
+		{"memory", module(typeSection, applyFunc, section(5, append([]byte{0x00}, leb(MaximumLinearMemory/wasmPageSize)...)),
+			exportApply, emptyBody), 0},
+		{"memory too large", module(typeSection, applyFunc, section(5, append([]byte{0x00}, leb(MaximumLinearMemory/wasmPageSize+1)...)),
+			exportApply, emptyBody), executionError},
+		{"data too far", module(typeSection, applyFunc, section(5, []byte{0x00, 0x02}), exportApply, emptyBody,
+			section(11, []byte{0x00, 0x41, 0xff, 0xff, 0x03, 0x0b, 0x02, 0x01, 0x02})), executionError},
+		{"table too large", module(typeSection, applyFunc, section(4, append([]byte{0x70, 0x00}, leb(MaximumTableElements+1)...)),
+			exportApply, emptyBody), executionError},
+		{"nested too deep", module(typeSection, applyFunc, exportApply, section(10, body(nested...))), executionError},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.err, validateError(test.code), test.name)
+	}
+
+	hello, err := ioutil.ReadFile("testdata_context/hello.wasm")
+	assert.NoError(t, err)
+	assert.Equal(t, exception.ExcTypes(0), validateError(hello))
+}
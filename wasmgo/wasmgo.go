@@ -7,7 +7,7 @@ import (
 	"github.com/eosspark/eos-go/crypto"
 	"github.com/eosspark/eos-go/crypto/rlp"
 	"github.com/eosspark/eos-go/exception"
-	"log"
+	"github.com/eosspark/eos-go/exception/try"
 	"reflect"
 
 	"github.com/eosspark/eos-go/wasmgo/wagon/exec"
@@ -15,219 +15,298 @@ import (
 )
 
 var (
-	wasmGo *WasmGo
 	ignore bool = false
 )
 
 type size_t int
 
+/*
+WasmGo runs the contracts. The one created by NewWasmGo only holds what its executions share, Apply runs
+every action on an execution of its own: a WasmGo with the VM, the memory and the context of the action,
+that the intrinsics are called with. Applies on different contexts can therefore run at the same time,
+such as read only calls while a block is produced
+*/
 type WasmGo struct {
+	*shared
+
 	context EnvContext
-	handles map[string]interface{}
 	vm      *exec.VM
 }
 
+// shared is what a WasmGo and its executions have in common, it is safe for concurrent use
+type shared struct {
+	codeCache        *codeCache
+	instructionLimit uint64
+}
+
 func NewWasmGo() *WasmGo {
+	return &WasmGo{shared: &shared{codeCache: newCodeCache(DefaultCodeCacheSize)}}
+}
 
-	if wasmGo != nil {
-		return wasmGo
-	}
+// execution is the WasmGo an action runs on, it has its own context and VM
+func (w *WasmGo) execution(context EnvContext) *WasmGo {
+	return &WasmGo{shared: w.shared, context: context}
+}
+
+// hostFunctions are the intrinsics contracts import from env, the table is built once when the package is
+// initialised and never modified afterwards, every execution reads it
+type hostFunctions struct {
+	handles map[string]interface{}
+	env     *wasm.Module
+}
 
-	w := WasmGo{handles: make(map[string]interface{})}
-
-	w.Register("action_data_size", actionDataSize)
-	w.Register("read_action_data", readActionData)
-	w.Register("current_receiver", currentReceiver)
-
-	w.Register("require_auth", requireAuthorization)
-	w.Register("has_auth", hasAuthorization)
-	w.Register("require_auth2", requireAuth2)
-	w.Register("require_recipient", requireRecipient)
-	w.Register("is_account", isAccount)
-
-	w.Register("prints", prints)
-	w.Register("prints_l", printsl)
-	w.Register("printi", printi)
-	w.Register("printui", printui)
-	w.Register("printi128", printi128)
-	w.Register("printui128", printui128)
-	w.Register("printsf", printsf)
-	w.Register("printdf", printdf)
-	w.Register("printqf", printqf)
-	w.Register("printn", printn)
-	w.Register("printhex", printhex)
-
-	w.Register("assert_recover_key", assertRecoverKey)
-	w.Register("recover_key", recoverKey)
-	w.Register("assert_sha256", assertSha256)
-	w.Register("assert_sha1", assertSha1)
-	w.Register("assert_sha256", assertSha256)
-	w.Register("assert_sha512", assertSha512)
-	w.Register("assert_ripemd160", assertRipemd160)
-	w.Register("sha1", sha1)
-	w.Register("sha256", sha256)
-	w.Register("sha512", sha512)
-	w.Register("ripemd160", ripemd160)
-
-	w.Register("db_store_i64", dbStoreI64)
-	w.Register("db_update_i64", dbUpdateI64)
-	w.Register("db_remove_i64", dbRemoveI64)
-	w.Register("db_get_i64", dbGetI64)
-	w.Register("db_next_i64", dbNextI64)
-	w.Register("db_previous_i64", dbPreviousI64)
-	w.Register("db_find_i64", dbFindI64)
-	w.Register("db_lowerbound_i64", dbLowerboundI64)
-	w.Register("db_upperbound_i64", dbUpperboundI64)
-	w.Register("db_end_i64", dbEndI64)
-
-	w.Register("db_idx64_store", dbIdx64Store)
-	w.Register("db_idx64_remove", dbIdx64Remove)
-	w.Register("db_idx64_update", dbIdx64Update)
-	w.Register("db_idx64_find_secondary", dbIdx64findSecondary)
-	w.Register("db_idx64_lowerbound", dbIdx64Lowerbound)
-	w.Register("db_idx64_upperbound", dbIdx64Upperbound)
-	w.Register("db_idx64_end", dbIdx64End)
-	w.Register("db_idx64_next", dbIdx64Next)
-	w.Register("db_idx64_previous", dbIdx64Previous)
-	w.Register("db_idx64_find_primary", dbIdx64FindPrimary)
-
-	w.Register("db_idx_double_store", dbIdxDoubleStore)
-	w.Register("db_idx_double_remove", dbIdxDoubleRemove)
-	w.Register("db_idx_double_update", dbIdxDoubleUpdate)
-	w.Register("db_idx_double_find_secondary", dbIdxDoublefindSecondary)
-	w.Register("db_idx_double_lowerbound", dbIdxDoubleLowerbound)
-	w.Register("db_idx_double_upperbound", dbIdxDoubleUpperbound)
-	w.Register("db_idx_double_end", dbIdxDoubleEnd)
-	w.Register("db_idx_double_next", dbIdxDoubleNext)
-	w.Register("db_idx_double_previous", dbIdxDoublePrevious)
-	w.Register("db_idx_double_find_primary", dbIdxDoubleFindPrimary)
-
-	w.Register("memcpy", memcpy)
-	w.Register("memmove", memmove)
-	w.Register("memcmp", memcmp)
-	w.Register("memset", memset)
-	w.Register("free", free)
-
-	w.Register("check_transaction_authorization", checkTransactionAuthorization)
-	w.Register("check_permission_authorization", checkPermissionAuthorization)
-	w.Register("get_permission_last_used", getPermissionLastUsed)
-	w.Register("get_account_creation_time", getAccountCreationTime)
-
-	w.Register("is_feature_active", isFeatureActive)
-	w.Register("activate_feature", activateFeature)
-	w.Register("set_resource_limits", setResourceLimits)
-	w.Register("get_resource_limits", getResourceLimits)
-	w.Register("get_blockchain_parameters_packed", getBlockchainParametersPacked)
-	w.Register("set_blockchain_parameters_packed", setBlockchainParametersPacked)
-	w.Register("is_privileged", isPrivileged)
-	w.Register("set_privileged", setPrivileged)
-
-	w.Register("set_proposed_producers", setProposedProducers)
-	w.Register("get_active_producers", getActiveProducers)
-
-	w.Register("checktime", checkTime)
-	w.Register("current_time", currentTime)
-	w.Register("publication_time", publicationTime)
-	w.Register("abort", abort)
-	w.Register("eosio_assert", eosioAssert)
-	w.Register("eosio_assert_message", eosioAssertMessage)
-	w.Register("eosio_assert_code", eosioAssertCode)
-	w.Register("eosio_exit", eosioExit)
-
-	w.Register("send_inline", sendInline)
-	w.Register("send_context_free_inline", sendContextFreeInline)
-	w.Register("send_deferred", sendDeferred)
-	w.Register("cancel_deferred", cancelDeferred)
-	w.Register("read_transaction", readTransaction)
-	w.Register("transaction_size", transactionSize)
-	w.Register("expiration", expiration)
-	w.Register("tapos_block_num", taposBlockNum)
-	w.Register("tapos_block_prefix", taposBlockPrefix)
-	w.Register("get_action", getAction)
-	w.Register("get_context_free_data", getContextFreeData)
-
-	wasmGo = &w
-
-	return wasmGo
+var intrinsics = newHostFunctions()
+
+func newHostFunctions() *hostFunctions {
+	h := &hostFunctions{handles: make(map[string]interface{})}
+
+	h.register("action_data_size", actionDataSize)
+	h.register("read_action_data", readActionData)
+	h.register("current_receiver", currentReceiver)
+
+	h.register("require_auth", requireAuthorization)
+	h.register("has_auth", hasAuthorization)
+	h.register("require_auth2", requireAuth2)
+	h.register("require_recipient", requireRecipient)
+	h.register("is_account", isAccount)
+
+	h.register("prints", prints)
+	h.register("prints_l", printsl)
+	h.register("printi", printi)
+	h.register("printui", printui)
+	h.register("printi128", printi128)
+	h.register("printui128", printui128)
+	h.register("printsf", printsf)
+	h.register("printdf", printdf)
+	h.register("printqf", printqf)
+	h.register("printn", printn)
+	h.register("printhex", printhex)
+
+	h.register("assert_recover_key", assertRecoverKey)
+	h.register("recover_key", recoverKey)
+	h.register("assert_sha256", assertSha256)
+	h.register("assert_sha1", assertSha1)
+	h.register("assert_sha256", assertSha256)
+	h.register("assert_sha512", assertSha512)
+	h.register("assert_ripemd160", assertRipemd160)
+	h.register("sha1", sha1)
+	h.register("sha256", sha256)
+	h.register("sha512", sha512)
+	h.register("ripemd160", ripemd160)
+
+	h.register("db_store_i64", dbStoreI64)
+	h.register("db_update_i64", dbUpdateI64)
+	h.register("db_remove_i64", dbRemoveI64)
+	h.register("db_get_i64", dbGetI64)
+	h.register("db_next_i64", dbNextI64)
+	h.register("db_previous_i64", dbPreviousI64)
+	h.register("db_find_i64", dbFindI64)
+	h.register("db_lowerbound_i64", dbLowerboundI64)
+	h.register("db_upperbound_i64", dbUpperboundI64)
+	h.register("db_end_i64", dbEndI64)
+
+	h.register("db_idx64_store", dbIdx64Store)
+	h.register("db_idx64_remove", dbIdx64Remove)
+	h.register("db_idx64_update", dbIdx64Update)
+	h.register("db_idx64_find_secondary", dbIdx64findSecondary)
+	h.register("db_idx64_lowerbound", dbIdx64Lowerbound)
+	h.register("db_idx64_upperbound", dbIdx64Upperbound)
+	h.register("db_idx64_end", dbIdx64End)
+	h.register("db_idx64_next", dbIdx64Next)
+	h.register("db_idx64_previous", dbIdx64Previous)
+	h.register("db_idx64_find_primary", dbIdx64FindPrimary)
+
+	h.register("db_idx_double_store", dbIdxDoubleStore)
+	h.register("db_idx_double_remove", dbIdxDoubleRemove)
+	h.register("db_idx_double_update", dbIdxDoubleUpdate)
+	h.register("db_idx_double_find_secondary", dbIdxDoublefindSecondary)
+	h.register("db_idx_double_lowerbound", dbIdxDoubleLowerbound)
+	h.register("db_idx_double_upperbound", dbIdxDoubleUpperbound)
+	h.register("db_idx_double_end", dbIdxDoubleEnd)
+	h.register("db_idx_double_next", dbIdxDoubleNext)
+	h.register("db_idx_double_previous", dbIdxDoublePrevious)
+	h.register("db_idx_double_find_primary", dbIdxDoubleFindPrimary)
+
+	h.register("memcpy", memcpy)
+	h.register("memmove", memmove)
+	h.register("memcmp", memcmp)
+	h.register("memset", memset)
+	h.register("free", free)
+
+	h.register("check_transaction_authorization", checkTransactionAuthorization)
+	h.register("check_permission_authorization", checkPermissionAuthorization)
+	h.register("get_permission_last_used", getPermissionLastUsed)
+	h.register("get_account_creation_time", getAccountCreationTime)
+
+	h.register("is_feature_active", isFeatureActive)
+	h.register("activate_feature", activateFeature)
+	h.register("set_resource_limits", setResourceLimits)
+	h.register("get_resource_limits", getResourceLimits)
+	h.register("get_blockchain_parameters_packed", getBlockchainParametersPacked)
+	h.register("set_blockchain_parameters_packed", setBlockchainParametersPacked)
+	h.register("is_privileged", isPrivileged)
+	h.register("set_privileged", setPrivileged)
+
+	h.register("set_proposed_producers", setProposedProducers)
+	h.register("get_active_producers", getActiveProducers)
+
+	h.register("checktime", checkTime)
+	h.register("current_time", currentTime)
+	h.register("publication_time", publicationTime)
+	h.register("abort", abort)
+	h.register("eosio_assert", eosioAssert)
+	h.register("eosio_assert_message", eosioAssertMessage)
+	h.register("eosio_assert_code", eosioAssertCode)
+	h.register("eosio_exit", eosioExit)
+
+	h.register("send_inline", sendInline)
+	h.register("send_context_free_inline", sendContextFreeInline)
+	h.register("send_deferred", sendDeferred)
+	h.register("cancel_deferred", cancelDeferred)
+	h.register("read_transaction", readTransaction)
+	h.register("transaction_size", transactionSize)
+	h.register("expiration", expiration)
+	h.register("tapos_block_num", taposBlockNum)
+	h.register("tapos_block_prefix", taposBlockPrefix)
+	h.register("get_action", getAction)
+	h.register("get_context_free_data", getContextFreeData)
+
+	h.env = h.module()
+	return h
 }
 
-func (w *WasmGo) Apply(code_id *crypto.Sha256, code []byte, context EnvContext) {
-	w.context = context
+/*
+Apply runs the apply function of the contract for the action of context. A contract that can not be read
+or instantiated and the traps of the VM (out of bounds memory access, unreachable, call stack exhausted...)
+are thrown as wasm exceptions through try, the transaction fails and is rolled back, the node keeps running
+*/
+func (w *WasmGo) Apply(code_id *crypto.Sha256, vmType, vmVersion uint8, code []byte, context EnvContext) {
+	module := w.compiledModule(code_id, vmType, vmVersion, code)
+	apply, ok := module.Module().Export.Entries["apply"]
+	exception.EosAssert(ok && apply.Kind == wasm.ExternalFunction, &exception.WasmExecutionError{},
+		"Smart contract's apply function not exported")
+
+	exe := w.execution(context)
+	try.Try(func() {
+		vm, err := module.NewVM(exe)
+		exception.EosAssert(err == nil, &exception.WasmExecutionError{}, "could not create VM: %v", err)
+
+		exe.vm = vm
+		vm.CheckTime = context.CheckTime
+		vm.InstructionLimit = w.instructionLimit
+		vm.MaxCallDepth = MaximumCallDepth
+
+		_, err = vm.ExecCode(int64(apply.Index), uint64(context.GetReceiver()), uint64(context.GetCode()), uint64(context.GetAct()))
+		exception.EosAssert(err == nil, &exception.WasmExecutionError{}, "%v", err)
+	}).Catch(func(e exception.Exception) {
+		try.Throw(e) // deadline_exception from checktime, eosio_assert...
+	}).Catch(func(e error) {
+		exception.EosThrow(&exception.WasmExecutionError{}, "%s", e.Error())
+	}).Catch(func(e interface{}) {
+		exception.EosThrow(&exception.WasmExecutionError{}, "%v", e)
+	}).End()
+}
+
+// compiledModule returns the code compiled from the cache, the code is read and compiled when it is not there.
+// code_id is the sha256 digest of the code, it is computed when nil
+func (w *WasmGo) compiledModule(code_id *crypto.Sha256, vmType, vmVersion uint8, code []byte) *exec.CompiledModule {
+	if code_id == nil {
+		h := crypto.NewSha256()
+		h.Write(code)
+		code_id = crypto.NewSha256Byte(h.Sum(nil))
+	}
+	key := codeKey{codeId: *code_id, vmType: vmType, vmVersion: vmVersion}
+	if module := w.codeCache.get(key); module != nil {
+		return module
+	}
 
 	bf := bytes.NewReader(code)
 
 	m, err := wasm.ReadModule(bf, w.importer)
-	if err != nil {
-		log.Fatalf("could not read module: %v", err)
-	}
+	exception.EosAssert(err == nil, &exception.WasmSerializationError{}, "could not read module: %v", err)
 
-	// if *verify {
-	// 	err = validate.VerifyModule(m)
-	// 	if err != nil {
-	// 		log.Fatalf("could not verify module: %v", err)
-	// 	}
-	// }
+	// the code is verified by Validate when it is set, see: applyEosioSetcode
 
-	if m.Export == nil {
-		log.Fatalf("module has no export section")
-	}
+	exception.EosAssert(m.Export != nil, &exception.WasmExecutionError{}, "module has no export section")
 
-	vm, err := exec.NewVM(m, w)
-	if err != nil {
-		log.Fatalf("could not create VM: %v", err)
-	}
+	module, err := exec.CompileModule(m)
+	exception.EosAssert(err == nil, &exception.WasmSerializationError{}, "could not compile module: %v", err)
+	w.codeCache.put(key, module)
+	return module
+}
 
-	e, _ := m.Export.Entries["apply"]
-	i := int64(e.Index)
-	//fidx := m.Function.Types[int(i)]
-	//ftype := m.Types.Entries[int(fidx)]
+// SetInstructionLimit sets the number of instructions an action may execute, 0 does not meter them.
+// Running over the limit aborts the action with wasm_execution_error
+func (w *WasmGo) SetInstructionLimit(limit uint64) {
+	w.instructionLimit = limit
+}
 
-	w.vm = vm
+// SetCodeCacheSize sets the number of compiled contracts kept, the least recently used ones are evicted
+func (w *WasmGo) SetCodeCacheSize(size int) {
+	w.codeCache.resize(size)
+}
 
-	args := make([]uint64, 3)
-	args[0] = uint64(context.GetReceiver())
-	args[1] = uint64(context.GetCode())
-	args[2] = uint64(context.GetAct())
+// InvalidateCode drops the compiled code of code_id, setcode calls it for the code it replaces
+func (w *WasmGo) InvalidateCode(code_id crypto.Sha256) {
+	w.codeCache.invalidate(code_id)
+}
 
-	o, err := vm.ExecCode(i, args[0], args[1], args[2])
-	if err != nil {
-		fmt.Printf("\n")
-		log.Printf("err=%v", err)
-	}
-	//if len(ftype.ReturnTypes) == 0 {
-	//	fmt.Printf("\n")
-	//}
-	if o != nil {
-		fmt.Printf("%[1]v (%[1]T)\n", o)
-	}
+// CodeCacheMetrics are the hits, misses and evictions of the compiled contract cache
+func (w *WasmGo) CodeCacheMetrics() CodeCacheMetrics {
+	return w.codeCache.stats()
 }
 
-func (w *WasmGo) Register(name string, handler interface{}) bool {
-	if _, ok := w.handles[name]; ok {
+func (h *hostFunctions) register(name string, handler interface{}) bool {
+	if _, ok := h.handles[name]; ok {
 		return false
 	}
 
-	w.handles[name] = handler
+	h.handles[name] = handler
 	return true
 }
 
-func (w *WasmGo) Add(handles map[string]interface{}) bool {
-	for k, v := range handles {
-		if _, ok := w.handles[k]; !ok {
-			w.handles[k] = v
+// module is the env module of the intrinsics that contracts are linked against
+func (h *hostFunctions) module() *wasm.Module {
+	count := len(h.handles)
+
+	m := wasm.NewModule()
+	m.Types.Entries = make([]wasm.FunctionSig, count)
+	m.FunctionIndexSpace = make([]wasm.Function, count)
+	m.Export.Entries = make(map[string]wasm.ExportEntry, count)
+
+	i := 0
+	for k, v := range h.handles {
+
+		m.Types.Entries[i] = hostSig(v)
+
+		m.FunctionIndexSpace[i] = wasm.Function{
+			Sig:  &m.Types.Entries[i],
+			Host: reflect.ValueOf(v),
+			Body: &wasm.FunctionBody{},
+			Name: k,
 		}
+
+		m.Export.Entries[k] = wasm.ExportEntry{
+			FieldStr: k,
+			Kind:     wasm.ExternalFunction,
+			Index:    uint32(i),
+		}
+
+		i++
+
 	}
-	return true
+
+	return m
 }
 
 func (w *WasmGo) GetHandles() map[string]interface{} {
-	return w.handles
+	return intrinsics.handles
 }
 
 func (w *WasmGo) GetHandle(name string) interface{} {
 
-	if _, ok := w.handles[name]; ok {
-		return w.handles[name]
+	if _, ok := intrinsics.handles[name]; ok {
+		return intrinsics.handles[name]
 	}
 
 	return nil
@@ -236,55 +315,7 @@ func (w *WasmGo) GetHandle(name string) interface{} {
 func (w *WasmGo) importer(name string) (*wasm.Module, error) {
 
 	if name == "env" {
-
-		count := len(w.handles)
-
-		m := wasm.NewModule()
-		m.Types.Entries = make([]wasm.FunctionSig, count)
-		m.FunctionIndexSpace = make([]wasm.Function, count)
-		m.Export.Entries = make(map[string]wasm.ExportEntry, count)
-
-		i := 0
-		for k, v := range w.handles {
-
-			// 1st param is *wasm_interface should be deleted
-			numIn := reflect.TypeOf(v).NumIn() - 1
-			args := make([]wasm.ValueType, numIn)
-			for j := int(0); j < numIn; j++ {
-				args[j] = reflect2wasm(reflect.TypeOf(v).In(j + 1).Kind())
-			}
-
-			numOut := reflect.TypeOf(v).NumOut()
-			rtrns := make([]wasm.ValueType, numOut)
-			for m := int(0); m < numOut; m++ {
-				rtrns[m] = reflect2wasm(reflect.TypeOf(v).Out(m).Kind())
-			}
-
-			m.Types.Entries[i] = wasm.FunctionSig{
-				//Form:        0,
-				ParamTypes:  args,
-				ReturnTypes: rtrns,
-			}
-
-			m.FunctionIndexSpace[i] = wasm.Function{
-				Sig:  &m.Types.Entries[i],
-				Host: reflect.ValueOf(v),
-				Body: &wasm.FunctionBody{},
-				Name: k,
-			}
-
-			m.Export.Entries[k] = wasm.ExportEntry{
-				FieldStr: k,
-				Kind:     wasm.ExternalFunction,
-				Index:    uint32(i),
-			}
-
-			i++
-
-		}
-
-		return m, nil
-
+		return intrinsics.env, nil
 	}
 
 	return nil, errors.New("Only env module availible")
@@ -328,9 +359,9 @@ func reflect2wasm(kind reflect.Kind) wasm.ValueType {
 		return wasm.ValueTypeF64
 	case reflect.Float32:
 		return wasm.ValueTypeF32
-	case reflect.Uint, reflect.Uint32, reflect.Uint64:
-		return wasm.ValueTypeI32
-	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Struct:
+	case reflect.Uint64, reflect.Int64:
+		return wasm.ValueTypeI64
+	case reflect.Uint, reflect.Uint32, reflect.Int, reflect.Int32, reflect.Struct:
 		return wasm.ValueTypeI32
 	case reflect.Ptr:
 		return wasm.ValueTypeI64
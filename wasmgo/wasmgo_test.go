@@ -0,0 +1,99 @@
+package wasmgo_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/eosspark/eos-go/chain"
+	"github.com/eosspark/eos-go/chain/types"
+	"github.com/eosspark/eos-go/common"
+	"github.com/eosspark/eos-go/exception"
+	"github.com/eosspark/eos-go/exception/try"
+	"github.com/eosspark/eos-go/wasmgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// contract exports apply(i64,i64,i64) with the given memory section and code section
+func contract(memory []byte, code ...byte) []byte {
+	m := []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+		0x01, 0x07, 0x01, 0x60, 0x03, 0x7e, 0x7e, 0x7e, 0x00,
+		0x03, 0x02, 0x01, 0x00,
+	}
+	m = append(m, memory...)
+	m = append(m, 0x07, 0x09, 0x01, 0x05, 'a', 'p', 'p', 'l', 'y', 0x00, 0x00)
+	return append(m, code...)
+}
+
+// applyError is the code of the exception Apply throws, 0 when the contract runs
+func applyError(code []byte) (excType exception.ExcTypes) {
+	applyContext := &chain.ApplyContext{
+		Receiver: common.AccountName(common.N("trap")),
+		Act: &types.Action{
+			Account: common.AccountName(common.N("trap")),
+			Name:    common.ActionName(common.N("trap")),
+		},
+	}
+	try.Try(func() {
+		wasmgo.NewWasmGo().Apply(nil, 0, 0, code, applyContext)
+	}).Catch(func(e exception.Exception) {
+		excType = e.Code()
+	}).End()
+	return
+}
+
+func TestApplyTraps(t *testing.T) {
+	executionError := exception.WasmExecutionError{}.Code()
+
+	// apply() { }
+	assert.Equal(t, exception.ExcTypes(0), applyError(contract(nil, 0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b)))
+
+	// apply() { unreachable }
+	assert.Equal(t, executionError, applyError(contract(nil, 0x0a, 0x05, 0x01, 0x03, 0x00, 0x00, 0x0b)))
+
+	// apply(a, b, c) { apply(a, b, c) }
+	assert.Equal(t, executionError, applyError(contract(nil,
+		0x0a, 0x0c, 0x01, 0x0a, 0x00, 0x20, 0x00, 0x20, 0x01, 0x20, 0x02, 0x10, 0x00, 0x0b)))
+
+	// apply() { i32.load(-1) }
+	assert.Equal(t, executionError, applyError(contract([]byte{0x05, 0x03, 0x01, 0x00, 0x01},
+		0x0a, 0x0a, 0x01, 0x08, 0x00, 0x41, 0x7f, 0x28, 0x02, 0x00, 0x1a, 0x0b)))
+
+	assert.Equal(t, exception.WasmSerializationError{}.Code(), applyError([]byte("not wasm")))
+}
+
+func TestApplyConcurrently(t *testing.T) {
+	code, err := ioutil.ReadFile("testdata_context/hello.wasm")
+	assert.NoError(t, err)
+
+	wasm := wasmgo.NewWasmGo()
+	names := []string{"alice", "bob", "carol", "dave", "walker"}
+	outputs := make([]string, len(names)*10)
+
+	var wg sync.WaitGroup
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := make([]byte, 8)
+			binary.LittleEndian.PutUint64(data, common.N(names[i%len(names)]))
+			applyContext := &chain.ApplyContext{
+				Receiver: common.AccountName(common.N("hello")),
+				Act: &types.Action{
+					Account: common.AccountName(common.N("hello")),
+					Name:    common.ActionName(common.N("hi")),
+					Data:    data,
+				},
+			}
+			wasm.Apply(nil, 0, 0, code, applyContext)
+			outputs[i] = applyContext.PendingConsoleOutput
+		}(i)
+	}
+	wg.Wait()
+
+	for i, output := range outputs {
+		assert.Equal(t, "Hello, "+names[i%len(names)], output)
+	}
+}